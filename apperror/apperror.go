@@ -0,0 +1,133 @@
+package apperror
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"product-go-api/model"
+)
+
+// Kind classifies an error by what the client can do about it, and decides
+// the status and problem type it is reported with.
+type Kind string
+
+const (
+	KindValidation      Kind = "validation"
+	KindUnauthorized    Kind = "unauthorized"
+	KindForbidden       Kind = "forbidden"
+	KindNotFound        Kind = "not-found"
+	KindConflict        Kind = "conflict"
+	KindTooManyRequests Kind = "too-many-requests"
+	KindTimeout         Kind = "timeout"
+	KindInternal        Kind = "internal"
+)
+
+var kinds = map[Kind]struct {
+	status int
+	title  string
+}{
+	KindValidation:      {http.StatusBadRequest, "Invalid request"},
+	KindUnauthorized:    {http.StatusUnauthorized, "Unauthorized"},
+	KindForbidden:       {http.StatusForbidden, "Forbidden"},
+	KindNotFound:        {http.StatusNotFound, "Not found"},
+	KindConflict:        {http.StatusConflict, "Conflict"},
+	KindTooManyRequests: {http.StatusTooManyRequests, "Too many requests"},
+	KindTimeout:         {http.StatusGatewayTimeout, "Request timed out"},
+	KindInternal:        {http.StatusInternalServerError, "Internal server error"},
+}
+
+// Error is an error meant for the client. Detail and Fields are shown to the
+// client; Err is the underlying cause, which is only logged.
+type Error struct {
+	Kind   Kind
+	Detail string
+	Fields []model.FieldError
+	Err    error
+}
+
+func (e *Error) Error() string {
+	if e.Err != nil {
+		return e.Detail + ": " + e.Err.Error()
+	}
+	return e.Detail
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// Wrap returns a copy of e caused by err, so errors.Is matches both.
+func (e *Error) Wrap(err error) *Error {
+	wrapped := *e
+	wrapped.Err = err
+	return &wrapped
+}
+
+func Validation(detail string, fields ...model.FieldError) *Error {
+	return &Error{Kind: KindValidation, Detail: detail, Fields: fields}
+}
+
+func Unauthorized(detail string) *Error {
+	return &Error{Kind: KindUnauthorized, Detail: detail}
+}
+
+func Forbidden(detail string) *Error {
+	return &Error{Kind: KindForbidden, Detail: detail}
+}
+
+func NotFound(detail string) *Error {
+	return &Error{Kind: KindNotFound, Detail: detail}
+}
+
+func Conflict(detail string) *Error {
+	return &Error{Kind: KindConflict, Detail: detail}
+}
+
+func TooManyRequests(detail string) *Error {
+	return &Error{Kind: KindTooManyRequests, Detail: detail}
+}
+
+// Internal reports an unexpected failure with a detail safe to show, such as
+// "Failed to retrieve products.", keeping err for the logs.
+func Internal(detail string, err error) *Error {
+	return &Error{Kind: KindInternal, Detail: detail, Err: err}
+}
+
+// Field builds the error of one request field.
+func Field(field, message string) model.FieldError {
+	return model.FieldError{Field: field, Message: message}
+}
+
+// From returns err as an *Error. Errors without a kind are internal, except
+// queries that ran past their deadline, which are reported as timeouts so
+// clients can tell a slow database from a failure.
+func From(err error) *Error {
+	var appErr *Error
+	if errors.As(err, &appErr) && appErr.Kind != KindInternal {
+		return appErr
+	}
+	if errors.Is(err, context.DeadlineExceeded) {
+		return &Error{Kind: KindTimeout, Detail: "The request timed out.", Err: err}
+	}
+	if appErr != nil {
+		return appErr
+	}
+	return Internal("An unexpected error occurred.", err)
+}
+
+// Problem renders err as an RFC 7807 problem. Problem types are relative URIs
+// such as /problems/not-found.
+func Problem(err error) model.Problem {
+	appErr := From(err)
+	kind, ok := kinds[appErr.Kind]
+	if !ok {
+		kind = kinds[KindInternal]
+	}
+	return model.Problem{
+		Type:   "/problems/" + string(appErr.Kind),
+		Title:  kind.title,
+		Status: kind.status,
+		Detail: appErr.Detail,
+		Errors: appErr.Fields,
+	}
+}
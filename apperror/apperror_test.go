@@ -0,0 +1,51 @@
+package apperror
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+)
+
+func TestProblem(t *testing.T) {
+	errNotFound := NotFound("Order not found")
+	errCause := errors.New("connection reset")
+
+	tests := []struct {
+		name   string
+		err    error
+		status int
+		kind   Kind
+		detail string
+	}{
+		{"application error", errNotFound, http.StatusNotFound, KindNotFound, "Order not found"},
+		{"wrapped application error", fmt.Errorf("checkout: %w", errNotFound), http.StatusNotFound, KindNotFound, "Order not found"},
+		{"internal error", Internal("Failed to retrieve orders.", errCause), http.StatusInternalServerError, KindInternal, "Failed to retrieve orders."},
+		{"plain error", errCause, http.StatusInternalServerError, KindInternal, "An unexpected error occurred."},
+		{"deadline", Internal("Failed to retrieve orders.", context.DeadlineExceeded), http.StatusGatewayTimeout, KindTimeout, "The request timed out."},
+	}
+
+	for _, tt := range tests {
+		problem := Problem(tt.err)
+		if problem.Status != tt.status || problem.Type != "/problems/"+string(tt.kind) || problem.Detail != tt.detail {
+			t.Errorf("%s: problem = %+v", tt.name, problem)
+		}
+	}
+}
+
+func TestWrapKeepsBothErrors(t *testing.T) {
+	errCause := errors.New("insufficient stock")
+	errConflict := Conflict("Insufficient stock.")
+
+	err := errConflict.Wrap(errCause)
+	if !errors.Is(err, errCause) {
+		t.Fatal("wrapped error does not match its cause")
+	}
+	if errConflict.Err != nil {
+		t.Fatal("Wrap modified the original error")
+	}
+	if got := Problem(err).Detail; got != "Insufficient stock." {
+		t.Fatalf("detail = %q, want the client message only", got)
+	}
+}
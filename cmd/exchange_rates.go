@@ -0,0 +1,54 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"os"
+	"product-go-api/repository"
+	"product-go-api/usecase"
+	"time"
+)
+
+const exchangeRatesUsage = "usage: main exchange-rates load <file.csv>|list"
+
+// runExchangeRates handles the "exchange-rates" subcommand and returns the
+// process exit code. Rates are loaded offline from a CSV file, replacing the
+// whole rate table.
+func runExchangeRates(connection *sql.DB, queryTimeout time.Duration, args []string) int {
+	rates := usecase.NewExchangeRateUsecase(repository.NewExchangeRateRepository(connection, queryTimeout))
+	ctx := context.Background()
+
+	switch {
+	case len(args) == 2 && args[0] == "load":
+		file, err := os.Open(args[1])
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 1
+		}
+		defer file.Close()
+
+		loaded, err := rates.LoadExchangeRates(ctx, file)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 1
+		}
+		fmt.Printf("loaded %d exchange rates\n", loaded)
+
+	case len(args) == 1 && args[0] == "list":
+		list, err := rates.GetExchangeRates(ctx)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 1
+		}
+		for _, rate := range list {
+			fmt.Printf("%s %-26s %s\n", rate.Currency, rate.Rate, rate.UpdatedAt.Format("2006-01-02 15:04:05"))
+		}
+
+	default:
+		fmt.Fprintln(os.Stderr, exchangeRatesUsage)
+		return 2
+	}
+
+	return 0
+}
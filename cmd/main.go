@@ -1,63 +1,148 @@
 package main
 
 import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
 	"os"
-	"product-go-api/controller"
+	"os/signal"
+	"product-go-api/config"
 	"product-go-api/db"
+	"product-go-api/logging"
+	"product-go-api/metrics"
 	"product-go-api/middleware"
 	"product-go-api/repository"
+	"product-go-api/tracing"
 	"product-go-api/usecase"
-
-	"github.com/gin-contrib/cors"
-	"github.com/gin-gonic/gin"
-	"github.com/joho/godotenv"
+	"sync/atomic"
+	"syscall"
+	"time"
 )
 
 func main() {
-	godotenv.Load()
-	server := gin.Default()
-	server.Use(cors.New(cors.Config{
-		AllowOrigins:     []string{"*"},
-		AllowMethods:     []string{"GET", "POST", "PUT", "PATCH", "DELETE", "OPTIONS"},
-		AllowHeaders:     []string{"Origin", "Content-Type", "Accept", "Authorization"},
-		ExposeHeaders:    []string{"Content-Length"},
-		AllowCredentials: true,
-	}))
-	server.Use(middleware.RateLimiter())
-
-	dbConnection, err := db.ConnectDB()
+	cfg, err := config.Load()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+
+	logger := logging.New(os.Stdout, cfg.Log.Format, cfg.Log.Level)
+	slog.SetDefault(logger)
+
+	shutdownTracing, err := tracing.Setup(context.Background(), cfg.Tracing)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+
+	dbConnection, err := db.ConnectDB(cfg.DB, logger)
 	if err != nil {
 		panic(err)
 	}
 
-	UserRepository := repository.NewUserRepository(dbConnection)
-	UserUseCase := usecase.NewUserUsecase(UserRepository)
-	UserController := controller.NewUserController(UserUseCase)
+	// Orders placed before orders had a currency were placed in the store
+	// currency, and reservations made before they expired get the reservation
+	// TTL. The migrations that add them fill them in.
+	migrationSettings := db.MigrationSettings{
+		"store_currency":  cfg.Pricing.Currency,
+		"reservation_ttl": fmt.Sprintf("%d microseconds", cfg.Reservation.TTL.Microseconds()),
+	}
+
+	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+		code := runMigrate(dbConnection, migrationSettings, os.Args[2:])
+		dbConnection.Close()
+		os.Exit(code)
+	}
 
-	ProductRepository := repository.NewProductRepository(dbConnection)
-	ProductUseCase := usecase.NewProductUsecase(ProductRepository)
-	ProductController := controller.NewProductController(ProductUseCase)
+	if len(os.Args) > 1 && os.Args[1] == "exchange-rates" {
+		code := runExchangeRates(dbConnection, cfg.DB.QueryTimeout, os.Args[2:])
+		dbConnection.Close()
+		os.Exit(code)
+	}
 
-	server.POST("/register", UserController.CreateUser)
-	server.POST("/login", UserController.GetUserByEmail)
+	if cfg.AutoMigrate {
+		if _, err := db.MigrateUp(dbConnection, migrationSettings); err != nil {
+			panic(err)
+		}
+	}
 
-	protectedRoutes := server.Group("/api")
-	protectedRoutes.Use(middleware.AuthMiddleware())
+	rateLimits := repository.NewLocalRateLimitRepository()
+	if cfg.RateLimit.Store == "postgres" {
+		rateLimits = repository.NewRateLimitRepository(dbConnection, cfg.DB.QueryTimeout)
+	}
 
-	protectedRoutes.GET("/user/info", UserController.GetUserInfo)
-	protectedRoutes.GET("/users/:id_user", UserController.GetUserById)
-	protectedRoutes.PUT("/users/:id_user", UserController.UpdateUser)
+	appMetrics := metrics.New()
+	appMetrics.RegisterDB(dbConnection, cfg.DB.Name)
 
-	protectedRoutes.GET("/products", ProductController.GetProducts)
-	protectedRoutes.POST("/products", ProductController.CreateProduct)
-	protectedRoutes.GET("/products/:id_product", ProductController.GetProductById)
-	protectedRoutes.PUT("/products/:id_product", ProductController.UpdateProduct)
+	shuttingDown := &atomic.Bool{}
+	repos := repositories{
+		User:         repository.NewUserRepository(dbConnection, cfg.DB.QueryTimeout),
+		Token:        repository.NewTokenRepository(dbConnection, cfg.DB.QueryTimeout),
+		Category:     repository.NewCategoryRepository(dbConnection, cfg.DB.QueryTimeout),
+		Product:      repository.NewProductRepository(dbConnection, cfg.DB.QueryTimeout),
+		Inventory:    repository.NewInventoryRepository(dbConnection, cfg.DB.QueryTimeout),
+		Order:        repository.NewOrderRepository(dbConnection, cfg.DB.QueryTimeout),
+		Cart:         repository.NewCartRepository(dbConnection, cfg.DB.QueryTimeout),
+		Role:         repository.NewRoleRepository(dbConnection, cfg.DB.QueryTimeout),
+		Audit:        repository.NewAuditRepository(dbConnection, cfg.DB.QueryTimeout),
+		ExchangeRate: repository.NewExchangeRateRepository(dbConnection, cfg.DB.QueryTimeout),
+		RateLimit:    rateLimits,
+		Transactor:   repository.NewTransactor(dbConnection),
+	}
+	server := newRouter(cfg, repos, dbConnection, shuttingDown, logger, appMetrics)
 
-	adminRoutes := protectedRoutes.Group("/admin")
-	adminRoutes.Use(middleware.RequireAdmin())
-	adminRoutes.GET("/users", UserController.GetUsers)
-	adminRoutes.DELETE("/products/:id_product", ProductController.DeleteProduct)
-	adminRoutes.DELETE("/users/:id_user", UserController.DeleteUser)
+	httpServer := &http.Server{
+		Addr:    cfg.Port,
+		Handler: server,
+	}
+
+	signalCtx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+
+	go middleware.EvictRateLimits(signalCtx, cfg.RateLimit.EvictInterval, rateLimits.EvictIdle)
+
+	purge := usecase.NewPurgeUsecase(repos.Product, repos.User, cfg.Trash.Retention)
+	go purge.Run(signalCtx, cfg.Trash.PurgeInterval)
+
+	tokens := usecase.NewUserUsecase(repos.User, repos.Token, cfg.JWT)
+	go tokens.RunTokenCleanup(signalCtx, cfg.JWT.CleanupInterval)
+
+	prices := usecase.NewPriceScheduleUsecase(repos.Product)
+	go prices.Run(signalCtx, cfg.Pricing.ScheduleInterval)
+
+	reservations := usecase.NewInventoryUsecase(repos.Inventory, repos.Product, cfg.Reservation)
+	go reservations.RunReservationExpiry(signalCtx, cfg.Reservation.ExpireInterval)
+
+	go func() {
+		logger.Info("server listening", slog.String("addr", cfg.Port))
+		if err := httpServer.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			panic(err)
+		}
+	}()
+
+	<-signalCtx.Done()
+	stop()
+
+	// /readyz fails from now on. The listener stays open for the drain delay,
+	// so the orchestrator sees the failing probe and stops routing traffic
+	// here before connections are refused.
+	logger.Info("shutting down, failing readiness", slog.Duration("drain_delay", cfg.ReadinessDrain))
+	shuttingDown.Store(true)
+	time.Sleep(cfg.ReadinessDrain)
+
+	logger.Info("draining connections")
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), cfg.ShutdownTimeout)
+	defer cancel()
+
+	if err := httpServer.Shutdown(shutdownCtx); err != nil {
+		logger.Error("forced shutdown", slog.String("error", err.Error()))
+	}
+
+	if err := shutdownTracing(shutdownCtx); err != nil {
+		logger.Error("failed to flush traces", slog.String("error", err.Error()))
+	}
 
-	server.Run(os.Getenv("PORT"))
+	dbConnection.Close()
 }
@@ -0,0 +1,160 @@
+package main
+
+import (
+	"log/slog"
+	"net/http"
+	"product-go-api/config"
+	"product-go-api/controller"
+	"product-go-api/metrics"
+	"product-go-api/middleware"
+	"product-go-api/model"
+	"product-go-api/repository"
+	"product-go-api/usecase"
+	"sync/atomic"
+
+	"github.com/gin-contrib/cors"
+	"github.com/gin-gonic/gin"
+	"go.opentelemetry.io/contrib/instrumentation/github.com/gin-gonic/gin/otelgin"
+)
+
+// repositories groups the data access layer so the router can be built on
+// top of PostgreSQL in main and on top of the in-memory store in tests.
+type repositories struct {
+	User         repository.UserRepository
+	Token        repository.TokenRepository
+	Category     repository.CategoryRepository
+	Product      repository.ProductRepository
+	Inventory    repository.InventoryRepository
+	Order        repository.OrderRepository
+	Cart         repository.CartRepository
+	Role         repository.RoleRepository
+	Audit        repository.AuditRepository
+	ExchangeRate repository.ExchangeRateRepository
+	RateLimit    repository.RateLimitRepository
+	Transactor   repository.Transactor
+}
+
+func newRouter(cfg config.Config, repos repositories, database controller.DatabaseStatus, shuttingDown *atomic.Bool, logger *slog.Logger, metrics *metrics.Metrics) *gin.Engine {
+	server := gin.New()
+	// Tracing runs first so the request ID middleware can add the trace ID to
+	// the request logger. Probes and scrapes are not traced.
+	server.Use(otelgin.Middleware(cfg.Tracing.ServiceName, otelgin.WithFilter(func(r *http.Request) bool {
+		return r.URL.Path != "/healthz" && r.URL.Path != "/readyz" && r.URL.Path != "/metrics"
+	})))
+	server.Use(middleware.RequestID(logger), middleware.RequestLogger(), middleware.RequestMetrics(metrics.ObserveRequest), middleware.ErrorHandler(), middleware.Recovery())
+	server.Use(cors.New(cors.Config{
+		AllowOrigins:     cfg.CORS.AllowOrigins,
+		AllowMethods:     []string{"GET", "POST", "PUT", "PATCH", "DELETE", "OPTIONS"},
+		AllowHeaders:     []string{"Origin", "Content-Type", "Accept", "Authorization"},
+		ExposeHeaders:    []string{"Content-Length"},
+		AllowCredentials: true,
+	}))
+
+	// Every request is limited per IP before authentication, so requests
+	// with invalid tokens are throttled too. Probes and scrapes come from the
+	// address of the orchestrator, whose budget no client shares.
+	server.Use(middleware.RateLimiter("ip", cfg.RateLimit.IP, repos.RateLimit.Allow, metrics.RateLimitRejected))
+
+	HealthController := controller.NewHealthController(database, shuttingDown)
+	server.GET("/healthz", HealthController.Liveness)
+	server.GET("/readyz", HealthController.Readiness)
+	server.GET("/metrics", gin.WrapH(metrics.Handler()))
+	server.NoRoute(middleware.RouteNotFound)
+
+	AuditUseCase := usecase.NewAuditUsecase(repos.Audit, repos.Transactor)
+	AuditController := controller.NewAuditController(AuditUseCase)
+
+	RoleUseCase := usecase.NewRoleUsecase(repos.Role)
+	RoleController := controller.NewRoleController(RoleUseCase, AuditUseCase)
+
+	UserUseCase := usecase.NewUserUsecase(repos.User, repos.Token, cfg.JWT)
+	UserController := controller.NewUserController(UserUseCase, RoleUseCase, AuditUseCase, metrics)
+
+	CategoryUseCase := usecase.NewCategoryUsecase(repos.Category)
+	CategoryController := controller.NewCategoryController(CategoryUseCase, AuditUseCase)
+
+	ProductUseCase := usecase.NewProductUsecase(repos.Product, repos.Category, repos.ExchangeRate, repos.Transactor, cfg.Pricing.Currency)
+	ProductController := controller.NewProductController(ProductUseCase, AuditUseCase, cfg.Import)
+
+	InventoryUseCase := usecase.NewInventoryUsecase(repos.Inventory, repos.Product, cfg.Reservation)
+	InventoryController := controller.NewInventoryController(InventoryUseCase, AuditUseCase)
+
+	OrderUseCase := usecase.NewOrderUsecase(repos.Order, cfg.Pricing.Currency)
+	OrderController := controller.NewOrderController(OrderUseCase, AuditUseCase)
+
+	CartUseCase := usecase.NewCartUsecase(repos.Cart, repos.Product, cfg.Pricing.Currency)
+	CartController := controller.NewCartController(CartUseCase, OrderUseCase)
+
+	publicRateLimiter := middleware.RateLimiter("public", cfg.RateLimit.Public, repos.RateLimit.Allow, metrics.RateLimitRejected)
+	server.POST("/register", publicRateLimiter, UserController.CreateUser)
+	server.POST("/login", publicRateLimiter, UserController.GetUserByEmail)
+	server.POST("/refresh", publicRateLimiter, UserController.RefreshToken)
+
+	authMiddleware := middleware.AuthMiddleware(cfg.JWT.Secret, UserUseCase.IsAccessTokenRevoked)
+	server.POST("/logout", publicRateLimiter, authMiddleware, UserController.Logout)
+
+	// The API limiter runs after authentication so it counts requests per
+	// user on top of the limit per IP.
+	protectedRoutes := server.Group("/api")
+	protectedRoutes.Use(
+		authMiddleware,
+		middleware.RateLimiter("api", cfg.RateLimit.API, repos.RateLimit.Allow, metrics.RateLimitRejected),
+		middleware.LoadPermissions(RoleUseCase.GetRolePermissions),
+	)
+
+	protectedRoutes.GET("/user/info", UserController.GetUserInfo)
+	protectedRoutes.GET("/users/:id_user", UserController.GetUserById)
+	protectedRoutes.PUT("/users/:id_user", UserController.UpdateUser)
+
+	protectedRoutes.GET("/products", ProductController.GetProducts)
+	protectedRoutes.GET("/products/search", ProductController.SearchProducts)
+	protectedRoutes.POST("/products", middleware.RequirePermission(model.PermissionProductWrite), ProductController.CreateProduct)
+	protectedRoutes.GET("/products/:id_product", ProductController.GetProductById)
+	protectedRoutes.PUT("/products/:id_product", middleware.RequirePermission(model.PermissionProductWrite), ProductController.UpdateProduct)
+
+	protectedRoutes.GET("/products/:id_product/prices", ProductController.GetPriceHistory)
+	protectedRoutes.POST("/products/:id_product/prices", middleware.RequirePermission(model.PermissionProductWrite), ProductController.SchedulePrice)
+	protectedRoutes.GET("/products/:id_product/stock", InventoryController.GetStock)
+	protectedRoutes.POST("/reservations", InventoryController.Reserve)
+	protectedRoutes.POST("/reservations/:id_reservation/release", InventoryController.Release)
+
+	protectedRoutes.GET("/cart", CartController.GetCart)
+	protectedRoutes.POST("/cart/items", CartController.AddItem)
+	protectedRoutes.PUT("/cart/items/:id_product", CartController.UpdateItem)
+	protectedRoutes.DELETE("/cart/items/:id_product", CartController.RemoveItem)
+	protectedRoutes.POST("/cart/checkout", CartController.Checkout)
+
+	protectedRoutes.GET("/orders", OrderController.GetMyOrders)
+	protectedRoutes.GET("/orders/:id_order", OrderController.GetOrderById)
+
+	protectedRoutes.GET("/categories", CategoryController.GetCategories)
+	protectedRoutes.GET("/categories/:id_category", CategoryController.GetCategoryById)
+	protectedRoutes.POST("/categories", middleware.RequirePermission(model.PermissionCategoryWrite), CategoryController.CreateCategory)
+	protectedRoutes.PUT("/categories/:id_category", middleware.RequirePermission(model.PermissionCategoryWrite), CategoryController.UpdateCategory)
+	protectedRoutes.DELETE("/categories/:id_category", middleware.RequirePermission(model.PermissionCategoryWrite), CategoryController.DeleteCategory)
+
+	adminRoutes := protectedRoutes.Group("/admin")
+	adminRoutes.GET("/users", middleware.RequirePermission(model.PermissionUserRead), UserController.GetUsers)
+	adminRoutes.POST("/products/import", middleware.RequirePermission(model.PermissionProductImport), ProductController.ImportProducts)
+	adminRoutes.DELETE("/products/:id_product", middleware.RequirePermission(model.PermissionProductDelete), ProductController.DeleteProduct)
+	adminRoutes.GET("/products/trash", middleware.RequirePermission(model.PermissionProductDelete), ProductController.GetDeletedProducts)
+	adminRoutes.POST("/products/:id_product/restore", middleware.RequirePermission(model.PermissionProductDelete), ProductController.RestoreProduct)
+	adminRoutes.POST("/products/:id_product/stock", middleware.RequirePermission(model.PermissionStockWrite), InventoryController.AdjustStock)
+	adminRoutes.GET("/products/:id_product/stock/movements", middleware.RequirePermission(model.PermissionStockRead), InventoryController.GetMovements)
+	adminRoutes.GET("/products/:id_product/stock/reconcile", middleware.RequirePermission(model.PermissionStockRead), InventoryController.Reconcile)
+	adminRoutes.DELETE("/users/:id_user", middleware.RequirePermission(model.PermissionUserDelete), UserController.DeleteUser)
+	adminRoutes.GET("/users/trash", middleware.RequirePermission(model.PermissionUserDelete), UserController.GetDeletedUsers)
+	adminRoutes.POST("/users/:id_user/restore", middleware.RequirePermission(model.PermissionUserDelete), UserController.RestoreUser)
+	adminRoutes.GET("/orders", middleware.RequirePermission(model.PermissionOrderReadAll), OrderController.GetOrders)
+	adminRoutes.PUT("/orders/:id_order/status", middleware.RequirePermission(model.PermissionOrderUpdateStatus), OrderController.UpdateStatus)
+
+	adminRoutes.GET("/roles", middleware.RequirePermission(model.PermissionRoleManage), RoleController.GetRoles)
+	adminRoutes.POST("/roles", middleware.RequirePermission(model.PermissionRoleManage), RoleController.CreateRole)
+	adminRoutes.PUT("/roles/:role/permissions", middleware.RequirePermission(model.PermissionRoleManage), RoleController.SetRolePermissions)
+	adminRoutes.DELETE("/roles/:role", middleware.RequirePermission(model.PermissionRoleManage), RoleController.DeleteRole)
+
+	adminRoutes.GET("/audit", middleware.RequirePermission(model.PermissionAuditRead), AuditController.GetAuditLog)
+	adminRoutes.GET("/permissions", middleware.RequirePermission(model.PermissionRoleManage), RoleController.GetPermissions)
+
+	return server
+}
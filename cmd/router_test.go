@@ -0,0 +1,1475 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"product-go-api/config"
+	"product-go-api/controller"
+	"product-go-api/logging"
+	"product-go-api/metrics"
+	"product-go-api/model"
+	"product-go-api/repository"
+	"product-go-api/repository/memory"
+	"product-go-api/usecase"
+	"slices"
+	"sort"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func init() {
+	gin.SetMode(gin.TestMode)
+}
+
+type fakeDatabase struct {
+	err error
+}
+
+func (f fakeDatabase) PingContext(ctx context.Context) error {
+	return f.err
+}
+
+func (f fakeDatabase) Stats() sql.DBStats {
+	return sql.DBStats{}
+}
+
+type testServer struct {
+	t            *testing.T
+	store        *memory.Store
+	router       *gin.Engine
+	shuttingDown *atomic.Bool
+	logs         *bytes.Buffer
+}
+
+func newTestServer(t *testing.T, database controller.DatabaseStatus) *testServer {
+	t.Helper()
+	return newTestServerWithRateLimits(t, database, config.RateLimitConfig{
+		IP:     model.RateLimit{RequestsPerSecond: 1000, Burst: 1000},
+		Public: model.RateLimit{RequestsPerSecond: 1000, Burst: 1000},
+		API:    model.RateLimit{RequestsPerSecond: 1000, Burst: 1000},
+	})
+}
+
+func newTestServerWithRateLimits(t *testing.T, database controller.DatabaseStatus, rateLimits config.RateLimitConfig) *testServer {
+	t.Helper()
+	store := memory.NewStore()
+	shuttingDown := &atomic.Bool{}
+	logs := &bytes.Buffer{}
+	cfg := config.Config{
+		JWT: config.JWTConfig{
+			Secret:     "test-secret",
+			AccessTTL:  time.Minute,
+			RefreshTTL: time.Hour,
+		},
+		RateLimit:   rateLimits,
+		CORS:        config.CORSConfig{AllowOrigins: []string{"http://localhost"}},
+		Pricing:     config.PricingConfig{Currency: model.DefaultCurrency},
+		Import:      config.ImportConfig{MaxRows: 5, MaxFileBytes: 1 << 16},
+		Reservation: config.ReservationConfig{TTL: time.Minute, MaxQuantity: 10},
+	}
+	router := newRouter(cfg, repositories{
+		User:         store.UserRepository(),
+		Token:        store.TokenRepository(),
+		Category:     store.CategoryRepository(),
+		Product:      store.ProductRepository(),
+		Inventory:    store.InventoryRepository(),
+		Order:        store.OrderRepository(),
+		Cart:         store.CartRepository(),
+		Role:         store.RoleRepository(),
+		Audit:        store.AuditRepository(),
+		ExchangeRate: store.ExchangeRateRepository(),
+		RateLimit:    repository.NewLocalRateLimitRepository(),
+		Transactor:   store.Transactor(),
+	}, database, shuttingDown, logging.New(logs, "json", slog.LevelInfo), metrics.New())
+
+	return &testServer{t: t, store: store, router: router, shuttingDown: shuttingDown, logs: logs}
+}
+
+func (s *testServer) do(method, path, token string, body any) *httptest.ResponseRecorder {
+	s.t.Helper()
+	var reader *bytes.Reader
+	if body != nil {
+		payload, err := json.Marshal(body)
+		if err != nil {
+			s.t.Fatalf("marshal body: %v", err)
+		}
+		reader = bytes.NewReader(payload)
+	} else {
+		reader = bytes.NewReader(nil)
+	}
+
+	req := httptest.NewRequest(method, path, reader)
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	rec := httptest.NewRecorder()
+	s.router.ServeHTTP(rec, req)
+	return rec
+}
+
+// expect performs a request, checks the status code and decodes the JSON
+// response into out when it is not nil.
+func (s *testServer) expect(method, path, token string, body any, status int, out any) {
+	s.t.Helper()
+	rec := s.do(method, path, token, body)
+	if rec.Code != status {
+		s.t.Fatalf("%s %s: status %d, want %d; body %s", method, path, rec.Code, status, rec.Body.String())
+	}
+	if out != nil {
+		if err := json.Unmarshal(rec.Body.Bytes(), out); err != nil {
+			s.t.Fatalf("%s %s: decode response: %v", method, path, err)
+		}
+	}
+}
+
+// upload posts content as the multipart file field of a form, checks the
+// status code and decodes the JSON response into out when it is not nil.
+func (s *testServer) upload(path, token, filename, content string, status int, out any) {
+	s.t.Helper()
+	body := &bytes.Buffer{}
+	form := multipart.NewWriter(body)
+	part, err := form.CreateFormFile("file", filename)
+	if err != nil {
+		s.t.Fatalf("create form file: %v", err)
+	}
+	part.Write([]byte(content))
+	form.Close()
+
+	req := httptest.NewRequest(http.MethodPost, path, body)
+	req.Header.Set("Content-Type", form.FormDataContentType())
+	req.Header.Set("Authorization", "Bearer "+token)
+	rec := httptest.NewRecorder()
+	s.router.ServeHTTP(rec, req)
+	if rec.Code != status {
+		s.t.Fatalf("POST %s: status %d, want %d; body %s", path, rec.Code, status, rec.Body.String())
+	}
+	if out != nil {
+		if err := json.Unmarshal(rec.Body.Bytes(), out); err != nil {
+			s.t.Fatalf("POST %s: decode response: %v", path, err)
+		}
+	}
+}
+
+// register creates a user through the API, promotes it to role when role is
+// not "user" and returns its id and a token pair.
+func (s *testServer) register(email, role string) (int, model.TokenPair) {
+	s.t.Helper()
+	ctx := context.Background()
+	s.expect(http.MethodPost, "/register", "", gin.H{
+		"username": email,
+		"email":    email,
+		"password": "secret123",
+	}, http.StatusCreated, nil)
+
+	user, err := s.store.UserRepository().GetUserByEmail(ctx, email)
+	if err != nil || user == nil {
+		s.t.Fatalf("registered user not found: %v", err)
+	}
+	if role != "user" {
+		user.Role = role
+		if _, err := s.store.UserRepository().UpdateUser(ctx, *user); err != nil {
+			s.t.Fatalf("promote user: %v", err)
+		}
+	}
+
+	var tokens model.TokenPair
+	s.expect(http.MethodPost, "/login", "", gin.H{"email": email, "password": "secret123"}, http.StatusOK, &tokens)
+	return user.ID, tokens
+}
+
+func (s *testServer) createProduct(token, name string, price float64, stock int) int {
+	s.t.Helper()
+	var product model.Product
+	s.expect(http.MethodPost, "/api/products", token, gin.H{"sku": strings.ReplaceAll(name, " ", "-"), "name": name, "price": price}, http.StatusCreated, &product)
+	if stock > 0 {
+		s.expect(http.MethodPost, fmt.Sprintf("/api/admin/products/%d/stock", product.ID), token,
+			gin.H{"type": model.StockReceive, "quantity": stock, "reason": "initial stock"}, http.StatusCreated, nil)
+	}
+	return product.ID
+}
+
+// testedRoutes lists every route exercised below. TestEveryRouteIsTested
+// fails when a route is registered without being added here and tested.
+var testedRoutes = []string{
+	"GET /healthz",
+	"GET /readyz",
+	"GET /metrics",
+	"POST /register",
+	"POST /login",
+	"POST /refresh",
+	"POST /logout",
+	"GET /api/user/info",
+	"GET /api/users/:id_user",
+	"PUT /api/users/:id_user",
+	"GET /api/products",
+	"POST /api/products",
+	"GET /api/products/:id_product",
+	"PUT /api/products/:id_product",
+	"GET /api/products/search",
+	"GET /api/products/:id_product/prices",
+	"POST /api/products/:id_product/prices",
+	"GET /api/products/:id_product/stock",
+	"POST /api/reservations",
+	"POST /api/reservations/:id_reservation/release",
+	"GET /api/cart",
+	"POST /api/cart/items",
+	"PUT /api/cart/items/:id_product",
+	"DELETE /api/cart/items/:id_product",
+	"POST /api/cart/checkout",
+	"GET /api/orders",
+	"GET /api/orders/:id_order",
+	"GET /api/categories",
+	"GET /api/categories/:id_category",
+	"POST /api/categories",
+	"PUT /api/categories/:id_category",
+	"DELETE /api/categories/:id_category",
+	"GET /api/admin/users",
+	"POST /api/admin/products/import",
+	"DELETE /api/admin/products/:id_product",
+	"GET /api/admin/products/trash",
+	"POST /api/admin/products/:id_product/restore",
+	"POST /api/admin/products/:id_product/stock",
+	"GET /api/admin/products/:id_product/stock/movements",
+	"GET /api/admin/products/:id_product/stock/reconcile",
+	"DELETE /api/admin/users/:id_user",
+	"GET /api/admin/users/trash",
+	"POST /api/admin/users/:id_user/restore",
+	"GET /api/admin/orders",
+	"PUT /api/admin/orders/:id_order/status",
+	"GET /api/admin/roles",
+	"POST /api/admin/roles",
+	"PUT /api/admin/roles/:role/permissions",
+	"DELETE /api/admin/roles/:role",
+	"GET /api/admin/permissions",
+	"GET /api/admin/audit",
+}
+
+func TestEveryRouteIsTested(t *testing.T) {
+	server := newTestServer(t, fakeDatabase{})
+
+	var registered []string
+	for _, route := range server.router.Routes() {
+		registered = append(registered, route.Method+" "+route.Path)
+	}
+	tested := append([]string(nil), testedRoutes...)
+	sort.Strings(registered)
+	sort.Strings(tested)
+
+	if fmt.Sprint(registered) != fmt.Sprint(tested) {
+		t.Fatalf("registered routes differ from tested routes:\nregistered: %v\ntested:     %v", registered, tested)
+	}
+}
+
+func TestHealthRoutes(t *testing.T) {
+	server := newTestServer(t, fakeDatabase{})
+	server.expect(http.MethodGet, "/healthz", "", nil, http.StatusOK, nil)
+	server.expect(http.MethodGet, "/readyz", "", nil, http.StatusOK, nil)
+
+	server.shuttingDown.Store(true)
+	server.expect(http.MethodGet, "/readyz", "", nil, http.StatusServiceUnavailable, nil)
+	server.expect(http.MethodGet, "/healthz", "", nil, http.StatusOK, nil)
+
+	unreachable := newTestServer(t, fakeDatabase{err: errors.New("connection refused")})
+	unreachable.expect(http.MethodGet, "/readyz", "", nil, http.StatusServiceUnavailable, nil)
+}
+
+func TestAuthRoutes(t *testing.T) {
+	server := newTestServer(t, fakeDatabase{})
+
+	server.expect(http.MethodPost, "/register", "", gin.H{"email": "ana@example.com"}, http.StatusBadRequest, nil)
+	id_user, tokens := server.register("ana@example.com", "user")
+
+	server.expect(http.MethodPost, "/login", "", gin.H{"email": "ana@example.com", "password": "wrong"}, http.StatusUnauthorized, nil)
+	server.expect(http.MethodPost, "/login", "", gin.H{"email": "nobody@example.com", "password": "secret123"}, http.StatusUnauthorized, nil)
+
+	var info model.User
+	server.expect(http.MethodGet, "/api/user/info", tokens.AccessToken, nil, http.StatusOK, &info)
+	if info.ID != id_user {
+		t.Fatalf("user info id = %d, want %d", info.ID, id_user)
+	}
+	server.expect(http.MethodGet, "/api/user/info", "", nil, http.StatusUnauthorized, nil)
+	server.expect(http.MethodGet, "/api/user/info", "not-a-jwt", nil, http.StatusUnauthorized, nil)
+
+	var rotated model.TokenPair
+	server.expect(http.MethodPost, "/refresh", "", gin.H{"refresh_token": tokens.RefreshToken}, http.StatusOK, &rotated)
+	server.expect(http.MethodPost, "/refresh", "", gin.H{"refresh_token": "not-a-token"}, http.StatusUnauthorized, nil)
+	// Refreshing revokes the access token it was issued with.
+	server.expect(http.MethodGet, "/api/user/info", tokens.AccessToken, nil, http.StatusUnauthorized, nil)
+
+	server.expect(http.MethodPost, "/logout", rotated.AccessToken, gin.H{"refresh_token": rotated.RefreshToken}, http.StatusOK, nil)
+	server.expect(http.MethodGet, "/api/user/info", rotated.AccessToken, nil, http.StatusUnauthorized, nil)
+	server.expect(http.MethodPost, "/refresh", "", gin.H{"refresh_token": rotated.RefreshToken}, http.StatusUnauthorized, nil)
+}
+
+func TestRequestIDAndLogging(t *testing.T) {
+	server := newTestServer(t, fakeDatabase{})
+	id_user, user := server.register("ana@example.com", "user")
+	server.logs.Reset()
+
+	req := httptest.NewRequest(http.MethodGet, "/api/user/info", nil)
+	req.Header.Set("Authorization", "Bearer "+user.AccessToken)
+	req.Header.Set("X-Request-ID", "client-id-1")
+	rec := httptest.NewRecorder()
+	server.router.ServeHTTP(rec, req)
+	if got := rec.Header().Get("X-Request-ID"); got != "client-id-1" {
+		t.Fatalf("X-Request-ID = %q, want the incoming ID", got)
+	}
+
+	var entry struct {
+		Msg       string
+		RequestID string `json:"request_id"`
+		Route     string
+		Status    int
+		UserID    int `json:"user_id"`
+		Latency   int64
+	}
+	if err := json.Unmarshal(server.logs.Bytes(), &entry); err != nil {
+		t.Fatalf("decode log entry %q: %v", server.logs.String(), err)
+	}
+	if entry.Msg != "request" || entry.RequestID != "client-id-1" || entry.Route != "/api/user/info" || entry.Status != http.StatusOK || entry.UserID != id_user {
+		t.Fatalf("log entry = %+v", entry)
+	}
+	if strings.Contains(server.logs.String(), user.AccessToken) {
+		t.Fatal("access token leaked into the logs")
+	}
+
+	rec = server.do(http.MethodGet, "/healthz", "", nil)
+	if id := rec.Header().Get("X-Request-ID"); len(id) != 32 {
+		t.Fatalf("generated X-Request-ID = %q, want 32 hex characters", id)
+	}
+	req = httptest.NewRequest(http.MethodGet, "/healthz", nil)
+	req.Header.Set("X-Request-ID", "bad id\n")
+	rec = httptest.NewRecorder()
+	server.router.ServeHTTP(rec, req)
+	if id := rec.Header().Get("X-Request-ID"); id == "bad id\n" || len(id) != 32 {
+		t.Fatalf("invalid X-Request-ID was not replaced: %q", id)
+	}
+}
+
+func TestTracing(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	previousProvider, previousPropagator := otel.GetTracerProvider(), otel.GetTextMapPropagator()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+	t.Cleanup(func() {
+		otel.SetTracerProvider(previousProvider)
+		otel.SetTextMapPropagator(previousPropagator)
+	})
+
+	server := newTestServer(t, fakeDatabase{})
+	_, user := server.register("ana@example.com", "user")
+	server.logs.Reset()
+
+	const traceID = "4bf92f3577b34da6a3ce929d0e0e4736"
+	req := httptest.NewRequest(http.MethodGet, "/api/user/info", nil)
+	req.Header.Set("Authorization", "Bearer "+user.AccessToken)
+	req.Header.Set("traceparent", "00-"+traceID+"-00f067aa0ba902b7-01")
+	server.router.ServeHTTP(httptest.NewRecorder(), req)
+	server.expect(http.MethodGet, "/healthz", "", nil, http.StatusOK, nil)
+
+	var names []string
+	for _, span := range recorder.Ended() {
+		names = append(names, span.Name())
+		if span.Name() == "/api/user/info" && span.SpanContext().TraceID().String() != traceID {
+			t.Fatalf("span trace ID = %s, want the incoming %s", span.SpanContext().TraceID(), traceID)
+		}
+	}
+	if fmt.Sprint(names) != "[/register /login /api/user/info]" {
+		t.Fatalf("spans = %v, want one per request except probes", names)
+	}
+	if !strings.Contains(server.logs.String(), `"trace_id":"`+traceID+`"`) {
+		t.Fatalf("request log has no trace_id: %s", server.logs.String())
+	}
+}
+
+func TestErrorsAreProblems(t *testing.T) {
+	server := newTestServer(t, fakeDatabase{})
+	_, user := server.register("ana@example.com", "user")
+
+	tests := []struct {
+		name, method, path, token string
+		body                      any
+		status                    int
+		problemType               string
+		field                     string
+	}{
+		{"validation", http.MethodGet, "/api/products?page=0", user.AccessToken, nil, http.StatusBadRequest, "/problems/validation", "page"},
+		{"usecase validation", http.MethodPost, "/api/products", user.AccessToken, gin.H{"sku": "PEN", "name": "Pen", "price": 1, "category_id": 999}, http.StatusBadRequest, "/problems/validation", "category_id"},
+		{"not found", http.MethodGet, "/api/products/999", user.AccessToken, nil, http.StatusNotFound, "/problems/not-found", ""},
+		{"unauthorized", http.MethodGet, "/api/products", "", nil, http.StatusUnauthorized, "/problems/unauthorized", ""},
+		{"forbidden", http.MethodGet, "/api/admin/users", user.AccessToken, nil, http.StatusForbidden, "/problems/forbidden", ""},
+		{"unknown route", http.MethodGet, "/no/such/route", "", nil, http.StatusNotFound, "/problems/not-found", ""},
+	}
+
+	for _, tt := range tests {
+		rec := server.do(tt.method, tt.path, tt.token, tt.body)
+		if rec.Code != tt.status {
+			t.Fatalf("%s: status %d, want %d; body %s", tt.name, rec.Code, tt.status, rec.Body.String())
+		}
+		if got := rec.Header().Get("Content-Type"); got != "application/problem+json" {
+			t.Fatalf("%s: Content-Type = %q", tt.name, got)
+		}
+
+		var problem model.Problem
+		if err := json.Unmarshal(rec.Body.Bytes(), &problem); err != nil {
+			t.Fatalf("%s: decode problem: %v", tt.name, err)
+		}
+		if problem.Type != tt.problemType || problem.Status != tt.status || problem.Title == "" || problem.Detail == "" {
+			t.Fatalf("%s: problem = %+v", tt.name, problem)
+		}
+		if problem.RequestID == "" || problem.RequestID != rec.Header().Get("X-Request-ID") {
+			t.Fatalf("%s: request_id = %q, want the X-Request-ID header", tt.name, problem.RequestID)
+		}
+		if tt.field != "" && (len(problem.Errors) != 1 || problem.Errors[0].Field != tt.field) {
+			t.Fatalf("%s: field errors = %+v, want one for %s", tt.name, problem.Errors, tt.field)
+		}
+	}
+}
+
+func TestRequestValidation(t *testing.T) {
+	server := newTestServer(t, fakeDatabase{})
+	id_user, user := server.register("ana@example.com", "user")
+	_, admin := server.register("admin@example.com", "super_admin")
+	id_product := server.createProduct(admin.AccessToken, "Pen", 2, 0)
+
+	tests := []struct {
+		name, method, path, token string
+		body                      any
+		fields                    map[string]string
+	}{
+		{"register", http.MethodPost, "/register", "", gin.H{"username": "al", "email": "not-an-email", "password": "short"}, map[string]string{
+			"username": "must be at least 3 characters",
+			"email":    "must be a valid email address",
+			"password": "must be at least 8 characters",
+		}},
+		{"login", http.MethodPost, "/login", "", gin.H{"email": "ana@example.com"}, map[string]string{"password": "is required"}},
+		{"create product", http.MethodPost, "/api/products", admin.AccessToken, gin.H{"sku": "-pen", "price": -1, "status": "hidden"}, map[string]string{
+			"sku":    "must match " + model.SKUPattern.String(),
+			"name":   "is required",
+			"price":  "must be greater than or equal to 0",
+			"status": "must be one of: draft, active, archived",
+		}},
+		{"product type", http.MethodPost, "/api/products", admin.AccessToken, gin.H{"sku": "PEN", "name": "Pen", "price": "cheap"}, map[string]string{"price": "must be a number with at most 2 decimal places"}},
+		{"update product", http.MethodPut, fmt.Sprintf("/api/products/%d", id_product), admin.AccessToken, gin.H{"name": "", "category_id": 0}, map[string]string{
+			"name":        "is required",
+			"category_id": "must be greater than 0",
+		}},
+		{"update user", http.MethodPut, fmt.Sprintf("/api/users/%d", id_user), admin.AccessToken, gin.H{"email": "nope", "password": "", "role": "Admin!"}, map[string]string{
+			"email":    "must be a valid email address",
+			"password": "must be at least 8 characters",
+			"role":     "must match ^[a-z][a-z0-9_]{0,19}$",
+		}},
+	}
+
+	for _, tt := range tests {
+		var problem model.Problem
+		server.expect(tt.method, tt.path, tt.token, tt.body, http.StatusBadRequest, &problem)
+		got := map[string]string{}
+		for _, field := range problem.Errors {
+			got[field.Field] = field.Message
+		}
+		if fmt.Sprint(got) != fmt.Sprint(tt.fields) {
+			t.Fatalf("%s: field errors = %v, want %v", tt.name, got, tt.fields)
+		}
+	}
+
+	// Updates only validate and change the fields that are sent.
+	var product model.Product
+	server.expect(http.MethodPut, fmt.Sprintf("/api/products/%d", id_product), admin.AccessToken, gin.H{"price": 3.5}, http.StatusOK, &product)
+	if product.Name != "Pen" || product.Price.String() != "3.5" {
+		t.Fatalf("updated product = %+v", product)
+	}
+	var updated model.User
+	server.expect(http.MethodPut, fmt.Sprintf("/api/users/%d", id_user), user.AccessToken, gin.H{"username": "Ana"}, http.StatusOK, &updated)
+	if updated.Username != "Ana" || updated.Email != "ana@example.com" {
+		t.Fatalf("updated user = %+v", updated)
+	}
+}
+
+func TestPagination(t *testing.T) {
+	server := newTestServer(t, fakeDatabase{})
+	_, admin := server.register("admin@example.com", "super_admin")
+	var ids []int
+	for _, name := range []string{"A", "B", "C", "D", "E"} {
+		ids = append(ids, server.createProduct(admin.AccessToken, name, 1, 0))
+	}
+
+	var page model.Page[model.Product]
+	server.expect(http.MethodGet, "/api/products?page=1&limit=2&name=", admin.AccessToken, nil, http.StatusOK, &page)
+	if len(page.Items) != 2 || page.Total != 5 || page.Page != 1 || page.Limit != 2 {
+		t.Fatalf("first page = %+v", page)
+	}
+	if page.Next != "/api/products?limit=2&name=&page=2" || page.Prev != "" {
+		t.Fatalf("first page links: next %q, prev %q", page.Next, page.Prev)
+	}
+	page = model.Page[model.Product]{}
+	server.expect(http.MethodGet, "/api/products?page=3&limit=2", admin.AccessToken, nil, http.StatusOK, &page)
+	if len(page.Items) != 1 || page.Items[0].ID != ids[4] || page.Next != "" || page.Prev != "/api/products?limit=2&page=2" {
+		t.Fatalf("last page = %+v", page)
+	}
+
+	// Keyset pagination starts with an empty cursor and follows the next links.
+	var walked []int
+	next := "/api/products?cursor=&limit=2"
+	for next != "" {
+		page = model.Page[model.Product]{}
+		server.expect(http.MethodGet, next, admin.AccessToken, nil, http.StatusOK, &page)
+		if page.Total != 5 || page.Page != 0 || page.Prev != "" {
+			t.Fatalf("cursor page = %+v", page)
+		}
+		for _, product := range page.Items {
+			walked = append(walked, product.ID)
+		}
+		next = page.Next
+	}
+	if fmt.Sprint(walked) != fmt.Sprint(ids) {
+		t.Fatalf("walked products %v, want %v", walked, ids)
+	}
+
+	var users model.Page[model.User]
+	server.expect(http.MethodGet, "/api/admin/users?cursor=&limit=1", admin.AccessToken, nil, http.StatusOK, &users)
+	if len(users.Items) != 1 || users.NextCursor != "" || users.Total != 1 {
+		t.Fatalf("users cursor page = %+v", users)
+	}
+
+	for _, query := range []string{"limit=0", "limit=101", "page=abc", "page=1&cursor=", "cursor=not-a-cursor"} {
+		server.expect(http.MethodGet, "/api/products?"+query, admin.AccessToken, nil, http.StatusBadRequest, nil)
+	}
+}
+
+func TestProductFiltersAndSorting(t *testing.T) {
+	server := newTestServer(t, fakeDatabase{})
+	_, admin := server.register("admin@example.com", "super_admin")
+	pen := server.createProduct(admin.AccessToken, "Pen", 2, 0)
+	book := server.createProduct(admin.AccessToken, "Book", 30, 0)
+	bag := server.createProduct(admin.AccessToken, "Bag", 30, 0)
+	lamp := server.createProduct(admin.AccessToken, "Lamp", 55.5, 0)
+
+	list := func(query string) []int {
+		t.Helper()
+		var page model.Page[model.Product]
+		server.expect(http.MethodGet, "/api/products?"+query, admin.AccessToken, nil, http.StatusOK, &page)
+		ids := []int{}
+		for _, product := range page.Items {
+			ids = append(ids, product.ID)
+		}
+		return ids
+	}
+
+	tests := []struct {
+		query string
+		want  []int
+	}{
+		{"sort=price", []int{pen, book, bag, lamp}},
+		{"sort=-price,name", []int{lamp, bag, book, pen}},
+		{"sort=name", []int{bag, book, lamp, pen}},
+		{"min_price=10&max_price=50&sort=name", []int{bag, book}},
+		{"min_price=30", []int{book, bag, lamp}},
+		{fmt.Sprintf("ids=%d,%d", lamp, pen), []int{pen, lamp}},
+		{"created_from=2000-01-01&updated_to=2999-01-01", []int{pen, book, bag, lamp}},
+		{"created_from=2999-01-01T00:00:00Z", []int{}},
+		{"sort=price&limit=2&page=2", []int{bag, lamp}},
+	}
+	for _, tt := range tests {
+		if got := list(tt.query); fmt.Sprint(got) != fmt.Sprint(tt.want) {
+			t.Fatalf("%s: got %v, want %v", tt.query, got, tt.want)
+		}
+	}
+
+	var problem model.Problem
+	server.expect(http.MethodGet, "/api/products?min_price=-1&max_price=x&ids=1,a&created_to=yesterday&sort=stock", admin.AccessToken, nil, http.StatusBadRequest, &problem)
+	var fields []string
+	for _, field := range problem.Errors {
+		fields = append(fields, field.Field)
+	}
+	if fmt.Sprint(fields) != "[ids min_price max_price created_to sort]" {
+		t.Fatalf("invalid filter fields = %v", fields)
+	}
+	for _, query := range []string{"sort=price,price", "sort=price&cursor=", "min_price=5&max_price=1"} {
+		server.expect(http.MethodGet, "/api/products?"+query, admin.AccessToken, nil, http.StatusBadRequest, nil)
+	}
+}
+
+func TestProductSearch(t *testing.T) {
+	server := newTestServer(t, fakeDatabase{})
+	_, admin := server.register("admin@example.com", "super_admin")
+	chair := server.createProduct(admin.AccessToken, "Office Chair", 80, 0)
+	armchair := server.createProduct(admin.AccessToken, "Armchair", 300, 0)
+	chairLeg := server.createProduct(admin.AccessToken, "Chair leg for office chair", 5, 0)
+	server.createProduct(admin.AccessToken, "Desk", 200, 0)
+
+	search := func(query string) model.Page[model.ProductSearchResult] {
+		t.Helper()
+		var page model.Page[model.ProductSearchResult]
+		server.expect(http.MethodGet, "/api/products/search?"+query, admin.AccessToken, nil, http.StatusOK, &page)
+		return page
+	}
+	ids := func(page model.Page[model.ProductSearchResult]) []int {
+		ids := []int{}
+		for _, result := range page.Items {
+			ids = append(ids, result.ID)
+		}
+		return ids
+	}
+
+	// Prefix matching, ranked by the share of the name that matched.
+	results := search("q=chai")
+	if fmt.Sprint(ids(results)) != fmt.Sprint([]int{chair, chairLeg}) || results.Total != 2 {
+		t.Fatalf("chai: got %v", ids(results))
+	}
+	if results.Items[0].Highlight != "Office <mark>Chair</mark>" || results.Items[0].Rank <= results.Items[1].Rank {
+		t.Fatalf("chai: first result = %+v", results.Items[0])
+	}
+	if got := ids(search("q=office+CHAIR")); fmt.Sprint(got) != fmt.Sprint([]int{chair, chairLeg}) {
+		t.Fatalf("office chair: got %v", got)
+	}
+	if got := ids(search("q=chiar")); len(got) != 0 {
+		t.Fatalf("typo without fuzzy: got %v", got)
+	}
+	// Fuzzy matching tolerates typos.
+	if got := ids(search("q=armchiar&fuzzy=true")); fmt.Sprint(got) != fmt.Sprint([]int{armchair}) {
+		t.Fatalf("fuzzy armchiar: got %v", got)
+	}
+	if got := ids(search("q=chai&limit=1&page=2")); fmt.Sprint(got) != fmt.Sprint([]int{chairLeg}) {
+		t.Fatalf("second page: got %v", got)
+	}
+
+	for _, query := range []string{"", "q=", "q=%21%3A%2A", "q=chair&cursor=", "q=" + strings.Repeat("a", 201)} {
+		server.expect(http.MethodGet, "/api/products/search?"+query, admin.AccessToken, nil, http.StatusBadRequest, nil)
+	}
+	server.expect(http.MethodGet, "/api/products/search?q=chair", "", nil, http.StatusUnauthorized, nil)
+}
+
+func TestProductCatalogFields(t *testing.T) {
+	server := newTestServer(t, fakeDatabase{})
+	_, admin := server.register("admin@example.com", "super_admin")
+	_, user := server.register("ana@example.com", "user")
+
+	var lamp model.Product
+	server.expect(http.MethodPost, "/api/products", admin.AccessToken, gin.H{
+		"sku": "LAMP-01", "name": "Desk lamp", "description": "Warm LED light", "brand": "Lumen", "price": 40,
+		"attributes": gin.H{"color": "black", "watts": 8},
+	}, http.StatusCreated, &lamp)
+	if lamp.SKU != "LAMP-01" || lamp.Brand != "Lumen" || lamp.Status != model.ProductStatusActive || lamp.Attributes["color"] != "black" {
+		t.Fatalf("created product = %+v", lamp)
+	}
+	var draft model.Product
+	server.expect(http.MethodPost, "/api/products", admin.AccessToken, gin.H{"sku": "LAMP-02", "name": "Floor lamp", "price": 90, "status": "draft"}, http.StatusCreated, &draft)
+	server.expect(http.MethodPost, "/api/products", admin.AccessToken, gin.H{"sku": "LAMP-01", "name": "Copy", "price": 1}, http.StatusConflict, nil)
+	server.expect(http.MethodPut, fmt.Sprintf("/api/products/%d", draft.ID), admin.AccessToken, gin.H{"sku": "LAMP-01"}, http.StatusConflict, nil)
+
+	// Fields left out are kept and attributes are replaced as a whole.
+	var updated model.Product
+	server.expect(http.MethodPut, fmt.Sprintf("/api/products/%d", lamp.ID), admin.AccessToken, gin.H{"attributes": gin.H{"color": "white"}}, http.StatusOK, &updated)
+	if updated.SKU != "LAMP-01" || updated.Description != "Warm LED light" || len(updated.Attributes) != 1 || updated.Attributes["color"] != "white" {
+		t.Fatalf("updated product = %+v", updated)
+	}
+
+	// Users without product:read_all only see active products.
+	list := func(token, query string) []int {
+		t.Helper()
+		var page model.Page[model.Product]
+		server.expect(http.MethodGet, "/api/products"+query, token, nil, http.StatusOK, &page)
+		ids := []int{}
+		for _, product := range page.Items {
+			ids = append(ids, product.ID)
+		}
+		return ids
+	}
+	if got := list(user.AccessToken, ""); fmt.Sprint(got) != fmt.Sprint([]int{lamp.ID}) {
+		t.Fatalf("user listing: got %v", got)
+	}
+	if got := list(admin.AccessToken, ""); fmt.Sprint(got) != fmt.Sprint([]int{lamp.ID, draft.ID}) {
+		t.Fatalf("admin listing: got %v", got)
+	}
+	if got := list(admin.AccessToken, "?status=draft"); fmt.Sprint(got) != fmt.Sprint([]int{draft.ID}) {
+		t.Fatalf("admin draft listing: got %v", got)
+	}
+	server.expect(http.MethodGet, "/api/products?status=draft", user.AccessToken, nil, http.StatusForbidden, nil)
+	server.expect(http.MethodGet, "/api/products?status=deleted", admin.AccessToken, nil, http.StatusBadRequest, nil)
+	server.expect(http.MethodGet, fmt.Sprintf("/api/products/%d", draft.ID), user.AccessToken, nil, http.StatusNotFound, nil)
+	server.expect(http.MethodGet, fmt.Sprintf("/api/products/%d", draft.ID), admin.AccessToken, nil, http.StatusOK, nil)
+
+	var results model.Page[model.ProductSearchResult]
+	server.expect(http.MethodGet, "/api/products/search?q=lamp", user.AccessToken, nil, http.StatusOK, &results)
+	if results.Total != 1 || results.Items[0].ID != lamp.ID {
+		t.Fatalf("user search = %+v", results)
+	}
+	server.expect(http.MethodGet, "/api/products/search?q=lumen", admin.AccessToken, nil, http.StatusOK, &results)
+	if results.Total != 1 || results.Items[0].ID != lamp.ID {
+		t.Fatalf("brand search = %+v", results)
+	}
+}
+
+func TestRateLimits(t *testing.T) {
+	// A rate this low never refills a request during the test.
+	slow := 0.001
+	server := newTestServerWithRateLimits(t, fakeDatabase{}, config.RateLimitConfig{
+		IP:     model.RateLimit{RequestsPerSecond: 1000, Burst: 1000},
+		Public: model.RateLimit{RequestsPerSecond: slow, Burst: 4},
+		API:    model.RateLimit{RequestsPerSecond: slow, Burst: 2},
+	})
+
+	// Each registration uses two public requests from the same IP.
+	_, ana := server.register("ana@example.com", "user")
+	_, bia := server.register("bia@example.com", "user")
+	rec := server.do(http.MethodPost, "/login", "", gin.H{"email": "ana@example.com", "password": "secret123"})
+	if rec.Code != http.StatusTooManyRequests {
+		t.Fatalf("fifth public request: status %d, want 429", rec.Code)
+	}
+	if rec.Header().Get("Retry-After") == "" || rec.Header().Get("RateLimit-Remaining") != "0" {
+		t.Fatalf("rejected request headers = %v", rec.Header())
+	}
+
+	for i, wantRemaining := range []string{"1", "0"} {
+		rec := server.do(http.MethodGet, "/api/user/info", ana.AccessToken, nil)
+		if rec.Code != http.StatusOK {
+			t.Fatalf("API request %d: status %d, want 200", i+1, rec.Code)
+		}
+		if got := rec.Header().Get("RateLimit-Limit"); got != "2" {
+			t.Fatalf("RateLimit-Limit = %q, want 2", got)
+		}
+		if got := rec.Header().Get("RateLimit-Remaining"); got != wantRemaining {
+			t.Fatalf("API request %d: RateLimit-Remaining = %q, want %s", i+1, got, wantRemaining)
+		}
+	}
+	server.expect(http.MethodGet, "/api/user/info", ana.AccessToken, nil, http.StatusTooManyRequests, nil)
+
+	// The API is limited per user, not per IP.
+	server.expect(http.MethodGet, "/api/user/info", bia.AccessToken, nil, http.StatusOK, nil)
+}
+
+func TestRateLimitPerIP(t *testing.T) {
+	slow := 0.001
+	server := newTestServerWithRateLimits(t, fakeDatabase{}, config.RateLimitConfig{
+		IP:     model.RateLimit{RequestsPerSecond: slow, Burst: 3},
+		Public: model.RateLimit{RequestsPerSecond: 1000, Burst: 1000},
+		API:    model.RateLimit{RequestsPerSecond: 1000, Burst: 1000},
+	})
+
+	// Requests rejected by authentication and probes count against the IP.
+	server.expect(http.MethodGet, "/api/user/info", "not-a-token", nil, http.StatusUnauthorized, nil)
+	server.expect(http.MethodGet, "/api/user/info", "not-a-token", nil, http.StatusUnauthorized, nil)
+	server.expect(http.MethodGet, "/healthz", "", nil, http.StatusOK, nil)
+	server.expect(http.MethodGet, "/api/user/info", "not-a-token", nil, http.StatusTooManyRequests, nil)
+	server.expect(http.MethodGet, "/metrics", "", nil, http.StatusTooManyRequests, nil)
+}
+
+func TestMetrics(t *testing.T) {
+	slow := 0.001
+	server := newTestServerWithRateLimits(t, fakeDatabase{}, config.RateLimitConfig{
+		IP:     model.RateLimit{RequestsPerSecond: 1000, Burst: 1000},
+		Public: model.RateLimit{RequestsPerSecond: slow, Burst: 3},
+		API:    model.RateLimit{RequestsPerSecond: 1000, Burst: 1000},
+	})
+
+	_, ana := server.register("ana@example.com", "user")
+	server.expect(http.MethodPost, "/login", "", gin.H{"email": "ana@example.com", "password": "wrong"}, http.StatusUnauthorized, nil)
+	server.expect(http.MethodPost, "/login", "", gin.H{"email": "ana@example.com", "password": "secret123"}, http.StatusTooManyRequests, nil)
+	server.expect(http.MethodGet, "/api/users/1", ana.AccessToken, nil, http.StatusOK, nil)
+	server.expect(http.MethodGet, "/api/users/2", ana.AccessToken, nil, http.StatusNotFound, nil)
+	server.expect(http.MethodGet, "/no/such/route", "", nil, http.StatusNotFound, nil)
+
+	rec := server.do(http.MethodGet, "/metrics", "", nil)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("GET /metrics: status %d", rec.Code)
+	}
+	body := rec.Body.String()
+	for _, line := range []string{
+		`http_requests_total{method="GET",route="/api/users/:id_user",status="200"} 1`,
+		`http_requests_total{method="GET",route="/api/users/:id_user",status="404"} 1`,
+		`http_requests_total{method="GET",route="unmatched",status="404"} 1`,
+		`http_request_duration_seconds_count{method="POST",route="/login",status="429"} 1`,
+		`rate_limit_rejections_total{group="public"} 1`,
+		`logins_total{result="success"} 1`,
+		`logins_total{result="failure"} 1`,
+	} {
+		if !strings.Contains(body, line+"\n") {
+			t.Errorf("metrics do not contain %q", line)
+		}
+	}
+}
+
+func TestUserRoutes(t *testing.T) {
+	server := newTestServer(t, fakeDatabase{})
+	id_user, user := server.register("ana@example.com", "user")
+	id_admin, admin := server.register("admin@example.com", "admin")
+	id_superAdmin, superAdmin := server.register("root@example.com", "super_admin")
+
+	server.expect(http.MethodGet, fmt.Sprintf("/api/users/%d", id_user), user.AccessToken, nil, http.StatusOK, nil)
+	server.expect(http.MethodGet, "/api/users/999", user.AccessToken, nil, http.StatusNotFound, nil)
+	server.expect(http.MethodGet, "/api/users/abc", user.AccessToken, nil, http.StatusBadRequest, nil)
+
+	var updated model.User
+	server.expect(http.MethodPut, fmt.Sprintf("/api/users/%d", id_user), user.AccessToken, gin.H{"username": "ana maria"}, http.StatusOK, &updated)
+	if updated.Username != "ana maria" {
+		t.Fatalf("username = %q, want %q", updated.Username, "ana maria")
+	}
+	server.expect(http.MethodPut, fmt.Sprintf("/api/users/%d", id_user), user.AccessToken, gin.H{"role": "admin"}, http.StatusForbidden, nil)
+	server.expect(http.MethodPut, fmt.Sprintf("/api/users/%d", id_user), admin.AccessToken, gin.H{"role": "super_admin"}, http.StatusForbidden, nil)
+	server.expect(http.MethodPut, fmt.Sprintf("/api/users/%d", id_user), admin.AccessToken, gin.H{"role": "ghost"}, http.StatusBadRequest, nil)
+	server.expect(http.MethodPut, fmt.Sprintf("/api/users/%d", id_admin), admin.AccessToken, gin.H{"role": "user"}, http.StatusForbidden, nil)
+	server.expect(http.MethodPut, "/api/users/999", user.AccessToken, gin.H{"username": "x"}, http.StatusNotFound, nil)
+
+	var users model.Page[model.User]
+	server.expect(http.MethodGet, "/api/admin/users", admin.AccessToken, nil, http.StatusOK, &users)
+	if len(users.Items) != 3 || users.Total != 3 {
+		t.Fatalf("listed %d of %d users, want 3", len(users.Items), users.Total)
+	}
+	server.expect(http.MethodGet, "/api/admin/users", user.AccessToken, nil, http.StatusForbidden, nil)
+
+	server.expect(http.MethodDelete, fmt.Sprintf("/api/admin/users/%d", id_admin), admin.AccessToken, nil, http.StatusForbidden, nil)
+	server.expect(http.MethodDelete, fmt.Sprintf("/api/admin/users/%d", id_superAdmin), superAdmin.AccessToken, nil, http.StatusForbidden, nil)
+	server.expect(http.MethodDelete, fmt.Sprintf("/api/admin/users/%d", id_admin), superAdmin.AccessToken, nil, http.StatusOK, nil)
+	server.expect(http.MethodDelete, fmt.Sprintf("/api/admin/users/%d", id_user), superAdmin.AccessToken, nil, http.StatusOK, nil)
+	server.expect(http.MethodDelete, "/api/admin/users/999", superAdmin.AccessToken, nil, http.StatusNotFound, nil)
+}
+
+func TestTrash(t *testing.T) {
+	server := newTestServer(t, fakeDatabase{})
+	id_user, user := server.register("ana@example.com", "user")
+	_, admin := server.register("admin@example.com", "admin")
+	id_other, _ := server.register("bo@example.com", "admin")
+	_, superAdmin := server.register("root@example.com", "super_admin")
+
+	// Deleted products are hidden from reads and carts until restored.
+	lamp := server.createProduct(admin.AccessToken, "Lamp", 40, 5)
+	server.expect(http.MethodPost, "/api/cart/items", user.AccessToken, gin.H{"product_id": lamp, "quantity": 1}, http.StatusOK, nil)
+	server.expect(http.MethodDelete, fmt.Sprintf("/api/admin/products/%d", lamp), admin.AccessToken, nil, http.StatusOK, nil)
+	server.expect(http.MethodGet, fmt.Sprintf("/api/products/%d", lamp), admin.AccessToken, nil, http.StatusNotFound, nil)
+	server.expect(http.MethodPut, fmt.Sprintf("/api/products/%d", lamp), admin.AccessToken, gin.H{"price": 1}, http.StatusNotFound, nil)
+	var cart model.Cart
+	server.expect(http.MethodGet, "/api/cart", user.AccessToken, nil, http.StatusOK, &cart)
+	if len(cart.Items) != 0 {
+		t.Fatalf("cart with a deleted product = %+v", cart)
+	}
+	// The SKU stays taken while the product is in the trash.
+	server.expect(http.MethodPost, "/api/products", admin.AccessToken, gin.H{"sku": "Lamp", "name": "Lamp", "price": 1}, http.StatusConflict, nil)
+
+	var products model.Page[model.Product]
+	server.expect(http.MethodGet, "/api/admin/products/trash", admin.AccessToken, nil, http.StatusOK, &products)
+	if products.Total != 1 || products.Items[0].ID != lamp || products.Items[0].DeletedAt == nil {
+		t.Fatalf("product trash = %+v", products)
+	}
+	server.expect(http.MethodGet, "/api/admin/products/trash", user.AccessToken, nil, http.StatusForbidden, nil)
+
+	var restored model.Product
+	server.expect(http.MethodPost, fmt.Sprintf("/api/admin/products/%d/restore", lamp), admin.AccessToken, nil, http.StatusOK, &restored)
+	if restored.ID != lamp || restored.DeletedAt != nil {
+		t.Fatalf("restored product = %+v", restored)
+	}
+	server.expect(http.MethodPost, fmt.Sprintf("/api/admin/products/%d/restore", lamp), admin.AccessToken, nil, http.StatusNotFound, nil)
+	server.expect(http.MethodGet, fmt.Sprintf("/api/products/%d", lamp), user.AccessToken, nil, http.StatusOK, nil)
+	server.expect(http.MethodGet, "/api/cart", user.AccessToken, nil, http.StatusOK, &cart)
+	if len(cart.Items) != 1 {
+		t.Fatalf("cart after restore = %+v", cart)
+	}
+
+	// Deleted users cannot sign in and keep their email until purged.
+	server.expect(http.MethodDelete, fmt.Sprintf("/api/admin/users/%d", id_user), admin.AccessToken, nil, http.StatusOK, nil)
+	server.expect(http.MethodPost, "/login", "", gin.H{"email": "ana@example.com", "password": "secret123"}, http.StatusUnauthorized, nil)
+	server.expect(http.MethodPost, "/refresh", "", gin.H{"refresh_token": user.RefreshToken}, http.StatusUnauthorized, nil)
+	server.expect(http.MethodPost, "/register", "", gin.H{"username": "ana", "email": "ana@example.com", "password": "secret123"}, http.StatusConflict, nil)
+	server.expect(http.MethodGet, fmt.Sprintf("/api/users/%d", id_user), admin.AccessToken, nil, http.StatusNotFound, nil)
+
+	var users model.Page[model.User]
+	server.expect(http.MethodGet, "/api/admin/users", admin.AccessToken, nil, http.StatusOK, &users)
+	if users.Total != 3 {
+		t.Fatalf("listed %d users, want 3", users.Total)
+	}
+	server.expect(http.MethodDelete, fmt.Sprintf("/api/admin/users/%d", id_other), superAdmin.AccessToken, nil, http.StatusOK, nil)
+	server.expect(http.MethodGet, "/api/admin/users/trash", admin.AccessToken, nil, http.StatusOK, &users)
+	if users.Total != 2 || users.Items[0].ID != id_user || users.Items[0].DeletedAt == nil || users.Items[0].Password != "" {
+		t.Fatalf("user trash = %+v", users)
+	}
+
+	// Restoring follows the same role levels as deleting.
+	server.expect(http.MethodPost, fmt.Sprintf("/api/admin/users/%d/restore", id_other), admin.AccessToken, nil, http.StatusForbidden, nil)
+	server.expect(http.MethodPost, fmt.Sprintf("/api/admin/users/%d/restore", id_other), superAdmin.AccessToken, nil, http.StatusOK, nil)
+	server.expect(http.MethodPost, fmt.Sprintf("/api/admin/users/%d/restore", id_user), admin.AccessToken, nil, http.StatusOK, nil)
+	server.expect(http.MethodPost, fmt.Sprintf("/api/admin/users/%d/restore", id_user), admin.AccessToken, nil, http.StatusNotFound, nil)
+	server.expect(http.MethodPost, "/api/admin/users/abc/restore", admin.AccessToken, nil, http.StatusBadRequest, nil)
+	server.expect(http.MethodPost, "/login", "", gin.H{"email": "ana@example.com", "password": "secret123"}, http.StatusOK, nil)
+}
+
+func TestPriceHistory(t *testing.T) {
+	server := newTestServer(t, fakeDatabase{})
+	_, user := server.register("ana@example.com", "user")
+	_, admin := server.register("admin@example.com", "admin")
+	_, superAdmin := server.register("root@example.com", "super_admin")
+
+	lamp := server.createProduct(admin.AccessToken, "Lamp", 40, 5)
+	prices := fmt.Sprintf("/api/products/%d/prices", lamp)
+	server.expect(http.MethodPut, fmt.Sprintf("/api/products/%d", lamp), admin.AccessToken, gin.H{"price": 45}, http.StatusOK, nil)
+	// Updates that keep the price are not part of its history.
+	server.expect(http.MethodPut, fmt.Sprintf("/api/products/%d", lamp), admin.AccessToken, gin.H{"name": "Desk Lamp"}, http.StatusOK, nil)
+
+	var history model.Page[model.ProductPrice]
+	server.expect(http.MethodGet, prices, user.AccessToken, nil, http.StatusOK, &history)
+	if history.Total != 2 || history.Items[0].Price.String() != "45" || history.Items[1].Price.String() != "40" {
+		t.Fatalf("price history = %+v", history)
+	}
+
+	effectiveFrom := time.Now().Add(24 * time.Hour).UTC().Truncate(time.Second)
+	var scheduled model.ProductPrice
+	server.expect(http.MethodPost, prices, admin.AccessToken, gin.H{"price": 30, "effective_from": effectiveFrom}, http.StatusCreated, &scheduled)
+	if scheduled.ProductID != lamp || scheduled.Price.String() != "30" || !scheduled.EffectiveFrom.Equal(effectiveFrom) {
+		t.Fatalf("scheduled price = %+v", scheduled)
+	}
+	server.expect(http.MethodGet, prices, user.AccessToken, nil, http.StatusOK, &history)
+	if history.Total != 3 || history.Items[0].ID != scheduled.ID {
+		t.Fatalf("price history with a scheduled price = %+v", history)
+	}
+
+	// Scheduled prices only apply once their date is reached.
+	var product model.Product
+	server.expect(http.MethodGet, fmt.Sprintf("/api/products/%d", lamp), user.AccessToken, nil, http.StatusOK, &product)
+	if product.Price.String() != "45" {
+		t.Fatalf("price before the scheduled date = %v, want 45", product.Price)
+	}
+
+	server.expect(http.MethodPost, prices, admin.AccessToken, gin.H{"price": 30, "effective_from": time.Now().Add(-time.Hour)}, http.StatusBadRequest, nil)
+	server.expect(http.MethodPost, prices, admin.AccessToken, gin.H{"effective_from": effectiveFrom}, http.StatusBadRequest, nil)
+	server.expect(http.MethodPost, prices, admin.AccessToken, gin.H{"price": -1, "effective_from": effectiveFrom}, http.StatusBadRequest, nil)
+	server.expect(http.MethodPost, "/api/products/999/prices", admin.AccessToken, gin.H{"price": 30, "effective_from": effectiveFrom}, http.StatusNotFound, nil)
+	server.expect(http.MethodGet, "/api/products/999/prices", user.AccessToken, nil, http.StatusNotFound, nil)
+	server.expect(http.MethodGet, "/api/products/abc/prices", user.AccessToken, nil, http.StatusBadRequest, nil)
+
+	var entries model.Page[model.AuditEntry]
+	server.expect(http.MethodGet, "/api/admin/audit?action=product.price_schedule", superAdmin.AccessToken, nil, http.StatusOK, &entries)
+	if entries.Total != 1 || entries.Items[0].TargetID != lamp || entries.Items[0].Changes["price"].After != 30.0 {
+		t.Fatalf("price schedule audit entries = %+v", entries)
+	}
+}
+
+func TestCurrencies(t *testing.T) {
+	server := newTestServer(t, fakeDatabase{})
+	_, user := server.register("ana@example.com", "user")
+	_, admin := server.register("admin@example.com", "admin")
+
+	var pen model.Product
+	server.expect(http.MethodPost, "/api/products", admin.AccessToken, gin.H{
+		"sku": "PEN", "name": "Pen", "price": 0.1, "price_list": gin.H{"EUR": 0.09, "JPY": 15},
+	}, http.StatusCreated, &pen)
+	if pen.Currency != "USD" || pen.Price.String() != "0.1" || pen.PriceList["JPY"].String() != "15" {
+		t.Fatalf("created product = %+v", pen)
+	}
+	lamp := server.createProduct(admin.AccessToken, "Lamp", 19.99, 5)
+
+	// Amounts are exact: three pens cost 0.3, not 0.30000000000000004.
+	var cart model.Cart
+	server.expect(http.MethodPost, "/api/cart/items", user.AccessToken, gin.H{"product_id": pen.ID, "quantity": 3}, http.StatusOK, &cart)
+	if cart.Total.String() != "0.3" || cart.Currency != "USD" {
+		t.Fatalf("cart = %+v, want a total of 0.3 USD", cart)
+	}
+
+	// Prices from the price list are used as they are.
+	var product model.Product
+	server.expect(http.MethodGet, fmt.Sprintf("/api/products/%d?currency=jpy", pen.ID), user.AccessToken, nil, http.StatusOK, &product)
+	if product.Currency != "JPY" || product.Price.String() != "15" {
+		t.Fatalf("pen in JPY = %v %s", product.Price, product.Currency)
+	}
+
+	// Other prices need an exchange rate for both currencies.
+	lampPath := fmt.Sprintf("/api/products/%d?currency=EUR", lamp)
+	server.expect(http.MethodGet, lampPath, user.AccessToken, nil, http.StatusBadRequest, nil)
+	rates := usecase.NewExchangeRateUsecase(server.store.ExchangeRateRepository())
+	if _, err := rates.LoadExchangeRates(context.Background(), strings.NewReader("currency,rate\nUSD,1\nEUR,0.92\nJPY,151.3\n")); err != nil {
+		t.Fatalf("load exchange rates: %v", err)
+	}
+	server.expect(http.MethodGet, lampPath, user.AccessToken, nil, http.StatusOK, &product)
+	if product.Currency != "EUR" || product.Price.String() != "18.39" {
+		t.Fatalf("lamp in EUR = %v %s, want 18.39 EUR", product.Price, product.Currency)
+	}
+	var products model.Page[model.Product]
+	server.expect(http.MethodGet, "/api/products?currency=JPY&sort=name", user.AccessToken, nil, http.StatusOK, &products)
+	if len(products.Items) != 2 || products.Items[0].Price.String() != "3024" || products.Items[1].Price.String() != "15" {
+		t.Fatalf("products in JPY = %+v", products.Items)
+	}
+	var results model.Page[model.ProductSearchResult]
+	server.expect(http.MethodGet, "/api/products/search?q=lamp&currency=EUR", user.AccessToken, nil, http.StatusOK, &results)
+	if results.Total != 1 || results.Items[0].Price.String() != "18.39" {
+		t.Fatalf("search results in EUR = %+v", results.Items)
+	}
+
+	server.expect(http.MethodGet, fmt.Sprintf("/api/products/%d?currency=XYZ", lamp), user.AccessToken, nil, http.StatusBadRequest, nil)
+	server.expect(http.MethodGet, fmt.Sprintf("/api/products/%d?currency=GBP", lamp), user.AccessToken, nil, http.StatusBadRequest, nil)
+
+	// Prices must fit the minor unit of their currency.
+	for _, body := range []gin.H{
+		{"price": 1.005},
+		{"price_list": gin.H{"JPY": 15.5}},
+		{"price_list": gin.H{"USD": 1}},
+		{"price_list": gin.H{"XYZ": 1}},
+		{"price_list": gin.H{"EUR": -1}},
+	} {
+		server.expect(http.MethodPut, fmt.Sprintf("/api/products/%d", pen.ID), admin.AccessToken, body, http.StatusBadRequest, nil)
+	}
+
+	// A price list that is sent replaces the current one.
+	var updated model.Product
+	server.expect(http.MethodPut, fmt.Sprintf("/api/products/%d", pen.ID), admin.AccessToken, gin.H{"price_list": gin.H{"EUR": 0.1}}, http.StatusOK, &updated)
+	if len(updated.PriceList) != 1 || updated.PriceList["EUR"].String() != "0.1" {
+		t.Fatalf("price list = %v", updated.PriceList)
+	}
+}
+
+func TestProductImport(t *testing.T) {
+	server := newTestServer(t, fakeDatabase{})
+	_, user := server.register("ana@example.com", "user")
+	_, admin := server.register("admin@example.com", "admin")
+	_, superAdmin := server.register("root@example.com", "super_admin")
+	lamp := server.createProduct(admin.AccessToken, "Lamp", 40, 0)
+	server.expect(http.MethodPut, fmt.Sprintf("/api/products/%d", lamp), admin.AccessToken, gin.H{"status": "draft"}, http.StatusOK, nil)
+	gone := server.createProduct(admin.AccessToken, "Gone", 5, 0)
+	server.expect(http.MethodDelete, fmt.Sprintf("/api/admin/products/%d", gone), admin.AccessToken, nil, http.StatusOK, nil)
+
+	csvFile := "sku,name,price,category_id,attributes\n" +
+		"Lamp,Desk lamp,45.50,,\n" +
+		"PEN,Pen,0.1,,\"{\"\"color\"\": \"\"blue\"\"}\"\n" +
+		"Gone,Gone,5,,\n" +
+		"CUP,Cup,-1,x,\n" +
+		"PEN,Pen again,1,,\n"
+	path := "/api/admin/products/import"
+	server.upload(path, user.AccessToken, "products.csv", csvFile, http.StatusForbidden, nil)
+
+	// A dry run reports the rows without saving them.
+	var report model.ImportReport
+	server.upload(path+"?dry_run=true", admin.AccessToken, "products.csv", csvFile, http.StatusOK, &report)
+	if !report.DryRun || report.Created != 1 || report.Updated != 1 || report.Failed != 3 || report.Rows[1].ProductID != 0 {
+		t.Fatalf("dry run report = %+v", report)
+	}
+	var products model.Page[model.Product]
+	server.expect(http.MethodGet, "/api/products?name=pen", admin.AccessToken, nil, http.StatusOK, &products)
+	if products.Total != 0 {
+		t.Fatalf("dry run created %d products", products.Total)
+	}
+
+	server.upload(path+"?batch_size=1", admin.AccessToken, "products.csv", csvFile, http.StatusOK, &report)
+	statuses := make([]string, len(report.Rows))
+	for i, row := range report.Rows {
+		statuses[i] = row.Status
+	}
+	want := []string{model.ImportUpdated, model.ImportCreated, model.ImportFailed, model.ImportFailed, model.ImportFailed}
+	if !slices.Equal(statuses, want) || report.Rows[0].ProductID != lamp || report.Rows[4].Line != 6 {
+		t.Fatalf("import report = %+v, want statuses %v", report, want)
+	}
+	if fields := report.Rows[3].Errors; len(fields) != 1 || fields[0].Field != "category_id" {
+		t.Fatalf("errors of the invalid row = %+v", fields)
+	}
+	if fields := report.Rows[2].Errors; len(fields) != 1 || fields[0].Field != "sku" {
+		t.Fatalf("errors of the trashed sku = %+v", fields)
+	}
+
+	// Updates keep the status, which the file leaves out.
+	var product model.Product
+	server.expect(http.MethodGet, fmt.Sprintf("/api/products/%d", lamp), admin.AccessToken, nil, http.StatusOK, &product)
+	if product.Name != "Desk lamp" || product.Price.String() != "45.5" || product.Status != model.ProductStatusDraft {
+		t.Fatalf("updated product = %+v", product)
+	}
+	server.expect(http.MethodGet, fmt.Sprintf("/api/products/%d", report.Rows[1].ProductID), admin.AccessToken, nil, http.StatusOK, &product)
+	if product.Status != model.ProductStatusActive || product.Attributes["color"] != "blue" {
+		t.Fatalf("created product = %+v", product)
+	}
+	// Each saved row is audited like the matching product request.
+	var entries model.Page[model.AuditEntry]
+	server.expect(http.MethodGet, fmt.Sprintf("/api/admin/audit?target_type=product&target_id=%d&action=product.update", lamp), superAdmin.AccessToken, nil, http.StatusOK, &entries)
+	if entries.Total != 2 || entries.Items[0].Changes["name"].After != "Desk lamp" {
+		t.Fatalf("audit entries of the lamp = %+v", entries.Items)
+	}
+
+	jsonl := `{"sku": "PEN", "name": "Blue pen", "price": "0.15"}` + "\n\n" + `{"sku": "BAD", "price": -1}` + "\n" + `not json`
+	server.upload(path, admin.AccessToken, "products.jsonl", jsonl, http.StatusOK, &report)
+	if report.Updated != 1 || report.Failed != 2 || report.Rows[1].Line != 3 || report.Rows[2].Errors[0].Field != "row" {
+		t.Fatalf("jsonl report = %+v", report)
+	}
+
+	// Files that cannot be read are rejected as a whole.
+	server.upload(path, admin.AccessToken, "products.txt", csvFile, http.StatusBadRequest, nil)
+	server.upload(path+"?format=csv", admin.AccessToken, "products.txt", "sku,name,colour\n", http.StatusBadRequest, nil)
+	server.upload(path, admin.AccessToken, "products.csv", "sku,name\n", http.StatusBadRequest, nil)
+	server.upload(path, admin.AccessToken, "products.csv", "sku,name\n"+strings.Repeat("A,A\n", 6), http.StatusBadRequest, nil)
+	server.upload(path+"?batch_size=-1", admin.AccessToken, "products.csv", csvFile, http.StatusBadRequest, nil)
+	server.expect(http.MethodPost, path, admin.AccessToken, gin.H{"sku": "PEN"}, http.StatusBadRequest, nil)
+}
+
+func TestAuditLog(t *testing.T) {
+	server := newTestServer(t, fakeDatabase{})
+	id_user, user := server.register("ana@example.com", "user")
+	id_admin, admin := server.register("admin@example.com", "admin")
+	_, superAdmin := server.register("root@example.com", "super_admin")
+
+	lamp := server.createProduct(admin.AccessToken, "Lamp", 40, 5)
+	server.expect(http.MethodPut, fmt.Sprintf("/api/products/%d", lamp), admin.AccessToken, gin.H{"price": 45}, http.StatusOK, nil)
+	server.expect(http.MethodDelete, fmt.Sprintf("/api/admin/products/%d", lamp), admin.AccessToken, nil, http.StatusOK, nil)
+	// Deleting a product that is already gone is not found and not recorded.
+	server.expect(http.MethodDelete, fmt.Sprintf("/api/admin/products/%d", lamp), admin.AccessToken, nil, http.StatusNotFound, nil)
+	server.expect(http.MethodPut, fmt.Sprintf("/api/users/%d", id_user), superAdmin.AccessToken, gin.H{"role": "admin", "password": "changed123"}, http.StatusOK, nil)
+	server.expect(http.MethodPut, fmt.Sprintf("/api/users/%d", id_user), superAdmin.AccessToken, gin.H{"username": "ana2"}, http.StatusOK, nil)
+
+	// Only super admins can read the audit log.
+	server.expect(http.MethodGet, "/api/admin/audit", admin.AccessToken, nil, http.StatusForbidden, nil)
+	server.expect(http.MethodGet, "/api/admin/audit", user.AccessToken, nil, http.StatusForbidden, nil)
+
+	var entries model.Page[model.AuditEntry]
+	server.expect(http.MethodGet, "/api/admin/audit", superAdmin.AccessToken, nil, http.StatusOK, &entries)
+	var actions []string
+	for _, entry := range entries.Items {
+		actions = append(actions, entry.Action)
+	}
+	want := []string{model.AuditUserUpdate, model.AuditUserRoleChange, model.AuditProductDelete, model.AuditProductUpdate, model.AuditProductStockAdjust, model.AuditProductCreate}
+	if !slices.Equal(actions, want) {
+		t.Fatalf("audit actions = %v, want %v", actions, want)
+	}
+
+	roleChange := entries.Items[1]
+	if roleChange.TargetType != model.AuditTargetUser || roleChange.TargetID != id_user || roleChange.IP == "" || roleChange.RequestID == "" {
+		t.Fatalf("role change entry = %+v", roleChange)
+	}
+	if change := roleChange.Changes["role"]; change.Before != "user" || change.After != "admin" {
+		t.Fatalf("role change = %+v", change)
+	}
+	if change := roleChange.Changes["password"]; change.Before != logging.Redacted || change.After != logging.Redacted {
+		t.Fatalf("password change = %+v", change)
+	}
+
+	update := entries.Items[3]
+	if update.ActorID != id_admin || len(update.Changes) != 1 || update.Changes["price"].Before != 40.0 || update.Changes["price"].After != 45.0 {
+		t.Fatalf("product update entry = %+v", update)
+	}
+	if deletion := entries.Items[2]; deletion.Changes["sku"].Before != "Lamp" || deletion.Changes["sku"].After != nil {
+		t.Fatalf("product delete entry = %+v", deletion)
+	}
+
+	server.expect(http.MethodGet, fmt.Sprintf("/api/admin/audit?target_type=product&target_id=%d&action=product.update", lamp), superAdmin.AccessToken, nil, http.StatusOK, &entries)
+	if entries.Total != 1 || entries.Items[0].Action != model.AuditProductUpdate {
+		t.Fatalf("filtered audit log = %+v", entries)
+	}
+	server.expect(http.MethodGet, fmt.Sprintf("/api/admin/audit?actor_id=%d&from=2000-01-01", id_admin), superAdmin.AccessToken, nil, http.StatusOK, &entries)
+	if entries.Total != 4 {
+		t.Fatalf("audit entries of the admin = %d, want 4", entries.Total)
+	}
+	server.expect(http.MethodGet, "/api/admin/audit?to=2000-01-01", superAdmin.AccessToken, nil, http.StatusOK, &entries)
+	if entries.Total != 0 {
+		t.Fatalf("audit entries before 2000 = %d", entries.Total)
+	}
+	server.expect(http.MethodGet, "/api/admin/audit?actor_id=abc&from=yesterday", superAdmin.AccessToken, nil, http.StatusBadRequest, nil)
+
+	// A restore is recorded against the trashed product, so only deleted_at
+	// changes.
+	server.expect(http.MethodPost, fmt.Sprintf("/api/admin/products/%d/restore", lamp), admin.AccessToken, nil, http.StatusOK, nil)
+	server.expect(http.MethodGet, "/api/admin/audit?action=product.restore", superAdmin.AccessToken, nil, http.StatusOK, &entries)
+	if entries.Total != 1 || len(entries.Items[0].Changes) != 1 {
+		t.Fatalf("restore entries = %+v", entries.Items)
+	}
+	if change := entries.Items[0].Changes["deleted_at"]; change.Before == nil || change.After != nil {
+		t.Fatalf("restore change = %+v", change)
+	}
+}
+
+func TestAuditLogAdminChanges(t *testing.T) {
+	server := newTestServer(t, fakeDatabase{})
+	_, user := server.register("ana@example.com", "user")
+	_, admin := server.register("admin@example.com", "admin")
+	_, superAdmin := server.register("root@example.com", "super_admin")
+
+	var category model.Category
+	server.expect(http.MethodPost, "/api/categories", admin.AccessToken, gin.H{"name": "Lighting"}, http.StatusCreated, &category)
+	server.expect(http.MethodPut, fmt.Sprintf("/api/categories/%d", category.ID), admin.AccessToken, gin.H{"name": "Lamps"}, http.StatusOK, nil)
+	server.expect(http.MethodDelete, fmt.Sprintf("/api/categories/%d", category.ID), admin.AccessToken, nil, http.StatusOK, nil)
+
+	lamp := server.createProduct(admin.AccessToken, "Lamp", 40, 5)
+	server.expect(http.MethodPost, fmt.Sprintf("/api/admin/products/%d/stock", lamp), admin.AccessToken, gin.H{"type": "receive", "quantity": 3, "reason": "delivery"}, http.StatusCreated, nil)
+
+	var order model.Order
+	server.expect(http.MethodPost, "/api/cart/items", user.AccessToken, gin.H{"product_id": lamp, "quantity": 1}, http.StatusOK, nil)
+	server.expect(http.MethodPost, "/api/cart/checkout", user.AccessToken, nil, http.StatusCreated, &order)
+	server.expect(http.MethodPut, fmt.Sprintf("/api/admin/orders/%d/status", order.ID), admin.AccessToken, gin.H{"status": "paid"}, http.StatusOK, nil)
+	// A rejected transition changes nothing and is not recorded.
+	server.expect(http.MethodPut, fmt.Sprintf("/api/admin/orders/%d/status", order.ID), admin.AccessToken, gin.H{"status": "pending"}, http.StatusConflict, nil)
+
+	server.expect(http.MethodPost, "/api/admin/roles", superAdmin.AccessToken, gin.H{"name": "support", "level": 10, "permissions": []string{"order:read_all"}}, http.StatusCreated, nil)
+	server.expect(http.MethodPut, "/api/admin/roles/support/permissions", superAdmin.AccessToken, gin.H{"permissions": []string{"order:read_all", "user:read"}}, http.StatusOK, nil)
+	server.expect(http.MethodDelete, "/api/admin/roles/support", superAdmin.AccessToken, nil, http.StatusOK, nil)
+	server.expect(http.MethodDelete, "/api/admin/roles/support", superAdmin.AccessToken, nil, http.StatusNotFound, nil)
+
+	var entries model.Page[model.AuditEntry]
+	server.expect(http.MethodGet, "/api/admin/audit", superAdmin.AccessToken, nil, http.StatusOK, &entries)
+	var actions []string
+	for _, entry := range entries.Items {
+		actions = append(actions, entry.Action)
+	}
+	want := []string{
+		model.AuditRoleDelete, model.AuditRolePermissionChange, model.AuditRoleCreate,
+		model.AuditOrderStatusChange, model.AuditProductStockAdjust, model.AuditProductStockAdjust, model.AuditProductCreate,
+		model.AuditCategoryDelete, model.AuditCategoryUpdate, model.AuditCategoryCreate,
+	}
+	if !slices.Equal(actions, want) {
+		t.Fatalf("audit actions = %v, want %v", actions, want)
+	}
+
+	if change := entries.Items[1].Changes["permissions"]; change.After == nil || len(change.After.([]any)) != 2 {
+		t.Fatalf("role permissions change = %+v", entries.Items[1])
+	}
+	if status := entries.Items[3]; status.TargetType != model.AuditTargetOrder || status.TargetID != order.ID || status.Changes["status"].Before != "pending" || status.Changes["status"].After != "paid" {
+		t.Fatalf("order status entry = %+v", status)
+	}
+	if stock := entries.Items[4]; stock.TargetID != lamp || stock.Changes["reason"].After != "delivery" || stock.Changes["balance_after"].After != 8.0 || stock.Changes["quantity"].After != 3.0 {
+		t.Fatalf("stock adjustment entry = %+v", stock)
+	}
+	if update := entries.Items[8]; update.TargetType != model.AuditTargetCategory || len(update.Changes) != 1 || update.Changes["name"].After != "Lamps" {
+		t.Fatalf("category update entry = %+v", update)
+	}
+}
+
+func TestRoleRoutes(t *testing.T) {
+	server := newTestServer(t, fakeDatabase{})
+	id_manager, manager := server.register("ana@example.com", "user")
+	_, admin := server.register("admin@example.com", "admin")
+	_, superAdmin := server.register("root@example.com", "super_admin")
+
+	var roles []model.Role
+	server.expect(http.MethodGet, "/api/admin/roles", superAdmin.AccessToken, nil, http.StatusOK, &roles)
+	if len(roles) != 3 || roles[0].Name != "super_admin" {
+		t.Fatalf("roles = %+v, want super_admin, admin and user", roles)
+	}
+	server.expect(http.MethodGet, "/api/admin/roles", admin.AccessToken, nil, http.StatusForbidden, nil)
+
+	var permissions []model.Permission
+	server.expect(http.MethodGet, "/api/admin/permissions", superAdmin.AccessToken, nil, http.StatusOK, &permissions)
+	if len(permissions) == 0 {
+		t.Fatal("no permissions listed")
+	}
+
+	// A catalog manager role is added and granted without code changes.
+	var catalogManager model.Role
+	server.expect(http.MethodPost, "/api/admin/roles", superAdmin.AccessToken,
+		gin.H{"name": "catalog_manager", "level": 10, "permissions": []string{model.PermissionProductWrite}}, http.StatusCreated, &catalogManager)
+	if catalogManager.Name != "catalog_manager" || len(catalogManager.Permissions) != 1 {
+		t.Fatalf("created role = %+v", catalogManager)
+	}
+	server.expect(http.MethodPost, "/api/admin/roles", superAdmin.AccessToken, gin.H{"name": "catalog_manager"}, http.StatusConflict, nil)
+	server.expect(http.MethodPost, "/api/admin/roles", superAdmin.AccessToken, gin.H{"name": "Bad Name"}, http.StatusBadRequest, nil)
+	server.expect(http.MethodPost, "/api/admin/roles", superAdmin.AccessToken, gin.H{"name": "x", "permissions": []string{"fly"}}, http.StatusBadRequest, nil)
+
+	server.expect(http.MethodPut, fmt.Sprintf("/api/users/%d", id_manager), admin.AccessToken, gin.H{"role": "catalog_manager"}, http.StatusOK, nil)
+	server.expect(http.MethodPost, "/api/categories", manager.AccessToken, gin.H{"name": "Toys"}, http.StatusForbidden, nil)
+
+	server.expect(http.MethodPut, "/api/admin/roles/catalog_manager/permissions", superAdmin.AccessToken,
+		gin.H{"permissions": []string{model.PermissionProductWrite, model.PermissionCategoryWrite}}, http.StatusOK, nil)
+	var managerTokens model.TokenPair
+	server.expect(http.MethodPost, "/login", "", gin.H{"email": "ana@example.com", "password": "secret123"}, http.StatusOK, &managerTokens)
+	server.expect(http.MethodPost, "/api/categories", managerTokens.AccessToken, gin.H{"name": "Toys"}, http.StatusCreated, nil)
+	server.expect(http.MethodGet, "/api/admin/users", managerTokens.AccessToken, nil, http.StatusForbidden, nil)
+
+	server.expect(http.MethodPut, "/api/admin/roles/ghost/permissions", superAdmin.AccessToken, gin.H{"permissions": []string{}}, http.StatusNotFound, nil)
+	server.expect(http.MethodPut, "/api/admin/roles/super_admin/permissions", superAdmin.AccessToken, gin.H{"permissions": []string{}}, http.StatusConflict, nil)
+
+	server.expect(http.MethodDelete, "/api/admin/roles/catalog_manager", superAdmin.AccessToken, nil, http.StatusConflict, nil)
+	server.expect(http.MethodPut, fmt.Sprintf("/api/users/%d", id_manager), superAdmin.AccessToken, gin.H{"role": "user"}, http.StatusOK, nil)
+	server.expect(http.MethodDelete, "/api/admin/roles/catalog_manager", superAdmin.AccessToken, nil, http.StatusOK, nil)
+	server.expect(http.MethodDelete, "/api/admin/roles/catalog_manager", superAdmin.AccessToken, nil, http.StatusNotFound, nil)
+}
+
+func TestProductAndCategoryRoutes(t *testing.T) {
+	server := newTestServer(t, fakeDatabase{})
+	_, user := server.register("ana@example.com", "user")
+	_, admin := server.register("admin@example.com", "admin")
+
+	var electronics, laptops model.Category
+	server.expect(http.MethodPost, "/api/categories", admin.AccessToken, gin.H{"name": "Electronics"}, http.StatusCreated, &electronics)
+	server.expect(http.MethodPost, "/api/categories", admin.AccessToken, gin.H{"name": "Laptops", "parent_id": electronics.ID}, http.StatusCreated, &laptops)
+	server.expect(http.MethodPost, "/api/categories", user.AccessToken, gin.H{"name": "Toys"}, http.StatusForbidden, nil)
+
+	var tree []model.Category
+	server.expect(http.MethodGet, "/api/categories", user.AccessToken, nil, http.StatusOK, &tree)
+	if len(tree) != 1 || len(tree[0].Children) != 1 {
+		t.Fatalf("category tree = %+v, want Electronics > Laptops", tree)
+	}
+	server.expect(http.MethodGet, fmt.Sprintf("/api/categories/%d", laptops.ID), user.AccessToken, nil, http.StatusOK, nil)
+	server.expect(http.MethodGet, "/api/categories/999", user.AccessToken, nil, http.StatusNotFound, nil)
+
+	server.expect(http.MethodPut, fmt.Sprintf("/api/categories/%d", electronics.ID), admin.AccessToken, gin.H{"parent_id": laptops.ID}, http.StatusBadRequest, nil)
+	server.expect(http.MethodPut, fmt.Sprintf("/api/categories/%d", laptops.ID), admin.AccessToken, gin.H{"name": "Notebooks"}, http.StatusOK, nil)
+
+	var notebook model.Product
+	server.expect(http.MethodPost, "/api/products", user.AccessToken, gin.H{"sku": "NB-1", "name": "Notebook", "price": 900, "category_id": laptops.ID}, http.StatusCreated, &notebook)
+	server.expect(http.MethodPost, "/api/products", user.AccessToken, gin.H{"sku": "GHOST", "name": "Ghost", "price": 1, "category_id": 999}, http.StatusBadRequest, nil)
+	server.expect(http.MethodPost, "/api/products", user.AccessToken, gin.H{"sku": "NAMELESS", "price": 1}, http.StatusBadRequest, nil)
+	chair := server.createProduct(admin.AccessToken, "Chair", 80, 0)
+
+	var products model.Page[model.Product]
+	server.expect(http.MethodGet, "/api/products", user.AccessToken, nil, http.StatusOK, &products)
+	if len(products.Items) != 2 || products.Total != 2 {
+		t.Fatalf("listed %d of %d products, want 2", len(products.Items), products.Total)
+	}
+	server.expect(http.MethodGet, fmt.Sprintf("/api/products?category=%d&include_subcategories=true", electronics.ID), user.AccessToken, nil, http.StatusOK, &products)
+	if len(products.Items) != 1 || products.Items[0].ID != notebook.ID {
+		t.Fatalf("products under Electronics = %+v, want the notebook", products.Items)
+	}
+	server.expect(http.MethodGet, "/api/products?page=0", user.AccessToken, nil, http.StatusBadRequest, nil)
+
+	server.expect(http.MethodGet, fmt.Sprintf("/api/products/%d", chair), user.AccessToken, nil, http.StatusOK, nil)
+	server.expect(http.MethodGet, "/api/products/999", user.AccessToken, nil, http.StatusNotFound, nil)
+
+	var updated model.Product
+	server.expect(http.MethodPut, fmt.Sprintf("/api/products/%d", chair), user.AccessToken, gin.H{"price": 75.5}, http.StatusOK, &updated)
+	if updated.Price.String() != "75.5" {
+		t.Fatalf("price = %v, want 75.5", updated.Price)
+	}
+
+	server.expect(http.MethodDelete, fmt.Sprintf("/api/categories/%d", electronics.ID), admin.AccessToken, nil, http.StatusConflict, nil)
+	server.expect(http.MethodDelete, fmt.Sprintf("/api/categories/%d", laptops.ID), admin.AccessToken, nil, http.StatusOK, nil)
+
+	server.expect(http.MethodDelete, fmt.Sprintf("/api/admin/products/%d", chair), user.AccessToken, nil, http.StatusForbidden, nil)
+	server.expect(http.MethodDelete, fmt.Sprintf("/api/admin/products/%d", chair), admin.AccessToken, nil, http.StatusOK, nil)
+	server.expect(http.MethodGet, fmt.Sprintf("/api/products/%d", chair), user.AccessToken, nil, http.StatusNotFound, nil)
+}
+
+func TestInventoryRoutes(t *testing.T) {
+	server := newTestServer(t, fakeDatabase{})
+	_, owner := server.register("ana@example.com", "user")
+	_, other := server.register("bob@example.com", "user")
+	_, admin := server.register("admin@example.com", "admin")
+	id_product := server.createProduct(admin.AccessToken, "Keyboard", 100, 5)
+
+	var stock model.Stock
+	server.expect(http.MethodGet, fmt.Sprintf("/api/products/%d/stock", id_product), owner.AccessToken, nil, http.StatusOK, &stock)
+	if stock.Quantity != 5 {
+		t.Fatalf("stock = %d, want 5", stock.Quantity)
+	}
+
+	stockPath := fmt.Sprintf("/api/admin/products/%d/stock", id_product)
+	server.expect(http.MethodPost, stockPath, owner.AccessToken, gin.H{"type": model.StockReceive, "quantity": 1, "reason": "x"}, http.StatusForbidden, nil)
+	server.expect(http.MethodPost, stockPath, admin.AccessToken, gin.H{"type": model.StockWriteOff, "quantity": 10, "reason": "lost"}, http.StatusConflict, nil)
+	server.expect(http.MethodPost, stockPath, admin.AccessToken, gin.H{"type": model.StockReceive, "quantity": 1}, http.StatusBadRequest, nil)
+	server.expect(http.MethodPost, "/api/admin/products/999/stock", admin.AccessToken, gin.H{"type": model.StockReceive, "quantity": 1, "reason": "x"}, http.StatusNotFound, nil)
+
+	var reservation model.Reservation
+	server.expect(http.MethodPost, "/api/reservations", owner.AccessToken, gin.H{"product_id": id_product, "quantity": 2}, http.StatusCreated, &reservation)
+	server.expect(http.MethodPost, "/api/reservations", owner.AccessToken, gin.H{"product_id": id_product, "quantity": 4}, http.StatusConflict, nil)
+
+	releasePath := fmt.Sprintf("/api/reservations/%d/release", reservation.ID)
+	server.expect(http.MethodPost, releasePath, other.AccessToken, nil, http.StatusForbidden, nil)
+	server.expect(http.MethodPost, releasePath, owner.AccessToken, nil, http.StatusOK, nil)
+	server.expect(http.MethodPost, releasePath, owner.AccessToken, nil, http.StatusConflict, nil)
+	server.expect(http.MethodPost, "/api/reservations/999/release", owner.AccessToken, nil, http.StatusNotFound, nil)
+
+	var movements []model.StockMovement
+	server.expect(http.MethodGet, stockPath+"/movements", admin.AccessToken, nil, http.StatusOK, &movements)
+	if len(movements) != 3 {
+		t.Fatalf("listed %d movements, want 3", len(movements))
+	}
+
+	var reconciliation model.StockReconciliation
+	server.expect(http.MethodGet, stockPath+"/reconcile", admin.AccessToken, nil, http.StatusOK, &reconciliation)
+	if !reconciliation.Consistent || reconciliation.Quantity != 5 {
+		t.Fatalf("reconciliation = %+v, want 5 units and consistent", reconciliation)
+	}
+}
+
+func TestCartAndOrderRoutes(t *testing.T) {
+	server := newTestServer(t, fakeDatabase{})
+	_, user := server.register("ana@example.com", "user")
+	_, other := server.register("bob@example.com", "user")
+	_, admin := server.register("admin@example.com", "admin")
+	keyboard := server.createProduct(admin.AccessToken, "Keyboard", 100, 3)
+	mouse := server.createProduct(admin.AccessToken, "Mouse", 25, 3)
+
+	server.expect(http.MethodPost, "/api/cart/checkout", user.AccessToken, nil, http.StatusBadRequest, nil)
+
+	server.expect(http.MethodPost, "/api/cart/items", user.AccessToken, gin.H{"product_id": keyboard, "quantity": 2}, http.StatusOK, nil)
+	server.expect(http.MethodPost, "/api/cart/items", user.AccessToken, gin.H{"product_id": mouse, "quantity": 1}, http.StatusOK, nil)
+	server.expect(http.MethodPost, "/api/cart/items", user.AccessToken, gin.H{"product_id": 999, "quantity": 1}, http.StatusNotFound, nil)
+	server.expect(http.MethodPut, fmt.Sprintf("/api/cart/items/%d", mouse), user.AccessToken, gin.H{"quantity": 3}, http.StatusOK, nil)
+	server.expect(http.MethodPut, fmt.Sprintf("/api/cart/items/%d", mouse), user.AccessToken, gin.H{"quantity": 0}, http.StatusBadRequest, nil)
+
+	var cart model.Cart
+	server.expect(http.MethodDelete, fmt.Sprintf("/api/cart/items/%d", mouse), user.AccessToken, nil, http.StatusOK, &cart)
+	server.expect(http.MethodDelete, fmt.Sprintf("/api/cart/items/%d", mouse), user.AccessToken, nil, http.StatusNotFound, nil)
+	server.expect(http.MethodGet, "/api/cart", user.AccessToken, nil, http.StatusOK, &cart)
+	if len(cart.Items) != 1 || cart.Total.String() != "200" {
+		t.Fatalf("cart = %+v, want 2 keyboards totalling 200", cart)
+	}
+
+	var order model.Order
+	server.expect(http.MethodPost, "/api/cart/checkout", user.AccessToken, nil, http.StatusCreated, &order)
+	if order.Total.String() != "200" || order.Status != model.OrderPending {
+		t.Fatalf("order = %+v, want a pending order totalling 200", order)
+	}
+
+	var orders []model.Order
+	server.expect(http.MethodGet, "/api/orders", user.AccessToken, nil, http.StatusOK, &orders)
+	if len(orders) != 1 {
+		t.Fatalf("listed %d orders, want 1", len(orders))
+	}
+	orderPath := fmt.Sprintf("/api/orders/%d", order.ID)
+	server.expect(http.MethodGet, orderPath, user.AccessToken, nil, http.StatusOK, nil)
+	server.expect(http.MethodGet, orderPath, other.AccessToken, nil, http.StatusNotFound, nil)
+	server.expect(http.MethodGet, orderPath, admin.AccessToken, nil, http.StatusOK, nil)
+
+	server.expect(http.MethodGet, "/api/admin/orders?status=pending", admin.AccessToken, nil, http.StatusOK, &orders)
+	if len(orders) != 1 {
+		t.Fatalf("listed %d pending orders, want 1", len(orders))
+	}
+	server.expect(http.MethodGet, "/api/admin/orders?status=lost", admin.AccessToken, nil, http.StatusBadRequest, nil)
+	server.expect(http.MethodGet, "/api/admin/orders", user.AccessToken, nil, http.StatusForbidden, nil)
+
+	statusPath := fmt.Sprintf("/api/admin/orders/%d/status", order.ID)
+	server.expect(http.MethodPut, statusPath, admin.AccessToken, gin.H{"status": model.OrderShipped}, http.StatusConflict, nil)
+	server.expect(http.MethodPut, statusPath, admin.AccessToken, gin.H{"status": model.OrderCancelled}, http.StatusOK, nil)
+	server.expect(http.MethodPut, "/api/admin/orders/999/status", admin.AccessToken, gin.H{"status": model.OrderPaid}, http.StatusNotFound, nil)
+
+	var stock model.Stock
+	server.expect(http.MethodGet, fmt.Sprintf("/api/products/%d/stock", keyboard), user.AccessToken, nil, http.StatusOK, &stock)
+	if stock.Quantity != 3 {
+		t.Fatalf("stock after cancel = %d, want 3", stock.Quantity)
+	}
+}
+
+func TestQueryTimeoutReturns504(t *testing.T) {
+	server := newTestServer(t, fakeDatabase{})
+	_, user := server.register("ana@example.com", "user")
+
+	expired, cancel := context.WithDeadline(context.Background(), time.Now().Add(-time.Second))
+	defer cancel()
+
+	for _, path := range []string{"/api/products", "/api/cart"} {
+		req := httptest.NewRequest(http.MethodGet, path, nil).WithContext(expired)
+		req.Header.Set("Authorization", "Bearer "+user.AccessToken)
+		rec := httptest.NewRecorder()
+		server.router.ServeHTTP(rec, req)
+
+		if rec.Code != http.StatusGatewayTimeout {
+			t.Fatalf("GET %s: status %d, want 504; body %s", path, rec.Code, rec.Body.String())
+		}
+	}
+
+	body := bytes.NewReader([]byte(`{"email":"ana@example.com","password":"secret123"}`))
+	req := httptest.NewRequest(http.MethodPost, "/login", body).WithContext(expired)
+	req.Header.Set("Content-Type", "application/json")
+	rec := httptest.NewRecorder()
+	server.router.ServeHTTP(rec, req)
+	if rec.Code != http.StatusGatewayTimeout {
+		t.Fatalf("POST /login: status %d, want 504; body %s", rec.Code, rec.Body.String())
+	}
+}
@@ -0,0 +1,407 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"os"
+	"product-go-api/model"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/joho/godotenv"
+)
+
+type Config struct {
+	Port            string
+	ShutdownTimeout time.Duration
+	ReadinessDrain  time.Duration
+	AutoMigrate     bool
+	DB              DBConfig
+	JWT             JWTConfig
+	RateLimit       RateLimitConfig
+	CORS            CORSConfig
+	Log             LogConfig
+	Tracing         TracingConfig
+	Trash           TrashConfig
+	Pricing         PricingConfig
+	Import          ImportConfig
+	Reservation     ReservationConfig
+}
+
+type DBConfig struct {
+	DSN             string
+	Host            string
+	Port            int
+	User            string
+	Password        string
+	Name            string
+	SSLMode         string
+	MaxOpenConns    int
+	MaxIdleConns    int
+	ConnMaxLifetime time.Duration
+	QueryTimeout    time.Duration
+}
+
+// JWTConfig sets how tokens are signed and how long they last. Expired
+// refresh tokens and revoked access tokens are deleted every CleanupInterval.
+type JWTConfig struct {
+	Secret          string
+	AccessTTL       time.Duration
+	RefreshTTL      time.Duration
+	CleanupInterval time.Duration
+}
+
+// ReservationConfig sets how long stock reservations last and how many units
+// of a product a user can hold in active reservations. Expired reservations
+// are released every ExpireInterval.
+type ReservationConfig struct {
+	TTL            time.Duration
+	MaxQuantity    int
+	ExpireInterval time.Duration
+}
+
+// RateLimitConfig sets the limits of each route group. IP covers every
+// request, limited per IP before authentication; Public covers the routes
+// used before authentication, limited per IP; API covers /api, limited per
+// user. Store is "memory" for limits kept in each instance or "postgres" for
+// limits shared by every instance.
+type RateLimitConfig struct {
+	Store         string
+	EvictInterval time.Duration
+	IP            model.RateLimit
+	Public        model.RateLimit
+	API           model.RateLimit
+}
+
+type CORSConfig struct {
+	AllowOrigins []string
+}
+
+type LogConfig struct {
+	Level  slog.Level
+	Format string
+}
+
+// TracingConfig selects where spans are exported: "none", "otlp" (configured
+// by the standard OTEL_EXPORTER_OTLP_* variables), "stdout" or "file", which
+// appends to File. SampleRatio is the fraction of new traces recorded.
+type TracingConfig struct {
+	Exporter    string
+	File        string
+	SampleRatio float64
+	ServiceName string
+}
+
+// TrashConfig sets how long deleted products and users stay in the trash
+// before the purge job, run every PurgeInterval, removes them for good.
+type TrashConfig struct {
+	Retention     time.Duration
+	PurgeInterval time.Duration
+}
+
+// PricingConfig sets how often scheduled prices that reached their effective
+// date are applied, and the ISO 4217 currency of the store prices.
+type PricingConfig struct {
+	ScheduleInterval time.Duration
+	Currency         string
+}
+
+// ImportConfig limits product imports. BatchSize is the default number of
+// rows saved per transaction, where zero saves each file in one transaction.
+// MaxRows and MaxFileBytes bound the size of an uploaded file.
+type ImportConfig struct {
+	BatchSize    int
+	MaxRows      int
+	MaxFileBytes int
+}
+
+// ConnectionString returns DSN when it is set, or builds one from the
+// individual connection settings.
+func (c DBConfig) ConnectionString() string {
+	if c.DSN != "" {
+		return c.DSN
+	}
+	return fmt.Sprintf("host=%s port=%d user=%s password=%s dbname=%s sslmode=%s",
+		c.Host, c.Port, c.User, c.Password, c.Name, c.SSLMode)
+}
+
+// Load reads the configuration from the environment. Variables from the file
+// named by CONFIG_FILE (default .env) are loaded first, without overriding
+// variables already set. Every invalid or missing value is reported in the
+// returned error.
+func Load() (Config, error) {
+	file := os.Getenv("CONFIG_FILE")
+	if file == "" {
+		file = ".env"
+	}
+	if err := godotenv.Load(file); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return Config{}, fmt.Errorf("config: reading %s: %w", file, err)
+	}
+
+	l := loader{}
+	defaultRate := l.number("RATE_LIMIT_RPS", 3)
+	defaultBurst := l.integer("RATE_LIMIT_BURST", 5)
+	cfg := Config{
+		Port:            l.port("PORT", ":8000"),
+		ShutdownTimeout: l.duration("SHUTDOWN_TIMEOUT", 15*time.Second),
+		ReadinessDrain:  l.duration("READINESS_DRAIN_DELAY", 5*time.Second),
+		AutoMigrate:     l.boolean("AUTO_MIGRATE", false),
+		DB: DBConfig{
+			DSN:             os.Getenv("DB_DSN"),
+			Host:            os.Getenv("DB_HOST"),
+			Port:            l.integer("DB_PORT", 5432),
+			User:            os.Getenv("DB_USER"),
+			Password:        os.Getenv("DB_PASSWORD"),
+			Name:            os.Getenv("DB_NAME"),
+			SSLMode:         l.str("DB_SSLMODE", "disable"),
+			MaxOpenConns:    l.integer("DB_MAX_OPEN_CONNS", 25),
+			MaxIdleConns:    l.integer("DB_MAX_IDLE_CONNS", 25),
+			ConnMaxLifetime: l.duration("DB_CONN_MAX_LIFETIME", 5*time.Minute),
+			QueryTimeout:    l.duration("DB_QUERY_TIMEOUT", 5*time.Second),
+		},
+		JWT: JWTConfig{
+			Secret:          firstNonEmpty(os.Getenv("JWT_SECRET_KEY"), os.Getenv("JWT_SECRET")),
+			AccessTTL:       l.duration("JWT_ACCESS_TTL", 2*time.Hour),
+			RefreshTTL:      l.duration("JWT_REFRESH_TTL", 7*24*time.Hour),
+			CleanupInterval: l.duration("JWT_CLEANUP_INTERVAL", time.Hour),
+		},
+		RateLimit: RateLimitConfig{
+			Store:         l.str("RATE_LIMIT_STORE", "memory"),
+			EvictInterval: l.duration("RATE_LIMIT_EVICT_INTERVAL", time.Minute),
+			IP: model.RateLimit{
+				RequestsPerSecond: l.number("RATE_LIMIT_IP_RPS", defaultRate),
+				Burst:             l.integer("RATE_LIMIT_IP_BURST", defaultBurst),
+			},
+			Public: model.RateLimit{
+				RequestsPerSecond: l.number("RATE_LIMIT_PUBLIC_RPS", defaultRate),
+				Burst:             l.integer("RATE_LIMIT_PUBLIC_BURST", defaultBurst),
+			},
+			API: model.RateLimit{
+				RequestsPerSecond: l.number("RATE_LIMIT_API_RPS", defaultRate),
+				Burst:             l.integer("RATE_LIMIT_API_BURST", defaultBurst),
+			},
+		},
+		CORS: CORSConfig{
+			AllowOrigins: l.list("CORS_ALLOWED_ORIGINS", []string{"*"}),
+		},
+		Log: LogConfig{
+			Level:  l.logLevel("LOG_LEVEL", slog.LevelInfo),
+			Format: l.str("LOG_FORMAT", "json"),
+		},
+		Tracing: TracingConfig{
+			Exporter:    l.str("TRACING_EXPORTER", "none"),
+			File:        l.str("TRACING_FILE", "traces.json"),
+			SampleRatio: l.number("TRACING_SAMPLE_RATIO", 1),
+			ServiceName: l.str("OTEL_SERVICE_NAME", "product-go-api"),
+		},
+		Trash: TrashConfig{
+			Retention:     l.duration("TRASH_RETENTION", 30*24*time.Hour),
+			PurgeInterval: l.duration("TRASH_PURGE_INTERVAL", time.Hour),
+		},
+		Pricing: PricingConfig{
+			ScheduleInterval: l.duration("PRICE_SCHEDULE_INTERVAL", time.Minute),
+			Currency:         l.str("CURRENCY", model.DefaultCurrency),
+		},
+		Import: ImportConfig{
+			BatchSize:    l.integer("IMPORT_BATCH_SIZE", 0),
+			MaxRows:      l.integer("IMPORT_MAX_ROWS", 10000),
+			MaxFileBytes: l.integer("IMPORT_MAX_FILE_BYTES", 10<<20),
+		},
+		Reservation: ReservationConfig{
+			TTL:            l.duration("RESERVATION_TTL", 15*time.Minute),
+			MaxQuantity:    l.integer("RESERVATION_MAX_QUANTITY", 10),
+			ExpireInterval: l.duration("RESERVATION_EXPIRE_INTERVAL", time.Minute),
+		},
+	}
+
+	l.validate(cfg)
+	if len(l.errs) > 0 {
+		return Config{}, fmt.Errorf("config: %w", errors.Join(l.errs...))
+	}
+	return cfg, nil
+}
+
+type loader struct {
+	errs []error
+}
+
+func (l *loader) validate(cfg Config) {
+	if cfg.DB.DSN == "" {
+		required := []struct{ key, value string }{
+			{"DB_HOST", cfg.DB.Host},
+			{"DB_USER", cfg.DB.User},
+			{"DB_NAME", cfg.DB.Name},
+		}
+		for _, r := range required {
+			if r.value == "" {
+				l.errs = append(l.errs, fmt.Errorf("%s is required (or set DB_DSN)", r.key))
+			}
+		}
+	}
+	if cfg.JWT.Secret == "" {
+		l.errs = append(l.errs, errors.New("JWT_SECRET_KEY is required"))
+	}
+	if cfg.JWT.AccessTTL <= 0 || cfg.JWT.RefreshTTL <= 0 {
+		l.errs = append(l.errs, errors.New("JWT_ACCESS_TTL and JWT_REFRESH_TTL must be positive"))
+	}
+	if cfg.ShutdownTimeout <= 0 {
+		l.errs = append(l.errs, errors.New("SHUTDOWN_TIMEOUT must be positive"))
+	}
+	if cfg.ReadinessDrain < 0 {
+		l.errs = append(l.errs, errors.New("READINESS_DRAIN_DELAY must not be negative"))
+	}
+	if cfg.DB.QueryTimeout <= 0 {
+		l.errs = append(l.errs, errors.New("DB_QUERY_TIMEOUT must be positive"))
+	}
+	if cfg.DB.MaxOpenConns < 1 || cfg.DB.MaxIdleConns < 0 {
+		l.errs = append(l.errs, errors.New("DB_MAX_OPEN_CONNS must be positive and DB_MAX_IDLE_CONNS not negative"))
+	}
+	for _, limit := range []model.RateLimit{cfg.RateLimit.IP, cfg.RateLimit.Public, cfg.RateLimit.API} {
+		if limit.RequestsPerSecond <= 0 || limit.Burst < 1 {
+			l.errs = append(l.errs, errors.New("RATE_LIMIT_*_RPS and RATE_LIMIT_*_BURST must be positive"))
+			break
+		}
+	}
+	if cfg.RateLimit.Store != "memory" && cfg.RateLimit.Store != "postgres" {
+		l.errs = append(l.errs, fmt.Errorf("RATE_LIMIT_STORE must be memory or postgres, got %q", cfg.RateLimit.Store))
+	}
+	if cfg.Log.Format != "json" && cfg.Log.Format != "text" {
+		l.errs = append(l.errs, fmt.Errorf("LOG_FORMAT must be json or text, got %q", cfg.Log.Format))
+	}
+	switch cfg.Tracing.Exporter {
+	case "none", "otlp", "stdout", "file":
+	default:
+		l.errs = append(l.errs, fmt.Errorf("TRACING_EXPORTER must be none, otlp, stdout or file, got %q", cfg.Tracing.Exporter))
+	}
+	if cfg.Tracing.SampleRatio < 0 || cfg.Tracing.SampleRatio > 1 {
+		l.errs = append(l.errs, errors.New("TRACING_SAMPLE_RATIO must be between 0 and 1"))
+	}
+	if cfg.JWT.CleanupInterval <= 0 {
+		l.errs = append(l.errs, errors.New("JWT_CLEANUP_INTERVAL must be positive"))
+	}
+	if cfg.RateLimit.EvictInterval <= 0 {
+		l.errs = append(l.errs, errors.New("RATE_LIMIT_EVICT_INTERVAL must be positive"))
+	}
+	if cfg.Trash.Retention < 0 {
+		l.errs = append(l.errs, errors.New("TRASH_RETENTION must not be negative"))
+	}
+	if cfg.Trash.PurgeInterval <= 0 {
+		l.errs = append(l.errs, errors.New("TRASH_PURGE_INTERVAL must be positive"))
+	}
+	if cfg.Pricing.ScheduleInterval <= 0 {
+		l.errs = append(l.errs, errors.New("PRICE_SCHEDULE_INTERVAL must be positive"))
+	}
+	if !model.SupportedCurrency(cfg.Pricing.Currency) {
+		l.errs = append(l.errs, fmt.Errorf("CURRENCY must be an ISO 4217 code with at most 2 decimal places, got %q", cfg.Pricing.Currency))
+	}
+	if cfg.Import.BatchSize < 0 {
+		l.errs = append(l.errs, errors.New("IMPORT_BATCH_SIZE must not be negative"))
+	}
+	if cfg.Import.MaxRows < 1 || cfg.Import.MaxFileBytes < 1 {
+		l.errs = append(l.errs, errors.New("IMPORT_MAX_ROWS and IMPORT_MAX_FILE_BYTES must be positive"))
+	}
+	if cfg.Reservation.TTL <= 0 || cfg.Reservation.ExpireInterval <= 0 || cfg.Reservation.MaxQuantity < 1 {
+		l.errs = append(l.errs, errors.New("RESERVATION_TTL, RESERVATION_EXPIRE_INTERVAL and RESERVATION_MAX_QUANTITY must be positive"))
+	}
+}
+
+func (l *loader) str(key, fallback string) string {
+	if value := os.Getenv(key); value != "" {
+		return value
+	}
+	return fallback
+}
+
+func (l *loader) port(key, fallback string) string {
+	value := l.str(key, fallback)
+	if !strings.Contains(value, ":") {
+		value = ":" + value
+	}
+	return value
+}
+
+func (l *loader) integer(key string, fallback int) int {
+	value := os.Getenv(key)
+	if value == "" {
+		return fallback
+	}
+	n, err := strconv.Atoi(value)
+	if err != nil {
+		l.errs = append(l.errs, fmt.Errorf("%s must be an integer, got %q", key, value))
+	}
+	return n
+}
+
+func (l *loader) number(key string, fallback float64) float64 {
+	value := os.Getenv(key)
+	if value == "" {
+		return fallback
+	}
+	n, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		l.errs = append(l.errs, fmt.Errorf("%s must be a number, got %q", key, value))
+	}
+	return n
+}
+
+func (l *loader) boolean(key string, fallback bool) bool {
+	value := os.Getenv(key)
+	if value == "" {
+		return fallback
+	}
+	b, err := strconv.ParseBool(value)
+	if err != nil {
+		l.errs = append(l.errs, fmt.Errorf("%s must be true or false, got %q", key, value))
+	}
+	return b
+}
+
+func (l *loader) duration(key string, fallback time.Duration) time.Duration {
+	value := os.Getenv(key)
+	if value == "" {
+		return fallback
+	}
+	d, err := time.ParseDuration(value)
+	if err != nil {
+		l.errs = append(l.errs, fmt.Errorf("%s must be a duration such as 30s or 2h, got %q", key, value))
+	}
+	return d
+}
+
+func (l *loader) logLevel(key string, fallback slog.Level) slog.Level {
+	value := os.Getenv(key)
+	if value == "" {
+		return fallback
+	}
+	var level slog.Level
+	if err := level.UnmarshalText([]byte(value)); err != nil {
+		l.errs = append(l.errs, fmt.Errorf("%s must be debug, info, warn or error, got %q", key, value))
+	}
+	return level
+}
+
+func (l *loader) list(key string, fallback []string) []string {
+	value := os.Getenv(key)
+	if value == "" {
+		return fallback
+	}
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+func firstNonEmpty(values ...string) string {
+	for _, value := range values {
+		if value != "" {
+			return value
+		}
+	}
+	return ""
+}
@@ -0,0 +1,90 @@
+package controller
+
+import (
+	"context"
+	"net/http"
+	"product-go-api/apperror"
+	"product-go-api/model"
+	"product-go-api/usecase"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+type auditController struct {
+	auditUseCase usecase.AuditUsecase
+}
+
+func NewAuditController(usecase usecase.AuditUsecase) auditController {
+	return auditController{
+		auditUseCase: usecase,
+	}
+}
+
+// GetAuditLog lists the audit entries, newest first, filtered by actor_id,
+// action, target_type, target_id and the from and to dates.
+func (a *auditController) GetAuditLog(ctx *gin.Context) {
+	page, ok := pageRequest(ctx)
+	if !ok {
+		return
+	}
+
+	filter := model.AuditFilter{
+		Action:     ctx.Query("action"),
+		TargetType: ctx.Query("target_type"),
+	}
+	var fields []model.FieldError
+	for _, id := range []struct {
+		param string
+		value *int
+	}{{"actor_id", &filter.ActorID}, {"target_id", &filter.TargetID}} {
+		if value := ctx.Query(id.param); value != "" {
+			parsed, err := strconv.Atoi(value)
+			if err != nil || parsed < 1 {
+				fields = append(fields, apperror.Field(id.param, "must be a positive number"))
+				continue
+			}
+			*id.value = parsed
+		}
+	}
+	for _, date := range []struct {
+		param string
+		value *time.Time
+	}{{"from", &filter.From}, {"to", &filter.To}} {
+		if value := ctx.Query(date.param); value != "" {
+			parsed, err := parseDate(value)
+			if err != nil {
+				fields = append(fields, apperror.Field(date.param, "must be a date (2006-01-02) or an RFC 3339 timestamp"))
+				continue
+			}
+			*date.value = parsed
+		}
+	}
+	if !failFields(ctx, fields) {
+		return
+	}
+
+	entries, err := a.auditUseCase.GetAuditLog(ctx.Request.Context(), page, filter)
+	if err != nil {
+		handleError(ctx, err, "Failed to retrieve audit log.")
+		return
+	}
+	setPageLinks(ctx, &entries, func(entry model.AuditEntry) int { return entry.ID })
+	ctx.JSON(http.StatusOK, entries)
+}
+
+// recordAudit records action of the current user on a target, changed from
+// before to after. It is called with the context of AuditUsecase.WithinTx
+// that made the change, so an entry that fails to save undoes the change.
+func recordAudit(ctx *gin.Context, txCtx context.Context, auditUseCase usecase.AuditUsecase, action, targetType string, targetID int, before, after any) error {
+	entry := model.AuditEntry{
+		ActorID:    ctx.GetInt("user_id"),
+		Action:     action,
+		TargetType: targetType,
+		TargetID:   targetID,
+		IP:         ctx.ClientIP(),
+		RequestID:  ctx.GetString("request_id"),
+	}
+	return auditUseCase.Record(txCtx, entry, before, after)
+}
@@ -0,0 +1,95 @@
+package controller
+
+import (
+	"net/http"
+	"product-go-api/apperror"
+	"product-go-api/model"
+	"product-go-api/usecase"
+
+	"github.com/gin-gonic/gin"
+)
+
+type cartController struct {
+	cartUseCase  usecase.CartUsecase
+	orderUseCase usecase.OrderUsecase
+}
+
+func NewCartController(cartUsecase usecase.CartUsecase, orderUsecase usecase.OrderUsecase) cartController {
+	return cartController{
+		cartUseCase:  cartUsecase,
+		orderUseCase: orderUsecase,
+	}
+}
+
+func (c *cartController) GetCart(ctx *gin.Context) {
+	cart, err := c.cartUseCase.GetCart(ctx.Request.Context(), ctx.GetInt("user_id"))
+	if err != nil {
+		handleError(ctx, err, "Failed to retrieve cart.")
+		return
+	}
+
+	ctx.JSON(http.StatusOK, cart)
+}
+
+func (c *cartController) AddItem(ctx *gin.Context) {
+	var item model.CartItemRequest
+	if err := ctx.ShouldBindJSON(&item); err != nil || item.ProductID < 1 {
+		fail(ctx, apperror.Validation("Invalid request body"))
+		return
+	}
+
+	cart, err := c.cartUseCase.AddItem(ctx.Request.Context(), ctx.GetInt("user_id"), item)
+	if err != nil {
+		handleError(ctx, err, "Failed to add item to cart.")
+		return
+	}
+
+	ctx.JSON(http.StatusOK, cart)
+}
+
+func (c *cartController) UpdateItem(ctx *gin.Context) {
+	id_product, ok := productIdParam(ctx)
+	if !ok {
+		return
+	}
+
+	var item model.CartItemRequest
+	if err := ctx.ShouldBindJSON(&item); err != nil {
+		fail(ctx, apperror.Validation("Invalid request body"))
+		return
+	}
+	item.ProductID = id_product
+
+	cart, err := c.cartUseCase.UpdateItem(ctx.Request.Context(), ctx.GetInt("user_id"), item)
+	if err != nil {
+		handleError(ctx, err, "Failed to update cart item.")
+		return
+	}
+
+	ctx.JSON(http.StatusOK, cart)
+}
+
+func (c *cartController) RemoveItem(ctx *gin.Context) {
+	id_product, ok := productIdParam(ctx)
+	if !ok {
+		return
+	}
+
+	cart, err := c.cartUseCase.RemoveItem(ctx.Request.Context(), ctx.GetInt("user_id"), id_product)
+	if err != nil {
+		handleError(ctx, err, "Failed to remove cart item.")
+		return
+	}
+
+	ctx.JSON(http.StatusOK, cart)
+}
+
+func (c *cartController) Checkout(ctx *gin.Context) {
+	order, err := c.orderUseCase.Checkout(ctx.Request.Context(), ctx.GetInt("user_id"))
+	if err != nil {
+		handleError(ctx, err, "Failed to place order.")
+		return
+	}
+
+	ctx.JSON(http.StatusCreated, order)
+}
@@ -0,0 +1,187 @@
+package controller
+
+import (
+	"context"
+	"net/http"
+	"product-go-api/apperror"
+	"product-go-api/model"
+	"product-go-api/usecase"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+type categoryController struct {
+	categoryUseCase usecase.CategoryUsecase
+	auditUseCase    usecase.AuditUsecase
+}
+
+func NewCategoryController(usecase usecase.CategoryUsecase, auditUseCase usecase.AuditUsecase) categoryController {
+	return categoryController{
+		categoryUseCase: usecase,
+		auditUseCase:    auditUseCase,
+	}
+}
+
+func (c *categoryController) GetCategories(ctx *gin.Context) {
+	if ctx.Query("flat") == "true" {
+		categories, err := c.categoryUseCase.GetCategories(ctx.Request.Context())
+		if err != nil {
+			handleError(ctx, err, "Failed to retrieve categories.")
+			return
+		}
+		ctx.JSON(http.StatusOK, categories)
+		return
+	}
+
+	tree, err := c.categoryUseCase.GetCategoryTree(ctx.Request.Context())
+	if err != nil {
+		handleError(ctx, err, "Failed to retrieve categories.")
+		return
+	}
+	ctx.JSON(http.StatusOK, tree)
+}
+
+func (c *categoryController) GetCategoryById(ctx *gin.Context) {
+	id_category, ok := categoryIdParam(ctx)
+	if !ok {
+		return
+	}
+
+	category, err := c.categoryUseCase.GetCategoryById(ctx.Request.Context(), id_category)
+	if err != nil {
+		handleError(ctx, err, "Failed to retrieve category.")
+		return
+	}
+
+	if category == nil {
+		fail(ctx, usecase.ErrCategoryNotFound)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, category)
+}
+
+func (c *categoryController) CreateCategory(ctx *gin.Context) {
+	var category model.Category
+	if err := ctx.ShouldBindJSON(&category); err != nil {
+		fail(ctx, apperror.Validation("Invalid request body"))
+		return
+	}
+
+	if category.Name == "" {
+		fail(ctx, apperror.Validation("Category name is required.", apperror.Field("name", "is required")))
+		return
+	}
+
+	var insertedCategory model.Category
+	err := c.auditUseCase.WithinTx(ctx.Request.Context(), func(txCtx context.Context) error {
+		var err error
+		insertedCategory, err = c.categoryUseCase.CreateCategory(txCtx, category)
+		if err != nil {
+			return err
+		}
+		return recordAudit(ctx, txCtx, c.auditUseCase, model.AuditCategoryCreate, model.AuditTargetCategory, insertedCategory.ID, nil, insertedCategory)
+	})
+	if err != nil {
+		handleError(ctx, err, "Failed to create category.")
+		return
+	}
+
+	ctx.JSON(http.StatusCreated, insertedCategory)
+}
+
+func (c *categoryController) UpdateCategory(ctx *gin.Context) {
+	id_category, ok := categoryIdParam(ctx)
+	if !ok {
+		return
+	}
+
+	existingCategory, err := c.categoryUseCase.GetCategoryById(ctx.Request.Context(), id_category)
+	if err != nil {
+		handleError(ctx, err, "Failed to retrieve category.")
+		return
+	}
+
+	if existingCategory == nil {
+		fail(ctx, usecase.ErrCategoryNotFound)
+		return
+	}
+
+	var updateData map[string]interface{}
+	if err := ctx.ShouldBindJSON(&updateData); err != nil {
+		fail(ctx, apperror.Validation("Invalid JSON."))
+		return
+	}
+
+	before := *existingCategory
+
+	if name, ok := updateData["name"].(string); ok && name != "" {
+		existingCategory.Name = name
+	}
+
+	if parentRaw, ok := updateData["parent_id"]; ok {
+		switch parentID := parentRaw.(type) {
+		case nil:
+			existingCategory.ParentID = nil
+		case float64:
+			id_parent := int(parentID)
+			existingCategory.ParentID = &id_parent
+		}
+	}
+
+	var updatedCategory model.Category
+	err = c.auditUseCase.WithinTx(ctx.Request.Context(), func(txCtx context.Context) error {
+		var err error
+		updatedCategory, err = c.categoryUseCase.UpdateCategory(txCtx, *existingCategory)
+		if err != nil {
+			return err
+		}
+		return recordAudit(ctx, txCtx, c.auditUseCase, model.AuditCategoryUpdate, model.AuditTargetCategory, id_category, before, updatedCategory)
+	})
+	if err != nil {
+		handleError(ctx, err, "Failed to update category.")
+		return
+	}
+
+	ctx.JSON(http.StatusOK, updatedCategory)
+}
+
+func (c *categoryController) DeleteCategory(ctx *gin.Context) {
+	id_category, ok := categoryIdParam(ctx)
+	if !ok {
+		return
+	}
+
+	err := c.auditUseCase.WithinTx(ctx.Request.Context(), func(txCtx context.Context) error {
+		category, err := c.categoryUseCase.GetCategoryById(txCtx, id_category)
+		if err != nil {
+			return err
+		}
+		if category == nil {
+			return usecase.ErrCategoryNotFound
+		}
+		if err := c.categoryUseCase.DeleteCategory(txCtx, id_category); err != nil {
+			return err
+		}
+		return recordAudit(ctx, txCtx, c.auditUseCase, model.AuditCategoryDelete, model.AuditTargetCategory, id_category, category, nil)
+	})
+	if err != nil {
+		handleError(ctx, err, "Failed to delete category.")
+		return
+	}
+
+	response := model.Response{
+		Message: "Category deleted successfully",
+	}
+	ctx.JSON(http.StatusOK, response)
+}
+
+func categoryIdParam(ctx *gin.Context) (int, bool) {
+	id_category, err := strconv.Atoi(ctx.Param("id_category"))
+	if err != nil || id_category < 1 {
+		fail(ctx, apperror.Validation("id_category must be a positive number", apperror.Field("id_category", "must be a positive number")))
+		return 0, false
+	}
+	return id_category, true
+}
@@ -0,0 +1,26 @@
+package controller
+
+import (
+	"errors"
+	"product-go-api/apperror"
+
+	"github.com/gin-gonic/gin"
+)
+
+// fail records err for middleware.ErrorHandler, which answers the request
+// with an application/problem+json body.
+func fail(ctx *gin.Context, err error) {
+	_ = ctx.Error(err)
+	ctx.Abort()
+}
+
+// handleError reports an error returned by a usecase. Application errors keep
+// their kind and detail; any other error is internal and reported with
+// message, while the error itself only reaches the logs.
+func handleError(ctx *gin.Context, err error, message string) {
+	var appErr *apperror.Error
+	if !errors.As(err, &appErr) {
+		err = apperror.Internal(message, err)
+	}
+	fail(ctx, err)
+}
@@ -0,0 +1,71 @@
+package controller
+
+import (
+	"context"
+	"database/sql"
+	"net/http"
+	"sync/atomic"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// DatabaseStatus is the part of *sql.DB used by the readiness probe, so the
+// probe can be served without a real database in tests.
+type DatabaseStatus interface {
+	PingContext(ctx context.Context) error
+	Stats() sql.DBStats
+}
+
+type healthController struct {
+	connection   DatabaseStatus
+	shuttingDown *atomic.Bool
+}
+
+// NewHealthController takes the shutdown flag from the caller; setting it
+// makes readiness fail so the orchestrator stops routing new traffic while
+// in-flight requests drain.
+func NewHealthController(connection DatabaseStatus, shuttingDown *atomic.Bool) healthController {
+	return healthController{
+		connection:   connection,
+		shuttingDown: shuttingDown,
+	}
+}
+
+// Liveness reports that the process is up and serving HTTP.
+func (h *healthController) Liveness(ctx *gin.Context) {
+	ctx.JSON(http.StatusOK, gin.H{"status": "ok"})
+}
+
+// Readiness reports whether the server can take traffic, pinging the
+// database and including the connection pool stats.
+func (h *healthController) Readiness(ctx *gin.Context) {
+	stats := h.connection.Stats()
+	pool := gin.H{
+		"max_open_connections": stats.MaxOpenConnections,
+		"open_connections":     stats.OpenConnections,
+		"in_use":               stats.InUse,
+		"idle":                 stats.Idle,
+		"wait_count":           stats.WaitCount,
+		"wait_duration_ms":     stats.WaitDuration.Milliseconds(),
+	}
+
+	if h.shuttingDown.Load() {
+		ctx.JSON(http.StatusServiceUnavailable, gin.H{"status": "shutting_down", "database": pool})
+		return
+	}
+
+	pingCtx, cancel := context.WithTimeout(ctx.Request.Context(), 2*time.Second)
+	defer cancel()
+
+	if err := h.connection.PingContext(pingCtx); err != nil {
+		ctx.JSON(http.StatusServiceUnavailable, gin.H{
+			"status":   "unavailable",
+			"error":    "database unreachable",
+			"database": pool,
+		})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"status": "ready", "database": pool})
+}
@@ -0,0 +1,170 @@
+package controller
+
+import (
+	"context"
+	"net/http"
+	"product-go-api/apperror"
+	"product-go-api/middleware"
+	"product-go-api/model"
+	"product-go-api/usecase"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+type inventoryController struct {
+	inventoryUseCase usecase.InventoryUsecase
+	auditUseCase     usecase.AuditUsecase
+}
+
+func NewInventoryController(usecase usecase.InventoryUsecase, auditUseCase usecase.AuditUsecase) inventoryController {
+	return inventoryController{
+		inventoryUseCase: usecase,
+		auditUseCase:     auditUseCase,
+	}
+}
+
+func (i *inventoryController) GetStock(ctx *gin.Context) {
+	id_product, ok := productIdParam(ctx)
+	if !ok {
+		return
+	}
+
+	stock, err := i.inventoryUseCase.GetStock(ctx.Request.Context(), id_product)
+	if err != nil {
+		handleError(ctx, err, "Failed to retrieve stock.")
+		return
+	}
+
+	if stock == nil {
+		fail(ctx, usecase.ErrProductNotFound)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, stock)
+}
+
+func (i *inventoryController) AdjustStock(ctx *gin.Context) {
+	id_product, ok := productIdParam(ctx)
+	if !ok {
+		return
+	}
+
+	var req model.StockAdjustmentRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		fail(ctx, apperror.Validation("Invalid request body"))
+		return
+	}
+
+	id_user := ctx.GetInt("user_id")
+	var movement *model.StockMovement
+	err := i.auditUseCase.WithinTx(ctx.Request.Context(), func(txCtx context.Context) error {
+		var err error
+		movement, err = i.inventoryUseCase.AdjustStock(txCtx, id_product, id_user, req)
+		if err != nil {
+			return err
+		}
+		return recordAudit(ctx, txCtx, i.auditUseCase, model.AuditProductStockAdjust, model.AuditTargetProduct, id_product, nil, movement)
+	})
+	if err != nil {
+		handleError(ctx, err, "Failed to adjust stock.")
+		return
+	}
+
+	ctx.JSON(http.StatusCreated, movement)
+}
+
+func (i *inventoryController) GetMovements(ctx *gin.Context) {
+	id_product, ok := productIdParam(ctx)
+	if !ok {
+		return
+	}
+
+	page, err := strconv.Atoi(ctx.DefaultQuery("page", "1"))
+	if err != nil || page < 1 {
+		fail(ctx, apperror.Validation("Page must be a positive number.", apperror.Field("page", "must be a positive number")))
+		return
+	}
+
+	limit, err := strconv.Atoi(ctx.DefaultQuery("limit", "10"))
+	if err != nil || limit < 1 {
+		fail(ctx, apperror.Validation("Limit must be a positive number.", apperror.Field("limit", "must be a positive number")))
+		return
+	}
+
+	movements, err := i.inventoryUseCase.GetMovements(ctx.Request.Context(), id_product, page, limit)
+	if err != nil {
+		handleError(ctx, err, "Failed to retrieve stock movements.")
+		return
+	}
+
+	ctx.JSON(http.StatusOK, movements)
+}
+
+func (i *inventoryController) Reconcile(ctx *gin.Context) {
+	id_product, ok := productIdParam(ctx)
+	if !ok {
+		return
+	}
+
+	reconciliation, err := i.inventoryUseCase.Reconcile(ctx.Request.Context(), id_product)
+	if err != nil {
+		handleError(ctx, err, "Failed to reconcile stock.")
+		return
+	}
+
+	if reconciliation == nil {
+		fail(ctx, usecase.ErrProductNotFound)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, reconciliation)
+}
+
+func (i *inventoryController) Reserve(ctx *gin.Context) {
+	var req struct {
+		ProductID int `json:"product_id"`
+		Quantity  int `json:"quantity"`
+	}
+	if err := ctx.ShouldBindJSON(&req); err != nil || req.ProductID < 1 {
+		fail(ctx, apperror.Validation("Invalid request body"))
+		return
+	}
+
+	id_user := ctx.GetInt("user_id")
+	reservation, err := i.inventoryUseCase.Reserve(ctx.Request.Context(), req.ProductID, id_user, req.Quantity)
+	if err != nil {
+		handleError(ctx, err, "Failed to reserve stock.")
+		return
+	}
+
+	ctx.JSON(http.StatusCreated, reservation)
+}
+
+func (i *inventoryController) Release(ctx *gin.Context) {
+	id_reservation, err := strconv.Atoi(ctx.Param("id_reservation"))
+	if err != nil || id_reservation < 1 {
+		fail(ctx, apperror.Validation("id_reservation must be a positive number", apperror.Field("id_reservation", "must be a positive number")))
+		return
+	}
+
+	id_user := ctx.GetInt("user_id")
+	releaseAny := middleware.HasPermission(ctx, model.PermissionReservationReleaseAny)
+
+	reservation, err := i.inventoryUseCase.Release(ctx.Request.Context(), id_reservation, id_user, releaseAny)
+	if err != nil {
+		handleError(ctx, err, "Failed to release reservation.")
+		return
+	}
+
+	ctx.JSON(http.StatusOK, reservation)
+}
+
+func productIdParam(ctx *gin.Context) (int, bool) {
+	id_product, err := strconv.Atoi(ctx.Param("id_product"))
+	if err != nil || id_product < 1 {
+		fail(ctx, apperror.Validation("id_product must be a positive number", apperror.Field("id_product", "must be a positive number")))
+		return 0, false
+	}
+	return id_product, true
+}
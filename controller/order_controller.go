@@ -0,0 +1,124 @@
+package controller
+
+import (
+	"context"
+	"net/http"
+	"product-go-api/apperror"
+	"product-go-api/middleware"
+	"product-go-api/model"
+	"product-go-api/usecase"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+type orderController struct {
+	orderUseCase usecase.OrderUsecase
+	auditUseCase usecase.AuditUsecase
+}
+
+func NewOrderController(usecase usecase.OrderUsecase, auditUseCase usecase.AuditUsecase) orderController {
+	return orderController{
+		orderUseCase: usecase,
+		auditUseCase: auditUseCase,
+	}
+}
+
+// GetMyOrders lists the orders of the authenticated user.
+func (o *orderController) GetMyOrders(ctx *gin.Context) {
+	o.listOrders(ctx, ctx.GetInt("user_id"))
+}
+
+// GetOrders lists the orders of every user, for admins.
+func (o *orderController) GetOrders(ctx *gin.Context) {
+	o.listOrders(ctx, 0)
+}
+
+func (o *orderController) GetOrderById(ctx *gin.Context) {
+	id_order, ok := orderIdParam(ctx)
+	if !ok {
+		return
+	}
+
+	order, err := o.orderUseCase.GetOrderById(ctx.Request.Context(), id_order)
+	if err != nil {
+		handleError(ctx, err, "Failed to retrieve order.")
+		return
+	}
+
+	// Users cannot tell other users' orders apart from missing ones.
+	if order == nil || (order.UserID != ctx.GetInt("user_id") && !middleware.HasPermission(ctx, model.PermissionOrderReadAll)) {
+		fail(ctx, usecase.ErrOrderNotFound)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, order)
+}
+
+func (o *orderController) UpdateStatus(ctx *gin.Context) {
+	id_order, ok := orderIdParam(ctx)
+	if !ok {
+		return
+	}
+
+	var req struct {
+		Status string `json:"status"`
+	}
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		fail(ctx, apperror.Validation("Invalid request body"))
+		return
+	}
+
+	var order *model.Order
+	err := o.auditUseCase.WithinTx(ctx.Request.Context(), func(txCtx context.Context) error {
+		before, err := o.orderUseCase.GetOrderById(txCtx, id_order)
+		if err != nil {
+			return err
+		}
+		if before == nil {
+			return usecase.ErrOrderNotFound
+		}
+		order, err = o.orderUseCase.UpdateStatus(txCtx, id_order, req.Status, ctx.GetInt("user_id"))
+		if err != nil {
+			return err
+		}
+		return recordAudit(ctx, txCtx, o.auditUseCase, model.AuditOrderStatusChange, model.AuditTargetOrder, id_order, before, order)
+	})
+	if err != nil {
+		handleError(ctx, err, "Failed to update order status.")
+		return
+	}
+
+	ctx.JSON(http.StatusOK, order)
+}
+
+func (o *orderController) listOrders(ctx *gin.Context, id_user int) {
+	page, err := strconv.Atoi(ctx.DefaultQuery("page", "1"))
+	if err != nil || page < 1 {
+		fail(ctx, apperror.Validation("Page must be a positive number.", apperror.Field("page", "must be a positive number")))
+		return
+	}
+
+	limit, err := strconv.Atoi(ctx.DefaultQuery("limit", "10"))
+	if err != nil || limit < 1 {
+		fail(ctx, apperror.Validation("Limit must be a positive number.", apperror.Field("limit", "must be a positive number")))
+		return
+	}
+
+	orders, err := o.orderUseCase.GetOrders(ctx.Request.Context(), page, limit, id_user, ctx.Query("status"))
+	if err != nil {
+		handleError(ctx, err, "Failed to retrieve orders.")
+		return
+	}
+
+	ctx.JSON(http.StatusOK, orders)
+}
+
+func orderIdParam(ctx *gin.Context) (int, bool) {
+	id_order, err := strconv.Atoi(ctx.Param("id_order"))
+	if err != nil || id_order < 1 {
+		fail(ctx, apperror.Validation("id_order must be a positive number", apperror.Field("id_order", "must be a positive number")))
+		return 0, false
+	}
+	return id_order, true
+}
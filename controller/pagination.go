@@ -0,0 +1,106 @@
+package controller
+
+import (
+	"encoding/base64"
+	"product-go-api/apperror"
+	"product-go-api/model"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	defaultPageLimit = 10
+	maxPageLimit     = 100
+)
+
+// pageRequest reads the page, limit and cursor query parameters. Sending
+// cursor, even empty, switches to keyset pagination; page and cursor cannot be
+// combined. When they are invalid the request is answered with a validation
+// problem and pageRequest returns false.
+func pageRequest(ctx *gin.Context) (model.PageRequest, bool) {
+	req := model.PageRequest{Page: 1, Limit: defaultPageLimit}
+
+	if limit, ok := ctx.GetQuery("limit"); ok {
+		value, err := strconv.Atoi(limit)
+		if err != nil || value < 1 || value > maxPageLimit {
+			fail(ctx, apperror.Validation("Limit must be a number between 1 and 100.", apperror.Field("limit", "must be between 1 and 100")))
+			return req, false
+		}
+		req.Limit = value
+	}
+
+	cursor, cursorMode := ctx.GetQuery("cursor")
+	page, pageMode := ctx.GetQuery("page")
+	if cursorMode && pageMode {
+		fail(ctx, apperror.Validation("Use either page or cursor.", apperror.Field("cursor", "cannot be combined with page")))
+		return req, false
+	}
+
+	if cursorMode {
+		req.Cursor = true
+		req.Page = 0
+		if cursor != "" {
+			after, err := decodeCursor(cursor)
+			if err != nil {
+				fail(ctx, apperror.Validation("Cursor is invalid.", apperror.Field("cursor", "is invalid")))
+				return req, false
+			}
+			req.After = after
+		}
+		return req, true
+	}
+
+	if pageMode {
+		value, err := strconv.Atoi(page)
+		if err != nil || value < 1 {
+			fail(ctx, apperror.Validation("Page must be a positive number.", apperror.Field("page", "must be a positive number")))
+			return req, false
+		}
+		req.Page = value
+	}
+	return req, true
+}
+
+// setPageLinks fills the links to the neighbouring pages, keeping the other
+// query parameters of the request. id returns the ID the list is ordered by.
+func setPageLinks[T any](ctx *gin.Context, page *model.Page[T], id func(T) int) {
+	link := func(param, value string) string {
+		url := *ctx.Request.URL
+		query := url.Query()
+		query.Del("page")
+		query.Del("cursor")
+		query.Set(param, value)
+		query.Set("limit", strconv.Itoa(page.Limit))
+		url.RawQuery = query.Encode()
+		return url.RequestURI()
+	}
+
+	if page.Page == 0 {
+		if page.HasMore && len(page.Items) > 0 {
+			page.NextCursor = encodeCursor(id(page.Items[len(page.Items)-1]))
+			page.Next = link("cursor", page.NextCursor)
+		}
+		return
+	}
+
+	if page.HasMore {
+		page.Next = link("page", strconv.Itoa(page.Page+1))
+	}
+	if page.Page > 1 {
+		page.Prev = link("page", strconv.Itoa(page.Page-1))
+	}
+}
+
+// Cursors are opaque to clients; they hold the ID of the last item returned.
+func encodeCursor(id int) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(strconv.Itoa(id)))
+}
+
+func decodeCursor(cursor string) (int, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return 0, err
+	}
+	return strconv.Atoi(string(raw))
+}
@@ -1,86 +1,147 @@
 package controller
 
 import (
+	"context"
+	"errors"
+	"fmt"
 	"net/http"
+	"product-go-api/apperror"
+	"product-go-api/config"
+	"product-go-api/middleware"
 	"product-go-api/model"
 	"product-go-api/usecase"
 	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
 
 	"github.com/gin-gonic/gin"
 )
 
 type productController struct {
 	productUseCase usecase.ProductUsecase
+	auditUseCase   usecase.AuditUsecase
+	importConfig   config.ImportConfig
 }
 
-func NewProductController(usecase usecase.ProductUsecase) productController {
+func NewProductController(usecase usecase.ProductUsecase, auditUseCase usecase.AuditUsecase, importConfig config.ImportConfig) productController {
 	return productController{
 		productUseCase: usecase,
+		auditUseCase:   auditUseCase,
+		importConfig:   importConfig,
 	}
 }
 
 func (p *productController) GetProducts(ctx *gin.Context) {
-	page, err := strconv.Atoi(ctx.DefaultQuery("page", "1"))
-	if err != nil || page < 1 {
-		response := model.Response{
-			Message: "Page must be a positive number.",
-		}
-		ctx.JSON(http.StatusBadRequest, response)
+	page, ok := pageRequest(ctx)
+	if !ok {
 		return
 	}
 
-	limit, err := strconv.Atoi(ctx.DefaultQuery("limit", "10"))
-	if err != nil || limit < 1 {
-		response := model.Response{
-			Message: "Limit must be a positive number.",
-		}
-		ctx.JSON(http.StatusBadRequest, response)
+	filter, ok := productFilter(ctx, page)
+	if !ok {
+		return
+	}
+	currency, ok := priceCurrency(ctx)
+	if !ok {
 		return
 	}
-	name := ctx.Query("name")
 
-	products, err := p.productUseCase.GetProducts(page, limit, name)
+	products, err := p.productUseCase.GetProducts(ctx.Request.Context(), page, filter)
 	if err != nil {
-		ctx.JSON(http.StatusInternalServerError, err)
+		handleError(ctx, err, "Failed to retrieve products.")
+		return
+	}
+	converted := make([]*model.Product, len(products.Items))
+	for i := range products.Items {
+		converted[i] = &products.Items[i]
 	}
+	if err := p.productUseCase.ConvertPrices(ctx.Request.Context(), currency, converted...); err != nil {
+		handleError(ctx, err, "Failed to convert prices.")
+		return
+	}
+	setPageLinks(ctx, &products, func(product model.Product) int { return product.ID })
 	ctx.JSON(http.StatusOK, products)
 }
 
-func (p *productController) CreateProduct(ctx *gin.Context) {
-	var product model.Product
-	err := ctx.BindJSON(&product)
+const maxSearchLength = 200
 
-	if err != nil {
-		response := model.Response{
-			Message: "Invalid request body",
-		}
-		ctx.JSON(http.StatusBadRequest, response)
+func (p *productController) SearchProducts(ctx *gin.Context) {
+	page, ok := pageRequest(ctx)
+	if !ok {
+		return
+	}
+	if page.Cursor {
+		fail(ctx, apperror.Validation("Search results are ranked and only support page pagination.", apperror.Field("cursor", "is not supported by search")))
 		return
 	}
 
-	if product.Name == "" {
-		response := model.Response{
-			Message: "Product name is required.",
-		}
-		ctx.JSON(http.StatusBadRequest, response)
+	search := model.ProductSearch{
+		Query: strings.TrimSpace(ctx.Query("q")),
+		Fuzzy: ctx.Query("fuzzy") == "true",
+	}
+	if !middleware.HasPermission(ctx, model.PermissionProductReadAll) {
+		search.Status = model.ProductStatusActive
+	}
+	if search.Query == "" {
+		fail(ctx, apperror.Validation("Search query is required.", apperror.Field("q", "is required")))
+		return
+	}
+	if utf8.RuneCountInString(search.Query) > maxSearchLength {
+		fail(ctx, apperror.Validation("Search query is too long.", apperror.Field("q", "must be at most 200 characters")))
+		return
+	}
+	currency, ok := priceCurrency(ctx)
+	if !ok {
 		return
 	}
 
-	if product.Price < 0 {
-		response := model.Response{
-			Message: "Price must be non-negative.",
-		}
-		ctx.JSON(http.StatusBadRequest, response)
+	results, err := p.productUseCase.SearchProducts(ctx.Request.Context(), page, search)
+	if err != nil {
+		handleError(ctx, err, "Failed to search products.")
+		return
+	}
+	converted := make([]*model.Product, len(results.Items))
+	for i := range results.Items {
+		converted[i] = &results.Items[i].Product
+	}
+	if err := p.productUseCase.ConvertPrices(ctx.Request.Context(), currency, converted...); err != nil {
+		handleError(ctx, err, "Failed to convert prices.")
 		return
 	}
+	setPageLinks(ctx, &results, func(result model.ProductSearchResult) int { return result.ID })
+	ctx.JSON(http.StatusOK, results)
+}
 
-	insertedProduct, err := p.productUseCase.CreateProduct(product)
+func (p *productController) CreateProduct(ctx *gin.Context) {
+	var req model.ProductRequest
+	if !bindJSON(ctx, &req) {
+		return
+	}
 
-	if err != nil {
-		response := model.Response{
-			Message: "Failed to create product.",
+	product := model.Product{
+		SKU:         req.SKU,
+		Name:        req.Name,
+		Description: req.Description,
+		Brand:       req.Brand,
+		Price:       req.Price,
+		PriceList:   req.PriceList,
+		Status:      req.Status,
+		CategoryID:  req.CategoryID,
+		Attributes:  req.Attributes,
+	}
+	var insertedProduct model.Product
+	err := p.auditUseCase.WithinTx(ctx.Request.Context(), func(txCtx context.Context) error {
+		var err error
+		insertedProduct, err = p.productUseCase.CreateProduct(txCtx, product)
+		if err != nil {
+			return err
 		}
-		ctx.JSON(http.StatusInternalServerError, response)
+		return recordAudit(ctx, txCtx, p.auditUseCase, model.AuditProductCreate, model.AuditTargetProduct, insertedProduct.ID, nil, insertedProduct)
+	})
+
+	if err != nil {
+		handleError(ctx, err, "Failed to create product.")
 		return
 	}
 
@@ -92,48 +153,41 @@ func (p *productController) GetProductById(ctx *gin.Context) {
 	id := ctx.Param("id_product")
 
 	if id == "" {
-		response := model.Response{
-			Message: "id_product is required",
-		}
-
-		ctx.JSON(http.StatusBadRequest, response)
+		fail(ctx, apperror.Validation("id_product is required", apperror.Field("id_product", "is required")))
 		return
 	}
 
 	id_product, err := strconv.Atoi(id)
 
 	if id_product < 1 {
-		response := model.Response{
-			Message: "id_product must be a positive number",
-		}
-		ctx.JSON(http.StatusBadRequest, response)
+		fail(ctx, apperror.Validation("id_product must be a positive number", apperror.Field("id_product", "must be a positive number")))
 		return
 	}
 
 	if err != nil {
-		response := model.Response{
-			Message: "id_product must be a number",
-		}
+		fail(ctx, apperror.Validation("id_product must be a number", apperror.Field("id_product", "must be a number")))
+		return
+	}
 
-		ctx.JSON(http.StatusBadRequest, response)
+	currency, ok := priceCurrency(ctx)
+	if !ok {
 		return
 	}
 
-	product, err := p.productUseCase.GetProductById(id_product)
+	product, err := p.productUseCase.GetProductById(ctx.Request.Context(), id_product)
 
 	if err != nil {
-		response := model.Response{
-			Message: "Failed to retrieve product",
-		}
-		ctx.JSON(http.StatusInternalServerError, response)
+		handleError(ctx, err, "Failed to retrieve product")
+		return
 	}
 
-	if product == nil {
-		response := model.Response{
-			Message: "Product not found",
-		}
+	if product == nil || !canReadProduct(ctx, *product) {
+		fail(ctx, usecase.ErrProductNotFound)
+		return
+	}
 
-		ctx.JSON(http.StatusNotFound, response)
+	if err := p.productUseCase.ConvertPrices(ctx.Request.Context(), currency, product); err != nil {
+		handleError(ctx, err, "Failed to convert prices.")
 		return
 	}
 
@@ -144,37 +198,37 @@ func (p *productController) DeleteProduct(ctx *gin.Context) {
 	id := ctx.Param("id_product")
 
 	if id == "" {
-		response := model.Response{
-			Message: "id_product is required",
-		}
-		ctx.JSON(http.StatusBadRequest, response)
+		fail(ctx, apperror.Validation("id_product is required", apperror.Field("id_product", "is required")))
 		return
 	}
 
 	id_product, err := strconv.Atoi(id)
 
 	if id_product < 1 {
-		response := model.Response{
-			Message: "id_product must be a positive number",
-		}
-		ctx.JSON(http.StatusBadRequest, response)
+		fail(ctx, apperror.Validation("id_product must be a positive number", apperror.Field("id_product", "must be a positive number")))
 		return
 	}
 
 	if err != nil {
-		response := model.Response{
-			Message: "id_product must be a number",
-		}
-		ctx.JSON(http.StatusBadRequest, response)
+		fail(ctx, apperror.Validation("id_product must be a number", apperror.Field("id_product", "must be a number")))
 		return
 	}
 
-	err = p.productUseCase.DeleteProduct(id_product)
-	if err != nil {
-		response := model.Response{
-			Message: "Failed to delete product.",
+	err = p.auditUseCase.WithinTx(ctx.Request.Context(), func(txCtx context.Context) error {
+		product, err := p.productUseCase.GetProductById(txCtx, id_product)
+		if err != nil {
+			return err
+		}
+		if product == nil {
+			return usecase.ErrProductNotFound
 		}
-		ctx.JSON(http.StatusInternalServerError, response)
+		if err := p.productUseCase.DeleteProduct(txCtx, id_product); err != nil {
+			return err
+		}
+		return recordAudit(ctx, txCtx, p.auditUseCase, model.AuditProductDelete, model.AuditTargetProduct, id_product, product, nil)
+	})
+	if err != nil {
+		handleError(ctx, err, "Failed to delete product.")
 		return
 	}
 
@@ -188,64 +242,404 @@ func (p *productController) UpdateProduct(ctx *gin.Context) {
 	id := ctx.Param("id_product")
 
 	if id == "" {
-		response := model.Response{
-			Message: "id_product is required",
-		}
-		ctx.JSON(http.StatusBadRequest, response)
+		fail(ctx, apperror.Validation("id_product is required", apperror.Field("id_product", "is required")))
 		return
 	}
 
 	id_product, err := strconv.Atoi(id)
 	if err != nil {
-		response := model.Response{
-			Message: "id_product must be a number",
+		fail(ctx, apperror.Validation("id_product must be a number", apperror.Field("id_product", "must be a number")))
+		return
+	}
+
+	existingProduct, err := p.productUseCase.GetProductById(ctx.Request.Context(), id_product)
+	if err != nil {
+		handleError(ctx, err, "Failed to retrieve product.")
+		return
+	}
+
+	if existingProduct == nil {
+		fail(ctx, usecase.ErrProductNotFound)
+		return
+	}
+
+	// The body is decoded over the current values, so fields left out are
+	// kept and a null category_id removes the category. Attributes and the
+	// price list are not merged: when sent they replace the current ones.
+	req := model.ProductRequest{
+		SKU:         existingProduct.SKU,
+		Name:        existingProduct.Name,
+		Description: existingProduct.Description,
+		Brand:       existingProduct.Brand,
+		Price:       existingProduct.Price,
+		Status:      existingProduct.Status,
+		CategoryID:  existingProduct.CategoryID,
+	}
+	if !bindJSON(ctx, &req) {
+		return
+	}
+
+	before := *existingProduct
+	existingProduct.SKU = req.SKU
+	existingProduct.Name = req.Name
+	existingProduct.Description = req.Description
+	existingProduct.Brand = req.Brand
+	existingProduct.Price = req.Price
+	existingProduct.Status = req.Status
+	existingProduct.CategoryID = req.CategoryID
+	if req.Attributes != nil {
+		existingProduct.Attributes = req.Attributes
+	}
+	if req.PriceList != nil {
+		existingProduct.PriceList = req.PriceList
+	}
+
+	var updatedProduct model.Product
+	err = p.auditUseCase.WithinTx(ctx.Request.Context(), func(txCtx context.Context) error {
+		var err error
+		updatedProduct, err = p.productUseCase.UpdateProduct(txCtx, *existingProduct)
+		if err != nil {
+			return err
 		}
-		ctx.JSON(http.StatusBadRequest, response)
+		return recordAudit(ctx, txCtx, p.auditUseCase, model.AuditProductUpdate, model.AuditTargetProduct, id_product, before, updatedProduct)
+	})
+	if err != nil {
+		handleError(ctx, err, "Failed to update product.")
 		return
 	}
 
-	existingProduct, err := p.productUseCase.GetProductById(id_product)
+	ctx.JSON(http.StatusOK, updatedProduct)
+}
+
+// ImportProducts creates and updates products from an uploaded CSV or JSON
+// Lines file, matched by SKU, and answers with the outcome of every row. With
+// dry_run=true the rows are checked and reported without being saved.
+func (p *productController) ImportProducts(ctx *gin.Context) {
+	var fields []model.FieldError
+	dryRun, err := strconv.ParseBool(ctx.DefaultQuery("dry_run", "false"))
 	if err != nil {
-		response := model.Response{
-			Message: "Failed to retrieve product.",
+		fields = append(fields, apperror.Field("dry_run", "must be true or false"))
+	}
+	batchSize := p.importConfig.BatchSize
+	if value := ctx.Query("batch_size"); value != "" {
+		if batchSize, err = strconv.Atoi(value); err != nil || batchSize < 0 {
+			fields = append(fields, apperror.Field("batch_size", "must be a non-negative integer"))
 		}
-		ctx.JSON(http.StatusInternalServerError, response)
+	}
+	if !failFields(ctx, fields) {
 		return
 	}
 
-	if existingProduct == nil {
-		response := model.Response{
-			Message: "Product not found",
+	ctx.Request.Body = http.MaxBytesReader(ctx.Writer, ctx.Request.Body, int64(p.importConfig.MaxFileBytes))
+	upload, err := ctx.FormFile("file")
+	if err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			fail(ctx, apperror.Validation("The file is too large.", apperror.Field("file", fmt.Sprintf("must be at most %d bytes", p.importConfig.MaxFileBytes))))
+			return
+		}
+		fail(ctx, apperror.Validation("A file is required.", apperror.Field("file", "is required")))
+		return
+	}
+	format, formatErr := importFormat(ctx.Query("format"), upload.Filename)
+	if formatErr != nil {
+		fail(ctx, formatErr)
+		return
+	}
+	file, err := upload.Open()
+	if err != nil {
+		handleError(ctx, err, "Failed to read the file.")
+		return
+	}
+	defer file.Close()
+	rows, parseErr := parseImport(file, format, p.importConfig.MaxRows)
+	if parseErr != nil {
+		fail(ctx, parseErr)
+		return
+	}
+
+	options := model.ImportOptions{DryRun: dryRun, BatchSize: batchSize}
+	if !dryRun {
+		options.Audit = func(txCtx context.Context, row model.ImportRowResult) error {
+			if row.Status == model.ImportCreated {
+				return recordAudit(ctx, txCtx, p.auditUseCase, model.AuditProductCreate, model.AuditTargetProduct, row.ProductID, nil, *row.After)
+			}
+			return recordAudit(ctx, txCtx, p.auditUseCase, model.AuditProductUpdate, model.AuditTargetProduct, row.ProductID, *row.Before, *row.After)
 		}
-		ctx.JSON(http.StatusNotFound, response)
+	}
+	report, err := p.productUseCase.ImportProducts(ctx.Request.Context(), rows, options)
+	if err != nil {
+		handleError(ctx, err, "Failed to import products.")
+		return
+	}
+
+	ctx.JSON(http.StatusOK, report)
+}
+
+// GetDeletedProducts lists the products in the trash.
+func (p *productController) GetDeletedProducts(ctx *gin.Context) {
+	page, ok := pageRequest(ctx)
+	if !ok {
+		return
+	}
+
+	products, err := p.productUseCase.GetDeletedProducts(ctx.Request.Context(), page)
+	if err != nil {
+		handleError(ctx, err, "Failed to retrieve deleted products.")
+		return
+	}
+	setPageLinks(ctx, &products, func(product model.Product) int { return product.ID })
+	ctx.JSON(http.StatusOK, products)
+}
+
+func (p *productController) RestoreProduct(ctx *gin.Context) {
+	id_product, err := strconv.Atoi(ctx.Param("id_product"))
+	if err != nil || id_product < 1 {
+		fail(ctx, apperror.Validation("id_product must be a positive number", apperror.Field("id_product", "must be a positive number")))
+		return
+	}
+
+	trashedProduct, err := p.productUseCase.GetDeletedProductById(ctx.Request.Context(), id_product)
+	if err != nil {
+		handleError(ctx, err, "Failed to retrieve product.")
+		return
+	}
+	if trashedProduct == nil {
+		fail(ctx, usecase.ErrProductNotInTrash)
 		return
 	}
 
-	var updateData map[string]interface{}
-	if err := ctx.BindJSON(&updateData); err != nil {
-		response := model.Response{
-			Message: "Invalid JSON.",
+	var product model.Product
+	err = p.auditUseCase.WithinTx(ctx.Request.Context(), func(txCtx context.Context) error {
+		var err error
+		product, err = p.productUseCase.RestoreProduct(txCtx, id_product)
+		if err != nil {
+			return err
 		}
-		ctx.JSON(http.StatusBadRequest, response)
+		return recordAudit(ctx, txCtx, p.auditUseCase, model.AuditProductRestore, model.AuditTargetProduct, id_product, trashedProduct, product)
+	})
+	if err != nil {
+		handleError(ctx, err, "Failed to restore product.")
 		return
 	}
+	ctx.JSON(http.StatusOK, product)
+}
 
-	if name, ok := updateData["name"].(string); ok {
-		existingProduct.Name = name
+// GetPriceHistory lists the prices of a product, including the scheduled ones.
+func (p *productController) GetPriceHistory(ctx *gin.Context) {
+	id_product, err := strconv.Atoi(ctx.Param("id_product"))
+	if err != nil || id_product < 1 {
+		fail(ctx, apperror.Validation("id_product must be a positive number", apperror.Field("id_product", "must be a positive number")))
+		return
+	}
+	page, ok := pageRequest(ctx)
+	if !ok {
+		return
 	}
 
-	if price, ok := updateData["price"].(float64); ok {
-		existingProduct.Price = price
+	product, err := p.productUseCase.GetProductById(ctx.Request.Context(), id_product)
+	if err != nil {
+		handleError(ctx, err, "Failed to retrieve product.")
+		return
+	}
+	if product == nil || !canReadProduct(ctx, *product) {
+		fail(ctx, usecase.ErrProductNotFound)
+		return
 	}
 
-	updatedProduct, err := p.productUseCase.UpdateProduct(*existingProduct)
+	prices, err := p.productUseCase.GetPriceHistory(ctx.Request.Context(), id_product, page)
 	if err != nil {
-		response := model.Response{
-			Message: "Failed to update product.",
+		handleError(ctx, err, "Failed to retrieve price history.")
+		return
+	}
+	setPageLinks(ctx, &prices, func(price model.ProductPrice) int { return price.ID })
+	ctx.JSON(http.StatusOK, prices)
+}
+
+// SchedulePrice schedules a future price for a product. The price scheduler
+// applies it once its effective date is reached.
+func (p *productController) SchedulePrice(ctx *gin.Context) {
+	id_product, err := strconv.Atoi(ctx.Param("id_product"))
+	if err != nil || id_product < 1 {
+		fail(ctx, apperror.Validation("id_product must be a positive number", apperror.Field("id_product", "must be a positive number")))
+		return
+	}
+
+	var req model.SchedulePriceRequest
+	if !bindJSON(ctx, &req) {
+		return
+	}
+
+	var price model.ProductPrice
+	err = p.auditUseCase.WithinTx(ctx.Request.Context(), func(txCtx context.Context) error {
+		var err error
+		price, err = p.productUseCase.SchedulePrice(txCtx, model.ProductPrice{
+			ProductID:     id_product,
+			Price:         *req.Price,
+			EffectiveFrom: req.EffectiveFrom,
+		})
+		if err != nil {
+			return err
 		}
-		ctx.JSON(http.StatusInternalServerError, response)
+		return recordAudit(ctx, txCtx, p.auditUseCase, model.AuditProductPriceSchedule, model.AuditTargetProduct, id_product, nil, price)
+	})
+	if err != nil {
+		handleError(ctx, err, "Failed to schedule price.")
 		return
 	}
 
-	ctx.JSON(http.StatusOK, updatedProduct)
+	ctx.JSON(http.StatusCreated, price)
+}
+
+// priceCurrency reads the currency query parameter, in which product prices
+// are returned. It is empty when prices are returned in the store currency.
+func priceCurrency(ctx *gin.Context) (string, bool) {
+	currency := strings.ToUpper(ctx.Query("currency"))
+	if currency != "" && !model.SupportedCurrency(currency) {
+		fail(ctx, apperror.Validation("Invalid currency.", apperror.Field("currency", "must be a supported ISO 4217 currency code")))
+		return "", false
+	}
+	return currency, true
+}
+
+const maxFilterIDs = 100
+
+// productFilter reads the filter and sort query parameters of the product
+// listing. Every invalid parameter is reported in one validation problem.
+func productFilter(ctx *gin.Context, page model.PageRequest) (model.ProductFilter, bool) {
+	filter := model.ProductFilter{
+		Name:                 ctx.Query("name"),
+		IncludeSubcategories: ctx.Query("include_subcategories") == "true",
+	}
+	var fields []model.FieldError
+	invalid := func(field, message string) {
+		fields = append(fields, apperror.Field(field, message))
+	}
+
+	// Draft and archived products are only listed to users allowed to read
+	// them; the others only see active products.
+	switch status := ctx.Query("status"); status {
+	case "":
+		if !middleware.HasPermission(ctx, model.PermissionProductReadAll) {
+			filter.Status = model.ProductStatusActive
+		}
+	case model.ProductStatusDraft, model.ProductStatusActive, model.ProductStatusArchived:
+		if status != model.ProductStatusActive && !middleware.HasPermission(ctx, model.PermissionProductReadAll) {
+			fail(ctx, apperror.Forbidden("You do not have permission to list "+status+" products."))
+			return filter, false
+		}
+		filter.Status = status
+	default:
+		invalid("status", "must be one of: draft, active, archived")
+	}
+
+	if category := ctx.Query("category"); category != "" {
+		id_category, err := strconv.Atoi(category)
+		if err != nil || id_category < 1 {
+			invalid("category", "must be a positive number")
+		}
+		filter.CategoryID = id_category
+	}
+
+	if ids := ctx.Query("ids"); ids != "" {
+		for _, value := range strings.Split(ids, ",") {
+			id, err := strconv.Atoi(strings.TrimSpace(value))
+			if err != nil || id < 1 {
+				invalid("ids", "must be a comma-separated list of positive numbers")
+				break
+			}
+			filter.IDs = append(filter.IDs, id)
+		}
+		if len(filter.IDs) > maxFilterIDs {
+			invalid("ids", "must have at most 100 IDs")
+		}
+	}
+
+	for _, price := range []struct {
+		param string
+		value **model.Money
+	}{{"min_price", &filter.MinPrice}, {"max_price", &filter.MaxPrice}} {
+		if value := ctx.Query(price.param); value != "" {
+			parsed, err := model.ParseMoney(value)
+			if err != nil || parsed < 0 {
+				invalid(price.param, "must be a non-negative number with at most 2 decimal places")
+				continue
+			}
+			*price.value = &parsed
+		}
+	}
+	if filter.MinPrice != nil && filter.MaxPrice != nil && *filter.MinPrice > *filter.MaxPrice {
+		invalid("max_price", "must be greater than or equal to min_price")
+	}
+
+	for _, date := range []struct {
+		param string
+		value *time.Time
+	}{
+		{"created_from", &filter.CreatedFrom},
+		{"created_to", &filter.CreatedTo},
+		{"updated_from", &filter.UpdatedFrom},
+		{"updated_to", &filter.UpdatedTo},
+	} {
+		if value := ctx.Query(date.param); value != "" {
+			parsed, err := parseDate(value)
+			if err != nil {
+				invalid(date.param, "must be a date (2006-01-02) or an RFC 3339 timestamp")
+				continue
+			}
+			*date.value = parsed
+		}
+	}
+
+	if sort := ctx.Query("sort"); sort != "" {
+		if page.Cursor {
+			invalid("sort", "cannot be combined with cursor")
+		}
+		seen := map[string]bool{}
+		for _, value := range strings.Split(sort, ",") {
+			key := model.SortKey{Field: strings.TrimSpace(value)}
+			if strings.HasPrefix(key.Field, "-") {
+				key.Field, key.Desc = key.Field[1:], true
+			}
+			switch key.Field {
+			case model.ProductSortName, model.ProductSortPrice, model.ProductSortCreatedAt:
+			default:
+				invalid("sort", "must be a comma-separated list of name, price or created_at, each optionally prefixed with -")
+				return filter, failFields(ctx, fields)
+			}
+			if seen[key.Field] {
+				invalid("sort", "must not repeat a field")
+				return filter, failFields(ctx, fields)
+			}
+			seen[key.Field] = true
+			filter.Sort = append(filter.Sort, key)
+		}
+	}
+
+	return filter, failFields(ctx, fields)
+}
+
+// canReadProduct reports whether the current user may see product: products
+// that are not active are hidden from users without product:read_all.
+func canReadProduct(ctx *gin.Context, product model.Product) bool {
+	return product.Status == model.ProductStatusActive || middleware.HasPermission(ctx, model.PermissionProductReadAll)
+}
+
+// failFields answers with a validation problem listing fields and returns
+// false, or returns true when there are none.
+func failFields(ctx *gin.Context, fields []model.FieldError) bool {
+	if len(fields) == 0 {
+		return true
+	}
+	fail(ctx, apperror.Validation("Invalid query parameters.", fields...))
+	return false
+}
+
+// parseDate accepts an RFC 3339 timestamp or a date, which starts at
+// midnight UTC.
+func parseDate(value string) (time.Time, error) {
+	if parsed, err := time.Parse(time.RFC3339, value); err == nil {
+		return parsed, nil
+	}
+	return time.Parse(time.DateOnly, value)
 }
@@ -0,0 +1,206 @@
+package controller
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"path/filepath"
+	"product-go-api/apperror"
+	"product-go-api/model"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// Formats of product import files.
+const (
+	importFormatCSV   = "csv"
+	importFormatJSONL = "jsonl"
+)
+
+// importColumns are the columns a CSV import file may have, named like the
+// fields of model.ProductRequest. Attributes and price_list hold JSON objects.
+var importColumns = []string{"sku", "name", "description", "brand", "price", "status", "category_id", "attributes", "price_list"}
+
+// importFormat returns the format of an import file: the format query
+// parameter when set, and otherwise the one of the file extension.
+func importFormat(format, filename string) (string, *apperror.Error) {
+	if format == "" {
+		switch strings.ToLower(filepath.Ext(filename)) {
+		case ".csv":
+			format = importFormatCSV
+		case ".jsonl", ".ndjson":
+			format = importFormatJSONL
+		}
+	}
+	switch strings.ToLower(format) {
+	case importFormatCSV:
+		return importFormatCSV, nil
+	case importFormatJSONL:
+		return importFormatJSONL, nil
+	}
+	return "", apperror.Validation("Unknown file format.", apperror.Field("format", "must be csv or jsonl"))
+}
+
+// parseImport reads the rows of an import file. Rows that cannot be decoded
+// or break the rules of product requests carry their errors; the file itself
+// is rejected when it is malformed, empty or has more than maxRows rows.
+func parseImport(file io.Reader, format string, maxRows int) ([]model.ProductImportRow, *apperror.Error) {
+	var rows []model.ProductImportRow
+	var err *apperror.Error
+	if format == importFormatCSV {
+		rows, err = parseImportCSV(file, maxRows)
+	} else {
+		rows, err = parseImportJSONL(file, maxRows)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if len(rows) == 0 {
+		return nil, apperror.Validation("The file has no products.", apperror.Field("file", "has no rows"))
+	}
+	return rows, nil
+}
+
+// parseImportCSV reads a CSV file whose first record names its columns.
+func parseImportCSV(file io.Reader, maxRows int) ([]model.ProductImportRow, *apperror.Error) {
+	reader := csv.NewReader(file)
+	reader.TrimLeadingSpace = true
+	header, err := reader.Read()
+	if err == io.EOF {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, importFileError(err)
+	}
+	// Spreadsheet exports may start with a byte order mark.
+	for i, column := range header {
+		header[i] = strings.ToLower(strings.TrimSpace(strings.TrimPrefix(column, "\ufeff")))
+		if !slices.Contains(importColumns, header[i]) {
+			return nil, apperror.Validation("The file has an unknown column.", apperror.Field("file", fmt.Sprintf("column %q is not one of: %s", column, strings.Join(importColumns, ", "))))
+		}
+		if slices.Contains(header[:i], header[i]) {
+			return nil, apperror.Validation("The file repeats a column.", apperror.Field("file", fmt.Sprintf("column %q appears more than once", column)))
+		}
+	}
+
+	var rows []model.ProductImportRow
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			return rows, nil
+		}
+		if err != nil && !errors.Is(err, csv.ErrFieldCount) {
+			return nil, importFileError(err)
+		}
+		if len(rows) == maxRows {
+			return nil, importTooLong(maxRows)
+		}
+		line, _ := reader.FieldPos(0)
+		if err != nil {
+			message := fmt.Sprintf("has %d columns, the header has %d", len(record), len(header))
+			rows = append(rows, model.ProductImportRow{Line: line, Errors: []model.FieldError{apperror.Field("row", message)}})
+			continue
+		}
+		rows = append(rows, csvRow(line, header, record))
+	}
+}
+
+// csvRow decodes a CSV record as the JSON object of a product request, so it
+// is checked like the bodies of product requests. Empty cells are left out.
+func csvRow(line int, header, record []string) model.ProductImportRow {
+	row := model.ProductImportRow{Line: line}
+	object := map[string]json.RawMessage{}
+	for i, column := range header {
+		value := strings.TrimSpace(record[i])
+		if value == "" {
+			continue
+		}
+		switch column {
+		case "category_id":
+			// Values that are not integers are passed as strings, to be
+			// reported as the wrong type.
+			if _, err := strconv.Atoi(value); err == nil {
+				object[column] = json.RawMessage(value)
+				continue
+			}
+		case "attributes", "price_list":
+			if !json.Valid([]byte(value)) || !strings.HasPrefix(value, "{") {
+				row.Errors = append(row.Errors, apperror.Field(column, "must be a JSON object"))
+				continue
+			}
+			object[column] = json.RawMessage(value)
+			continue
+		}
+		object[column], _ = json.Marshal(value)
+	}
+	if len(row.Errors) > 0 {
+		return row
+	}
+	body, _ := json.Marshal(object)
+	return decodeImportRow(row, body)
+}
+
+// parseImportJSONL reads a JSON Lines file, with one product request object
+// per line. Blank lines are skipped.
+func parseImportJSONL(file io.Reader, maxRows int) ([]model.ProductImportRow, *apperror.Error) {
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(nil, 1<<20)
+	var rows []model.ProductImportRow
+	for line := 1; scanner.Scan(); line++ {
+		body := bytes.TrimSpace(scanner.Bytes())
+		if len(body) == 0 {
+			continue
+		}
+		if len(rows) == maxRows {
+			return nil, importTooLong(maxRows)
+		}
+		rows = append(rows, decodeImportRow(model.ProductImportRow{Line: line}, body))
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, importFileError(err)
+	}
+	return rows, nil
+}
+
+// decodeImportRow decodes and validates body into the product of row.
+func decodeImportRow(row model.ProductImportRow, body []byte) model.ProductImportRow {
+	var req model.ProductRequest
+	if err := decodeJSON(body, &req); err != nil {
+		row.Errors = err.Fields
+		if len(row.Errors) == 0 {
+			row.Errors = []model.FieldError{apperror.Field("row", "must be a JSON object")}
+		}
+		// The SKU is kept for the report when it could be read.
+		var sku struct {
+			SKU string `json:"sku"`
+		}
+		_ = json.Unmarshal(body, &sku)
+		row.Product.SKU = sku.SKU
+		return row
+	}
+	row.Product = model.Product{
+		SKU:         req.SKU,
+		Name:        req.Name,
+		Description: req.Description,
+		Brand:       req.Brand,
+		Price:       req.Price,
+		PriceList:   req.PriceList,
+		Status:      req.Status,
+		CategoryID:  req.CategoryID,
+		Attributes:  req.Attributes,
+	}
+	return row
+}
+
+func importFileError(err error) *apperror.Error {
+	return apperror.Validation("The file could not be read.", apperror.Field("file", err.Error()))
+}
+
+func importTooLong(maxRows int) *apperror.Error {
+	return apperror.Validation("The file has too many rows.", apperror.Field("file", fmt.Sprintf("must have at most %d rows", maxRows)))
+}
@@ -0,0 +1,120 @@
+package controller
+
+import (
+	"context"
+	"net/http"
+	"product-go-api/apperror"
+	"product-go-api/model"
+	"product-go-api/usecase"
+
+	"github.com/gin-gonic/gin"
+)
+
+type roleController struct {
+	roleUseCase  usecase.RoleUsecase
+	auditUseCase usecase.AuditUsecase
+}
+
+func NewRoleController(usecase usecase.RoleUsecase, auditUseCase usecase.AuditUsecase) roleController {
+	return roleController{
+		roleUseCase:  usecase,
+		auditUseCase: auditUseCase,
+	}
+}
+
+func (r *roleController) GetRoles(ctx *gin.Context) {
+	roles, err := r.roleUseCase.GetRoles(ctx.Request.Context())
+	if err != nil {
+		handleError(ctx, err, "Failed to retrieve roles.")
+		return
+	}
+	ctx.JSON(http.StatusOK, roles)
+}
+
+func (r *roleController) GetPermissions(ctx *gin.Context) {
+	permissions, err := r.roleUseCase.GetPermissions(ctx.Request.Context())
+	if err != nil {
+		handleError(ctx, err, "Failed to retrieve permissions.")
+		return
+	}
+	ctx.JSON(http.StatusOK, permissions)
+}
+
+func (r *roleController) CreateRole(ctx *gin.Context) {
+	var role model.Role
+	if err := ctx.ShouldBindJSON(&role); err != nil {
+		fail(ctx, apperror.Validation("Invalid request body"))
+		return
+	}
+
+	var createdRole model.Role
+	err := r.auditUseCase.WithinTx(ctx.Request.Context(), func(txCtx context.Context) error {
+		var err error
+		createdRole, err = r.roleUseCase.CreateRole(txCtx, role)
+		if err != nil {
+			return err
+		}
+		return recordAudit(ctx, txCtx, r.auditUseCase, model.AuditRoleCreate, model.AuditTargetRole, createdRole.ID, nil, createdRole)
+	})
+	if err != nil {
+		handleError(ctx, err, "Failed to create role.")
+		return
+	}
+
+	ctx.JSON(http.StatusCreated, createdRole)
+}
+
+func (r *roleController) SetRolePermissions(ctx *gin.Context) {
+	var req model.RolePermissionsRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		fail(ctx, apperror.Validation("Invalid request body"))
+		return
+	}
+
+	var role model.Role
+	err := r.auditUseCase.WithinTx(ctx.Request.Context(), func(txCtx context.Context) error {
+		before, err := r.roleUseCase.GetRoleByName(txCtx, ctx.Param("role"))
+		if err != nil {
+			return err
+		}
+		if before == nil {
+			return usecase.ErrRoleNotFound
+		}
+		role, err = r.roleUseCase.SetRolePermissions(txCtx, ctx.GetString("role"), ctx.Param("role"), req.Permissions)
+		if err != nil {
+			return err
+		}
+		return recordAudit(ctx, txCtx, r.auditUseCase, model.AuditRolePermissionChange, model.AuditTargetRole, role.ID, before, role)
+	})
+	if err != nil {
+		handleError(ctx, err, "Failed to update role permissions.")
+		return
+	}
+
+	ctx.JSON(http.StatusOK, role)
+}
+
+func (r *roleController) DeleteRole(ctx *gin.Context) {
+	err := r.auditUseCase.WithinTx(ctx.Request.Context(), func(txCtx context.Context) error {
+		role, err := r.roleUseCase.GetRoleByName(txCtx, ctx.Param("role"))
+		if err != nil {
+			return err
+		}
+		if role == nil {
+			return usecase.ErrRoleNotFound
+		}
+		if err := r.roleUseCase.DeleteRole(txCtx, role.Name); err != nil {
+			return err
+		}
+		return recordAudit(ctx, txCtx, r.auditUseCase, model.AuditRoleDelete, model.AuditTargetRole, role.ID, role, nil)
+	})
+	if err != nil {
+		handleError(ctx, err, "Failed to delete role.")
+		return
+	}
+
+	response := model.Response{
+		Message: "Role deleted successfully",
+	}
+	ctx.JSON(http.StatusOK, response)
+}
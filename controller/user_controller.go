@@ -1,73 +1,66 @@
 package controller
 
 import (
-	"fmt"
+	"context"
+	"errors"
 	"net/http"
+	"product-go-api/apperror"
+	"product-go-api/metrics"
+	"product-go-api/middleware"
 	"product-go-api/model"
 	"product-go-api/usecase"
 	"strconv"
+	"time"
 
 	"github.com/gin-gonic/gin"
 )
 
 type UserController struct {
-	userUseCase usecase.UserUsecase
+	userUseCase  usecase.UserUsecase
+	roleUseCase  usecase.RoleUsecase
+	auditUseCase usecase.AuditUsecase
+	metrics      *metrics.Metrics
 }
 
-func NewUserController(usecase usecase.UserUsecase) UserController {
+func NewUserController(usecase usecase.UserUsecase, roleUseCase usecase.RoleUsecase, auditUseCase usecase.AuditUsecase, metrics *metrics.Metrics) UserController {
 	return UserController{
-		userUseCase: usecase,
+		userUseCase:  usecase,
+		roleUseCase:  roleUseCase,
+		auditUseCase: auditUseCase,
+		metrics:      metrics,
 	}
 }
 
 func (uc *UserController) GetUsers(ctx *gin.Context) {
-	page, err := strconv.Atoi(ctx.DefaultQuery("page", "1"))
-	if err != nil || page < 1 {
-		response := model.Response{
-			Message: "Page must be a positive number.",
-		}
-		ctx.JSON(http.StatusBadRequest, response)
-		return
-	}
-	limit, err := strconv.Atoi(ctx.DefaultQuery("limit", "10"))
-	if err != nil || limit < 1 {
-		response := model.Response{
-			Message: "Limit must be a positive number.",
-		}
-		ctx.JSON(http.StatusBadRequest, response)
+	page, ok := pageRequest(ctx)
+	if !ok {
 		return
 	}
 	name := ctx.Query("name")
-	users, err := uc.userUseCase.GetUsers(page, limit, name)
+	users, err := uc.userUseCase.GetUsers(ctx.Request.Context(), page, name)
 	if err != nil {
-		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		handleError(ctx, err, "Failed to retrieve users.")
 		return
 	}
+	setPageLinks(ctx, &users, func(user model.User) int { return user.ID })
 	ctx.JSON(http.StatusOK, users)
 }
 
 func (uc *UserController) CreateUser(ctx *gin.Context) {
-	var user model.User
-	err := ctx.BindJSON(&user)
-
-	if user.Role == "" {
-		user.Role = "user"
-	}
-
-	if err != nil || user.Username == "" || user.Password == "" {
-		response := model.Response{
-			Message: "Invalid request body",
-		}
-		ctx.JSON(http.StatusBadRequest, response)
+	var req model.RegisterRequest
+	if !bindJSON(ctx, &req) {
 		return
 	}
 
-	createdUser, err := uc.userUseCase.CreateUser(user)
+	user := model.User{
+		Username: req.Username,
+		Email:    req.Email,
+		Password: req.Password,
+		Role:     "user",
+	}
+	createdUser, err := uc.userUseCase.CreateUser(ctx.Request.Context(), user)
 	if err != nil {
-		response := model.Response{
-			Message: "Failed to create user",
-		}
-		ctx.JSON(http.StatusInternalServerError, response)
+		handleError(ctx, err, "Failed to create user")
 		return
 	}
 
@@ -88,70 +81,107 @@ func (uc *UserController) CreateUser(ctx *gin.Context) {
 
 func (uc *UserController) GetUserByEmail(ctx *gin.Context) {
 	var req model.LoginRequest
-	err := ctx.BindJSON(&req)
-
-	if err != nil || req.Password == "" || req.Email == "" {
-		response := model.Response{
-			Message: "Invalid request body",
-		}
-		ctx.JSON(http.StatusBadRequest, response)
+	if !bindJSON(ctx, &req) {
 		return
 	}
 
-	token, err := uc.userUseCase.GetUserByEmail(req)
-
+	tokens, err := uc.userUseCase.GetUserByEmail(ctx.Request.Context(), req)
+	if errors.Is(err, usecase.ErrInvalidCredentials) {
+		uc.metrics.LoginAttempt(metrics.LoginFailure)
+		fail(ctx, apperror.Unauthorized("Invalid email or password"))
+		return
+	}
 	if err != nil {
-		response := model.Response{
-			Message: "Invalid email or password",
-		}
-		ctx.JSON(http.StatusUnauthorized, response)
+		uc.metrics.LoginAttempt(metrics.LoginError)
+		handleError(ctx, err, "Failed to login.")
 		return
 	}
+	uc.metrics.LoginAttempt(metrics.LoginSuccess)
 
 	response := model.Response{
 		Message: "Login successful",
 	}
 
 	ctx.JSON(http.StatusOK, gin.H{
-		"Message": response.Message,
-		"token":   token,
+		"Message":       response.Message,
+		"token":         tokens.AccessToken,
+		"refresh_token": tokens.RefreshToken,
+		"expires_in":    tokens.ExpiresIn,
 	})
 }
 
+func (uc *UserController) RefreshToken(ctx *gin.Context) {
+	var req model.RefreshRequest
+	err := ctx.ShouldBindJSON(&req)
+
+	if err != nil || req.RefreshToken == "" {
+		fail(ctx, apperror.Validation("Invalid request body"))
+		return
+	}
+
+	tokens, err := uc.userUseCase.RefreshToken(ctx.Request.Context(), req.RefreshToken)
+	if errors.Is(err, usecase.ErrInvalidRefreshToken) || errors.Is(err, usecase.ErrRefreshTokenReused) {
+		fail(ctx, apperror.Unauthorized("Invalid refresh token"))
+		return
+	}
+	if err != nil {
+		handleError(ctx, err, "Failed to refresh token.")
+		return
+	}
+
+	ctx.JSON(http.StatusOK, tokens)
+}
+
+func (uc *UserController) Logout(ctx *gin.Context) {
+	var req model.RefreshRequest
+	if ctx.Request.ContentLength > 0 {
+		if err := ctx.ShouldBindJSON(&req); err != nil {
+			fail(ctx, apperror.Validation("Invalid request body"))
+			return
+		}
+	}
+
+	expiresAt, _ := ctx.Get("token_expires_at")
+	accessExpiresAt, _ := expiresAt.(time.Time)
+
+	err := uc.userUseCase.Logout(ctx.Request.Context(), ctx.GetInt("user_id"), ctx.GetString("jti"), accessExpiresAt, req.RefreshToken)
+	if errors.Is(err, usecase.ErrInvalidRefreshToken) {
+		fail(ctx, apperror.Validation("Invalid refresh token"))
+		return
+	}
+	if err != nil {
+		handleError(ctx, err, "Failed to logout.")
+		return
+	}
+
+	response := model.Response{
+		Message: "Logout successful",
+	}
+	ctx.JSON(http.StatusOK, response)
+}
+
 func (uc *UserController) GetUserById(ctx *gin.Context) {
 	id := ctx.Param("id_user")
 
 	if id == "" {
-		response := model.Response{
-			Message: "id_user is required",
-		}
-		ctx.JSON(http.StatusBadRequest, response)
+		fail(ctx, apperror.Validation("id_user is required", apperror.Field("id_user", "is required")))
 		return
 	}
 
 	id_user, err := strconv.Atoi(id)
 	if err != nil || id_user < 1 {
-		response := model.Response{
-			Message: "id_user must be a positive number",
-		}
-		ctx.JSON(http.StatusBadRequest, response)
+		fail(ctx, apperror.Validation("id_user must be a positive number", apperror.Field("id_user", "must be a positive number")))
 		return
 	}
 
-	user, err := uc.userUseCase.GetUserById(id_user)
+	user, err := uc.userUseCase.GetUserById(ctx.Request.Context(), id_user)
 	if err != nil {
-		response := model.Response{
-			Message: "Failed to retrieve user",
-		}
-		ctx.JSON(http.StatusInternalServerError, response)
+		handleError(ctx, err, "Failed to retrieve user")
 		return
 	}
 
 	if user == nil {
-		response := model.Response{
-			Message: "User not found",
-		}
-		ctx.JSON(http.StatusNotFound, response)
+		fail(ctx, usecase.ErrUserNotFound)
 		return
 	}
 
@@ -161,18 +191,22 @@ func (uc *UserController) GetUserById(ctx *gin.Context) {
 func (uc *UserController) GetUserInfo(ctx *gin.Context) {
 	userIDValue, exists := ctx.Get("user_id")
 	if !exists {
-		ctx.JSON(http.StatusUnauthorized, gin.H{"error": "User ID not found in context"})
+		fail(ctx, apperror.Unauthorized("User ID not found in context"))
 		return
 	}
 	userID, ok := userIDValue.(int)
 	if !ok {
-		ctx.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid user ID in context"})
+		fail(ctx, apperror.Unauthorized("Invalid user ID in context"))
 		return
 	}
 
-	user, err := uc.userUseCase.GetUserById(userID)
-	if err != nil || user == nil {
-		ctx.JSON(http.StatusUnauthorized, gin.H{"error": "User not found"})
+	user, err := uc.userUseCase.GetUserById(ctx.Request.Context(), userID)
+	if err != nil {
+		handleError(ctx, err, "Failed to retrieve user.")
+		return
+	}
+	if user == nil {
+		fail(ctx, apperror.Unauthorized("User not found"))
 		return
 	}
 
@@ -183,65 +217,47 @@ func (uc *UserController) DeleteUser(ctx *gin.Context) {
 	id := ctx.Param("id_user")
 
 	if id == "" {
-		response := model.Response{
-			Message: "id_user is required",
-		}
-		ctx.JSON(http.StatusBadRequest, response)
+		fail(ctx, apperror.Validation("id_user is required", apperror.Field("id_user", "is required")))
 		return
 	}
 
 	id_user, err := strconv.Atoi(id)
 	if err != nil || id_user < 1 {
-		response := model.Response{
-			Message: "id_user must be a positive number",
-		}
-		ctx.JSON(http.StatusBadRequest, response)
+		fail(ctx, apperror.Validation("id_user must be a positive number", apperror.Field("id_user", "must be a positive number")))
 		return
 	}
 
-	roleRaw, _ := ctx.Get("role")
-	role, _ := roleRaw.(string)
-	userIDRaw, _ := ctx.Get("id_user")
-	requesterID, _ := userIDRaw.(int)
-
-	targetUser, err := uc.userUseCase.GetUserById(id_user)
+	targetUser, err := uc.userUseCase.GetUserById(ctx.Request.Context(), id_user)
 	if err != nil {
-		response := model.Response{
-			Message: "User not found.",
-		}
-		ctx.JSON(http.StatusNotFound, response)
+		handleError(ctx, err, "Failed to retrieve user.")
 		return
 	}
-
-	if targetUser.Role == "super_admin" && requesterID == targetUser.ID {
-		response := model.Response{
-			Message: "Super admin cannot delete themselves.",
-		}
-		ctx.JSON(http.StatusForbidden, response)
+	if targetUser == nil {
+		fail(ctx, usecase.ErrUserNotFound)
 		return
 	}
 
-	if targetUser.Role == "super_admin" && role != "super_admin" {
-		response := model.Response{
-			Message: "Only super admin can delete another super admin.",
-		}
-		ctx.JSON(http.StatusForbidden, response)
+	if targetUser.ID == ctx.GetInt("user_id") {
+		fail(ctx, apperror.Forbidden("You cannot delete yourself."))
 		return
 	}
-	if targetUser.Role == "admin" && role != "super_admin" {
-		response := model.Response{
-			Message: "Only super admin can delete an admin.",
+
+	if !middleware.HasPermission(ctx, model.PermissionUserManageAll) {
+		err := uc.roleUseCase.CheckOutranks(ctx.Request.Context(), ctx.GetString("role"), targetUser.Role)
+		if err != nil {
+			handleError(ctx, err, "Failed to delete user.")
+			return
 		}
-		ctx.JSON(http.StatusForbidden, response)
-		return
 	}
 
-	err = uc.userUseCase.DeleteUser(id_user)
-	if err != nil {
-		response := model.Response{
-			Message: "Failed to delete user.",
+	err = uc.auditUseCase.WithinTx(ctx.Request.Context(), func(txCtx context.Context) error {
+		if err := uc.userUseCase.DeleteUser(txCtx, id_user); err != nil {
+			return err
 		}
-		ctx.JSON(http.StatusInternalServerError, response)
+		return recordAudit(ctx, txCtx, uc.auditUseCase, model.AuditUserDelete, model.AuditTargetUser, id_user, targetUser, nil)
+	})
+	if err != nil {
+		handleError(ctx, err, "Failed to delete user.")
 		return
 	}
 
@@ -255,97 +271,149 @@ func (uc *UserController) UpdateUser(ctx *gin.Context) {
 	id := ctx.Param("id_user")
 
 	if id == "" {
-		response := model.Response{
-			Message: "id_user is required",
-		}
-		ctx.JSON(http.StatusBadRequest, response)
+		fail(ctx, apperror.Validation("id_user is required", apperror.Field("id_user", "is required")))
 		return
 	}
 
 	id_user, err := strconv.Atoi(id)
 	if err != nil || id_user < 1 {
-		response := model.Response{
-			Message: "id_user must be a positive number",
-		}
-		ctx.JSON(http.StatusBadRequest, response)
+		fail(ctx, apperror.Validation("id_user must be a positive number", apperror.Field("id_user", "must be a positive number")))
 		return
 	}
 
-	existingUser, err := uc.userUseCase.GetUserById(id_user)
+	existingUser, err := uc.userUseCase.GetUserById(ctx.Request.Context(), id_user)
 	if err != nil {
-		response := model.Response{
-			Message: "Failed to retrieve user.",
-		}
-		ctx.JSON(http.StatusInternalServerError, response)
+		handleError(ctx, err, "Failed to retrieve user.")
 		return
 	}
 
 	if existingUser == nil {
-		response := model.Response{
-			Message: "User not found",
-		}
-		ctx.JSON(http.StatusNotFound, response)
+		fail(ctx, usecase.ErrUserNotFound)
 		return
 	}
 
-	var updateData map[string]interface{}
-	if err := ctx.BindJSON(&updateData); err != nil {
-		response := model.Response{
-			Message: "Invalid JSON.",
-		}
-		ctx.JSON(http.StatusBadRequest, response)
+	var req model.UpdateUserRequest
+	if !bindJSON(ctx, &req) {
 		return
 	}
+	before := *existingUser
 
-	requesterRoleRaw, _ := ctx.Get("role")
-	requesterRole := fmt.Sprintf("%v", requesterRoleRaw)
-
-	if newRole, ok := updateData["role"].(string); ok {
-		if requesterRole != "admin" && requesterRole != "super_admin" {
-			response := model.Response{
-				Message: "Only admins can change user role.",
-			}
-			ctx.JSON(http.StatusForbidden, response)
+	if req.Role != nil {
+		newRole := *req.Role
+		if !middleware.HasPermission(ctx, model.PermissionUserAssignRole) {
+			fail(ctx, apperror.Forbidden("You do not have permission to change user roles."))
 			return
 		}
 
-		if (existingUser.Role == "admin" || existingUser.Role == "super_admin") && requesterRole != "super_admin" {
-			response := model.Response{
-				Message: "Only super admin can change roles of admins or super admins.",
+		requesterRole := ctx.GetString("role")
+		manageAll := middleware.HasPermission(ctx, model.PermissionUserManageAll)
+		if !manageAll {
+			err := uc.roleUseCase.CheckOutranks(ctx.Request.Context(), requesterRole, existingUser.Role)
+			if err != nil {
+				handleError(ctx, err, "Failed to update user.")
+				return
 			}
-			ctx.JSON(http.StatusForbidden, response)
+		}
+		err := uc.roleUseCase.CheckAssignable(ctx.Request.Context(), requesterRole, newRole, manageAll)
+		if errors.Is(err, usecase.ErrRoleNotFound) {
+			fail(ctx, apperror.Validation("Role not found.", apperror.Field("role", "role does not exist")))
 			return
 		}
-
-		if newRole == "super_admin" && requesterRole != "super_admin" {
-			response := model.Response{
-				Message: "Only super admin can assign super admin role.",
-			}
-			ctx.JSON(http.StatusForbidden, response)
+		if err != nil {
+			handleError(ctx, err, "Failed to update user.")
 			return
 		}
 
 		existingUser.Role = newRole
 	}
 
-	if username, ok := updateData["username"].(string); ok {
-		existingUser.Username = username
+	if req.Username != nil {
+		existingUser.Username = *req.Username
 	}
-	if email, ok := updateData["email"].(string); ok {
-		existingUser.Email = email
+	if req.Email != nil {
+		existingUser.Email = *req.Email
 	}
-	if password, ok := updateData["password"].(string); ok && password != "" {
-		existingUser.Password = password
+	if req.Password != nil {
+		existingUser.Password = *req.Password
 	}
 
-	updatedUser, err := uc.userUseCase.UpdateUser(*existingUser)
-	if err != nil {
-		response := model.Response{
-			Message: "Failed to update user.",
+	var updatedUser model.User
+	err = uc.auditUseCase.WithinTx(ctx.Request.Context(), func(txCtx context.Context) error {
+		var err error
+		updatedUser, err = uc.userUseCase.UpdateUser(txCtx, *existingUser)
+		if err != nil {
+			return err
+		}
+		action := model.AuditUserUpdate
+		if updatedUser.Role != before.Role {
+			action = model.AuditUserRoleChange
 		}
-		ctx.JSON(http.StatusInternalServerError, response)
+		return recordAudit(ctx, txCtx, uc.auditUseCase, action, model.AuditTargetUser, id_user, before, updatedUser)
+	})
+	if err != nil {
+		handleError(ctx, err, "Failed to update user.")
 		return
 	}
 
 	ctx.JSON(http.StatusOK, updatedUser)
 }
+
+// GetDeletedUsers lists the users in the trash.
+func (uc *UserController) GetDeletedUsers(ctx *gin.Context) {
+	page, ok := pageRequest(ctx)
+	if !ok {
+		return
+	}
+
+	users, err := uc.userUseCase.GetDeletedUsers(ctx.Request.Context(), page)
+	if err != nil {
+		handleError(ctx, err, "Failed to retrieve deleted users.")
+		return
+	}
+	setPageLinks(ctx, &users, func(user model.User) int { return user.ID })
+	ctx.JSON(http.StatusOK, users)
+}
+
+// RestoreUser takes a user out of the trash. Like deletion, it is limited to
+// users whose role has a lower level, unless the requester has
+// user:manage_all.
+func (uc *UserController) RestoreUser(ctx *gin.Context) {
+	id_user, err := strconv.Atoi(ctx.Param("id_user"))
+	if err != nil || id_user < 1 {
+		fail(ctx, apperror.Validation("id_user must be a positive number", apperror.Field("id_user", "must be a positive number")))
+		return
+	}
+
+	targetUser, err := uc.userUseCase.GetDeletedUserById(ctx.Request.Context(), id_user)
+	if err != nil {
+		handleError(ctx, err, "Failed to retrieve user.")
+		return
+	}
+	if targetUser == nil {
+		fail(ctx, usecase.ErrUserNotInTrash)
+		return
+	}
+
+	if !middleware.HasPermission(ctx, model.PermissionUserManageAll) {
+		err := uc.roleUseCase.CheckOutranks(ctx.Request.Context(), ctx.GetString("role"), targetUser.Role)
+		if err != nil {
+			handleError(ctx, err, "Failed to restore user.")
+			return
+		}
+	}
+
+	var restoredUser model.User
+	err = uc.auditUseCase.WithinTx(ctx.Request.Context(), func(txCtx context.Context) error {
+		var err error
+		restoredUser, err = uc.userUseCase.RestoreUser(txCtx, id_user)
+		if err != nil {
+			return err
+		}
+		return recordAudit(ctx, txCtx, uc.auditUseCase, model.AuditUserRestore, model.AuditTargetUser, id_user, targetUser, restoredUser)
+	})
+	if err != nil {
+		handleError(ctx, err, "Failed to restore user.")
+		return
+	}
+	ctx.JSON(http.StatusOK, restoredUser)
+}
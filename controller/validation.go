@@ -0,0 +1,161 @@
+package controller
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"product-go-api/apperror"
+	"product-go-api/model"
+	"reflect"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+	"github.com/go-playground/validator/v10"
+)
+
+// Request bodies are validated by gin with the `binding` tags of the model
+// request types. Field errors are reported with the JSON field names.
+func init() {
+	validate, ok := binding.Validator.Engine().(*validator.Validate)
+	if !ok {
+		return
+	}
+	validate.RegisterTagNameFunc(func(field reflect.StructField) string {
+		name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+		if name == "-" {
+			return ""
+		}
+		return name
+	})
+	_ = validate.RegisterValidation("role", func(field validator.FieldLevel) bool {
+		return model.RoleNamePattern.MatchString(field.Field().String())
+	})
+	_ = validate.RegisterValidation("sku", func(field validator.FieldLevel) bool {
+		return model.SKUPattern.MatchString(field.Field().String())
+	})
+	_ = validate.RegisterValidation("currency", func(field validator.FieldLevel) bool {
+		return model.SupportedCurrency(field.Field().String())
+	})
+}
+
+// bindJSON decodes and validates the request body into obj. When it fails the
+// request is answered with a validation problem listing the invalid fields,
+// and bindJSON returns false.
+func bindJSON(ctx *gin.Context, obj any) bool {
+	body, err := io.ReadAll(ctx.Request.Body)
+	if err != nil {
+		fail(ctx, apperror.Validation("Invalid request body"))
+		return false
+	}
+	if err := decodeJSON(body, obj); err != nil {
+		fail(ctx, err)
+		return false
+	}
+	return true
+}
+
+// decodeJSON decodes and validates body into obj, and returns the validation
+// problem listing the invalid fields when it fails.
+func decodeJSON(body []byte, obj any) *apperror.Error {
+	if err := binding.JSON.BindBody(body, obj); err != nil {
+		var typeError *json.UnmarshalTypeError
+		if errors.As(err, &typeError) && typeError.Field == "" {
+			typeError.Field = unmarshalField(body, obj)
+		}
+		return bindError(err)
+	}
+	return nil
+}
+
+// unmarshalField returns the top-level field of body that cannot be decoded
+// into obj. Newer Go versions return the errors of UnmarshalJSON methods, such
+// as the one of model.Money, without the field they were decoding.
+func unmarshalField(body []byte, obj any) string {
+	var fields map[string]json.RawMessage
+	if json.Unmarshal(body, &fields) != nil {
+		return ""
+	}
+	t := reflect.TypeOf(obj).Elem()
+	for i := 0; i < t.NumField(); i++ {
+		name, _, _ := strings.Cut(t.Field(i).Tag.Get("json"), ",")
+		raw, ok := fields[name]
+		if ok && json.Unmarshal(raw, reflect.New(t.Field(i).Type).Interface()) != nil {
+			return name
+		}
+	}
+	return ""
+}
+
+func bindError(err error) *apperror.Error {
+	var validationErrors validator.ValidationErrors
+	if errors.As(err, &validationErrors) {
+		fields := make([]model.FieldError, 0, len(validationErrors))
+		for _, fieldError := range validationErrors {
+			fields = append(fields, apperror.Field(fieldError.Field(), fieldMessage(fieldError)))
+		}
+		return apperror.Validation("Request body has invalid fields.", fields...)
+	}
+
+	var typeError *json.UnmarshalTypeError
+	if errors.As(err, &typeError) && typeError.Field != "" {
+		return apperror.Validation("Request body has invalid fields.", apperror.Field(typeError.Field, "must be "+jsonType(typeError.Type)))
+	}
+
+	return apperror.Validation("Invalid request body")
+}
+
+func fieldMessage(fieldError validator.FieldError) string {
+	unit := ""
+	if fieldError.Kind() == reflect.String {
+		unit = " characters"
+	}
+
+	switch fieldError.Tag() {
+	case "required":
+		return "is required"
+	case "email":
+		return "must be a valid email address"
+	case "min":
+		return fmt.Sprintf("must be at least %s%s", fieldError.Param(), unit)
+	case "max":
+		return fmt.Sprintf("must be at most %s%s", fieldError.Param(), unit)
+	case "gt":
+		return "must be greater than " + fieldError.Param()
+	case "gte":
+		return "must be greater than or equal to " + fieldError.Param()
+	case "oneof":
+		return "must be one of: " + strings.ReplaceAll(fieldError.Param(), " ", ", ")
+	case "role":
+		return "must match " + model.RoleNamePattern.String()
+	case "sku":
+		return "must match " + model.SKUPattern.String()
+	case "currency":
+		return "must be a supported ISO 4217 currency code"
+	}
+	return "is invalid"
+}
+
+func jsonType(t reflect.Type) string {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	if t == reflect.TypeOf(model.Money(0)) {
+		return "a number with at most 2 decimal places"
+	}
+	switch t.Kind() {
+	case reflect.String:
+		return "a string"
+	case reflect.Bool:
+		return "a boolean"
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return "an integer"
+	case reflect.Float32, reflect.Float64:
+		return "a number"
+	case reflect.Slice, reflect.Array:
+		return "an array"
+	}
+	return "an object"
+}
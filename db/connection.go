@@ -1,37 +1,54 @@
 package db
 
 import (
+	"context"
 	"database/sql"
-	"fmt"
-	"os"
-	"strconv"
+	"database/sql/driver"
+	"log/slog"
+	"product-go-api/config"
+	"product-go-api/tracing"
 
-	"github.com/joho/godotenv"
+	"github.com/XSAM/otelsql"
 	_ "github.com/lib/pq"
+	"go.opentelemetry.io/otel/attribute"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
 )
 
-func ConnectDB() (*sql.DB, error) {
-	godotenv.Load(os.ExpandEnv("../.env"))
-	var (
-		host      = os.Getenv("DB_HOST")
-		port, err = strconv.Atoi(os.Getenv("DB_PORT"))
-		user      = os.Getenv("DB_USER")
-		password  = os.Getenv("DB_PASSWORD")
-		dbname    = os.Getenv("DB_NAME")
+// ConnectDB opens the connection pool with every query traced. Statements are
+// sanitized before being attached to spans, and query arguments never are.
+func ConnectDB(cfg config.DBConfig, logger *slog.Logger) (*sql.DB, error) {
+	db, err := otelsql.Open("postgres", cfg.ConnectionString(),
+		otelsql.WithAttributes(semconv.DBSystemPostgreSQL, semconv.DBNamespace(cfg.Name)),
+		otelsql.WithSpanOptions(otelsql.SpanOptions{
+			DisableQuery:         true,
+			DisableErrSkip:       true,
+			OmitRows:             true,
+			OmitConnResetSession: true,
+		}),
+		otelsql.WithAttributesGetter(statementAttributes),
 	)
-	psqlInfo := fmt.Sprintf("host=%s port=%d user=%s password=%s dbname=%s sslmode=disable",
-		host, port, user, password, dbname)
-	db, err := sql.Open("postgres", psqlInfo)
 	if err != nil {
-		panic(err)
+		return nil, err
 	}
 
+	db.SetMaxOpenConns(cfg.MaxOpenConns)
+	db.SetMaxIdleConns(cfg.MaxIdleConns)
+	db.SetConnMaxLifetime(cfg.ConnMaxLifetime)
+
 	err = db.Ping()
 	if err != nil {
-		panic(err)
+		db.Close()
+		return nil, err
 	}
 
-	fmt.Println("Connected to " + dbname)
+	logger.Info("database connected", slog.String("db", cfg.Name))
 
 	return db, nil
 }
+
+func statementAttributes(ctx context.Context, method otelsql.Method, query string, args []driver.NamedValue) []attribute.KeyValue {
+	if query == "" {
+		return nil
+	}
+	return []attribute.KeyValue{semconv.DBQueryText(tracing.SanitizeSQL(query))}
+}
@@ -0,0 +1,13 @@
+CREATE TABLE IF NOT EXISTS product (
+  id SERIAL PRIMARY KEY,
+  product_name VARCHAR(50) NOT NULL,
+  price NUMERIC(10, 2) NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS users (
+  id SERIAL PRIMARY KEY,
+  email VARCHAR(255) UNIQUE NOT NULL,
+  username VARCHAR(255) NOT NULL,
+  password VARCHAR(255) NOT NULL,
+  role VARCHAR(20) NOT NULL DEFAULT 'user'
+);
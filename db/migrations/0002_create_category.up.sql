@@ -0,0 +1,8 @@
+CREATE TABLE IF NOT EXISTS category (
+  id SERIAL PRIMARY KEY,
+  category_name VARCHAR(100) NOT NULL,
+  parent_id INTEGER REFERENCES category(id) ON DELETE RESTRICT
+);
+
+ALTER TABLE product ADD COLUMN IF NOT EXISTS category_id INTEGER REFERENCES category(id) ON DELETE SET NULL;
+CREATE INDEX IF NOT EXISTS idx_product_category_id ON product (category_id);
@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS stock_movement;
+DROP TABLE IF EXISTS stock_reservation;
+ALTER TABLE product DROP COLUMN IF EXISTS stock_quantity;
@@ -0,0 +1,23 @@
+ALTER TABLE product ADD COLUMN IF NOT EXISTS stock_quantity INTEGER NOT NULL DEFAULT 0 CHECK (stock_quantity >= 0);
+
+CREATE TABLE IF NOT EXISTS stock_reservation (
+  id SERIAL PRIMARY KEY,
+  product_id INTEGER NOT NULL REFERENCES product(id) ON DELETE CASCADE,
+  user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+  quantity INTEGER NOT NULL CHECK (quantity > 0),
+  status VARCHAR(20) NOT NULL DEFAULT 'active',
+  created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE TABLE IF NOT EXISTS stock_movement (
+  id SERIAL PRIMARY KEY,
+  product_id INTEGER NOT NULL REFERENCES product(id) ON DELETE CASCADE,
+  movement_type VARCHAR(20) NOT NULL,
+  quantity INTEGER NOT NULL,
+  balance_after INTEGER NOT NULL,
+  reason TEXT NOT NULL DEFAULT '',
+  user_id INTEGER REFERENCES users(id) ON DELETE SET NULL,
+  reservation_id INTEGER REFERENCES stock_reservation(id) ON DELETE SET NULL,
+  created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+CREATE INDEX IF NOT EXISTS idx_stock_movement_product_id ON stock_movement (product_id);
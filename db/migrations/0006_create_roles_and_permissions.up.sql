@@ -0,0 +1,54 @@
+CREATE TABLE IF NOT EXISTS role (
+  id SERIAL PRIMARY KEY,
+  role_name VARCHAR(20) UNIQUE NOT NULL,
+  level INTEGER NOT NULL DEFAULT 0
+);
+
+CREATE TABLE IF NOT EXISTS permission (
+  id SERIAL PRIMARY KEY,
+  permission_name VARCHAR(50) UNIQUE NOT NULL,
+  description VARCHAR(255) NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS role_permission (
+  role_id INTEGER NOT NULL REFERENCES role(id) ON DELETE CASCADE,
+  permission_id INTEGER NOT NULL REFERENCES permission(id) ON DELETE CASCADE,
+  PRIMARY KEY (role_id, permission_id)
+);
+
+INSERT INTO role (role_name, level) VALUES
+  ('user', 0),
+  ('admin', 50),
+  ('super_admin', 100)
+ON CONFLICT (role_name) DO NOTHING;
+
+-- Keep any role already stored on a user so the foreign key below can be added.
+INSERT INTO role (role_name, level)
+SELECT DISTINCT role, 0 FROM users
+ON CONFLICT (role_name) DO NOTHING;
+
+INSERT INTO permission (permission_name, description) VALUES
+  ('product:write', 'Create and update products'),
+  ('product:delete', 'Delete products'),
+  ('category:write', 'Create, update and delete categories'),
+  ('stock:write', 'Adjust product stock'),
+  ('stock:read', 'Read stock movements and reconciliations'),
+  ('reservation:release_any', 'Release reservations of other users'),
+  ('order:read_all', 'List and read orders of every user'),
+  ('order:update_status', 'Change the status of orders'),
+  ('user:read', 'List users'),
+  ('user:delete', 'Delete users with a lower role'),
+  ('user:assign_role', 'Change roles of users with a lower role'),
+  ('user:manage_all', 'Delete and change roles of users regardless of their role'),
+  ('role:manage', 'Manage roles and their permissions')
+ON CONFLICT (permission_name) DO NOTHING;
+
+INSERT INTO role_permission (role_id, permission_id)
+SELECT r.id, p.id FROM role r JOIN permission p ON
+  (r.role_name = 'user' AND p.permission_name = 'product:write')
+  OR (r.role_name = 'admin' AND p.permission_name NOT IN ('user:manage_all', 'role:manage'))
+  OR r.role_name = 'super_admin'
+ON CONFLICT DO NOTHING;
+
+ALTER TABLE users
+  ADD CONSTRAINT fk_users_role FOREIGN KEY (role) REFERENCES role(role_name) ON UPDATE CASCADE;
@@ -0,0 +1,7 @@
+-- One row per rate limit key. tat is the theoretical arrival time of the
+-- generic cell rate algorithm: the key is idle once tat is in the past.
+CREATE TABLE IF NOT EXISTS rate_limit (
+  bucket_key VARCHAR(255) PRIMARY KEY,
+  tat TIMESTAMPTZ NOT NULL
+);
+CREATE INDEX IF NOT EXISTS idx_rate_limit_tat ON rate_limit (tat);
@@ -0,0 +1,7 @@
+DROP INDEX IF EXISTS idx_product_updated_at;
+DROP INDEX IF EXISTS idx_product_created_at;
+DROP INDEX IF EXISTS idx_product_price;
+
+ALTER TABLE product
+  DROP COLUMN IF EXISTS updated_at,
+  DROP COLUMN IF EXISTS created_at;
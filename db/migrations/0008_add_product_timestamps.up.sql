@@ -0,0 +1,8 @@
+ALTER TABLE product
+  ADD COLUMN IF NOT EXISTS created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+  ADD COLUMN IF NOT EXISTS updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW();
+
+-- Indexes for the price and date filters and sorts of the product listing.
+CREATE INDEX IF NOT EXISTS idx_product_price ON product (price);
+CREATE INDEX IF NOT EXISTS idx_product_created_at ON product (created_at);
+CREATE INDEX IF NOT EXISTS idx_product_updated_at ON product (updated_at);
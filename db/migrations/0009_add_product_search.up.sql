@@ -0,0 +1,22 @@
+CREATE EXTENSION IF NOT EXISTS pg_trgm;
+
+-- The search vector uses the simple configuration, which lowercases words
+-- without stemming, since product names mix languages and brand names.
+ALTER TABLE product ADD COLUMN IF NOT EXISTS search_vector TSVECTOR;
+
+CREATE OR REPLACE FUNCTION product_search_vector_update() RETURNS TRIGGER AS $$
+BEGIN
+  NEW.search_vector := to_tsvector('simple', COALESCE(NEW.product_name, ''));
+  RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS product_search_vector_trigger ON product;
+CREATE TRIGGER product_search_vector_trigger
+  BEFORE INSERT OR UPDATE OF product_name ON product
+  FOR EACH ROW EXECUTE FUNCTION product_search_vector_update();
+
+UPDATE product SET search_vector = to_tsvector('simple', product_name);
+
+CREATE INDEX IF NOT EXISTS idx_product_search_vector ON product USING GIN (search_vector);
+CREATE INDEX IF NOT EXISTS idx_product_name_trgm ON product USING GIN (product_name gin_trgm_ops);
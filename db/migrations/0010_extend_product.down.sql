@@ -0,0 +1,25 @@
+DELETE FROM permission WHERE permission_name = 'product:read_all';
+
+CREATE OR REPLACE FUNCTION product_search_vector_update() RETURNS TRIGGER AS $$
+BEGIN
+  NEW.search_vector := to_tsvector('simple', COALESCE(NEW.product_name, ''));
+  RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS product_search_vector_trigger ON product;
+CREATE TRIGGER product_search_vector_trigger
+  BEFORE INSERT OR UPDATE OF product_name ON product
+  FOR EACH ROW EXECUTE FUNCTION product_search_vector_update();
+
+UPDATE product SET search_vector = to_tsvector('simple', product_name);
+
+DROP INDEX IF EXISTS idx_product_status;
+DROP INDEX IF EXISTS idx_product_sku;
+
+ALTER TABLE product
+  DROP COLUMN IF EXISTS attributes,
+  DROP COLUMN IF EXISTS status,
+  DROP COLUMN IF EXISTS brand,
+  DROP COLUMN IF EXISTS description,
+  DROP COLUMN IF EXISTS sku;
@@ -0,0 +1,43 @@
+ALTER TABLE product
+  ADD COLUMN IF NOT EXISTS sku VARCHAR(64),
+  ADD COLUMN IF NOT EXISTS description TEXT NOT NULL DEFAULT '',
+  ADD COLUMN IF NOT EXISTS brand VARCHAR(100) NOT NULL DEFAULT '',
+  ADD COLUMN IF NOT EXISTS status VARCHAR(20) NOT NULL DEFAULT 'active'
+    CHECK (status IN ('draft', 'active', 'archived')),
+  ADD COLUMN IF NOT EXISTS attributes JSONB NOT NULL DEFAULT '{}';
+
+-- Products created before SKUs existed get one derived from their ID.
+UPDATE product SET sku = 'SKU-' || id WHERE sku IS NULL;
+ALTER TABLE product ALTER COLUMN sku SET NOT NULL;
+CREATE UNIQUE INDEX IF NOT EXISTS idx_product_sku ON product (sku);
+CREATE INDEX IF NOT EXISTS idx_product_status ON product (status);
+
+-- Search brands and descriptions too, ranking name matches first.
+CREATE OR REPLACE FUNCTION product_search_vector_update() RETURNS TRIGGER AS $$
+BEGIN
+  NEW.search_vector :=
+    setweight(to_tsvector('simple', COALESCE(NEW.product_name, '')), 'A') ||
+    setweight(to_tsvector('simple', COALESCE(NEW.brand, '')), 'B') ||
+    setweight(to_tsvector('simple', COALESCE(NEW.description, '')), 'C');
+  RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS product_search_vector_trigger ON product;
+CREATE TRIGGER product_search_vector_trigger
+  BEFORE INSERT OR UPDATE OF product_name, brand, description ON product
+  FOR EACH ROW EXECUTE FUNCTION product_search_vector_update();
+
+UPDATE product SET search_vector =
+  setweight(to_tsvector('simple', product_name), 'A') ||
+  setweight(to_tsvector('simple', brand), 'B') ||
+  setweight(to_tsvector('simple', description), 'C');
+
+INSERT INTO permission (permission_name, description) VALUES
+  ('product:read_all', 'List and read draft and archived products')
+ON CONFLICT (permission_name) DO NOTHING;
+
+INSERT INTO role_permission (role_id, permission_id)
+SELECT r.id, p.id FROM role r JOIN permission p ON p.permission_name = 'product:read_all'
+WHERE r.role_name IN ('admin', 'super_admin')
+ON CONFLICT DO NOTHING;
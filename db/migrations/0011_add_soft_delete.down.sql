@@ -0,0 +1,9 @@
+-- Trashed rows were deleted as far as the application is concerned.
+DELETE FROM product WHERE deleted_at IS NOT NULL;
+DELETE FROM users WHERE deleted_at IS NOT NULL AND NOT EXISTS (SELECT 1 FROM orders WHERE orders.user_id = users.id);
+
+DROP INDEX IF EXISTS idx_users_deleted_at;
+DROP INDEX IF EXISTS idx_product_deleted_at;
+
+ALTER TABLE users DROP COLUMN IF EXISTS deleted_at;
+ALTER TABLE product DROP COLUMN IF EXISTS deleted_at;
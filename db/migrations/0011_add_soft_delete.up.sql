@@ -0,0 +1,7 @@
+ALTER TABLE product ADD COLUMN IF NOT EXISTS deleted_at TIMESTAMPTZ;
+ALTER TABLE users ADD COLUMN IF NOT EXISTS deleted_at TIMESTAMPTZ;
+
+-- Only trashed rows are indexed: the trash listings and the purge job read
+-- them by deletion date.
+CREATE INDEX IF NOT EXISTS idx_product_deleted_at ON product (deleted_at) WHERE deleted_at IS NOT NULL;
+CREATE INDEX IF NOT EXISTS idx_users_deleted_at ON users (deleted_at) WHERE deleted_at IS NOT NULL;
@@ -0,0 +1,4 @@
+DELETE FROM permission WHERE permission_name = 'audit:read';
+
+DROP TABLE IF EXISTS audit_log;
+DROP FUNCTION IF EXISTS audit_log_append_only();
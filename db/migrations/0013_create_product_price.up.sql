@@ -0,0 +1,15 @@
+CREATE TABLE IF NOT EXISTS product_price (
+  id SERIAL PRIMARY KEY,
+  product_id INTEGER NOT NULL REFERENCES product(id) ON DELETE CASCADE,
+  price NUMERIC(10,2) NOT NULL,
+  effective_from TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+  created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_product_price_product ON product_price (product_id, effective_from DESC);
+CREATE INDEX IF NOT EXISTS idx_product_price_effective_from ON product_price (effective_from);
+
+-- The current prices start the history of existing products.
+INSERT INTO product_price (product_id, price, effective_from)
+SELECT p.id, p.price, p.created_at FROM product p
+WHERE NOT EXISTS (SELECT 1 FROM product_price pp WHERE pp.product_id = p.id);
@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS exchange_rate;
+DROP TABLE IF EXISTS product_currency_price;
+ALTER TABLE orders DROP COLUMN IF EXISTS currency;
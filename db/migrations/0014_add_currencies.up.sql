@@ -0,0 +1,24 @@
+-- Orders keep the currency they were placed in. Product prices, price
+-- history and carts are in the store currency set by CURRENCY, in which the
+-- existing orders were placed. There is no default, so every insert must
+-- name the currency.
+ALTER TABLE orders ADD COLUMN IF NOT EXISTS currency CHAR(3);
+UPDATE orders SET currency = current_setting('app.store_currency') WHERE currency IS NULL;
+ALTER TABLE orders ALTER COLUMN currency SET NOT NULL;
+
+-- Prices of a product in other currencies, used instead of converting its
+-- price with the exchange rates.
+CREATE TABLE IF NOT EXISTS product_currency_price (
+  product_id INTEGER NOT NULL REFERENCES product(id) ON DELETE CASCADE,
+  currency CHAR(3) NOT NULL,
+  price NUMERIC(10,2) NOT NULL CHECK (price >= 0),
+  PRIMARY KEY (product_id, currency)
+);
+
+-- Units of each currency worth one unit of a common reference currency,
+-- replaced as a whole when a rate table is loaded.
+CREATE TABLE IF NOT EXISTS exchange_rate (
+  currency CHAR(3) PRIMARY KEY,
+  rate NUMERIC(24,12) NOT NULL CHECK (rate > 0),
+  updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
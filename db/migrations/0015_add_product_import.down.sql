@@ -0,0 +1 @@
+DELETE FROM permission WHERE permission_name = 'product:import';
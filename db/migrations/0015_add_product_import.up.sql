@@ -0,0 +1,8 @@
+INSERT INTO permission (permission_name, description) VALUES
+  ('product:import', 'Create and update products in bulk from files')
+ON CONFLICT (permission_name) DO NOTHING;
+
+INSERT INTO role_permission (role_id, permission_id)
+SELECT r.id, p.id FROM role r JOIN permission p ON p.permission_name = 'product:import'
+WHERE r.role_name IN ('admin', 'super_admin')
+ON CONFLICT DO NOTHING;
@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS idx_stock_reservation_active_user_product;
+DROP INDEX IF EXISTS idx_stock_reservation_active_expires_at;
+ALTER TABLE stock_reservation DROP COLUMN IF EXISTS expires_at;
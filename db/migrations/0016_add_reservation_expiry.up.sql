@@ -0,0 +1,9 @@
+-- Reservations hold stock until they expire. Active reservations made before
+-- expiry existed get the reservation TTL set by RESERVATION_TTL, counted from
+-- when they were made.
+ALTER TABLE stock_reservation ADD COLUMN IF NOT EXISTS expires_at TIMESTAMPTZ;
+UPDATE stock_reservation SET expires_at = created_at + current_setting('app.reservation_ttl')::interval WHERE expires_at IS NULL;
+ALTER TABLE stock_reservation ALTER COLUMN expires_at SET NOT NULL;
+
+CREATE INDEX IF NOT EXISTS idx_stock_reservation_active_expires_at ON stock_reservation (expires_at) WHERE status = 'active';
+CREATE INDEX IF NOT EXISTS idx_stock_reservation_active_user_product ON stock_reservation (user_id, product_id) WHERE status = 'active';
@@ -0,0 +1,46 @@
+package logging
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"testing"
+)
+
+func TestNewRedactsSensitiveAttributes(t *testing.T) {
+	var buf bytes.Buffer
+	logger := New(&buf, "json", slog.LevelInfo)
+
+	logger.Info("login",
+		slog.String("email", "ana@example.com"),
+		slog.String("Password", "secret123"),
+		slog.Group("headers", slog.String("Authorization", "Bearer abc")),
+	)
+
+	var entry struct {
+		Email    string
+		Password string
+		Headers  struct{ Authorization string }
+	}
+	if err := json.Unmarshal(buf.Bytes(), &entry); err != nil {
+		t.Fatalf("decode log entry %q: %v", buf.String(), err)
+	}
+	if entry.Email != "ana@example.com" {
+		t.Fatalf("email = %q, want it untouched", entry.Email)
+	}
+	if entry.Password != Redacted || entry.Headers.Authorization != Redacted {
+		t.Fatalf("log entry leaks secrets: %s", buf.String())
+	}
+}
+
+func TestFromContext(t *testing.T) {
+	if FromContext(context.Background()) != slog.Default() {
+		t.Fatal("empty context should give the default logger")
+	}
+
+	logger := New(&bytes.Buffer{}, "text", slog.LevelInfo)
+	if FromContext(WithLogger(context.Background(), logger)) != logger {
+		t.Fatal("context logger not returned")
+	}
+}
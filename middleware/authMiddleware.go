@@ -1,48 +1,65 @@
 package middleware
 
 import (
-	"net/http"
-	"os"
-	"product-go-api/model"
+	"context"
+	"log/slog"
+	"product-go-api/apperror"
+	"product-go-api/logging"
 	"strings"
 
 	"github.com/gin-gonic/gin"
 	"github.com/golang-jwt/jwt/v5"
-	"github.com/joho/godotenv"
 )
 
-func AuthMiddleware() gin.HandlerFunc {
-	godotenv.Load()
-	var jwtKey = []byte(os.Getenv("JWT_SECRET_KEY"))
+// AuthMiddleware validates the bearer token and rejects tokens whose jti was
+// revoked, as reported by isRevoked.
+func AuthMiddleware(secret string, isRevoked func(ctx context.Context, jti string) (bool, error)) gin.HandlerFunc {
+	var jwtKey = []byte(secret)
 	return func(ctx *gin.Context) {
 		authHeader := ctx.GetHeader("Authorization")
 		if authHeader == "" || !strings.HasPrefix(authHeader, "Bearer ") {
-			response := model.Response{
-				Message: "Missing or invalid token",
-			}
-			ctx.AbortWithStatusJSON(http.StatusUnauthorized, response)
+			abort(ctx, apperror.Unauthorized("Missing or invalid token"))
 			return
 		}
 		tokenString := strings.TrimPrefix(authHeader, "Bearer ")
 
 		token, err := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
 			return jwtKey, nil
-		})
+		}, jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg()}))
 		if err != nil || !token.Valid {
-			response := model.Response{
-				Message: "Invalid Token",
-			}
-			ctx.AbortWithStatusJSON(http.StatusUnauthorized, response)
+			abort(ctx, apperror.Unauthorized("Invalid Token"))
 			return
 		}
 
-		if claims, ok := token.Claims.(jwt.MapClaims); ok && token.Valid {
-			if role, ok := claims["role"].(string); ok {
-				ctx.Set("role", role)
-			}
-			if id, ok := claims["id"].(float64); ok {
-				ctx.Set("user_id", int(id))
-			}
+		claims, ok := token.Claims.(jwt.MapClaims)
+		jti, _ := claims["jti"].(string)
+		if !ok || jti == "" {
+			abort(ctx, apperror.Unauthorized("Invalid Token"))
+			return
+		}
+
+		revoked, err := isRevoked(ctx.Request.Context(), jti)
+		if err != nil {
+			abort(ctx, apperror.Internal("Failed to validate token", err))
+			return
+		}
+		if revoked {
+			abort(ctx, apperror.Unauthorized("Token has been revoked"))
+			return
+		}
+
+		ctx.Set("jti", jti)
+		if exp, err := claims.GetExpirationTime(); err == nil && exp != nil {
+			ctx.Set("token_expires_at", exp.Time)
+		}
+		if role, ok := claims["role"].(string); ok {
+			ctx.Set("role", role)
+		}
+		if id, ok := claims["id"].(float64); ok {
+			ctx.Set("user_id", int(id))
+			requestCtx := ctx.Request.Context()
+			logger := logging.FromContext(requestCtx).With(slog.Int("user_id", int(id)))
+			ctx.Request = ctx.Request.WithContext(logging.WithLogger(requestCtx, logger))
 		}
 
 		ctx.Next()
@@ -0,0 +1,42 @@
+package middleware
+
+import (
+	"product-go-api/apperror"
+
+	"github.com/gin-gonic/gin"
+)
+
+const ProblemContentType = "application/problem+json"
+
+// ErrorHandler renders the last error recorded with ctx.Error as an
+// application/problem+json response, unless the handler already wrote one.
+// Handlers and middlewares report errors with ctx.Error and abort instead of
+// writing error bodies themselves, so every error has the same shape.
+func ErrorHandler() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		ctx.Next()
+
+		if ctx.Writer.Written() || len(ctx.Errors) == 0 {
+			return
+		}
+
+		problem := apperror.Problem(ctx.Errors.Last().Err)
+		problem.Instance = ctx.Request.URL.Path
+		problem.RequestID = ctx.GetString("request_id")
+
+		ctx.Header("Content-Type", ProblemContentType)
+		ctx.JSON(problem.Status, problem)
+	}
+}
+
+// RouteNotFound answers requests that match no route with a problem instead
+// of gin's plain text 404.
+func RouteNotFound(ctx *gin.Context) {
+	abort(ctx, apperror.NotFound("Route not found."))
+}
+
+// abort records err for ErrorHandler and stops the chain.
+func abort(ctx *gin.Context, err error) {
+	_ = ctx.Error(err)
+	ctx.Abort()
+}
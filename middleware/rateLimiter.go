@@ -1,35 +1,73 @@
 package middleware
 
 import (
-	"net/http"
-	"sync"
+	"context"
+	"fmt"
+	"log/slog"
+	"math"
+	"product-go-api/apperror"
+	"product-go-api/logging"
+	"product-go-api/model"
+	"strconv"
+	"time"
 
 	"github.com/gin-gonic/gin"
-	"golang.org/x/time/rate"
 )
 
-var visitors = make(map[string]*rate.Limiter)
-var mu sync.Mutex
+// RateLimiter limits requests per authenticated user, or per client IP when
+// the request is not authenticated, with the decision made by allow. Keys are
+// prefixed with group so every route group has its own budget. The standard
+// RateLimit-Limit, RateLimit-Remaining and RateLimit-Reset headers are set on
+// every response, and Retry-After on rejected ones. Rejections are reported to
+// rejected with the group.
+func RateLimiter(group string, limit model.RateLimit, allow func(ctx context.Context, key string, limit model.RateLimit) (model.RateLimitDecision, error), rejected func(group string)) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		key := group + ":ip:" + c.ClientIP()
+		if id, ok := c.Get("user_id"); ok {
+			key = fmt.Sprintf("%s:user:%v", group, id)
+		}
 
-func getVisitor(ip string) *rate.Limiter {
-	mu.Lock()
-	defer mu.Unlock()
-	limiter, exists := visitors[ip]
-	if !exists {
-		limiter = rate.NewLimiter(3, 5)
-		visitors[ip] = limiter
+		decision, err := allow(c.Request.Context(), key, limit)
+		if err != nil {
+			// An unavailable store must not take the whole API down, so the
+			// request goes through and the error is left for the logger.
+			_ = c.Error(fmt.Errorf("rate limiter: %w", err))
+			c.Next()
+			return
+		}
+
+		c.Header("RateLimit-Limit", strconv.Itoa(limit.Burst))
+		c.Header("RateLimit-Remaining", strconv.Itoa(decision.Remaining))
+		c.Header("RateLimit-Reset", seconds(decision.ResetAfter))
+		if !decision.Allowed {
+			rejected(group)
+			c.Header("Retry-After", seconds(decision.RetryAfter))
+			abort(c, apperror.TooManyRequests("Too many requests, retry after the time in the Retry-After header."))
+			return
+		}
+		c.Next()
 	}
-	return limiter
 }
 
-func RateLimiter() gin.HandlerFunc {
-	return func(c *gin.Context) {
-		ip := c.ClientIP()
-		limiter := getVisitor(ip)
-		if !limiter.Allow() {
-			c.AbortWithStatusJSON(http.StatusTooManyRequests, gin.H{"error": "Too many requests"})
+// EvictRateLimits calls evict every interval until ctx is done, so keys of
+// clients that went away do not pile up.
+func EvictRateLimits(ctx context.Context, interval time.Duration, evict func(ctx context.Context) (int64, error)) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
 			return
+		case <-ticker.C:
+			if _, err := evict(ctx); err != nil && ctx.Err() == nil {
+				logging.FromContext(ctx).ErrorContext(ctx, "failed to evict idle rate limits", slog.String("error", err.Error()))
+			}
 		}
-		c.Next()
 	}
 }
+
+// seconds rounds up, so clients never retry before the limit allows it.
+func seconds(d time.Duration) string {
+	return strconv.Itoa(int(math.Ceil(d.Seconds())))
+}
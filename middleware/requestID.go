@@ -0,0 +1,50 @@
+package middleware
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"log/slog"
+	"product-go-api/logging"
+	"regexp"
+
+	"github.com/gin-gonic/gin"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const RequestIDHeader = "X-Request-ID"
+
+// requestIDPattern keeps client supplied IDs short and safe to log.
+var requestIDPattern = regexp.MustCompile(`^[A-Za-z0-9._:-]{1,128}$`)
+
+// RequestID reuses the X-Request-ID header of the request when it is valid
+// and generates one otherwise. The ID is echoed in the response and added to
+// the logger carried by the request context, along with the trace and span
+// IDs when the request is traced.
+func RequestID(logger *slog.Logger) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		id := ctx.GetHeader(RequestIDHeader)
+		if !requestIDPattern.MatchString(id) {
+			id = newRequestID()
+		}
+
+		ctx.Set("request_id", id)
+		ctx.Header(RequestIDHeader, id)
+		requestLogger := logger.With(slog.String("request_id", id))
+		if span := trace.SpanContextFromContext(ctx.Request.Context()); span.IsValid() {
+			requestLogger = requestLogger.With(
+				slog.String("trace_id", span.TraceID().String()),
+				slog.String("span_id", span.SpanID().String()),
+			)
+		}
+		requestCtx := logging.WithLogger(ctx.Request.Context(), requestLogger)
+		ctx.Request = ctx.Request.WithContext(requestCtx)
+
+		ctx.Next()
+	}
+}
+
+func newRequestID() string {
+	b := make([]byte, 16)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
@@ -0,0 +1,65 @@
+package middleware
+
+import (
+	"fmt"
+	"log/slog"
+	"net/http"
+	"product-go-api/apperror"
+	"product-go-api/logging"
+	"runtime/debug"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RequestLogger writes one structured entry per request with its route,
+// status, latency and user, plus the errors handlers attached with ctx.Error.
+// Server errors are logged at error level and client errors at warn level.
+func RequestLogger() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		start := time.Now()
+		ctx.Next()
+
+		status := ctx.Writer.Status()
+		attrs := []slog.Attr{
+			slog.String("method", ctx.Request.Method),
+			slog.String("route", ctx.FullPath()),
+			slog.String("path", ctx.Request.URL.Path),
+			slog.Int("status", status),
+			slog.Duration("latency", time.Since(start)),
+			slog.String("client_ip", ctx.ClientIP()),
+			slog.Int("response_size", ctx.Writer.Size()),
+		}
+		if id, ok := ctx.Get("user_id"); ok {
+			attrs = append(attrs, slog.Any("user_id", id))
+		}
+		if len(ctx.Errors) > 0 {
+			attrs = append(attrs, slog.Any("errors", ctx.Errors.Errors()))
+		}
+
+		level := slog.LevelInfo
+		switch {
+		case status >= http.StatusInternalServerError:
+			level = slog.LevelError
+		case status >= http.StatusBadRequest:
+			level = slog.LevelWarn
+		}
+
+		requestCtx := ctx.Request.Context()
+		logging.FromContext(requestCtx).LogAttrs(requestCtx, level, "request", attrs...)
+	}
+}
+
+// Recovery reports a panicking handler as an internal error, rendered by
+// ErrorHandler, and logs the panic with its stack trace instead of writing it
+// to stderr.
+func Recovery() gin.HandlerFunc {
+	return gin.CustomRecoveryWithWriter(nil, func(ctx *gin.Context, recovered any) {
+		requestCtx := ctx.Request.Context()
+		logging.FromContext(requestCtx).ErrorContext(requestCtx, "panic recovered",
+			slog.String("panic", fmt.Sprint(recovered)),
+			slog.String("stack", string(debug.Stack())),
+		)
+		abort(ctx, apperror.Internal("An unexpected error occurred.", fmt.Errorf("panic: %v", recovered)))
+	})
+}
@@ -0,0 +1,18 @@
+package middleware
+
+import (
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RequestMetrics passes the method, route template, status and latency of
+// every request to observe. The route template keeps the number of series
+// bounded, unlike the raw path.
+func RequestMetrics(observe func(method, route string, status int, latency time.Duration)) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		start := time.Now()
+		ctx.Next()
+		observe(ctx.Request.Method, ctx.FullPath(), ctx.Writer.Status(), time.Since(start))
+	}
+}
@@ -0,0 +1,45 @@
+package middleware
+
+import (
+	"context"
+	"product-go-api/apperror"
+
+	"github.com/gin-gonic/gin"
+)
+
+// LoadPermissions stores the permissions of the role from the access token in
+// the context, as reported by rolePermissions. It runs after AuthMiddleware so
+// changes to a role take effect on the next request.
+func LoadPermissions(rolePermissions func(ctx context.Context, role string) ([]string, error)) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		permissions, err := rolePermissions(ctx.Request.Context(), ctx.GetString("role"))
+		if err != nil {
+			abort(ctx, apperror.Internal("Failed to load permissions", err))
+			return
+		}
+
+		ctx.Set("permissions", permissions)
+		ctx.Next()
+	}
+}
+
+func RequirePermission(permission string) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		if !HasPermission(ctx, permission) {
+			abort(ctx, apperror.Forbidden("You do not have permission to do this."))
+			return
+		}
+		ctx.Next()
+	}
+}
+
+// HasPermission reports whether LoadPermissions granted permission to the
+// current request.
+func HasPermission(ctx *gin.Context, permission string) bool {
+	for _, granted := range ctx.GetStringSlice("permissions") {
+		if granted == permission {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,65 @@
+package model
+
+import "time"
+
+// Audited actions, named <target type>.<verb>.
+const (
+	AuditProductCreate        = "product.create"
+	AuditProductUpdate        = "product.update"
+	AuditProductDelete        = "product.delete"
+	AuditProductRestore       = "product.restore"
+	AuditProductPriceSchedule = "product.price_schedule"
+	AuditProductStockAdjust   = "product.stock_adjust"
+	AuditCategoryCreate       = "category.create"
+	AuditCategoryUpdate       = "category.update"
+	AuditCategoryDelete       = "category.delete"
+	AuditOrderStatusChange    = "order.status_change"
+	AuditUserUpdate           = "user.update"
+	AuditUserRoleChange       = "user.role_change"
+	AuditUserDelete           = "user.delete"
+	AuditUserRestore          = "user.restore"
+	AuditRoleCreate           = "role.create"
+	AuditRolePermissionChange = "role.permission_change"
+	AuditRoleDelete           = "role.delete"
+)
+
+// Types of the targets of audited actions.
+const (
+	AuditTargetProduct  = "product"
+	AuditTargetCategory = "category"
+	AuditTargetOrder    = "order"
+	AuditTargetUser     = "user"
+	AuditTargetRole     = "role"
+)
+
+// AuditEntry records an administrative change: who made it, from where, and
+// the fields of the target it changed.
+type AuditEntry struct {
+	ID         int                    `json:"id_audit"`
+	ActorID    int                    `json:"actor_id"`
+	Action     string                 `json:"action"`
+	TargetType string                 `json:"target_type"`
+	TargetID   int                    `json:"target_id"`
+	Changes    map[string]FieldChange `json:"changes"`
+	IP         string                 `json:"ip"`
+	RequestID  string                 `json:"request_id"`
+	CreatedAt  time.Time              `json:"created_at"`
+}
+
+// FieldChange is the value of a field before and after a change. Before is
+// null for created targets and After is null for deleted ones.
+type FieldChange struct {
+	Before any `json:"before"`
+	After  any `json:"after"`
+}
+
+// AuditFilter selects audit entries. Zero values leave a filter out. The
+// date range includes From and excludes To.
+type AuditFilter struct {
+	ActorID    int
+	Action     string
+	TargetType string
+	TargetID   int
+	From       time.Time
+	To         time.Time
+}
@@ -0,0 +1,21 @@
+package model
+
+type CartItem struct {
+	ProductID int    `json:"product_id"`
+	Name      string `json:"name"`
+	Price     Money  `json:"price"`
+	Quantity  int    `json:"quantity"`
+	Subtotal  Money  `json:"subtotal"`
+}
+
+type Cart struct {
+	UserID   int        `json:"user_id"`
+	Items    []CartItem `json:"items"`
+	Total    Money      `json:"total"`
+	Currency string     `json:"currency"`
+}
+
+type CartItemRequest struct {
+	ProductID int `json:"product_id"`
+	Quantity  int `json:"quantity"`
+}
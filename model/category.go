@@ -0,0 +1,8 @@
+package model
+
+type Category struct {
+	ID       int        `json:"id_category"`
+	Name     string     `json:"name"`
+	ParentID *int       `json:"parent_id"`
+	Children []Category `json:"children,omitempty"`
+}
@@ -0,0 +1,57 @@
+package model
+
+import "strings"
+
+// DefaultCurrency is the store currency when CURRENCY is not set.
+const DefaultCurrency = "USD"
+
+// isoCurrencies lists the active ISO 4217 currency codes.
+var isoCurrencies = strings.Fields(`
+	AED AFN ALL AMD ANG AOA ARS AUD AWG AZN BAM BBD BDT BGN BHD BIF BMD BND BOB
+	BOV BRL BSD BTN BWP BYN BZD CAD CDF CHE CHF CHW CLF CLP CNY COP COU CRC CUP
+	CVE CZK DJF DKK DOP DZD EGP ERN ETB EUR FJD FKP GBP GEL GHS GIP GMD GNF GTQ
+	GYD HKD HNL HTG HUF IDR ILS INR IQD IRR ISK JMD JOD JPY KES KGS KHR KMF KPW
+	KRW KWD KYD KZT LAK LBP LKR LRD LSL LYD MAD MDL MGA MKD MMK MNT MOP MRU MUR
+	MVR MWK MXN MXV MYR MZN NAD NGN NIO NOK NPR NZD OMR PAB PEN PGK PHP PKR PLN
+	PYG QAR RON RSD RUB RWF SAR SBD SCR SDG SEK SGD SHP SLE SOS SRD SSP STN SVC
+	SYP SZL THB TJS TMT TND TOP TRY TTD TWD TZS UAH UGX USD USN UYI UYU UYW UZS
+	VED VES VND VUV WST XAF XCD XOF XPF YER ZAR ZMW ZWG
+`)
+
+// currencyDigits holds the minor unit digits of the currencies that do not
+// have two.
+var currencyDigits = map[string]int{
+	"BIF": 0, "CLP": 0, "DJF": 0, "GNF": 0, "ISK": 0, "JPY": 0, "KMF": 0, "KRW": 0, "PYG": 0,
+	"RWF": 0, "UGX": 0, "UYI": 0, "VND": 0, "VUV": 0, "XAF": 0, "XOF": 0, "XPF": 0,
+	"BHD": 3, "IQD": 3, "JOD": 3, "KWD": 3, "LYD": 3, "OMR": 3, "TND": 3,
+	"CLF": 4, "UYW": 4,
+}
+
+var knownCurrencies = func() map[string]bool {
+	known := make(map[string]bool, len(isoCurrencies))
+	for _, code := range isoCurrencies {
+		known[code] = true
+	}
+	return known
+}()
+
+// CurrencyDigits returns the number of decimal places of a currency.
+func CurrencyDigits(currency string) int {
+	if digits, ok := currencyDigits[currency]; ok {
+		return digits
+	}
+	return 2
+}
+
+// SupportedCurrency reports whether currency is an uppercase ISO 4217 code
+// that Money can hold exactly, which excludes the currencies with more than
+// two decimal places.
+func SupportedCurrency(currency string) bool {
+	return knownCurrencies[currency] && CurrencyDigits(currency) <= MoneyScale
+}
+
+// FitsCurrency reports whether amount has no more decimal places than
+// currency allows, as whole yen.
+func FitsCurrency(amount Money, currency string) bool {
+	return RoundMoney(amount.Rat(), CurrencyDigits(currency)) == amount
+}
@@ -0,0 +1,12 @@
+package model
+
+import "time"
+
+// ExchangeRate is the number of units of Currency worth one unit of the
+// reference currency of the rate table, as exact decimal text. Any currency
+// can be the reference, as long as every rate of the table uses the same one.
+type ExchangeRate struct {
+	Currency  string    `json:"currency"`
+	Rate      string    `json:"rate"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
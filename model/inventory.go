@@ -0,0 +1,62 @@
+package model
+
+import "time"
+
+const (
+	StockReceive  = "receive"
+	StockAdjust   = "adjust"
+	StockWriteOff = "write_off"
+	StockReserve  = "reserve"
+	StockRelease  = "release"
+	StockSale     = "sale"
+	StockReturn   = "return"
+)
+
+// An active reservation holds stock until it is released, consumed by a
+// checkout of its user or expires.
+const (
+	ReservationActive   = "active"
+	ReservationReleased = "released"
+	ReservationConsumed = "consumed"
+	ReservationExpired  = "expired"
+)
+
+type Stock struct {
+	ProductID int `json:"product_id"`
+	Quantity  int `json:"quantity"`
+}
+
+type StockMovement struct {
+	ID            int       `json:"id_movement"`
+	ProductID     int       `json:"product_id"`
+	Type          string    `json:"type"`
+	Quantity      int       `json:"quantity"`
+	BalanceAfter  int       `json:"balance_after"`
+	Reason        string    `json:"reason"`
+	UserID        *int      `json:"user_id"`
+	ReservationID *int      `json:"reservation_id"`
+	CreatedAt     time.Time `json:"created_at"`
+}
+
+type StockAdjustmentRequest struct {
+	Type     string `json:"type"`
+	Quantity int    `json:"quantity"`
+	Reason   string `json:"reason"`
+}
+
+type Reservation struct {
+	ID        int       `json:"id_reservation"`
+	ProductID int       `json:"product_id"`
+	UserID    int       `json:"user_id"`
+	Quantity  int       `json:"quantity"`
+	Status    string    `json:"status"`
+	CreatedAt time.Time `json:"created_at"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+type StockReconciliation struct {
+	ProductID   int  `json:"product_id"`
+	Quantity    int  `json:"quantity"`
+	LedgerTotal int  `json:"ledger_total"`
+	Consistent  bool `json:"consistent"`
+}
@@ -0,0 +1,152 @@
+package model
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// Money is an exact amount in hundredths of a currency unit, the scale of the
+// NUMERIC price columns. It is read from and written to JSON as a plain
+// number, and to the database as decimal text, so it never goes through a
+// float.
+type Money int64
+
+// MoneyScale is the number of decimal places of a Money.
+const MoneyScale = 2
+
+var errInvalidMoney = errors.New("must be a number with at most 2 decimal places")
+
+// ParseMoney reads a decimal amount such as "13.2", "-1" or "0.05". Amounts
+// with more than two decimal places are rejected instead of rounded.
+func ParseMoney(value string) (Money, error) {
+	negative := strings.HasPrefix(value, "-")
+	digits := strings.TrimPrefix(value, "-")
+	whole, fraction, _ := strings.Cut(digits, ".")
+	if whole == "" || len(fraction) > MoneyScale || !isDigits(whole) || !isDigits(fraction) {
+		return 0, errInvalidMoney
+	}
+	fraction += strings.Repeat("0", MoneyScale-len(fraction))
+
+	amount, err := strconv.ParseInt(whole+fraction, 10, 64)
+	if err != nil {
+		return 0, errInvalidMoney
+	}
+	if negative {
+		amount = -amount
+	}
+	return Money(amount), nil
+}
+
+func isDigits(value string) bool {
+	for _, r := range value {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+// String formats m without trailing zeros, as "13.2" or "13".
+func (m Money) String() string {
+	sign := ""
+	amount := int64(m)
+	if amount < 0 {
+		sign, amount = "-", -amount
+	}
+	whole, fraction := amount/100, amount%100
+	switch {
+	case fraction == 0:
+		return fmt.Sprintf("%s%d", sign, whole)
+	case fraction%10 == 0:
+		return fmt.Sprintf("%s%d.%d", sign, whole, fraction/10)
+	default:
+		return fmt.Sprintf("%s%d.%02d", sign, whole, fraction)
+	}
+}
+
+// Mul returns m times quantity.
+func (m Money) Mul(quantity int) Money {
+	return m * Money(quantity)
+}
+
+// Rat returns m as a fraction of currency units.
+func (m Money) Rat() *big.Rat {
+	return big.NewRat(int64(m), 100)
+}
+
+// RoundMoney rounds amount, in currency units, half away from zero to digits
+// decimal places, at most MoneyScale.
+func RoundMoney(amount *big.Rat, digits int) Money {
+	step := int64(1)
+	for i := digits; i < MoneyScale; i++ {
+		step *= 10
+	}
+	// Count steps of 10^-digits units, rounding half away from zero.
+	steps := new(big.Rat).Mul(amount, big.NewRat(100, step))
+	num, den := steps.Num(), steps.Denom()
+	quotient, remainder := new(big.Int).QuoRem(num, den, new(big.Int))
+	if new(big.Int).Abs(new(big.Int).Mul(remainder, big.NewInt(2))).Cmp(den) >= 0 {
+		quotient.Add(quotient, big.NewInt(int64(num.Sign())))
+	}
+	return Money(quotient.Int64() * step)
+}
+
+func (m Money) MarshalJSON() ([]byte, error) {
+	return []byte(m.String()), nil
+}
+
+// UnmarshalJSON accepts a number, or a string holding one.
+func (m *Money) UnmarshalJSON(data []byte) error {
+	value := strings.Trim(string(data), `"`)
+	if value == "null" {
+		return nil
+	}
+	parsed, err := ParseMoney(value)
+	if err != nil {
+		return &json.UnmarshalTypeError{Value: "number " + value, Type: reflect.TypeOf(Money(0))}
+	}
+	*m = parsed
+	return nil
+}
+
+// Scan reads a NUMERIC column, which the driver returns as text.
+func (m *Money) Scan(src any) error {
+	switch value := src.(type) {
+	case []byte:
+		return m.scanText(string(value))
+	case string:
+		return m.scanText(value)
+	case int64:
+		*m = Money(value * 100)
+		return nil
+	default:
+		return fmt.Errorf("model: cannot scan %T into Money", src)
+	}
+}
+
+func (m *Money) scanText(value string) error {
+	// NUMERIC columns with a larger scale, like sums, may carry trailing
+	// zeros beyond the two decimal places.
+	if whole, fraction, ok := strings.Cut(value, "."); ok {
+		value = whole + "." + strings.TrimRight(fraction, "0")
+		value = strings.TrimSuffix(value, ".")
+	}
+	parsed, err := ParseMoney(value)
+	if err != nil {
+		return fmt.Errorf("model: scan Money %q: %w", value, err)
+	}
+	*m = parsed
+	return nil
+}
+
+// Value writes m as decimal text, which PostgreSQL reads into NUMERIC
+// exactly.
+func (m Money) Value() (driver.Value, error) {
+	return m.String(), nil
+}
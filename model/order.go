@@ -0,0 +1,40 @@
+package model
+
+import "time"
+
+const (
+	OrderPending   = "pending"
+	OrderPaid      = "paid"
+	OrderShipped   = "shipped"
+	OrderCancelled = "cancelled"
+)
+
+// OrderTransitions lists the statuses an order may move to from each status.
+var OrderTransitions = map[string][]string{
+	OrderPending:   {OrderPaid, OrderCancelled},
+	OrderPaid:      {OrderShipped, OrderCancelled},
+	OrderShipped:   {},
+	OrderCancelled: {},
+}
+
+type Order struct {
+	ID        int         `json:"id_order"`
+	UserID    int         `json:"user_id"`
+	Status    string      `json:"status"`
+	Total     Money       `json:"total"`
+	Currency  string      `json:"currency"`
+	Items     []OrderItem `json:"items,omitempty"`
+	CreatedAt time.Time   `json:"created_at"`
+	UpdatedAt time.Time   `json:"updated_at"`
+}
+
+// OrderItem keeps the product name and price as they were at checkout, so
+// later catalog changes do not alter past orders.
+type OrderItem struct {
+	ID          int    `json:"id_order_item"`
+	ProductID   *int   `json:"product_id"`
+	ProductName string `json:"product_name"`
+	UnitPrice   Money  `json:"unit_price"`
+	Quantity    int    `json:"quantity"`
+	Subtotal    Money  `json:"subtotal"`
+}
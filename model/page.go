@@ -0,0 +1,25 @@
+package model
+
+// PageRequest selects a page of a list ordered by ID. Offset pagination
+// returns page Page; with Cursor set, keyset pagination returns the items
+// with an ID greater than After, which stays fast on large tables.
+type PageRequest struct {
+	Page   int
+	Limit  int
+	Cursor bool
+	After  int
+}
+
+// Page is one page of a list with the metadata needed to walk it. Next and
+// Prev are links to the neighbouring pages, and NextCursor continues a keyset
+// pagination; they are empty on the last (or first) page.
+type Page[T any] struct {
+	Items      []T    `json:"items"`
+	Total      int    `json:"total"`
+	Page       int    `json:"page,omitempty"`
+	Limit      int    `json:"limit"`
+	Next       string `json:"next,omitempty"`
+	Prev       string `json:"prev,omitempty"`
+	NextCursor string `json:"next_cursor,omitempty"`
+	HasMore    bool   `json:"-"`
+}
@@ -0,0 +1,21 @@
+package model
+
+import "time"
+
+// ProductPrice is an entry of the price history of a product, in the store
+// currency. The price of a product is the one of its latest entry that is
+// already effective; entries effective in the future are scheduled changes.
+type ProductPrice struct {
+	ID            int       `json:"id_price"`
+	ProductID     int       `json:"product_id"`
+	Price         Money     `json:"price"`
+	Currency      string    `json:"currency"`
+	EffectiveFrom time.Time `json:"effective_from"`
+	CreatedAt     time.Time `json:"created_at"`
+}
+
+// SchedulePriceRequest is the body of a scheduled price change.
+type SchedulePriceRequest struct {
+	Price         *Money    `json:"price" binding:"required,gte=0"`
+	EffectiveFrom time.Time `json:"effective_from" binding:"required"`
+}
@@ -1,7 +1,97 @@
 package model
 
+import (
+	"regexp"
+	"time"
+)
+
+// Product statuses. Only active products are listed to users without the
+// product:read_all permission.
+const (
+	ProductStatusDraft    = "draft"
+	ProductStatusActive   = "active"
+	ProductStatusArchived = "archived"
+)
+
+// SKUPattern is the format of product SKUs.
+var SKUPattern = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9._-]{0,63}$`)
+
 type Product struct {
-	ID    int     `json:"id_product"`
-	Name  string  `json:"name"`
-	Price float64 `json:"price"`
+	ID          int              `json:"id_product"`
+	SKU         string           `json:"sku"`
+	Name        string           `json:"name"`
+	Description string           `json:"description"`
+	Brand       string           `json:"brand"`
+	Price       Money            `json:"price"`
+	Currency    string           `json:"currency"`
+	PriceList   map[string]Money `json:"price_list"`
+	Status      string           `json:"status"`
+	CategoryID  *int             `json:"category_id"`
+	Attributes  map[string]any   `json:"attributes"`
+	CreatedAt   time.Time        `json:"created_at"`
+	UpdatedAt   time.Time        `json:"updated_at"`
+	DeletedAt   *time.Time       `json:"deleted_at,omitempty"`
+}
+
+// ProductRequest is the body of product create and update requests. Updates
+// decode it over the current product, so both paths apply the same rules;
+// attributes and price_list, when sent, replace the current ones.
+type ProductRequest struct {
+	SKU         string           `json:"sku" binding:"required,sku"`
+	Name        string           `json:"name" binding:"required,max=255"`
+	Description string           `json:"description" binding:"max=5000"`
+	Brand       string           `json:"brand" binding:"max=100"`
+	Price       Money            `json:"price" binding:"gte=0"`
+	PriceList   map[string]Money `json:"price_list" binding:"max=50,dive,keys,currency,endkeys,gte=0"`
+	Status      string           `json:"status" binding:"omitempty,oneof=draft active archived"`
+	CategoryID  *int             `json:"category_id" binding:"omitempty,gt=0"`
+	Attributes  map[string]any   `json:"attributes" binding:"max=50"`
+}
+
+// Fields products can be sorted by.
+const (
+	ProductSortName      = "name"
+	ProductSortPrice     = "price"
+	ProductSortCreatedAt = "created_at"
+)
+
+// SortKey orders a list by Field, ascending unless Desc is set.
+type SortKey struct {
+	Field string
+	Desc  bool
+}
+
+// ProductFilter selects the products of a listing. Zero values leave a
+// filter out. Date ranges include From and exclude To.
+type ProductFilter struct {
+	Name                 string
+	Status               string
+	CategoryID           int
+	IncludeSubcategories bool
+	IDs                  []int
+	MinPrice             *Money
+	MaxPrice             *Money
+	CreatedFrom          time.Time
+	CreatedTo            time.Time
+	UpdatedFrom          time.Time
+	UpdatedTo            time.Time
+	Sort                 []SortKey
+}
+
+// ProductSearch is a full-text search of products by name, brand and
+// description. Every word of Query must match the start of a word; with
+// Fuzzy, names similar to Query also match, to tolerate typos. An empty Status
+// searches every status.
+type ProductSearch struct {
+	Query  string
+	Fuzzy  bool
+	Status string
+}
+
+// ProductSearchResult is a product found by a search, with its relevance and
+// its name with the matched words wrapped in <mark> tags.
+type ProductSearchResult struct {
+	Product
+	Rank      float64 `json:"rank"`
+	Highlight string  `json:"highlight"`
 }
@@ -0,0 +1,52 @@
+package model
+
+import "context"
+
+// Outcomes of the rows of a product import.
+const (
+	ImportCreated = "created"
+	ImportUpdated = "updated"
+	ImportFailed  = "failed"
+)
+
+// ProductImportRow is one row of an uploaded product file. Line is its line in
+// the file. Errors holds the fields the row failed to parse or validate, in
+// which case Product is not imported.
+type ProductImportRow struct {
+	Line    int
+	Product Product
+	Errors  []FieldError
+}
+
+// ImportOptions controls a product import. With DryRun nothing is saved.
+// BatchSize is the number of rows saved per transaction; zero saves every row
+// in a single transaction. Audit, when set, is called for every saved row
+// within the transaction of its batch, which fails with it.
+type ImportOptions struct {
+	DryRun    bool
+	BatchSize int
+	Audit     func(ctx context.Context, row ImportRowResult) error
+}
+
+// ImportRowResult is the outcome of one row of a product import. Before and
+// After are the product before and after the row was saved, for the audit log;
+// Before is nil for created products.
+type ImportRowResult struct {
+	Line      int          `json:"line"`
+	SKU       string       `json:"sku"`
+	Status    string       `json:"status"`
+	ProductID int          `json:"id_product,omitempty"`
+	Errors    []FieldError `json:"errors,omitempty"`
+	Before    *Product     `json:"-"`
+	After     *Product     `json:"-"`
+}
+
+// ImportReport is the result of a product import, with one entry per row in
+// file order.
+type ImportReport struct {
+	DryRun  bool              `json:"dry_run"`
+	Created int               `json:"created"`
+	Updated int               `json:"updated"`
+	Failed  int               `json:"failed"`
+	Rows    []ImportRowResult `json:"rows"`
+}
@@ -0,0 +1,26 @@
+package model
+
+import "time"
+
+// RateLimit allows RequestsPerSecond requests on average, with bursts of up
+// to Burst requests.
+type RateLimit struct {
+	RequestsPerSecond float64
+	Burst             int
+}
+
+// Interval is the time it takes to earn back one request.
+func (l RateLimit) Interval() time.Duration {
+	return time.Duration(float64(time.Second) / l.RequestsPerSecond)
+}
+
+type RateLimitDecision struct {
+	Allowed bool
+	// Remaining is the number of requests that can still be made right now.
+	Remaining int
+	// ResetAfter is the time until the whole burst is available again.
+	ResetAfter time.Duration
+	// RetryAfter is the time until the next request is allowed, when the
+	// request was rejected.
+	RetryAfter time.Duration
+}
@@ -0,0 +1,48 @@
+package model
+
+import "regexp"
+
+// Permissions checked by the application. They are seeded by the roles and
+// permissions migration and granted to roles through the role_permission
+// table.
+const (
+	PermissionProductWrite          = "product:write"
+	PermissionProductImport         = "product:import"
+	PermissionProductDelete         = "product:delete"
+	PermissionProductReadAll        = "product:read_all"
+	PermissionCategoryWrite         = "category:write"
+	PermissionStockWrite            = "stock:write"
+	PermissionStockRead             = "stock:read"
+	PermissionReservationReleaseAny = "reservation:release_any"
+	PermissionOrderReadAll          = "order:read_all"
+	PermissionOrderUpdateStatus     = "order:update_status"
+	PermissionUserRead              = "user:read"
+	PermissionUserDelete            = "user:delete"
+	PermissionUserAssignRole        = "user:assign_role"
+	PermissionUserManageAll         = "user:manage_all"
+	PermissionRoleManage            = "role:manage"
+	PermissionAuditRead             = "audit:read"
+)
+
+// Role groups permissions. Level orders roles for the user management rules:
+// without user:manage_all, a user can only delete or change the role of users
+// whose role has a lower level, and can only assign roles up to their own.
+type Role struct {
+	ID          int      `json:"id_role"`
+	Name        string   `json:"name"`
+	Level       int      `json:"level"`
+	Permissions []string `json:"permissions"`
+}
+
+// RoleNamePattern is the format of role names.
+var RoleNamePattern = regexp.MustCompile(`^[a-z][a-z0-9_]{0,19}$`)
+
+type Permission struct {
+	ID          int    `json:"id_permission"`
+	Name        string `json:"name"`
+	Description string `json:"description"`
+}
+
+type RolePermissionsRequest struct {
+	Permissions []string `json:"permissions"`
+}
@@ -0,0 +1,26 @@
+package model
+
+import "time"
+
+type TokenPair struct {
+	AccessToken  string `json:"token"`
+	RefreshToken string `json:"refresh_token"`
+	ExpiresIn    int    `json:"expires_in"`
+}
+
+// RefreshToken is a stored refresh token. Only the SHA-256 hash of the token is
+// kept; every token issued by rotation shares the FamilyID of the login that
+// started the chain.
+type RefreshToken struct {
+	ID              int
+	UserID          int
+	TokenHash       string
+	FamilyID        string
+	AccessJTI       string
+	AccessExpiresAt time.Time
+	ExpiresAt       time.Time
+}
+
+type RefreshRequest struct {
+	RefreshToken string `json:"refresh_token"`
+}
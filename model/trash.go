@@ -0,0 +1,7 @@
+package model
+
+// PurgeResult counts the items permanently deleted from the trash.
+type PurgeResult struct {
+	Products int64
+	Users    int64
+}
@@ -1,14 +1,34 @@
 package model
 
+import "time"
+
 type User struct {
-	ID       int    `json:"id_user"`
-	Username string `json:"username"`
-	Email    string `json:"email"`
-	Password string `json:"password"`
-	Role     string `json:"role"`
+	ID        int        `json:"id_user"`
+	Username  string     `json:"username"`
+	Email     string     `json:"email"`
+	Password  string     `json:"password"`
+	Role      string     `json:"role"`
+	DeletedAt *time.Time `json:"deleted_at,omitempty"`
+}
+
+// RegisterRequest is the body of /register. New users always get the "user"
+// role.
+type RegisterRequest struct {
+	Username string `json:"username" binding:"required,min=3,max=50"`
+	Email    string `json:"email" binding:"required,email,max=254"`
+	Password string `json:"password" binding:"required,min=8,max=72"`
+}
+
+// UpdateUserRequest is the body of user updates. Fields left out are kept and
+// fields sent follow the same rules as RegisterRequest.
+type UpdateUserRequest struct {
+	Username *string `json:"username" binding:"omitempty,min=3,max=50"`
+	Email    *string `json:"email" binding:"omitempty,email,max=254"`
+	Password *string `json:"password" binding:"omitempty,min=8,max=72"`
+	Role     *string `json:"role" binding:"omitempty,role"`
 }
 
 type LoginRequest struct {
-	Email    string `json:"email"`
-	Password string `json:"password"`
+	Email    string `json:"email" binding:"required,email"`
+	Password string `json:"password" binding:"required"`
 }
@@ -0,0 +1,97 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"product-go-api/model"
+	"time"
+)
+
+type AuditRepository interface {
+	CreateAuditEntry(ctx context.Context, entry model.AuditEntry) (int, error)
+	GetAuditEntries(ctx context.Context, page model.PageRequest, filter model.AuditFilter) (model.Page[model.AuditEntry], error)
+}
+
+type auditRepository struct {
+	connection   *sql.DB
+	queryTimeout time.Duration
+}
+
+func NewAuditRepository(connection *sql.DB, queryTimeout time.Duration) AuditRepository {
+	return &auditRepository{
+		connection:   connection,
+		queryTimeout: queryTimeout,
+	}
+}
+
+const auditColumns = "id, actor_id, action, target_type, target_id, changes, ip, request_id, created_at"
+
+func (ar *auditRepository) CreateAuditEntry(ctx context.Context, entry model.AuditEntry) (int, error) {
+	ctx, cancel := context.WithTimeout(ctx, ar.queryTimeout)
+	defer cancel()
+
+	if entry.Changes == nil {
+		entry.Changes = map[string]model.FieldChange{}
+	}
+	changes, err := json.Marshal(entry.Changes)
+	if err != nil {
+		return 0, err
+	}
+
+	var id int
+	err = conn(ctx, ar.connection).QueryRowContext(ctx,
+		`INSERT INTO audit_log (actor_id, action, target_type, target_id, changes, ip, request_id)
+		VALUES ($1, $2, $3, $4, $5, $6, $7) RETURNING id;`,
+		entry.ActorID, entry.Action, entry.TargetType, entry.TargetID, changes, entry.IP, entry.RequestID,
+	).Scan(&id)
+	if err != nil {
+		return 0, queryError(ctx, err)
+	}
+	return id, nil
+}
+
+// GetAuditEntries lists the entries matching filter, newest first. Cursor
+// pages are ordered by ID, like every keyset page.
+func (ar *auditRepository) GetAuditEntries(ctx context.Context, page model.PageRequest, filter model.AuditFilter) (model.Page[model.AuditEntry], error) {
+	ctx, cancel := context.WithTimeout(ctx, ar.queryTimeout)
+	defer cancel()
+
+	var conditions []string
+	var args []interface{}
+	condition := func(format string, value interface{}) {
+		args = append(args, value)
+		conditions = append(conditions, fmt.Sprintf(format, len(args)))
+	}
+
+	if filter.ActorID > 0 {
+		condition("actor_id = $%d", filter.ActorID)
+	}
+	if filter.Action != "" {
+		condition("action = $%d", filter.Action)
+	}
+	if filter.TargetType != "" {
+		condition("target_type = $%d", filter.TargetType)
+	}
+	if filter.TargetID > 0 {
+		condition("target_id = $%d", filter.TargetID)
+	}
+	if !filter.From.IsZero() {
+		condition("created_at >= $%d", filter.From)
+	}
+	if !filter.To.IsZero() {
+		condition("created_at < $%d", filter.To)
+	}
+
+	return queryPage(ctx, ar.connection, auditColumns, "audit_log", conditions, args, page, "id DESC", func(row rowScanner) (model.AuditEntry, error) {
+		var entry model.AuditEntry
+		var changes []byte
+		err := row.Scan(&entry.ID, &entry.ActorID, &entry.Action, &entry.TargetType, &entry.TargetID,
+			&changes, &entry.IP, &entry.RequestID, &entry.CreatedAt)
+		if err != nil {
+			return model.AuditEntry{}, err
+		}
+		return entry, json.Unmarshal(changes, &entry.Changes)
+	})
+}
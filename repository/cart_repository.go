@@ -0,0 +1,96 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"product-go-api/model"
+	"time"
+)
+
+type CartRepository interface {
+	GetCartItems(ctx context.Context, id_user int) ([]model.CartItem, error)
+	AddItem(ctx context.Context, id_user int, item model.CartItemRequest) error
+	SetItemQuantity(ctx context.Context, id_user int, item model.CartItemRequest) (bool, error)
+	RemoveItem(ctx context.Context, id_user int, id_product int) (bool, error)
+}
+
+type cartRepository struct {
+	connection   *sql.DB
+	queryTimeout time.Duration
+}
+
+func NewCartRepository(connection *sql.DB, queryTimeout time.Duration) CartRepository {
+	return &cartRepository{
+		connection:   connection,
+		queryTimeout: queryTimeout,
+	}
+}
+
+func (cr *cartRepository) GetCartItems(ctx context.Context, id_user int) ([]model.CartItem, error) {
+	ctx, cancel := context.WithTimeout(ctx, cr.queryTimeout)
+	defer cancel()
+
+	rows, err := conn(ctx, cr.connection).QueryContext(ctx,
+		`SELECT c.product_id, p.product_name, p.price, c.quantity
+		FROM cart_item c JOIN product p ON p.id = c.product_id
+		WHERE c.user_id = $1 AND p.deleted_at IS NULL AND p.status = 'active' ORDER BY c.added_at;`, id_user,
+	)
+	if err != nil {
+		return []model.CartItem{}, queryError(ctx, err)
+	}
+	defer rows.Close()
+
+	itemList := []model.CartItem{}
+	for rows.Next() {
+		var item model.CartItem
+		if err := rows.Scan(&item.ProductID, &item.Name, &item.Price, &item.Quantity); err != nil {
+			return []model.CartItem{}, queryError(ctx, err)
+		}
+		item.Subtotal = item.Price.Mul(item.Quantity)
+		itemList = append(itemList, item)
+	}
+
+	return itemList, queryError(ctx, rows.Err())
+}
+
+// AddItem puts quantity units of a product in the cart, adding to the quantity
+// already there.
+func (cr *cartRepository) AddItem(ctx context.Context, id_user int, item model.CartItemRequest) error {
+	ctx, cancel := context.WithTimeout(ctx, cr.queryTimeout)
+	defer cancel()
+
+	_, err := conn(ctx, cr.connection).ExecContext(ctx,
+		`INSERT INTO cart_item (user_id, product_id, quantity) VALUES ($1, $2, $3)
+		ON CONFLICT (user_id, product_id) DO UPDATE SET quantity = cart_item.quantity + EXCLUDED.quantity;`,
+		id_user, item.ProductID, item.Quantity,
+	)
+	return queryError(ctx, err)
+}
+
+// SetItemQuantity returns false when the product is not in the cart.
+func (cr *cartRepository) SetItemQuantity(ctx context.Context, id_user int, item model.CartItemRequest) (bool, error) {
+	ctx, cancel := context.WithTimeout(ctx, cr.queryTimeout)
+	defer cancel()
+
+	result, err := conn(ctx, cr.connection).ExecContext(ctx,
+		"UPDATE cart_item SET quantity = $3 WHERE user_id = $1 AND product_id = $2;",
+		id_user, item.ProductID, item.Quantity,
+	)
+	if err != nil {
+		return false, queryError(ctx, err)
+	}
+	affected, err := result.RowsAffected()
+	return affected > 0, queryError(ctx, err)
+}
+
+func (cr *cartRepository) RemoveItem(ctx context.Context, id_user int, id_product int) (bool, error) {
+	ctx, cancel := context.WithTimeout(ctx, cr.queryTimeout)
+	defer cancel()
+
+	result, err := conn(ctx, cr.connection).ExecContext(ctx, "DELETE FROM cart_item WHERE user_id = $1 AND product_id = $2;", id_user, id_product)
+	if err != nil {
+		return false, queryError(ctx, err)
+	}
+	affected, err := result.RowsAffected()
+	return affected > 0, queryError(ctx, err)
+}
@@ -0,0 +1,209 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"product-go-api/model"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// ErrCategoryHasChildren is returned when a category is deleted while it has
+// subcategories, which the parent_id foreign key restricts.
+var ErrCategoryHasChildren = errors.New("category has children")
+
+type CategoryRepository interface {
+	GetCategories(ctx context.Context) ([]model.Category, error)
+	GetCategoryById(ctx context.Context, id_category int) (*model.Category, error)
+	CreateCategory(ctx context.Context, category model.Category) (int, error)
+	UpdateCategory(ctx context.Context, category model.Category) (*model.Category, error)
+	DeleteCategory(ctx context.Context, id_category int) (bool, error)
+	GetDescendantIds(ctx context.Context, id_category int) ([]int, error)
+	HasChildren(ctx context.Context, id_category int) (bool, error)
+}
+
+type categoryRepository struct {
+	connection   *sql.DB
+	queryTimeout time.Duration
+}
+
+func NewCategoryRepository(connection *sql.DB, queryTimeout time.Duration) CategoryRepository {
+	return &categoryRepository{
+		connection:   connection,
+		queryTimeout: queryTimeout,
+	}
+}
+
+func (cr *categoryRepository) GetCategories(ctx context.Context) ([]model.Category, error) {
+	ctx, cancel := context.WithTimeout(ctx, cr.queryTimeout)
+	defer cancel()
+
+	rows, err := conn(ctx, cr.connection).QueryContext(ctx, "SELECT id, category_name, parent_id FROM category ORDER BY category_name;")
+	if err != nil {
+		return []model.Category{}, queryError(ctx, err)
+	}
+	defer rows.Close()
+
+	var categoryList []model.Category
+
+	for rows.Next() {
+		var categoryObj model.Category
+		var parentID sql.NullInt64
+		if err := rows.Scan(&categoryObj.ID, &categoryObj.Name, &parentID); err != nil {
+			return []model.Category{}, queryError(ctx, err)
+		}
+		categoryObj.ParentID = nullIntToPtr(parentID)
+		categoryList = append(categoryList, categoryObj)
+	}
+
+	return categoryList, queryError(ctx, rows.Err())
+}
+
+func (cr *categoryRepository) GetCategoryById(ctx context.Context, id_category int) (*model.Category, error) {
+	ctx, cancel := context.WithTimeout(ctx, cr.queryTimeout)
+	defer cancel()
+
+	query, err := conn(ctx, cr.connection).PrepareContext(ctx, "SELECT id, category_name, parent_id FROM category WHERE id = $1;")
+	if err != nil {
+		return nil, queryError(ctx, err)
+	}
+	defer query.Close()
+
+	var category model.Category
+	var parentID sql.NullInt64
+
+	err = query.QueryRowContext(ctx, id_category).Scan(&category.ID, &category.Name, &parentID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, queryError(ctx, err)
+	}
+
+	category.ParentID = nullIntToPtr(parentID)
+	return &category, nil
+}
+
+func (cr *categoryRepository) CreateCategory(ctx context.Context, category model.Category) (int, error) {
+	ctx, cancel := context.WithTimeout(ctx, cr.queryTimeout)
+	defer cancel()
+
+	var id int
+	query, err := conn(ctx, cr.connection).PrepareContext(ctx,
+		"INSERT INTO category"+"(category_name, parent_id)"+"VALUES ($1, $2) RETURNING id;",
+	)
+	if err != nil {
+		return 0, queryError(ctx, err)
+	}
+	defer query.Close()
+
+	err = query.QueryRowContext(ctx, category.Name, category.ParentID).Scan(&id)
+	if err != nil {
+		return 0, queryError(ctx, err)
+	}
+
+	return id, nil
+}
+
+func (cr *categoryRepository) UpdateCategory(ctx context.Context, category model.Category) (*model.Category, error) {
+	ctx, cancel := context.WithTimeout(ctx, cr.queryTimeout)
+	defer cancel()
+
+	query, err := conn(ctx, cr.connection).PrepareContext(ctx,
+		"UPDATE category SET category_name = $2, parent_id = $3 WHERE id = $1 RETURNING id, category_name, parent_id;",
+	)
+	if err != nil {
+		return nil, queryError(ctx, err)
+	}
+	defer query.Close()
+
+	var updatedCategory model.Category
+	var parentID sql.NullInt64
+
+	err = query.QueryRowContext(ctx, category.ID, category.Name, category.ParentID).Scan(
+		&updatedCategory.ID,
+		&updatedCategory.Name,
+		&parentID,
+	)
+	if err != nil {
+		return nil, queryError(ctx, err)
+	}
+
+	updatedCategory.ParentID = nullIntToPtr(parentID)
+	return &updatedCategory, nil
+}
+
+// DeleteCategory reports whether the category existed and was deleted.
+func (cr *categoryRepository) DeleteCategory(ctx context.Context, id_category int) (bool, error) {
+	ctx, cancel := context.WithTimeout(ctx, cr.queryTimeout)
+	defer cancel()
+
+	query, err := conn(ctx, cr.connection).PrepareContext(ctx, "DELETE FROM category WHERE id = $1;")
+	if err != nil {
+		return false, queryError(ctx, err)
+	}
+	defer query.Close()
+
+	result, err := query.ExecContext(ctx, id_category)
+	if err != nil {
+		var pqErr *pq.Error
+		if errors.As(err, &pqErr) && pqErr.Code == "23503" && pqErr.Constraint == "category_parent_id_fkey" {
+			return false, ErrCategoryHasChildren
+		}
+		return false, queryError(ctx, err)
+	}
+	deleted, err := result.RowsAffected()
+	if err != nil {
+		return false, queryError(ctx, err)
+	}
+	return deleted > 0, nil
+}
+
+// GetDescendantIds returns the ids of every category below id_category in the
+// tree, not including id_category itself.
+func (cr *categoryRepository) GetDescendantIds(ctx context.Context, id_category int) ([]int, error) {
+	ctx, cancel := context.WithTimeout(ctx, cr.queryTimeout)
+	defer cancel()
+
+	rows, err := conn(ctx, cr.connection).QueryContext(ctx, `
+		WITH RECURSIVE tree AS (
+			SELECT id FROM category WHERE parent_id = $1
+			UNION ALL
+			SELECT c.id FROM category c JOIN tree t ON c.parent_id = t.id
+		)
+		SELECT id FROM tree;`, id_category)
+	if err != nil {
+		return nil, queryError(ctx, err)
+	}
+	defer rows.Close()
+
+	var ids []int
+	for rows.Next() {
+		var id int
+		if err := rows.Scan(&id); err != nil {
+			return nil, queryError(ctx, err)
+		}
+		ids = append(ids, id)
+	}
+
+	return ids, queryError(ctx, rows.Err())
+}
+
+func (cr *categoryRepository) HasChildren(ctx context.Context, id_category int) (bool, error) {
+	ctx, cancel := context.WithTimeout(ctx, cr.queryTimeout)
+	defer cancel()
+
+	var exists bool
+	err := conn(ctx, cr.connection).QueryRowContext(ctx, "SELECT EXISTS (SELECT 1 FROM category WHERE parent_id = $1);", id_category).Scan(&exists)
+	return exists, queryError(ctx, err)
+}
+
+func nullIntToPtr(value sql.NullInt64) *int {
+	if !value.Valid {
+		return nil
+	}
+	v := int(value.Int64)
+	return &v
+}
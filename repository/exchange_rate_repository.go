@@ -0,0 +1,70 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"product-go-api/model"
+	"time"
+)
+
+type ExchangeRateRepository interface {
+	GetExchangeRates(ctx context.Context) ([]model.ExchangeRate, error)
+	ReplaceExchangeRates(ctx context.Context, rates []model.ExchangeRate) error
+}
+
+type exchangeRateRepository struct {
+	connection   *sql.DB
+	queryTimeout time.Duration
+}
+
+func NewExchangeRateRepository(connection *sql.DB, queryTimeout time.Duration) ExchangeRateRepository {
+	return &exchangeRateRepository{
+		connection:   connection,
+		queryTimeout: queryTimeout,
+	}
+}
+
+func (er *exchangeRateRepository) GetExchangeRates(ctx context.Context) ([]model.ExchangeRate, error) {
+	ctx, cancel := context.WithTimeout(ctx, er.queryTimeout)
+	defer cancel()
+
+	// The rate is read as text so it keeps every decimal place.
+	rows, err := conn(ctx, er.connection).QueryContext(ctx, "SELECT currency, rate::text, updated_at FROM exchange_rate ORDER BY currency;")
+	if err != nil {
+		return nil, queryError(ctx, err)
+	}
+	defer rows.Close()
+
+	rates := []model.ExchangeRate{}
+	for rows.Next() {
+		var rate model.ExchangeRate
+		if err := rows.Scan(&rate.Currency, &rate.Rate, &rate.UpdatedAt); err != nil {
+			return nil, queryError(ctx, err)
+		}
+		rates = append(rates, rate)
+	}
+	return rates, queryError(ctx, rows.Err())
+}
+
+// ReplaceExchangeRates swaps the whole rate table for rates in one
+// transaction, so conversions never mix rates of two loads.
+func (er *exchangeRateRepository) ReplaceExchangeRates(ctx context.Context, rates []model.ExchangeRate) error {
+	ctx, cancel := context.WithTimeout(ctx, er.queryTimeout)
+	defer cancel()
+
+	tx, err := beginTx(ctx, er.connection)
+	if err != nil {
+		return queryError(ctx, err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, "DELETE FROM exchange_rate;"); err != nil {
+		return queryError(ctx, err)
+	}
+	for _, rate := range rates {
+		if _, err := tx.ExecContext(ctx, "INSERT INTO exchange_rate (currency, rate) VALUES ($1, $2);", rate.Currency, rate.Rate); err != nil {
+			return queryError(ctx, err)
+		}
+	}
+	return queryError(ctx, tx.Commit())
+}
@@ -0,0 +1,366 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"product-go-api/model"
+	"sort"
+	"time"
+)
+
+var ErrInsufficientStock = errors.New("insufficient stock")
+
+// ErrReservationLimit is returned when a reservation would take the quantity
+// a user holds in active reservations of a product above the limit.
+var ErrReservationLimit = errors.New("reservation limit exceeded")
+
+type InventoryRepository interface {
+	GetStock(ctx context.Context, id_product int) (*model.Stock, error)
+	ApplyMovement(ctx context.Context, movement model.StockMovement) (*model.StockMovement, error)
+	Reserve(ctx context.Context, reservation model.Reservation, maxQuantity int) (*model.Reservation, error)
+	GetReservationById(ctx context.Context, id_reservation int) (*model.Reservation, error)
+	Release(ctx context.Context, id_reservation int, id_user int) (*model.Reservation, error)
+	ExpireReservations(ctx context.Context, now time.Time) (int64, error)
+	GetMovements(ctx context.Context, id_product, page, limit int) ([]model.StockMovement, error)
+	Reconcile(ctx context.Context, id_product int) (*model.StockReconciliation, error)
+}
+
+type inventoryRepository struct {
+	connection   *sql.DB
+	queryTimeout time.Duration
+}
+
+func NewInventoryRepository(connection *sql.DB, queryTimeout time.Duration) InventoryRepository {
+	return &inventoryRepository{
+		connection:   connection,
+		queryTimeout: queryTimeout,
+	}
+}
+
+func (ir *inventoryRepository) GetStock(ctx context.Context, id_product int) (*model.Stock, error) {
+	ctx, cancel := context.WithTimeout(ctx, ir.queryTimeout)
+	defer cancel()
+
+	var stock model.Stock
+	err := conn(ctx, ir.connection).QueryRowContext(ctx,
+		"SELECT id, stock_quantity FROM product WHERE id = $1 AND deleted_at IS NULL;", id_product,
+	).Scan(&stock.ProductID, &stock.Quantity)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, queryError(ctx, err)
+	}
+	return &stock, nil
+}
+
+// ApplyMovement changes the stock of movement.ProductID by movement.Quantity and
+// records the movement in the ledger, in a single transaction.
+func (ir *inventoryRepository) ApplyMovement(ctx context.Context, movement model.StockMovement) (*model.StockMovement, error) {
+	ctx, cancel := context.WithTimeout(ctx, ir.queryTimeout)
+	defer cancel()
+
+	tx, err := beginTx(ctx, ir.connection)
+	if err != nil {
+		return nil, queryError(ctx, err)
+	}
+	defer tx.Rollback()
+
+	recorded, err := applyStockChange(ctx, tx, movement)
+	if err != nil {
+		return nil, queryError(ctx, err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, queryError(ctx, err)
+	}
+	return recorded, nil
+}
+
+// Reserve takes reservation.Quantity out of the product stock until
+// reservation.ExpiresAt. It returns ErrReservationLimit when the user would
+// hold more than maxQuantity units of the product in active reservations.
+func (ir *inventoryRepository) Reserve(ctx context.Context, reservation model.Reservation, maxQuantity int) (*model.Reservation, error) {
+	ctx, cancel := context.WithTimeout(ctx, ir.queryTimeout)
+	defer cancel()
+
+	tx, err := beginTx(ctx, ir.connection)
+	if err != nil {
+		return nil, queryError(ctx, err)
+	}
+	defer tx.Rollback()
+
+	// Locking the product serializes its reservations, so concurrent ones of
+	// the same user cannot both fit under the limit.
+	if _, err := tx.ExecContext(ctx, "SELECT 1 FROM product WHERE id = $1 FOR UPDATE;", reservation.ProductID); err != nil {
+		return nil, queryError(ctx, err)
+	}
+	var reserved int
+	err = tx.QueryRowContext(ctx,
+		`SELECT COALESCE(SUM(quantity), 0) FROM stock_reservation
+		WHERE user_id = $1 AND product_id = $2 AND status = $3 AND expires_at > NOW();`,
+		reservation.UserID, reservation.ProductID, model.ReservationActive,
+	).Scan(&reserved)
+	if err != nil {
+		return nil, queryError(ctx, err)
+	}
+	if reserved+reservation.Quantity > maxQuantity {
+		return nil, ErrReservationLimit
+	}
+
+	err = tx.QueryRowContext(ctx,
+		"INSERT INTO stock_reservation (product_id, user_id, quantity, status, expires_at) VALUES ($1, $2, $3, $4, $5) RETURNING id, created_at;",
+		reservation.ProductID, reservation.UserID, reservation.Quantity, model.ReservationActive, reservation.ExpiresAt,
+	).Scan(&reservation.ID, &reservation.CreatedAt)
+	if err != nil {
+		return nil, queryError(ctx, err)
+	}
+	reservation.Status = model.ReservationActive
+
+	_, err = applyStockChange(ctx, tx, model.StockMovement{
+		ProductID:     reservation.ProductID,
+		Type:          model.StockReserve,
+		Quantity:      -reservation.Quantity,
+		UserID:        &reservation.UserID,
+		ReservationID: &reservation.ID,
+	})
+	if err != nil {
+		return nil, queryError(ctx, err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, queryError(ctx, err)
+	}
+	return &reservation, nil
+}
+
+func (ir *inventoryRepository) GetReservationById(ctx context.Context, id_reservation int) (*model.Reservation, error) {
+	ctx, cancel := context.WithTimeout(ctx, ir.queryTimeout)
+	defer cancel()
+
+	var reservation model.Reservation
+	err := conn(ctx, ir.connection).QueryRowContext(ctx,
+		"SELECT id, product_id, user_id, quantity, status, created_at, expires_at FROM stock_reservation WHERE id = $1;", id_reservation,
+	).Scan(&reservation.ID, &reservation.ProductID, &reservation.UserID, &reservation.Quantity, &reservation.Status, &reservation.CreatedAt, &reservation.ExpiresAt)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, queryError(ctx, err)
+	}
+	return &reservation, nil
+}
+
+// Release returns the reserved quantity to stock. It returns nil, nil when the
+// reservation is not active anymore, so a reservation is never released twice.
+func (ir *inventoryRepository) Release(ctx context.Context, id_reservation int, id_user int) (*model.Reservation, error) {
+	ctx, cancel := context.WithTimeout(ctx, ir.queryTimeout)
+	defer cancel()
+
+	tx, err := beginTx(ctx, ir.connection)
+	if err != nil {
+		return nil, queryError(ctx, err)
+	}
+	defer tx.Rollback()
+
+	var reservation model.Reservation
+	err = tx.QueryRowContext(ctx,
+		"UPDATE stock_reservation SET status = $2 WHERE id = $1 AND status = $3 RETURNING id, product_id, user_id, quantity, status, created_at, expires_at;",
+		id_reservation, model.ReservationReleased, model.ReservationActive,
+	).Scan(&reservation.ID, &reservation.ProductID, &reservation.UserID, &reservation.Quantity, &reservation.Status, &reservation.CreatedAt, &reservation.ExpiresAt)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, queryError(ctx, err)
+	}
+
+	_, err = applyStockChange(ctx, tx, model.StockMovement{
+		ProductID:     reservation.ProductID,
+		Type:          model.StockRelease,
+		Quantity:      reservation.Quantity,
+		UserID:        &id_user,
+		ReservationID: &reservation.ID,
+	})
+	if err != nil {
+		return nil, queryError(ctx, err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, queryError(ctx, err)
+	}
+	return &reservation, nil
+}
+
+// ExpireReservations gives back the stock of the active reservations that
+// expired by now and reports how many it expired.
+func (ir *inventoryRepository) ExpireReservations(ctx context.Context, now time.Time) (int64, error) {
+	ctx, cancel := context.WithTimeout(ctx, ir.queryTimeout)
+	defer cancel()
+
+	tx, err := beginTx(ctx, ir.connection)
+	if err != nil {
+		return 0, queryError(ctx, err)
+	}
+	defer tx.Rollback()
+
+	expired, err := updateReservations(ctx, tx,
+		"UPDATE stock_reservation SET status = $2 WHERE status = $3 AND expires_at <= $1 RETURNING id, product_id, quantity;",
+		now, model.ReservationExpired, model.ReservationActive,
+	)
+	if err != nil {
+		return 0, queryError(ctx, err)
+	}
+	if err := returnReservedStock(ctx, tx, expired, "reservation expired", nil); err != nil {
+		return 0, queryError(ctx, err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, queryError(ctx, err)
+	}
+	return int64(len(expired)), nil
+}
+
+func (ir *inventoryRepository) GetMovements(ctx context.Context, id_product, page, limit int) ([]model.StockMovement, error) {
+	ctx, cancel := context.WithTimeout(ctx, ir.queryTimeout)
+	defer cancel()
+
+	if page < 1 {
+		page = 1
+	}
+
+	if limit < 1 {
+		limit = 10
+	}
+
+	offset := (page - 1) * limit
+
+	rows, err := conn(ctx, ir.connection).QueryContext(ctx,
+		`SELECT id, product_id, movement_type, quantity, balance_after, reason, user_id, reservation_id, created_at
+		FROM stock_movement WHERE product_id = $1 ORDER BY id DESC LIMIT $2 OFFSET $3;`,
+		id_product, limit, offset,
+	)
+	if err != nil {
+		return []model.StockMovement{}, queryError(ctx, err)
+	}
+	defer rows.Close()
+
+	var movementList []model.StockMovement
+	for rows.Next() {
+		var movement model.StockMovement
+		var userID, reservationID sql.NullInt64
+		if err := rows.Scan(
+			&movement.ID,
+			&movement.ProductID,
+			&movement.Type,
+			&movement.Quantity,
+			&movement.BalanceAfter,
+			&movement.Reason,
+			&userID,
+			&reservationID,
+			&movement.CreatedAt,
+		); err != nil {
+			return []model.StockMovement{}, queryError(ctx, err)
+		}
+		movement.UserID = nullIntToPtr(userID)
+		movement.ReservationID = nullIntToPtr(reservationID)
+		movementList = append(movementList, movement)
+	}
+
+	return movementList, queryError(ctx, rows.Err())
+}
+
+func (ir *inventoryRepository) Reconcile(ctx context.Context, id_product int) (*model.StockReconciliation, error) {
+	ctx, cancel := context.WithTimeout(ctx, ir.queryTimeout)
+	defer cancel()
+
+	var reconciliation model.StockReconciliation
+	err := conn(ctx, ir.connection).QueryRowContext(ctx,
+		`SELECT p.id, p.stock_quantity, COALESCE(SUM(m.quantity), 0)
+		FROM product p LEFT JOIN stock_movement m ON m.product_id = p.id
+		WHERE p.id = $1 GROUP BY p.id, p.stock_quantity;`, id_product,
+	).Scan(&reconciliation.ProductID, &reconciliation.Quantity, &reconciliation.LedgerTotal)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, queryError(ctx, err)
+	}
+	reconciliation.Consistent = reconciliation.Quantity == reconciliation.LedgerTotal
+	return &reconciliation, nil
+}
+
+// updateReservations runs query, an UPDATE of stock_reservation returning the
+// id, product_id and quantity of each row, and returns the rows it changed.
+func updateReservations(ctx context.Context, tx dbtx, query string, args ...interface{}) ([]model.Reservation, error) {
+	rows, err := tx.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var reservations []model.Reservation
+	for rows.Next() {
+		var reservation model.Reservation
+		if err := rows.Scan(&reservation.ID, &reservation.ProductID, &reservation.Quantity); err != nil {
+			return nil, err
+		}
+		reservations = append(reservations, reservation)
+	}
+	return reservations, rows.Err()
+}
+
+// returnReservedStock gives the quantity of each reservation back to the
+// product stock inside tx, recording reason and id_user in the ledger. The
+// products are locked in ID order, like Checkout does, so the two cannot
+// deadlock.
+func returnReservedStock(ctx context.Context, tx dbtx, reservations []model.Reservation, reason string, id_user *int) error {
+	sort.Slice(reservations, func(i, j int) bool {
+		if reservations[i].ProductID != reservations[j].ProductID {
+			return reservations[i].ProductID < reservations[j].ProductID
+		}
+		return reservations[i].ID < reservations[j].ID
+	})
+	for _, reservation := range reservations {
+		_, err := applyStockChange(ctx, tx, model.StockMovement{
+			ProductID:     reservation.ProductID,
+			Type:          model.StockRelease,
+			Quantity:      reservation.Quantity,
+			Reason:        reason,
+			UserID:        id_user,
+			ReservationID: &reservation.ID,
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// applyStockChange updates the product stock and writes the ledger entry inside
+// tx. The conditional UPDATE locks the product row, so concurrent changes are
+// serialized and the stock can never go below zero.
+func applyStockChange(ctx context.Context, tx dbtx, movement model.StockMovement) (*model.StockMovement, error) {
+	err := tx.QueryRowContext(ctx,
+		"UPDATE product SET stock_quantity = stock_quantity + $2 WHERE id = $1 AND stock_quantity + $2 >= 0 RETURNING stock_quantity;",
+		movement.ProductID, movement.Quantity,
+	).Scan(&movement.BalanceAfter)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrInsufficientStock
+		}
+		return nil, err
+	}
+
+	err = tx.QueryRowContext(ctx,
+		`INSERT INTO stock_movement (product_id, movement_type, quantity, balance_after, reason, user_id, reservation_id)
+		VALUES ($1, $2, $3, $4, $5, $6, $7) RETURNING id, created_at;`,
+		movement.ProductID, movement.Type, movement.Quantity, movement.BalanceAfter, movement.Reason, movement.UserID, movement.ReservationID,
+	).Scan(&movement.ID, &movement.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+
+	return &movement, nil
+}
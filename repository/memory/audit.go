@@ -0,0 +1,69 @@
+package memory
+
+import (
+	"context"
+	"maps"
+	"product-go-api/model"
+	"product-go-api/repository"
+	"slices"
+)
+
+type auditRepository struct {
+	store *Store
+}
+
+func (s *Store) AuditRepository() repository.AuditRepository {
+	return &auditRepository{store: s}
+}
+
+func (ar *auditRepository) CreateAuditEntry(ctx context.Context, entry model.AuditEntry) (int, error) {
+	s := ar.store
+	if err := s.lock(ctx); err != nil {
+		return 0, err
+	}
+	defer s.mu.Unlock()
+
+	entry.ID = s.nextID("audit_log")
+	entry.Changes = maps.Clone(entry.Changes)
+	if entry.Changes == nil {
+		entry.Changes = map[string]model.FieldChange{}
+	}
+	entry.CreatedAt = s.now()
+	s.auditLog = append(s.auditLog, entry)
+	return entry.ID, nil
+}
+
+func (ar *auditRepository) GetAuditEntries(ctx context.Context, page model.PageRequest, filter model.AuditFilter) (model.Page[model.AuditEntry], error) {
+	s := ar.store
+	if err := s.lock(ctx); err != nil {
+		return model.Page[model.AuditEntry]{}, err
+	}
+	defer s.mu.Unlock()
+
+	var entries []model.AuditEntry
+	for _, entry := range s.auditLog {
+		if matchesAuditFilter(entry, filter) {
+			entry.Changes = maps.Clone(entry.Changes)
+			entries = append(entries, entry)
+		}
+	}
+	// Entries are appended in ID order; offset pages list the newest first.
+	if !page.Cursor {
+		slices.Reverse(entries)
+	}
+	return pageOf(entries, page, func(entry model.AuditEntry) int { return entry.ID }), nil
+}
+
+func matchesAuditFilter(entry model.AuditEntry, filter model.AuditFilter) bool {
+	switch {
+	case filter.ActorID > 0 && entry.ActorID != filter.ActorID:
+		return false
+	case filter.Action != "" && entry.Action != filter.Action:
+		return false
+	case filter.TargetType != "" && entry.TargetType != filter.TargetType:
+		return false
+	case filter.TargetID > 0 && entry.TargetID != filter.TargetID:
+		return false
+	}
+	return inRange(entry.CreatedAt, filter.From, filter.To)
+}
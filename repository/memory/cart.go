@@ -0,0 +1,117 @@
+package memory
+
+import (
+	"context"
+	"errors"
+	"product-go-api/model"
+	"product-go-api/repository"
+	"sort"
+)
+
+type cartRepository struct {
+	store *Store
+}
+
+func (s *Store) CartRepository() repository.CartRepository {
+	return &cartRepository{store: s}
+}
+
+func (cr *cartRepository) GetCartItems(ctx context.Context, id_user int) ([]model.CartItem, error) {
+	s := cr.store
+	if err := s.lock(ctx); err != nil {
+		return nil, err
+	}
+	defer s.mu.Unlock()
+
+	return s.cartItems(id_user), nil
+}
+
+func (cr *cartRepository) AddItem(ctx context.Context, id_user int, item model.CartItemRequest) error {
+	s := cr.store
+	if err := s.lock(ctx); err != nil {
+		return err
+	}
+	defer s.mu.Unlock()
+
+	if _, ok := s.products[item.ProductID]; !ok {
+		return errors.New("memory: product does not exist")
+	}
+	if _, ok := s.users[id_user]; !ok {
+		return errors.New("memory: user does not exist")
+	}
+
+	cart, ok := s.carts[id_user]
+	if !ok {
+		cart = make(map[int]*cartRow)
+		s.carts[id_user] = cart
+	}
+	if row, ok := cart[item.ProductID]; ok {
+		row.quantity += item.Quantity
+		return nil
+	}
+	cart[item.ProductID] = &cartRow{quantity: item.Quantity, added: s.nextID("cart_item")}
+	return nil
+}
+
+func (cr *cartRepository) SetItemQuantity(ctx context.Context, id_user int, item model.CartItemRequest) (bool, error) {
+	s := cr.store
+	if err := s.lock(ctx); err != nil {
+		return false, err
+	}
+	defer s.mu.Unlock()
+
+	row, ok := s.carts[id_user][item.ProductID]
+	if !ok {
+		return false, nil
+	}
+	row.quantity = item.Quantity
+	return true, nil
+}
+
+func (cr *cartRepository) RemoveItem(ctx context.Context, id_user int, id_product int) (bool, error) {
+	s := cr.store
+	if err := s.lock(ctx); err != nil {
+		return false, err
+	}
+	defer s.mu.Unlock()
+
+	if _, ok := s.carts[id_user][id_product]; !ok {
+		return false, nil
+	}
+	delete(s.carts[id_user], id_product)
+	return true, nil
+}
+
+// cartItems returns the cart of a user in the order the items were added.
+// Callers must hold s.mu.
+func (s *Store) cartItems(id_user int) []model.CartItem {
+	type entry struct {
+		item  model.CartItem
+		added int
+	}
+
+	var entries []entry
+	for id_product, row := range s.carts[id_user] {
+		product := s.products[id_product].product
+		if product.DeletedAt != nil || product.Status != model.ProductStatusActive {
+			continue
+		}
+		entries = append(entries, entry{
+			item: model.CartItem{
+				ProductID: id_product,
+				Name:      product.Name,
+				Price:     product.Price,
+				Quantity:  row.quantity,
+				Subtotal:  product.Price.Mul(row.quantity),
+			},
+			added: row.added,
+		})
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].added < entries[j].added })
+
+	itemList := []model.CartItem{}
+	for _, e := range entries {
+		itemList = append(itemList, e.item)
+	}
+	return itemList
+}
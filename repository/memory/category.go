@@ -0,0 +1,165 @@
+package memory
+
+import (
+	"context"
+	"errors"
+	"product-go-api/model"
+	"product-go-api/repository"
+	"sort"
+)
+
+type categoryRepository struct {
+	store *Store
+}
+
+func (s *Store) CategoryRepository() repository.CategoryRepository {
+	return &categoryRepository{store: s}
+}
+
+func (cr *categoryRepository) GetCategories(ctx context.Context) ([]model.Category, error) {
+	s := cr.store
+	if err := s.lock(ctx); err != nil {
+		return nil, err
+	}
+	defer s.mu.Unlock()
+
+	var categoryList []model.Category
+	for _, category := range s.categories {
+		category.ParentID = copyIntPtr(category.ParentID)
+		categoryList = append(categoryList, category)
+	}
+	sort.Slice(categoryList, func(i, j int) bool {
+		if categoryList[i].Name != categoryList[j].Name {
+			return categoryList[i].Name < categoryList[j].Name
+		}
+		return categoryList[i].ID < categoryList[j].ID
+	})
+	return categoryList, nil
+}
+
+func (cr *categoryRepository) GetCategoryById(ctx context.Context, id_category int) (*model.Category, error) {
+	s := cr.store
+	if err := s.lock(ctx); err != nil {
+		return nil, err
+	}
+	defer s.mu.Unlock()
+
+	category, ok := s.categories[id_category]
+	if !ok {
+		return nil, nil
+	}
+	category.ParentID = copyIntPtr(category.ParentID)
+	return &category, nil
+}
+
+func (cr *categoryRepository) CreateCategory(ctx context.Context, category model.Category) (int, error) {
+	s := cr.store
+	if err := s.lock(ctx); err != nil {
+		return 0, err
+	}
+	defer s.mu.Unlock()
+
+	if err := s.checkCategoryRef(category.ParentID); err != nil {
+		return 0, err
+	}
+
+	category.ID = s.nextID("category")
+	category.ParentID = copyIntPtr(category.ParentID)
+	category.Children = nil
+	s.categories[category.ID] = category
+	return category.ID, nil
+}
+
+func (cr *categoryRepository) UpdateCategory(ctx context.Context, category model.Category) (*model.Category, error) {
+	s := cr.store
+	if err := s.lock(ctx); err != nil {
+		return nil, err
+	}
+	defer s.mu.Unlock()
+
+	if _, ok := s.categories[category.ID]; !ok {
+		return nil, errors.New("memory: category not found")
+	}
+	if err := s.checkCategoryRef(category.ParentID); err != nil {
+		return nil, err
+	}
+
+	category.ParentID = copyIntPtr(category.ParentID)
+	category.Children = nil
+	s.categories[category.ID] = category
+
+	updated := category
+	updated.ParentID = copyIntPtr(category.ParentID)
+	return &updated, nil
+}
+
+// DeleteCategory refuses to delete categories with children, like the
+// ON DELETE RESTRICT constraint, and detaches the products of the category.
+func (cr *categoryRepository) DeleteCategory(ctx context.Context, id_category int) (bool, error) {
+	s := cr.store
+	if err := s.lock(ctx); err != nil {
+		return false, err
+	}
+	defer s.mu.Unlock()
+
+	if _, ok := s.categories[id_category]; !ok {
+		return false, nil
+	}
+	for _, category := range s.categories {
+		if category.ParentID != nil && *category.ParentID == id_category {
+			return false, repository.ErrCategoryHasChildren
+		}
+	}
+
+	delete(s.categories, id_category)
+	for _, row := range s.products {
+		if row.product.CategoryID != nil && *row.product.CategoryID == id_category {
+			row.product.CategoryID = nil
+		}
+	}
+	return true, nil
+}
+
+func (cr *categoryRepository) GetDescendantIds(ctx context.Context, id_category int) ([]int, error) {
+	s := cr.store
+	if err := s.lock(ctx); err != nil {
+		return nil, err
+	}
+	defer s.mu.Unlock()
+
+	return s.descendants(id_category), nil
+}
+
+func (cr *categoryRepository) HasChildren(ctx context.Context, id_category int) (bool, error) {
+	s := cr.store
+	if err := s.lock(ctx); err != nil {
+		return false, err
+	}
+	defer s.mu.Unlock()
+
+	for _, category := range s.categories {
+		if category.ParentID != nil && *category.ParentID == id_category {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// descendants walks the category tree below id_category. Callers must hold
+// s.mu.
+func (s *Store) descendants(id_category int) []int {
+	var ids []int
+	queue := []int{id_category}
+	for len(queue) > 0 {
+		parent := queue[0]
+		queue = queue[1:]
+		for _, id := range sortedKeys(s.categories) {
+			category := s.categories[id]
+			if category.ParentID != nil && *category.ParentID == parent {
+				ids = append(ids, id)
+				queue = append(queue, id)
+			}
+		}
+	}
+	return ids
+}
@@ -0,0 +1,50 @@
+package memory
+
+import (
+	"context"
+	"product-go-api/model"
+	"product-go-api/repository"
+	"slices"
+	"strings"
+)
+
+type exchangeRateRepository struct {
+	store *Store
+}
+
+func (s *Store) ExchangeRateRepository() repository.ExchangeRateRepository {
+	return &exchangeRateRepository{store: s}
+}
+
+func (er *exchangeRateRepository) GetExchangeRates(ctx context.Context) ([]model.ExchangeRate, error) {
+	s := er.store
+	if err := s.lock(ctx); err != nil {
+		return nil, err
+	}
+	defer s.mu.Unlock()
+
+	rates := slices.Clone(s.exchangeRates)
+	if rates == nil {
+		rates = []model.ExchangeRate{}
+	}
+	slices.SortFunc(rates, func(a, b model.ExchangeRate) int {
+		return strings.Compare(a.Currency, b.Currency)
+	})
+	return rates, nil
+}
+
+func (er *exchangeRateRepository) ReplaceExchangeRates(ctx context.Context, rates []model.ExchangeRate) error {
+	s := er.store
+	if err := s.lock(ctx); err != nil {
+		return err
+	}
+	defer s.mu.Unlock()
+
+	now := s.now()
+	s.exchangeRates = make([]model.ExchangeRate, len(rates))
+	for i, rate := range rates {
+		rate.UpdatedAt = now
+		s.exchangeRates[i] = rate
+	}
+	return nil
+}
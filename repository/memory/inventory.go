@@ -0,0 +1,236 @@
+package memory
+
+import (
+	"context"
+	"errors"
+	"product-go-api/model"
+	"product-go-api/repository"
+	"time"
+)
+
+type inventoryRepository struct {
+	store *Store
+}
+
+func (s *Store) InventoryRepository() repository.InventoryRepository {
+	return &inventoryRepository{store: s}
+}
+
+func (ir *inventoryRepository) GetStock(ctx context.Context, id_product int) (*model.Stock, error) {
+	s := ir.store
+	if err := s.lock(ctx); err != nil {
+		return nil, err
+	}
+	defer s.mu.Unlock()
+
+	row, ok := s.products[id_product]
+	if !ok || row.product.DeletedAt != nil {
+		return nil, nil
+	}
+	return &model.Stock{ProductID: id_product, Quantity: row.stock}, nil
+}
+
+func (ir *inventoryRepository) ApplyMovement(ctx context.Context, movement model.StockMovement) (*model.StockMovement, error) {
+	s := ir.store
+	if err := s.lock(ctx); err != nil {
+		return nil, err
+	}
+	defer s.mu.Unlock()
+
+	return s.applyStockChange(movement)
+}
+
+func (ir *inventoryRepository) Reserve(ctx context.Context, reservation model.Reservation, maxQuantity int) (*model.Reservation, error) {
+	s := ir.store
+	if err := s.lock(ctx); err != nil {
+		return nil, err
+	}
+	defer s.mu.Unlock()
+
+	if s.reservedQuantity(reservation.UserID, reservation.ProductID)+reservation.Quantity > maxQuantity {
+		return nil, repository.ErrReservationLimit
+	}
+	if !s.canChangeStock(reservation.ProductID, -reservation.Quantity) {
+		return nil, repository.ErrInsufficientStock
+	}
+	if _, ok := s.users[reservation.UserID]; !ok {
+		return nil, errors.New("memory: user does not exist")
+	}
+
+	reservation.ID = s.nextID("stock_reservation")
+	reservation.Status = model.ReservationActive
+	reservation.CreatedAt = s.now()
+	s.reservations[reservation.ID] = reservation
+
+	_, err := s.applyStockChange(model.StockMovement{
+		ProductID:     reservation.ProductID,
+		Type:          model.StockReserve,
+		Quantity:      -reservation.Quantity,
+		UserID:        copyIntPtr(&reservation.UserID),
+		ReservationID: copyIntPtr(&reservation.ID),
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &reservation, nil
+}
+
+func (ir *inventoryRepository) GetReservationById(ctx context.Context, id_reservation int) (*model.Reservation, error) {
+	s := ir.store
+	if err := s.lock(ctx); err != nil {
+		return nil, err
+	}
+	defer s.mu.Unlock()
+
+	reservation, ok := s.reservations[id_reservation]
+	if !ok {
+		return nil, nil
+	}
+	return &reservation, nil
+}
+
+func (ir *inventoryRepository) Release(ctx context.Context, id_reservation int, id_user int) (*model.Reservation, error) {
+	s := ir.store
+	if err := s.lock(ctx); err != nil {
+		return nil, err
+	}
+	defer s.mu.Unlock()
+
+	reservation, ok := s.reservations[id_reservation]
+	if !ok || reservation.Status != model.ReservationActive {
+		return nil, nil
+	}
+
+	_, err := s.applyStockChange(model.StockMovement{
+		ProductID:     reservation.ProductID,
+		Type:          model.StockRelease,
+		Quantity:      reservation.Quantity,
+		UserID:        copyIntPtr(&id_user),
+		ReservationID: copyIntPtr(&reservation.ID),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	reservation.Status = model.ReservationReleased
+	s.reservations[id_reservation] = reservation
+	return &reservation, nil
+}
+
+func (ir *inventoryRepository) ExpireReservations(ctx context.Context, now time.Time) (int64, error) {
+	s := ir.store
+	if err := s.lock(ctx); err != nil {
+		return 0, err
+	}
+	defer s.mu.Unlock()
+
+	expired, err := s.returnReservedStock(func(reservation model.Reservation) bool {
+		return !reservation.ExpiresAt.After(now)
+	}, model.ReservationExpired, "reservation expired", nil)
+	return int64(expired), err
+}
+
+func (ir *inventoryRepository) GetMovements(ctx context.Context, id_product, page, limit int) ([]model.StockMovement, error) {
+	s := ir.store
+	if err := s.lock(ctx); err != nil {
+		return nil, err
+	}
+	defer s.mu.Unlock()
+
+	var movementList []model.StockMovement
+	for i := len(s.movements) - 1; i >= 0; i-- {
+		if s.movements[i].ProductID == id_product {
+			movementList = append(movementList, s.movements[i])
+		}
+	}
+	return paginate(movementList, page, limit), nil
+}
+
+func (ir *inventoryRepository) Reconcile(ctx context.Context, id_product int) (*model.StockReconciliation, error) {
+	s := ir.store
+	if err := s.lock(ctx); err != nil {
+		return nil, err
+	}
+	defer s.mu.Unlock()
+
+	row, ok := s.products[id_product]
+	if !ok {
+		return nil, nil
+	}
+
+	reconciliation := model.StockReconciliation{ProductID: id_product, Quantity: row.stock}
+	for _, movement := range s.movements {
+		if movement.ProductID == id_product {
+			reconciliation.LedgerTotal += movement.Quantity
+		}
+	}
+	reconciliation.Consistent = reconciliation.Quantity == reconciliation.LedgerTotal
+	return &reconciliation, nil
+}
+
+// reservedQuantity is the quantity of a product held by the unexpired active
+// reservations of a user. Callers must hold s.mu.
+func (s *Store) reservedQuantity(id_user, id_product int) int {
+	now := s.now()
+	reserved := 0
+	for _, reservation := range s.reservations {
+		if reservation.UserID == id_user && reservation.ProductID == id_product &&
+			reservation.Status == model.ReservationActive && reservation.ExpiresAt.After(now) {
+			reserved += reservation.Quantity
+		}
+	}
+	return reserved
+}
+
+// returnReservedStock gives back the stock of the active reservations matched
+// by match, moving them to status, and reports how many it changed. Callers
+// must hold s.mu.
+func (s *Store) returnReservedStock(match func(model.Reservation) bool, status, reason string, id_user *int) (int, error) {
+	changed := 0
+	for _, id := range sortedKeys(s.reservations) {
+		reservation := s.reservations[id]
+		if reservation.Status != model.ReservationActive || !match(reservation) {
+			continue
+		}
+		_, err := s.applyStockChange(model.StockMovement{
+			ProductID:     reservation.ProductID,
+			Type:          model.StockRelease,
+			Quantity:      reservation.Quantity,
+			Reason:        reason,
+			UserID:        copyIntPtr(id_user),
+			ReservationID: copyIntPtr(&reservation.ID),
+		})
+		if err != nil {
+			return changed, err
+		}
+		reservation.Status = status
+		s.reservations[id] = reservation
+		changed++
+	}
+	return changed, nil
+}
+
+// canChangeStock reports whether the stock of a product can change by delta
+// without going negative. Callers must hold s.mu.
+func (s *Store) canChangeStock(id_product, delta int) bool {
+	row, ok := s.products[id_product]
+	return ok && row.stock+delta >= 0
+}
+
+// applyStockChange is the in-memory counterpart of the conditional UPDATE and
+// ledger INSERT of the PostgreSQL repository. Callers must hold s.mu.
+func (s *Store) applyStockChange(movement model.StockMovement) (*model.StockMovement, error) {
+	if !s.canChangeStock(movement.ProductID, movement.Quantity) {
+		return nil, repository.ErrInsufficientStock
+	}
+
+	row := s.products[movement.ProductID]
+	row.stock += movement.Quantity
+
+	movement.ID = s.nextID("stock_movement")
+	movement.BalanceAfter = row.stock
+	movement.CreatedAt = s.now()
+	s.movements = append(s.movements, movement)
+
+	return &movement, nil
+}
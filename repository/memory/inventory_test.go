@@ -0,0 +1,52 @@
+package memory
+
+import (
+	"context"
+	"errors"
+	"product-go-api/model"
+	"product-go-api/repository"
+	"sync"
+	"testing"
+	"time"
+)
+
+// TestReserveConcurrent checks that concurrent reservations never oversell,
+// the same guarantee the conditional UPDATE gives in PostgreSQL.
+func TestReserveConcurrent(t *testing.T) {
+	ctx := context.Background()
+	store := NewStore()
+	id_user, _ := store.UserRepository().CreateUser(ctx, model.User{Email: "ana@example.com", Password: "secret123"})
+	id_product, _ := store.ProductRepository().CreateProduct(ctx, model.Product{SKU: "KB-1", Name: "Keyboard", Price: 100})
+	inventory := store.InventoryRepository()
+	inventory.ApplyMovement(ctx, model.StockMovement{ProductID: id_product, Type: model.StockReceive, Quantity: 10})
+
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	reserved, rejected := 0, 0
+	for range 50 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := inventory.Reserve(ctx, model.Reservation{ProductID: id_product, UserID: id_user, Quantity: 1, ExpiresAt: time.Now().Add(time.Hour)}, 50)
+			mu.Lock()
+			defer mu.Unlock()
+			switch {
+			case err == nil:
+				reserved++
+			case errors.Is(err, repository.ErrInsufficientStock):
+				rejected++
+			default:
+				t.Errorf("reserve: %v", err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if reserved != 10 || rejected != 40 {
+		t.Fatalf("reserved %d and rejected %d, want 10 and 40", reserved, rejected)
+	}
+	reconciliation, _ := inventory.Reconcile(ctx, id_product)
+	if reconciliation.Quantity != 0 || !reconciliation.Consistent {
+		t.Fatalf("reconciliation = %+v, want an empty consistent stock", reconciliation)
+	}
+}
@@ -0,0 +1,179 @@
+package memory
+
+import (
+	"context"
+	"fmt"
+	"product-go-api/model"
+	"product-go-api/repository"
+	"sort"
+)
+
+type orderRepository struct {
+	store *Store
+}
+
+func (s *Store) OrderRepository() repository.OrderRepository {
+	return &orderRepository{store: s}
+}
+
+// Checkout checks the stock of every item before changing anything, so a
+// failed checkout leaves the store untouched like a rolled back transaction.
+// The units the user reserved count as stock of their item, and the
+// reservations are consumed.
+func (or *orderRepository) Checkout(ctx context.Context, id_user int, currency string) (*model.Order, error) {
+	s := or.store
+	if err := s.lock(ctx); err != nil {
+		return nil, err
+	}
+	defer s.mu.Unlock()
+
+	cartItems := s.cartItems(id_user)
+	if len(cartItems) == 0 {
+		return nil, repository.ErrCartEmpty
+	}
+	sort.Slice(cartItems, func(i, j int) bool { return cartItems[i].ProductID < cartItems[j].ProductID })
+
+	for _, item := range cartItems {
+		if !s.canChangeStock(item.ProductID, s.reservedQuantity(id_user, item.ProductID)-item.Quantity) {
+			return nil, repository.ErrInsufficientStock
+		}
+	}
+
+	now := s.now()
+	order := model.Order{
+		ID:        s.nextID("orders"),
+		UserID:    id_user,
+		Status:    model.OrderPending,
+		Currency:  currency,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+
+	for _, cartItem := range cartItems {
+		_, err := s.returnReservedStock(func(reservation model.Reservation) bool {
+			return reservation.UserID == id_user && reservation.ProductID == cartItem.ProductID && reservation.ExpiresAt.After(now)
+		}, model.ReservationConsumed, fmt.Sprintf("order #%d", order.ID), &id_user)
+		if err != nil {
+			return nil, err
+		}
+
+		_, err = s.applyStockChange(model.StockMovement{
+			ProductID: cartItem.ProductID,
+			Type:      model.StockSale,
+			Quantity:  -cartItem.Quantity,
+			Reason:    fmt.Sprintf("order #%d", order.ID),
+			UserID:    copyIntPtr(&id_user),
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		item := model.OrderItem{
+			ID:          s.nextID("order_item"),
+			ProductID:   copyIntPtr(&cartItem.ProductID),
+			ProductName: cartItem.Name,
+			UnitPrice:   cartItem.Price,
+			Quantity:    cartItem.Quantity,
+			Subtotal:    cartItem.Subtotal,
+		}
+		order.Total += item.Subtotal
+		order.Items = append(order.Items, item)
+	}
+
+	s.orders[order.ID] = order
+	delete(s.carts, id_user)
+
+	return copyOrder(order, true), nil
+}
+
+func (or *orderRepository) GetOrders(ctx context.Context, page, limit int, id_user int, status string) ([]model.Order, error) {
+	s := or.store
+	if err := s.lock(ctx); err != nil {
+		return nil, err
+	}
+	defer s.mu.Unlock()
+
+	ids := sortedKeys(s.orders)
+	orderList := []model.Order{}
+	for i := len(ids) - 1; i >= 0; i-- {
+		order := s.orders[ids[i]]
+		if id_user > 0 && order.UserID != id_user {
+			continue
+		}
+		if status != "" && order.Status != status {
+			continue
+		}
+		orderList = append(orderList, *copyOrder(order, false))
+	}
+
+	page_items := paginate(orderList, page, limit)
+	if page_items == nil {
+		return []model.Order{}, nil
+	}
+	return page_items, nil
+}
+
+func (or *orderRepository) GetOrderById(ctx context.Context, id_order int) (*model.Order, error) {
+	s := or.store
+	if err := s.lock(ctx); err != nil {
+		return nil, err
+	}
+	defer s.mu.Unlock()
+
+	order, ok := s.orders[id_order]
+	if !ok {
+		return nil, nil
+	}
+	return copyOrder(order, true), nil
+}
+
+func (or *orderRepository) UpdateStatus(ctx context.Context, id_order int, from, to string, id_user int) (*model.Order, error) {
+	s := or.store
+	if err := s.lock(ctx); err != nil {
+		return nil, err
+	}
+	defer s.mu.Unlock()
+
+	order, ok := s.orders[id_order]
+	if !ok || order.Status != from {
+		return nil, nil
+	}
+
+	if to == model.OrderCancelled {
+		for _, item := range order.Items {
+			if item.ProductID == nil {
+				continue
+			}
+			if _, ok := s.products[*item.ProductID]; !ok {
+				continue
+			}
+			_, err := s.applyStockChange(model.StockMovement{
+				ProductID: *item.ProductID,
+				Type:      model.StockReturn,
+				Quantity:  item.Quantity,
+				Reason:    fmt.Sprintf("order #%d cancelled", order.ID),
+				UserID:    copyIntPtr(&id_user),
+			})
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	order.Status = to
+	order.UpdatedAt = s.now()
+	s.orders[id_order] = order
+	return copyOrder(order, true), nil
+}
+
+func copyOrder(order model.Order, withItems bool) *model.Order {
+	copied := order
+	copied.Items = nil
+	if withItems {
+		for _, item := range order.Items {
+			item.ProductID = copyIntPtr(item.ProductID)
+			copied.Items = append(copied.Items, item)
+		}
+	}
+	return &copied
+}
@@ -0,0 +1,93 @@
+package memory
+
+import (
+	"context"
+	"errors"
+	"product-go-api/model"
+	"sort"
+	"time"
+)
+
+// addPrice appends a price to the history of a product. Callers must hold
+// s.mu.
+func (s *Store) addPrice(id_product int, price model.Money, effectiveFrom time.Time) model.ProductPrice {
+	entry := model.ProductPrice{
+		ID:            s.nextID("product_price"),
+		ProductID:     id_product,
+		Price:         price,
+		EffectiveFrom: effectiveFrom,
+		CreatedAt:     s.now(),
+	}
+	s.prices = append(s.prices, entry)
+	return entry
+}
+
+func (pr *productRepository) GetPriceHistory(ctx context.Context, id_product int, page model.PageRequest) (model.Page[model.ProductPrice], error) {
+	s := pr.store
+	if err := s.lock(ctx); err != nil {
+		return model.Page[model.ProductPrice]{}, err
+	}
+	defer s.mu.Unlock()
+
+	var prices []model.ProductPrice
+	for _, price := range s.prices {
+		if price.ProductID == id_product {
+			prices = append(prices, price)
+		}
+	}
+	// Entries are appended in ID order, which cursor pages keep.
+	if !page.Cursor {
+		sort.SliceStable(prices, func(i, j int) bool {
+			if !prices[i].EffectiveFrom.Equal(prices[j].EffectiveFrom) {
+				return prices[i].EffectiveFrom.After(prices[j].EffectiveFrom)
+			}
+			return prices[i].ID > prices[j].ID
+		})
+	}
+	return pageOf(prices, page, func(price model.ProductPrice) int { return price.ID }), nil
+}
+
+func (pr *productRepository) SchedulePrice(ctx context.Context, price model.ProductPrice) (model.ProductPrice, error) {
+	s := pr.store
+	if err := s.lock(ctx); err != nil {
+		return model.ProductPrice{}, err
+	}
+	defer s.mu.Unlock()
+
+	if _, ok := s.products[price.ProductID]; !ok {
+		return model.ProductPrice{}, errors.New("memory: product does not exist")
+	}
+	return s.addPrice(price.ProductID, price.Price, price.EffectiveFrom), nil
+}
+
+func (pr *productRepository) ApplyScheduledPrices(ctx context.Context, now time.Time) (int64, error) {
+	s := pr.store
+	if err := s.lock(ctx); err != nil {
+		return 0, err
+	}
+	defer s.mu.Unlock()
+
+	current := map[int]model.ProductPrice{}
+	for _, price := range s.prices {
+		if price.EffectiveFrom.After(now) {
+			continue
+		}
+		latest, ok := current[price.ProductID]
+		if !ok || price.EffectiveFrom.After(latest.EffectiveFrom) ||
+			price.EffectiveFrom.Equal(latest.EffectiveFrom) && price.ID > latest.ID {
+			current[price.ProductID] = price
+		}
+	}
+
+	var applied int64
+	for id_product, price := range current {
+		row, ok := s.products[id_product]
+		if !ok || row.product.DeletedAt != nil || row.product.Price == price.Price {
+			continue
+		}
+		row.product.Price = price.Price
+		row.product.UpdatedAt = s.now()
+		applied++
+	}
+	return applied, nil
+}
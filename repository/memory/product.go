@@ -0,0 +1,533 @@
+package memory
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"maps"
+	"product-go-api/model"
+	"product-go-api/repository"
+	"slices"
+	"strings"
+	"time"
+	"unicode"
+)
+
+type productRepository struct {
+	store *Store
+}
+
+func (s *Store) ProductRepository() repository.ProductRepository {
+	return &productRepository{store: s}
+}
+
+func (pr *productRepository) GetProducts(ctx context.Context, page model.PageRequest, filter model.ProductFilter) (model.Page[model.Product], error) {
+	s := pr.store
+	if err := s.lock(ctx); err != nil {
+		return model.Page[model.Product]{}, err
+	}
+	defer s.mu.Unlock()
+
+	var categories map[int]bool
+	if filter.CategoryID > 0 {
+		categories = map[int]bool{filter.CategoryID: true}
+		if filter.IncludeSubcategories {
+			for _, id := range s.descendants(filter.CategoryID) {
+				categories[id] = true
+			}
+		}
+	}
+
+	var productList []model.Product
+	for _, id := range sortedKeys(s.products) {
+		product := s.products[id].product
+		if product.DeletedAt != nil {
+			continue
+		}
+		if filter.Name != "" && !strings.Contains(strings.ToLower(product.Name), strings.ToLower(filter.Name)) {
+			continue
+		}
+		if filter.Status != "" && product.Status != filter.Status {
+			continue
+		}
+		if categories != nil && (product.CategoryID == nil || !categories[*product.CategoryID]) {
+			continue
+		}
+		if !matchesProductFilter(product, filter) {
+			continue
+		}
+		product = copyProduct(product)
+		productList = append(productList, product)
+	}
+
+	if !page.Cursor {
+		if err := sortProducts(productList, filter.Sort); err != nil {
+			return model.Page[model.Product]{}, err
+		}
+	}
+	return pageOf(productList, page, func(product model.Product) int { return product.ID }), nil
+}
+
+func (pr *productRepository) CreateProduct(ctx context.Context, product model.Product) (int, error) {
+	s := pr.store
+	if err := s.lock(ctx); err != nil {
+		return 0, err
+	}
+	defer s.mu.Unlock()
+
+	if err := s.checkCategoryRef(product.CategoryID); err != nil {
+		return 0, err
+	}
+	if s.skuTaken(product.SKU, 0) {
+		return 0, repository.ErrDuplicateSKU
+	}
+
+	product.ID = s.nextID("product")
+	product = copyProduct(product)
+	product.CreatedAt = s.now()
+	product.UpdatedAt = product.CreatedAt
+	s.products[product.ID] = &productRow{product: product}
+	s.addPrice(product.ID, product.Price, product.CreatedAt)
+	return product.ID, nil
+}
+
+func (pr *productRepository) GetProductById(ctx context.Context, id_product int) (*model.Product, error) {
+	s := pr.store
+	if err := s.lock(ctx); err != nil {
+		return nil, err
+	}
+	defer s.mu.Unlock()
+
+	row, ok := s.products[id_product]
+	if !ok || row.product.DeletedAt != nil {
+		return nil, nil
+	}
+	product := row.product
+	product = copyProduct(product)
+	return &product, nil
+}
+
+// DeleteProduct moves the product to the trash.
+func (pr *productRepository) DeleteProduct(ctx context.Context, id_product int) (bool, error) {
+	s := pr.store
+	if err := s.lock(ctx); err != nil {
+		return false, err
+	}
+	defer s.mu.Unlock()
+
+	row, ok := s.products[id_product]
+	if !ok || row.product.DeletedAt != nil {
+		return false, nil
+	}
+	deletedAt := s.now()
+	row.product.DeletedAt = &deletedAt
+	return true, nil
+}
+
+func (pr *productRepository) GetDeletedProducts(ctx context.Context, page model.PageRequest) (model.Page[model.Product], error) {
+	s := pr.store
+	if err := s.lock(ctx); err != nil {
+		return model.Page[model.Product]{}, err
+	}
+	defer s.mu.Unlock()
+
+	var productList []model.Product
+	for _, id := range sortedKeys(s.products) {
+		if product := s.products[id].product; product.DeletedAt != nil {
+			productList = append(productList, copyProduct(product))
+		}
+	}
+	return pageOf(productList, page, func(product model.Product) int { return product.ID }), nil
+}
+
+func (pr *productRepository) GetDeletedProductById(ctx context.Context, id_product int) (*model.Product, error) {
+	s := pr.store
+	if err := s.lock(ctx); err != nil {
+		return nil, err
+	}
+	defer s.mu.Unlock()
+
+	row, ok := s.products[id_product]
+	if !ok || row.product.DeletedAt == nil {
+		return nil, nil
+	}
+	product := copyProduct(row.product)
+	return &product, nil
+}
+
+func (pr *productRepository) RestoreProduct(ctx context.Context, id_product int) (*model.Product, error) {
+	s := pr.store
+	if err := s.lock(ctx); err != nil {
+		return nil, err
+	}
+	defer s.mu.Unlock()
+
+	row, ok := s.products[id_product]
+	if !ok || row.product.DeletedAt == nil {
+		return nil, nil
+	}
+	row.product.DeletedAt = nil
+	product := copyProduct(row.product)
+	return &product, nil
+}
+
+func (pr *productRepository) PurgeProducts(ctx context.Context, deletedBefore time.Time) (int64, error) {
+	s := pr.store
+	if err := s.lock(ctx); err != nil {
+		return 0, err
+	}
+	defer s.mu.Unlock()
+
+	var purged int64
+	for id, row := range s.products {
+		if row.product.DeletedAt != nil && row.product.DeletedAt.Before(deletedBefore) {
+			s.deleteProduct(id)
+			purged++
+		}
+	}
+	return purged, nil
+}
+
+// deleteProduct follows the foreign keys of the product table: stock rows and
+// cart items are removed and order items keep their snapshot without the
+// product reference. Callers must hold s.mu.
+func (s *Store) deleteProduct(id_product int) {
+	delete(s.products, id_product)
+
+	movements := s.movements[:0]
+	for _, movement := range s.movements {
+		if movement.ProductID != id_product {
+			movements = append(movements, movement)
+		}
+	}
+	s.movements = movements
+
+	prices := s.prices[:0]
+	for _, price := range s.prices {
+		if price.ProductID != id_product {
+			prices = append(prices, price)
+		}
+	}
+	s.prices = prices
+
+	for id, reservation := range s.reservations {
+		if reservation.ProductID == id_product {
+			delete(s.reservations, id)
+		}
+	}
+
+	for _, cart := range s.carts {
+		delete(cart, id_product)
+	}
+
+	for id, order := range s.orders {
+		for i, item := range order.Items {
+			if item.ProductID != nil && *item.ProductID == id_product {
+				order.Items[i].ProductID = nil
+			}
+		}
+		s.orders[id] = order
+	}
+}
+
+func (pr *productRepository) UpdateProduct(ctx context.Context, product model.Product) (*model.Product, error) {
+	s := pr.store
+	if err := s.lock(ctx); err != nil {
+		return nil, err
+	}
+	defer s.mu.Unlock()
+
+	row, ok := s.products[product.ID]
+	if !ok || row.product.DeletedAt != nil {
+		return nil, nil
+	}
+	if err := s.checkCategoryRef(product.CategoryID); err != nil {
+		return nil, err
+	}
+	if s.skuTaken(product.SKU, product.ID) {
+		return nil, repository.ErrDuplicateSKU
+	}
+
+	product = copyProduct(product)
+	product.CreatedAt = row.product.CreatedAt
+	product.UpdatedAt = s.now()
+	if product.Price != row.product.Price {
+		s.addPrice(product.ID, product.Price, product.UpdatedAt)
+	}
+	row.product = product
+
+	updated := copyProduct(product)
+	return &updated, nil
+}
+
+// checkCategoryRef enforces the category foreign key. Callers must hold s.mu.
+func (s *Store) checkCategoryRef(id_category *int) error {
+	if id_category == nil {
+		return nil
+	}
+	if _, ok := s.categories[*id_category]; !ok {
+		return errors.New("memory: category does not exist")
+	}
+	return nil
+}
+
+// matchesProductFilter applies the ID, price and date filters.
+func matchesProductFilter(product model.Product, filter model.ProductFilter) bool {
+	if len(filter.IDs) > 0 && !slices.Contains(filter.IDs, product.ID) {
+		return false
+	}
+	if filter.MinPrice != nil && product.Price < *filter.MinPrice {
+		return false
+	}
+	if filter.MaxPrice != nil && product.Price > *filter.MaxPrice {
+		return false
+	}
+	return inRange(product.CreatedAt, filter.CreatedFrom, filter.CreatedTo) &&
+		inRange(product.UpdatedAt, filter.UpdatedFrom, filter.UpdatedTo)
+}
+
+// inRange reports whether t is in [from, to), where zero bounds are open.
+func inRange(t, from, to time.Time) bool {
+	return (from.IsZero() || !t.Before(from)) && (to.IsZero() || t.Before(to))
+}
+
+// sortProducts sorts products by keys and then by ID, like the ORDER BY of
+// the PostgreSQL repository.
+func sortProducts(products []model.Product, keys []model.SortKey) error {
+	for _, key := range keys {
+		switch key.Field {
+		case model.ProductSortName, model.ProductSortPrice, model.ProductSortCreatedAt:
+		default:
+			return fmt.Errorf("memory: unknown sort field %q", key.Field)
+		}
+	}
+
+	slices.SortStableFunc(products, func(a, b model.Product) int {
+		for _, key := range keys {
+			var order int
+			switch key.Field {
+			case model.ProductSortName:
+				order = strings.Compare(a.Name, b.Name)
+			case model.ProductSortPrice:
+				order = cmp.Compare(a.Price, b.Price)
+			case model.ProductSortCreatedAt:
+				order = a.CreatedAt.Compare(b.CreatedAt)
+			}
+			if key.Desc {
+				order = -order
+			}
+			if order != 0 {
+				return order
+			}
+		}
+		return cmp.Compare(a.ID, b.ID)
+	})
+	return nil
+}
+
+// similarityThreshold is the default pg_trgm.similarity_threshold used by
+// the % operator.
+const similarityThreshold = 0.3
+
+// SearchProducts approximates the PostgreSQL search: every term must prefix a
+// word of the name, brand or description, the rank is the share of those
+// words matched, and fuzzy matching uses the same trigram similarity as
+// pg_trgm.
+func (pr *productRepository) SearchProducts(ctx context.Context, page model.PageRequest, search model.ProductSearch) (model.Page[model.ProductSearchResult], error) {
+	s := pr.store
+	if err := s.lock(ctx); err != nil {
+		return model.Page[model.ProductSearchResult]{}, err
+	}
+	defer s.mu.Unlock()
+
+	terms := repository.SearchTerms(search.Query)
+	var results []model.ProductSearchResult
+	for _, id := range sortedKeys(s.products) {
+		product := s.products[id].product
+		if product.DeletedAt != nil {
+			continue
+		}
+		if search.Status != "" && product.Status != search.Status {
+			continue
+		}
+		product = copyProduct(product)
+
+		words := repository.SearchTerms(product.Name + " " + product.Brand + " " + product.Description)
+		matched := 0
+		for _, word := range words {
+			if slices.ContainsFunc(terms, func(term string) bool { return strings.HasPrefix(word, term) }) {
+				matched++
+			}
+		}
+		allTerms := len(terms) > 0 && !slices.ContainsFunc(terms, func(term string) bool {
+			return !slices.ContainsFunc(words, func(word string) bool { return strings.HasPrefix(word, term) })
+		})
+
+		result := model.ProductSearchResult{Product: product, Highlight: highlight(product.Name, terms)}
+		if allTerms {
+			result.Rank = float64(matched) / float64(len(words))
+		}
+		if search.Fuzzy {
+			if similarity := trigramSimilarity(product.Name, search.Query); similarity >= similarityThreshold {
+				allTerms = true
+				result.Rank = max(result.Rank, similarity)
+			}
+		}
+		if allTerms {
+			results = append(results, result)
+		}
+	}
+
+	slices.SortStableFunc(results, func(a, b model.ProductSearchResult) int {
+		return cmp.Compare(b.Rank, a.Rank)
+	})
+	page.Cursor = false
+	return pageOf(results, page, func(result model.ProductSearchResult) int { return result.ID }), nil
+}
+
+// highlight wraps the words of name starting with one of terms in <mark>
+// tags, like ts_headline.
+func highlight(name string, terms []string) string {
+	var b strings.Builder
+	for _, field := range strings.FieldsFunc(name, unicode.IsSpace) {
+		if b.Len() > 0 {
+			b.WriteByte(' ')
+		}
+		words := repository.SearchTerms(field)
+		if len(words) > 0 && slices.ContainsFunc(terms, func(term string) bool { return strings.HasPrefix(words[0], term) }) {
+			b.WriteString("<mark>" + field + "</mark>")
+		} else {
+			b.WriteString(field)
+		}
+	}
+	return b.String()
+}
+
+// trigramSimilarity follows pg_trgm: each word is padded with two spaces
+// before and one after, and the similarity is the number of shared trigrams
+// over the number of distinct trigrams of both strings.
+func trigramSimilarity(a, b string) float64 {
+	trigramsA, trigramsB := trigrams(a), trigrams(b)
+	if len(trigramsA) == 0 || len(trigramsB) == 0 {
+		return 0
+	}
+	shared := 0
+	for trigram := range trigramsA {
+		if trigramsB[trigram] {
+			shared++
+		}
+	}
+	return float64(shared) / float64(len(trigramsA)+len(trigramsB)-shared)
+}
+
+func trigrams(value string) map[string]bool {
+	set := map[string]bool{}
+	for _, word := range repository.SearchTerms(value) {
+		padded := []rune("  " + word + " ")
+		for i := 0; i+3 <= len(padded); i++ {
+			set[string(padded[i:i+3])] = true
+		}
+	}
+	return set
+}
+
+// skuTaken reports whether a product other than id_product has sku, like the
+// unique index on product.sku.
+func (s *Store) skuTaken(sku string, id_product int) bool {
+	for id, row := range s.products {
+		if id != id_product && row.product.SKU == sku {
+			return true
+		}
+	}
+	return false
+}
+
+// copyProduct copies the references of product. Unset attributes and price
+// lists become empty, as read from the database.
+func copyProduct(product model.Product) model.Product {
+	product.CategoryID = copyIntPtr(product.CategoryID)
+	if product.DeletedAt != nil {
+		deletedAt := *product.DeletedAt
+		product.DeletedAt = &deletedAt
+	}
+	product.Attributes = maps.Clone(product.Attributes)
+	if product.Attributes == nil {
+		product.Attributes = map[string]any{}
+	}
+	product.PriceList = maps.Clone(product.PriceList)
+	if product.PriceList == nil {
+		product.PriceList = map[string]model.Money{}
+	}
+	return product
+}
+
+// ImportProducts creates or updates each product by its SKU. A dry run checks
+// the products the same way without storing them.
+func (pr *productRepository) ImportProducts(ctx context.Context, products []model.Product, dryRun bool) ([]repository.ImportResult, error) {
+	s := pr.store
+	if err := s.lock(ctx); err != nil {
+		return nil, err
+	}
+	defer s.mu.Unlock()
+
+	results := make([]repository.ImportResult, len(products))
+	for i, product := range products {
+		results[i] = s.importProduct(product, dryRun)
+	}
+	return results, nil
+}
+
+// importProduct writes one product of ImportProducts. Callers must hold s.mu.
+func (s *Store) importProduct(product model.Product, dryRun bool) repository.ImportResult {
+	if err := s.checkCategoryRef(product.CategoryID); err != nil {
+		return repository.ImportResult{Err: err}
+	}
+
+	var row *productRow
+	for _, candidate := range s.products {
+		if candidate.product.SKU == product.SKU {
+			row = candidate
+		}
+	}
+	now := s.now()
+	if row == nil {
+		if product.Status == "" {
+			product.Status = model.ProductStatusActive
+		}
+		product = copyProduct(product)
+		product.CreatedAt = now
+		product.UpdatedAt = now
+		if !dryRun {
+			product.ID = s.nextID("product")
+			s.products[product.ID] = &productRow{product: copyProduct(product)}
+			s.addPrice(product.ID, product.Price, now)
+		}
+		return repository.ImportResult{After: product}
+	}
+	if row.product.DeletedAt != nil {
+		return repository.ImportResult{Err: repository.ErrSKUInTrash}
+	}
+
+	before := copyProduct(row.product)
+	product.ID = before.ID
+	if product.Status == "" {
+		product.Status = before.Status
+	}
+	if product.Attributes == nil {
+		product.Attributes = before.Attributes
+	}
+	if product.PriceList == nil {
+		product.PriceList = before.PriceList
+	}
+	product = copyProduct(product)
+	product.CreatedAt = before.CreatedAt
+	product.UpdatedAt = now
+	if !dryRun {
+		if product.Price != before.Price {
+			s.addPrice(product.ID, product.Price, now)
+		}
+		row.product = copyProduct(product)
+	}
+	return repository.ImportResult{Before: &before, After: product}
+}
@@ -0,0 +1,209 @@
+package memory
+
+import (
+	"context"
+	"errors"
+	"product-go-api/model"
+	"product-go-api/repository"
+	"sort"
+)
+
+type roleRepository struct {
+	store *Store
+}
+
+func (s *Store) RoleRepository() repository.RoleRepository {
+	return &roleRepository{store: s}
+}
+
+// seedRoles inserts the roles, permissions and grants of the roles and
+// permissions migration. It is called by NewStore.
+func (s *Store) seedRoles() {
+	permissions := []struct{ name, description string }{
+		{model.PermissionProductWrite, "Create and update products"},
+		{model.PermissionProductImport, "Create and update products in bulk from files"},
+		{model.PermissionProductDelete, "Delete products"},
+		{model.PermissionProductReadAll, "List and read draft and archived products"},
+		{model.PermissionCategoryWrite, "Create, update and delete categories"},
+		{model.PermissionStockWrite, "Adjust product stock"},
+		{model.PermissionStockRead, "Read stock movements and reconciliations"},
+		{model.PermissionReservationReleaseAny, "Release reservations of other users"},
+		{model.PermissionOrderReadAll, "List and read orders of every user"},
+		{model.PermissionOrderUpdateStatus, "Change the status of orders"},
+		{model.PermissionUserRead, "List users"},
+		{model.PermissionUserDelete, "Delete users with a lower role"},
+		{model.PermissionUserAssignRole, "Change roles of users with a lower role"},
+		{model.PermissionUserManageAll, "Delete and change roles of users regardless of their role"},
+		{model.PermissionRoleManage, "Manage roles and their permissions"},
+		{model.PermissionAuditRead, "Read the audit log"},
+	}
+
+	var all, admin []string
+	for _, permission := range permissions {
+		s.permissions = append(s.permissions, model.Permission{
+			ID:          s.nextID("permission"),
+			Name:        permission.name,
+			Description: permission.description,
+		})
+		all = append(all, permission.name)
+		switch permission.name {
+		case model.PermissionUserManageAll, model.PermissionRoleManage, model.PermissionAuditRead:
+		default:
+			admin = append(admin, permission.name)
+		}
+	}
+
+	s.insertRole(model.Role{Name: "user", Level: 0, Permissions: []string{model.PermissionProductWrite}})
+	s.insertRole(model.Role{Name: "admin", Level: 50, Permissions: admin})
+	s.insertRole(model.Role{Name: "super_admin", Level: 100, Permissions: all})
+}
+
+func (rr *roleRepository) GetRoles(ctx context.Context) ([]model.Role, error) {
+	s := rr.store
+	if err := s.lock(ctx); err != nil {
+		return nil, err
+	}
+	defer s.mu.Unlock()
+
+	roleList := []model.Role{}
+	for _, role := range s.roles {
+		roleList = append(roleList, copyRole(role))
+	}
+	sort.Slice(roleList, func(i, j int) bool {
+		if roleList[i].Level != roleList[j].Level {
+			return roleList[i].Level > roleList[j].Level
+		}
+		return roleList[i].Name < roleList[j].Name
+	})
+	return roleList, nil
+}
+
+func (rr *roleRepository) GetRoleByName(ctx context.Context, name string) (*model.Role, error) {
+	s := rr.store
+	if err := s.lock(ctx); err != nil {
+		return nil, err
+	}
+	defer s.mu.Unlock()
+
+	role, ok := s.roles[name]
+	if !ok {
+		return nil, nil
+	}
+	copied := copyRole(role)
+	return &copied, nil
+}
+
+func (rr *roleRepository) CreateRole(ctx context.Context, role model.Role) (int, error) {
+	s := rr.store
+	if err := s.lock(ctx); err != nil {
+		return 0, err
+	}
+	defer s.mu.Unlock()
+
+	if _, ok := s.roles[role.Name]; ok {
+		return 0, errors.New("memory: role already exists")
+	}
+	return s.insertRole(role), nil
+}
+
+func (rr *roleRepository) SetRolePermissions(ctx context.Context, name string, permissions []string) error {
+	s := rr.store
+	if err := s.lock(ctx); err != nil {
+		return err
+	}
+	defer s.mu.Unlock()
+
+	role, ok := s.roles[name]
+	if !ok {
+		return errors.New("memory: role not found")
+	}
+	role.Permissions = s.knownPermissions(permissions)
+	s.roles[name] = role
+	return nil
+}
+
+// DeleteRole refuses to delete roles held by users, like the foreign key on
+// users.role.
+func (rr *roleRepository) DeleteRole(ctx context.Context, name string) error {
+	s := rr.store
+	if err := s.lock(ctx); err != nil {
+		return err
+	}
+	defer s.mu.Unlock()
+
+	for _, user := range s.users {
+		if user.Role == name {
+			return errors.New("memory: role is assigned to users")
+		}
+	}
+	delete(s.roles, name)
+	return nil
+}
+
+func (rr *roleRepository) RoleInUse(ctx context.Context, name string) (bool, error) {
+	s := rr.store
+	if err := s.lock(ctx); err != nil {
+		return false, err
+	}
+	defer s.mu.Unlock()
+
+	for _, user := range s.users {
+		if user.Role == name {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (rr *roleRepository) GetPermissions(ctx context.Context) ([]model.Permission, error) {
+	s := rr.store
+	if err := s.lock(ctx); err != nil {
+		return nil, err
+	}
+	defer s.mu.Unlock()
+
+	permissionList := append([]model.Permission{}, s.permissions...)
+	sort.Slice(permissionList, func(i, j int) bool { return permissionList[i].Name < permissionList[j].Name })
+	return permissionList, nil
+}
+
+func (rr *roleRepository) GetRolePermissions(ctx context.Context, name string) ([]string, error) {
+	s := rr.store
+	if err := s.lock(ctx); err != nil {
+		return nil, err
+	}
+	defer s.mu.Unlock()
+
+	return append([]string(nil), s.roles[name].Permissions...), nil
+}
+
+// insertRole stores a role with the permissions that exist, ignoring unknown
+// names like the INSERT ... SELECT of the PostgreSQL repository. Callers must
+// hold s.mu.
+func (s *Store) insertRole(role model.Role) int {
+	role.ID = s.nextID("role")
+	role.Permissions = s.knownPermissions(role.Permissions)
+	s.roles[role.Name] = role
+	return role.ID
+}
+
+// knownPermissions returns the sorted, deduplicated permissions that exist.
+// Callers must hold s.mu.
+func (s *Store) knownPermissions(names []string) []string {
+	known := []string{}
+	for _, permission := range s.permissions {
+		for _, name := range names {
+			if name == permission.Name {
+				known = append(known, name)
+				break
+			}
+		}
+	}
+	sort.Strings(known)
+	return known
+}
+
+func copyRole(role model.Role) model.Role {
+	role.Permissions = append([]string{}, role.Permissions...)
+	return role
+}
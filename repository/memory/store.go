@@ -0,0 +1,153 @@
+// Package memory implements the repository interfaces on top of in-memory
+// maps. It mirrors the behaviour of the PostgreSQL repositories, including
+// cascading deletes and the stock rules, so usecases and handlers can be
+// tested without a database. All repositories created from the same Store
+// share its data and its lock, which makes multi-entity operations such as
+// checkout atomic.
+package memory
+
+import (
+	"context"
+	"product-go-api/model"
+	"sort"
+	"sync"
+	"time"
+)
+
+type Store struct {
+	mu sync.Mutex
+
+	products      map[int]*productRow
+	users         map[int]model.User
+	categories    map[int]model.Category
+	movements     []model.StockMovement
+	reservations  map[int]model.Reservation
+	carts         map[int]map[int]*cartRow
+	orders        map[int]model.Order
+	refreshTokens map[string]*refreshRow
+	revokedTokens map[string]time.Time
+	roles         map[string]model.Role
+	permissions   []model.Permission
+	auditLog      []model.AuditEntry
+	prices        []model.ProductPrice
+	exchangeRates []model.ExchangeRate
+
+	lastID map[string]int
+	now    func() time.Time
+}
+
+type productRow struct {
+	product model.Product
+	stock   int
+}
+
+type cartRow struct {
+	quantity int
+	added    int
+}
+
+type refreshRow struct {
+	token   model.RefreshToken
+	revoked bool
+}
+
+func NewStore() *Store {
+	s := &Store{
+		products:      make(map[int]*productRow),
+		users:         make(map[int]model.User),
+		categories:    make(map[int]model.Category),
+		reservations:  make(map[int]model.Reservation),
+		carts:         make(map[int]map[int]*cartRow),
+		orders:        make(map[int]model.Order),
+		refreshTokens: make(map[string]*refreshRow),
+		revokedTokens: make(map[string]time.Time),
+		roles:         make(map[string]model.Role),
+		lastID:        make(map[string]int),
+		now:           time.Now,
+	}
+	s.seedRoles()
+	return s
+}
+
+// lock takes the store lock unless ctx is already done, so a request that ran
+// out of time fails the same way as a cancelled query.
+func (s *Store) lock(ctx context.Context) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	s.mu.Lock()
+	return nil
+}
+
+// nextID works like a SERIAL column. Callers must hold s.mu.
+func (s *Store) nextID(table string) int {
+	s.lastID[table]++
+	return s.lastID[table]
+}
+
+func sortedKeys[V any](m map[int]V) []int {
+	keys := make([]int, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Ints(keys)
+	return keys
+}
+
+func paginate[T any](items []T, page, limit int) []T {
+	if page < 1 {
+		page = 1
+	}
+	if limit < 1 {
+		limit = 10
+	}
+	offset := (page - 1) * limit
+	if offset >= len(items) {
+		return nil
+	}
+	end := offset + limit
+	if end > len(items) {
+		end = len(items)
+	}
+	return items[offset:end]
+}
+
+// pageOf returns one page of items, which are sorted by the ID returned by id,
+// the same way the PostgreSQL repositories page their queries.
+func pageOf[T any](items []T, page model.PageRequest, id func(T) int) model.Page[T] {
+	if page.Limit < 1 {
+		page.Limit = 10
+	}
+	if page.Cursor {
+		result := model.Page[T]{Items: []T{}, Total: len(items), Limit: page.Limit}
+		for _, item := range items {
+			if id(item) <= page.After {
+				continue
+			}
+			if len(result.Items) == page.Limit {
+				result.HasMore = true
+				break
+			}
+			result.Items = append(result.Items, item)
+		}
+		return result
+	}
+
+	if page.Page < 1 {
+		page.Page = 1
+	}
+	result := model.Page[T]{Items: paginate(items, page.Page, page.Limit), Total: len(items), Page: page.Page, Limit: page.Limit}
+	if result.Items == nil {
+		result.Items = []T{}
+	}
+	result.HasMore = page.Page*page.Limit < len(items)
+	return result
+}
+
+func copyIntPtr(value *int) *int {
+	if value == nil {
+		return nil
+	}
+	v := *value
+	return &v
+}
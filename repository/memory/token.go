@@ -0,0 +1,129 @@
+package memory
+
+import (
+	"context"
+	"product-go-api/model"
+	"product-go-api/repository"
+	"time"
+)
+
+type tokenRepository struct {
+	store *Store
+}
+
+func (s *Store) TokenRepository() repository.TokenRepository {
+	return &tokenRepository{store: s}
+}
+
+func (tr *tokenRepository) CreateRefreshToken(ctx context.Context, token model.RefreshToken) error {
+	s := tr.store
+	if err := s.lock(ctx); err != nil {
+		return err
+	}
+	defer s.mu.Unlock()
+
+	token.ID = s.nextID("refresh_token")
+	s.refreshTokens[token.TokenHash] = &refreshRow{token: token}
+	return nil
+}
+
+func (tr *tokenRepository) UseRefreshToken(ctx context.Context, tokenHash string) (*model.RefreshToken, error) {
+	s := tr.store
+	if err := s.lock(ctx); err != nil {
+		return nil, err
+	}
+	defer s.mu.Unlock()
+
+	row, ok := s.refreshTokens[tokenHash]
+	if !ok || row.revoked || !row.token.ExpiresAt.After(s.now()) {
+		return nil, nil
+	}
+	row.revoked = true
+	token := row.token
+	return &token, nil
+}
+
+func (tr *tokenRepository) GetRefreshTokenByHash(ctx context.Context, tokenHash string) (*model.RefreshToken, error) {
+	s := tr.store
+	if err := s.lock(ctx); err != nil {
+		return nil, err
+	}
+	defer s.mu.Unlock()
+
+	row, ok := s.refreshTokens[tokenHash]
+	if !ok {
+		return nil, nil
+	}
+	token := row.token
+	return &token, nil
+}
+
+func (tr *tokenRepository) RevokeFamily(ctx context.Context, familyID string) error {
+	s := tr.store
+	if err := s.lock(ctx); err != nil {
+		return err
+	}
+	defer s.mu.Unlock()
+
+	now := s.now()
+	for _, row := range s.refreshTokens {
+		if row.token.FamilyID != familyID {
+			continue
+		}
+		row.revoked = true
+		if row.token.AccessExpiresAt.After(now) {
+			if _, ok := s.revokedTokens[row.token.AccessJTI]; !ok {
+				s.revokedTokens[row.token.AccessJTI] = row.token.AccessExpiresAt
+			}
+		}
+	}
+	return nil
+}
+
+func (tr *tokenRepository) RevokeAccessToken(ctx context.Context, jti string, expiresAt time.Time) error {
+	s := tr.store
+	if err := s.lock(ctx); err != nil {
+		return err
+	}
+	defer s.mu.Unlock()
+
+	if _, ok := s.revokedTokens[jti]; !ok {
+		s.revokedTokens[jti] = expiresAt
+	}
+	return nil
+}
+
+func (tr *tokenRepository) IsAccessTokenRevoked(ctx context.Context, jti string) (bool, error) {
+	s := tr.store
+	if err := s.lock(ctx); err != nil {
+		return false, err
+	}
+	defer s.mu.Unlock()
+
+	_, revoked := s.revokedTokens[jti]
+	return revoked, nil
+}
+
+func (tr *tokenRepository) DeleteExpired(ctx context.Context) (int64, error) {
+	s := tr.store
+	if err := s.lock(ctx); err != nil {
+		return 0, err
+	}
+	defer s.mu.Unlock()
+
+	now := s.now()
+	var deleted int64
+	for jti, expiresAt := range s.revokedTokens {
+		if !expiresAt.After(now) {
+			delete(s.revokedTokens, jti)
+			deleted++
+		}
+	}
+	for hash, row := range s.refreshTokens {
+		if !row.token.ExpiresAt.After(now) && !row.token.AccessExpiresAt.After(now) {
+			delete(s.refreshTokens, hash)
+			deleted++
+		}
+	}
+	return deleted, nil
+}
@@ -0,0 +1,18 @@
+package memory
+
+import (
+	"context"
+	"product-go-api/repository"
+)
+
+type transactor struct{}
+
+// Transactor runs functions directly. Each repository call takes the store
+// lock on its own, so a failing function does not undo the writes it made.
+func (s *Store) Transactor() repository.Transactor {
+	return transactor{}
+}
+
+func (transactor) WithinTx(ctx context.Context, fn func(ctx context.Context) error) error {
+	return fn(ctx)
+}
@@ -0,0 +1,276 @@
+package memory
+
+import (
+	"context"
+	"errors"
+	"product-go-api/model"
+	"product-go-api/repository"
+	"strings"
+	"time"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// passwordCost is lower than the cost used by the PostgreSQL repository to
+// keep tests fast; hashes are still checked with bcrypt.
+const passwordCost = bcrypt.MinCost
+
+type userRepository struct {
+	store *Store
+}
+
+func (s *Store) UserRepository() repository.UserRepository {
+	return &userRepository{store: s}
+}
+
+func (ur *userRepository) CreateUser(ctx context.Context, user model.User) (int, error) {
+	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(user.Password), passwordCost)
+	if err != nil {
+		return 0, err
+	}
+
+	s := ur.store
+	if err := s.lock(ctx); err != nil {
+		return 0, err
+	}
+	defer s.mu.Unlock()
+
+	for _, existing := range s.users {
+		if existing.Email == user.Email {
+			return 0, repository.ErrDuplicateEmail
+		}
+	}
+
+	// Like the INSERT in the PostgreSQL repository, the role is never taken
+	// from the request and falls back to the column default.
+	user.ID = s.nextID("users")
+	user.Password = string(hashedPassword)
+	user.Role = "user"
+	s.users[user.ID] = user
+	return user.ID, nil
+}
+
+func (ur *userRepository) GetUserByEmail(ctx context.Context, email string) (*model.User, error) {
+	s := ur.store
+	if err := s.lock(ctx); err != nil {
+		return nil, err
+	}
+	defer s.mu.Unlock()
+
+	for _, user := range s.users {
+		if user.Email == email && user.DeletedAt == nil {
+			return &user, nil
+		}
+	}
+	return nil, nil
+}
+
+func (ur *userRepository) GetUserById(ctx context.Context, id_user int) (*model.User, error) {
+	s := ur.store
+	if err := s.lock(ctx); err != nil {
+		return nil, err
+	}
+	defer s.mu.Unlock()
+
+	user, ok := s.users[id_user]
+	if !ok || user.DeletedAt != nil {
+		return nil, nil
+	}
+	return &user, nil
+}
+
+// DeleteUser moves the user to the trash, releases their active reservations
+// and revokes their access tokens before dropping their refresh tokens.
+func (ur *userRepository) DeleteUser(ctx context.Context, id_user int) error {
+	s := ur.store
+	if err := s.lock(ctx); err != nil {
+		return err
+	}
+	defer s.mu.Unlock()
+
+	user, ok := s.users[id_user]
+	if !ok || user.DeletedAt != nil {
+		return nil
+	}
+	deletedAt := s.now()
+	user.DeletedAt = &deletedAt
+	s.users[id_user] = user
+	if err := s.releaseUserReservations(id_user); err != nil {
+		return err
+	}
+	for hash, row := range s.refreshTokens {
+		if row.token.UserID != id_user {
+			continue
+		}
+		if row.token.AccessExpiresAt.After(deletedAt) {
+			if _, ok := s.revokedTokens[row.token.AccessJTI]; !ok {
+				s.revokedTokens[row.token.AccessJTI] = row.token.AccessExpiresAt
+			}
+		}
+		delete(s.refreshTokens, hash)
+	}
+	return nil
+}
+
+func (ur *userRepository) GetDeletedUsers(ctx context.Context, page model.PageRequest) (model.Page[model.User], error) {
+	s := ur.store
+	if err := s.lock(ctx); err != nil {
+		return model.Page[model.User]{}, err
+	}
+	defer s.mu.Unlock()
+
+	var userList []model.User
+	for _, id := range sortedKeys(s.users) {
+		if user := s.users[id]; user.DeletedAt != nil {
+			user.Password = ""
+			userList = append(userList, user)
+		}
+	}
+	return pageOf(userList, page, func(user model.User) int { return user.ID }), nil
+}
+
+func (ur *userRepository) GetDeletedUserById(ctx context.Context, id_user int) (*model.User, error) {
+	s := ur.store
+	if err := s.lock(ctx); err != nil {
+		return nil, err
+	}
+	defer s.mu.Unlock()
+
+	user, ok := s.users[id_user]
+	if !ok || user.DeletedAt == nil {
+		return nil, nil
+	}
+	user.Password = ""
+	return &user, nil
+}
+
+func (ur *userRepository) RestoreUser(ctx context.Context, id_user int) (*model.User, error) {
+	s := ur.store
+	if err := s.lock(ctx); err != nil {
+		return nil, err
+	}
+	defer s.mu.Unlock()
+
+	user, ok := s.users[id_user]
+	if !ok || user.DeletedAt == nil {
+		return nil, nil
+	}
+	user.DeletedAt = nil
+	s.users[id_user] = user
+	user.Password = ""
+	return &user, nil
+}
+
+// PurgeUsers keeps the users with orders in the trash, like the orders
+// foreign key.
+func (ur *userRepository) PurgeUsers(ctx context.Context, deletedBefore time.Time) (int64, error) {
+	s := ur.store
+	if err := s.lock(ctx); err != nil {
+		return 0, err
+	}
+	defer s.mu.Unlock()
+
+	hasOrders := map[int]bool{}
+	for _, order := range s.orders {
+		hasOrders[order.UserID] = true
+	}
+
+	var purged int64
+	for id, user := range s.users {
+		if user.DeletedAt != nil && user.DeletedAt.Before(deletedBefore) && !hasOrders[id] {
+			if err := s.releaseUserReservations(id); err != nil {
+				return purged, err
+			}
+			s.deleteUser(id)
+			purged++
+		}
+	}
+	return purged, nil
+}
+
+// releaseUserReservations returns the stock held by the active reservations of
+// a user, like the PostgreSQL repository does before a user is deleted.
+// Callers must hold s.mu.
+func (s *Store) releaseUserReservations(id_user int) error {
+	_, err := s.returnReservedStock(func(reservation model.Reservation) bool {
+		return reservation.UserID == id_user
+	}, model.ReservationReleased, "user deleted", nil)
+	return err
+}
+
+// deleteUser removes the rows that reference the user with ON DELETE CASCADE.
+// Callers must hold s.mu.
+func (s *Store) deleteUser(id_user int) {
+	delete(s.users, id_user)
+	delete(s.carts, id_user)
+	for id, reservation := range s.reservations {
+		if reservation.UserID == id_user {
+			delete(s.reservations, id)
+		}
+	}
+	for hash, row := range s.refreshTokens {
+		if row.token.UserID == id_user {
+			delete(s.refreshTokens, hash)
+		}
+	}
+}
+
+func (ur *userRepository) UpdateUser(ctx context.Context, user model.User) (*model.User, error) {
+	s := ur.store
+	if err := s.lock(ctx); err != nil {
+		return nil, err
+	}
+	current, ok := s.users[user.ID]
+	s.mu.Unlock()
+	if !ok || current.DeletedAt != nil {
+		return nil, errors.New("memory: user not found")
+	}
+
+	passwordToSave := current.Password
+	if user.Password != "" && user.Password != current.Password {
+		hashedPassword, err := bcrypt.GenerateFromPassword([]byte(user.Password), passwordCost)
+		if err != nil {
+			return nil, err
+		}
+		passwordToSave = string(hashedPassword)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, existing := range s.users {
+		if existing.ID != user.ID && existing.Email == user.Email {
+			return nil, repository.ErrDuplicateEmail
+		}
+	}
+	if _, ok := s.roles[user.Role]; !ok {
+		return nil, errors.New("memory: role does not exist")
+	}
+
+	user.Password = passwordToSave
+	s.users[user.ID] = user
+	return &user, nil
+}
+
+func (ur *userRepository) GetUsers(ctx context.Context, page model.PageRequest, name string) (model.Page[model.User], error) {
+	s := ur.store
+	if err := s.lock(ctx); err != nil {
+		return model.Page[model.User]{}, err
+	}
+	defer s.mu.Unlock()
+
+	var userList []model.User
+	for _, id := range sortedKeys(s.users) {
+		user := s.users[id]
+		if user.DeletedAt != nil {
+			continue
+		}
+		if name != "" && !strings.Contains(strings.ToLower(user.Username), strings.ToLower(name)) {
+			continue
+		}
+		user.Password = ""
+		userList = append(userList, user)
+	}
+
+	return pageOf(userList, page, func(user model.User) int { return user.ID }), nil
+}
@@ -0,0 +1,296 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"product-go-api/model"
+	"strings"
+	"time"
+)
+
+var ErrCartEmpty = errors.New("cart is empty")
+
+type OrderRepository interface {
+	Checkout(ctx context.Context, id_user int, currency string) (*model.Order, error)
+	GetOrders(ctx context.Context, page, limit int, id_user int, status string) ([]model.Order, error)
+	GetOrderById(ctx context.Context, id_order int) (*model.Order, error)
+	UpdateStatus(ctx context.Context, id_order int, from, to string, id_user int) (*model.Order, error)
+}
+
+type orderRepository struct {
+	connection   *sql.DB
+	queryTimeout time.Duration
+}
+
+func NewOrderRepository(connection *sql.DB, queryTimeout time.Duration) OrderRepository {
+	return &orderRepository{
+		connection:   connection,
+		queryTimeout: queryTimeout,
+	}
+}
+
+// Checkout turns the cart of id_user into a pending order. The order, its
+// items, the stock movements and the emptying of the cart happen in one
+// transaction, so a failure on any item leaves nothing behind. Items of
+// products that are trashed or no longer active are left out of the order.
+// The unexpired active reservations of id_user are consumed by the items of
+// their product, whose sale takes the reserved units before free stock.
+// Prices are in currency, the store currency.
+func (or *orderRepository) Checkout(ctx context.Context, id_user int, currency string) (*model.Order, error) {
+	ctx, cancel := context.WithTimeout(ctx, or.queryTimeout)
+	defer cancel()
+
+	tx, err := beginTx(ctx, or.connection)
+	if err != nil {
+		return nil, queryError(ctx, err)
+	}
+	defer tx.Rollback()
+
+	rows, err := tx.QueryContext(ctx,
+		`SELECT c.product_id, p.product_name, p.price, c.quantity
+		FROM cart_item c JOIN product p ON p.id = c.product_id
+		WHERE c.user_id = $1 AND p.deleted_at IS NULL AND p.status = 'active' ORDER BY c.product_id FOR UPDATE OF c;`, id_user,
+	)
+	if err != nil {
+		return nil, queryError(ctx, err)
+	}
+
+	order := model.Order{UserID: id_user, Status: model.OrderPending, Currency: currency}
+	for rows.Next() {
+		var item model.OrderItem
+		var id_product int
+		if err := rows.Scan(&id_product, &item.ProductName, &item.UnitPrice, &item.Quantity); err != nil {
+			rows.Close()
+			return nil, queryError(ctx, err)
+		}
+		item.ProductID = &id_product
+		item.Subtotal = item.UnitPrice.Mul(item.Quantity)
+		order.Total += item.Subtotal
+		order.Items = append(order.Items, item)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, queryError(ctx, err)
+	}
+
+	if len(order.Items) == 0 {
+		return nil, ErrCartEmpty
+	}
+
+	err = tx.QueryRowContext(ctx,
+		"INSERT INTO orders (user_id, status, total, currency) VALUES ($1, $2, $3, $4) RETURNING id, created_at, updated_at;",
+		order.UserID, order.Status, order.Total, order.Currency,
+	).Scan(&order.ID, &order.CreatedAt, &order.UpdatedAt)
+	if err != nil {
+		return nil, queryError(ctx, err)
+	}
+
+	for i := range order.Items {
+		item := &order.Items[i]
+
+		consumed, err := updateReservations(ctx, tx,
+			`UPDATE stock_reservation SET status = $3 WHERE user_id = $1 AND product_id = $2 AND status = $4 AND expires_at > NOW()
+			RETURNING id, product_id, quantity;`,
+			id_user, *item.ProductID, model.ReservationConsumed, model.ReservationActive,
+		)
+		if err != nil {
+			return nil, queryError(ctx, err)
+		}
+		if err := returnReservedStock(ctx, tx, consumed, fmt.Sprintf("order #%d", order.ID), &id_user); err != nil {
+			return nil, queryError(ctx, err)
+		}
+
+		_, err = applyStockChange(ctx, tx, model.StockMovement{
+			ProductID: *item.ProductID,
+			Type:      model.StockSale,
+			Quantity:  -item.Quantity,
+			Reason:    fmt.Sprintf("order #%d", order.ID),
+			UserID:    &id_user,
+		})
+		if err != nil {
+			return nil, queryError(ctx, err)
+		}
+
+		err = tx.QueryRowContext(ctx,
+			`INSERT INTO order_item (order_id, product_id, product_name, unit_price, quantity)
+			VALUES ($1, $2, $3, $4, $5) RETURNING id;`,
+			order.ID, item.ProductID, item.ProductName, item.UnitPrice, item.Quantity,
+		).Scan(&item.ID)
+		if err != nil {
+			return nil, queryError(ctx, err)
+		}
+	}
+
+	if _, err := tx.ExecContext(ctx, "DELETE FROM cart_item WHERE user_id = $1;", id_user); err != nil {
+		return nil, queryError(ctx, err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, queryError(ctx, err)
+	}
+	return &order, nil
+}
+
+// GetOrders lists orders without their items. A zero id_user lists the orders
+// of every user.
+func (or *orderRepository) GetOrders(ctx context.Context, page, limit int, id_user int, status string) ([]model.Order, error) {
+	ctx, cancel := context.WithTimeout(ctx, or.queryTimeout)
+	defer cancel()
+
+	if page < 1 {
+		page = 1
+	}
+
+	if limit < 1 {
+		limit = 10
+	}
+
+	offset := (page - 1) * limit
+
+	query := "SELECT id, user_id, status, total, currency, created_at, updated_at FROM orders"
+	var conditions []string
+	var args []interface{}
+	argIdx := 1
+
+	if id_user > 0 {
+		conditions = append(conditions, fmt.Sprintf("user_id = $%d", argIdx))
+		args = append(args, id_user)
+		argIdx++
+	}
+
+	if status != "" {
+		conditions = append(conditions, fmt.Sprintf("status = $%d", argIdx))
+		args = append(args, status)
+		argIdx++
+	}
+
+	if len(conditions) > 0 {
+		query += " WHERE " + strings.Join(conditions, " AND ")
+	}
+
+	query += fmt.Sprintf(" ORDER BY id DESC LIMIT $%d OFFSET $%d", argIdx, argIdx+1)
+	args = append(args, limit, offset)
+
+	rows, err := conn(ctx, or.connection).QueryContext(ctx, query, args...)
+	if err != nil {
+		return []model.Order{}, queryError(ctx, err)
+	}
+	defer rows.Close()
+
+	orderList := []model.Order{}
+	for rows.Next() {
+		var order model.Order
+		if err := rows.Scan(&order.ID, &order.UserID, &order.Status, &order.Total, &order.Currency, &order.CreatedAt, &order.UpdatedAt); err != nil {
+			return []model.Order{}, queryError(ctx, err)
+		}
+		orderList = append(orderList, order)
+	}
+
+	return orderList, queryError(ctx, rows.Err())
+}
+
+func (or *orderRepository) GetOrderById(ctx context.Context, id_order int) (*model.Order, error) {
+	ctx, cancel := context.WithTimeout(ctx, or.queryTimeout)
+	defer cancel()
+
+	var order model.Order
+	err := conn(ctx, or.connection).QueryRowContext(ctx,
+		"SELECT id, user_id, status, total, currency, created_at, updated_at FROM orders WHERE id = $1;", id_order,
+	).Scan(&order.ID, &order.UserID, &order.Status, &order.Total, &order.Currency, &order.CreatedAt, &order.UpdatedAt)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, queryError(ctx, err)
+	}
+
+	order.Items, err = getOrderItems(ctx, or.connection, id_order)
+	if err != nil {
+		return nil, queryError(ctx, err)
+	}
+	return &order, nil
+}
+
+// UpdateStatus moves an order from status from to status to. It returns nil,
+// nil when the order is no longer in status from. Cancelled orders give their
+// items back to stock.
+func (or *orderRepository) UpdateStatus(ctx context.Context, id_order int, from, to string, id_user int) (*model.Order, error) {
+	ctx, cancel := context.WithTimeout(ctx, or.queryTimeout)
+	defer cancel()
+
+	tx, err := beginTx(ctx, or.connection)
+	if err != nil {
+		return nil, queryError(ctx, err)
+	}
+	defer tx.Rollback()
+
+	var order model.Order
+	err = tx.QueryRowContext(ctx,
+		`UPDATE orders SET status = $3, updated_at = NOW() WHERE id = $1 AND status = $2
+		RETURNING id, user_id, status, total, currency, created_at, updated_at;`, id_order, from, to,
+	).Scan(&order.ID, &order.UserID, &order.Status, &order.Total, &order.Currency, &order.CreatedAt, &order.UpdatedAt)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, queryError(ctx, err)
+	}
+
+	order.Items, err = getOrderItems(ctx, tx, id_order)
+	if err != nil {
+		return nil, queryError(ctx, err)
+	}
+
+	if to == model.OrderCancelled {
+		for _, item := range order.Items {
+			if item.ProductID == nil {
+				continue
+			}
+			_, err = applyStockChange(ctx, tx, model.StockMovement{
+				ProductID: *item.ProductID,
+				Type:      model.StockReturn,
+				Quantity:  item.Quantity,
+				Reason:    fmt.Sprintf("order #%d cancelled", order.ID),
+				UserID:    &id_user,
+			})
+			if err != nil {
+				return nil, queryError(ctx, err)
+			}
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, queryError(ctx, err)
+	}
+	return &order, nil
+}
+
+type queryer interface {
+	QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error)
+}
+
+func getOrderItems(ctx context.Context, q queryer, id_order int) ([]model.OrderItem, error) {
+	rows, err := q.QueryContext(ctx,
+		"SELECT id, product_id, product_name, unit_price, quantity FROM order_item WHERE order_id = $1 ORDER BY id;", id_order,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var itemList []model.OrderItem
+	for rows.Next() {
+		var item model.OrderItem
+		var productID sql.NullInt64
+		if err := rows.Scan(&item.ID, &productID, &item.ProductName, &item.UnitPrice, &item.Quantity); err != nil {
+			return nil, err
+		}
+		item.ProductID = nullIntToPtr(productID)
+		item.Subtotal = item.UnitPrice.Mul(item.Quantity)
+		itemList = append(itemList, item)
+	}
+
+	return itemList, rows.Err()
+}
@@ -0,0 +1,105 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"product-go-api/model"
+	"strings"
+)
+
+// normalizePage applies the default page and limit.
+func normalizePage(page model.PageRequest) model.PageRequest {
+	if page.Page < 1 {
+		page.Page = 1
+	}
+	if page.Limit < 1 {
+		page.Limit = 10
+	}
+	if page.Cursor {
+		page.Page = 0
+	}
+	return page
+}
+
+// queryPage lists the rows of table matching conditions along with the total
+// count of matching rows. Offset pages use LIMIT and OFFSET and are sorted by
+// orderBy, which must end with a unique column; keyset pages are always
+// sorted by id, filter on it and fetch one extra row to know if more follow.
+// Both queries run in the transaction carried by ctx, if any.
+func queryPage[T any](ctx context.Context, connection *sql.DB, columns, table string, conditions []string, args []interface{}, page model.PageRequest, orderBy string, scan func(rowScanner) (T, error)) (model.Page[T], error) {
+	page = normalizePage(page)
+	db := conn(ctx, connection)
+	result := model.Page[T]{Items: []T{}, Page: page.Page, Limit: page.Limit}
+
+	where := ""
+	if len(conditions) > 0 {
+		where = " WHERE " + strings.Join(conditions, " AND ")
+	}
+	err := db.QueryRowContext(ctx, "SELECT COUNT(*) FROM "+table+where, args...).Scan(&result.Total)
+	if err != nil {
+		return model.Page[T]{}, queryError(ctx, err)
+	}
+
+	argIdx := len(args) + 1
+	if page.Cursor {
+		orderBy = "id"
+		conditions = append(conditions, fmt.Sprintf("id > $%d", argIdx))
+		args = append(args, page.After)
+		argIdx++
+		where = " WHERE " + strings.Join(conditions, " AND ")
+	}
+
+	query := fmt.Sprintf("SELECT %s FROM %s%s ORDER BY %s LIMIT $%d", columns, table, where, orderBy, argIdx)
+	if page.Cursor {
+		args = append(args, page.Limit+1)
+	} else {
+		query += fmt.Sprintf(" OFFSET $%d", argIdx+1)
+		args = append(args, page.Limit, (page.Page-1)*page.Limit)
+	}
+
+	rows, err := db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return model.Page[T]{}, queryError(ctx, err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		item, err := scan(rows)
+		if err != nil {
+			return model.Page[T]{}, queryError(ctx, err)
+		}
+		result.Items = append(result.Items, item)
+	}
+	if err := rows.Err(); err != nil {
+		return model.Page[T]{}, queryError(ctx, err)
+	}
+
+	if page.Cursor {
+		if len(result.Items) > page.Limit {
+			result.Items = result.Items[:page.Limit]
+			result.HasMore = true
+		}
+	} else {
+		result.HasMore = page.Page*page.Limit < result.Total
+	}
+	return result, nil
+}
+
+// sortClause builds an ORDER BY clause from keys, mapping each field to its
+// column through columns. id is appended so rows with equal keys keep the same
+// order on every page.
+func sortClause(keys []model.SortKey, columns map[string]string) (string, error) {
+	clause := make([]string, 0, len(keys)+1)
+	for _, key := range keys {
+		column, ok := columns[key.Field]
+		if !ok {
+			return "", fmt.Errorf("repository: unknown sort field %q", key.Field)
+		}
+		if key.Desc {
+			column += " DESC"
+		}
+		clause = append(clause, column)
+	}
+	return strings.Join(append(clause, "id"), ", "), nil
+}
@@ -0,0 +1,21 @@
+package repository
+
+import (
+	"product-go-api/model"
+	"testing"
+)
+
+func TestSortClause(t *testing.T) {
+	clause, err := sortClause([]model.SortKey{{Field: model.ProductSortPrice, Desc: true}, {Field: model.ProductSortName}}, productSortColumns)
+	if err != nil || clause != "price DESC, product_name, id" {
+		t.Fatalf("sortClause = %q, %v", clause, err)
+	}
+
+	if clause, _ := sortClause(nil, productSortColumns); clause != "id" {
+		t.Fatalf("default sortClause = %q, want id", clause)
+	}
+
+	if _, err := sortClause([]model.SortKey{{Field: "price; DROP TABLE product"}}, productSortColumns); err == nil {
+		t.Fatal("unknown sort field was accepted")
+	}
+}
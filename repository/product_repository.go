@@ -1,138 +1,613 @@
 package repository
 
 import (
+	"context"
 	"database/sql"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"product-go-api/model"
+	"time"
+
+	"github.com/lib/pq"
 )
 
-type ProductRepository struct {
-	connection *sql.DB
+// ErrDuplicateSKU is returned when a product is saved with the SKU of another
+// product.
+var ErrDuplicateSKU = errors.New("duplicate sku")
+
+// ErrSKUInTrash is returned when an import row has the SKU of a product in the
+// trash, which the import neither updates nor restores.
+var ErrSKUInTrash = errors.New("sku of a trashed product")
+
+type ProductRepository interface {
+	GetProducts(ctx context.Context, page model.PageRequest, filter model.ProductFilter) (model.Page[model.Product], error)
+	SearchProducts(ctx context.Context, page model.PageRequest, search model.ProductSearch) (model.Page[model.ProductSearchResult], error)
+	CreateProduct(ctx context.Context, product model.Product) (int, error)
+	GetProductById(ctx context.Context, id_product int) (*model.Product, error)
+	DeleteProduct(ctx context.Context, id_product int) (bool, error)
+	UpdateProduct(ctx context.Context, product model.Product) (*model.Product, error)
+	GetDeletedProducts(ctx context.Context, page model.PageRequest) (model.Page[model.Product], error)
+	GetDeletedProductById(ctx context.Context, id_product int) (*model.Product, error)
+	RestoreProduct(ctx context.Context, id_product int) (*model.Product, error)
+	PurgeProducts(ctx context.Context, deletedBefore time.Time) (int64, error)
+	GetPriceHistory(ctx context.Context, id_product int, page model.PageRequest) (model.Page[model.ProductPrice], error)
+	SchedulePrice(ctx context.Context, price model.ProductPrice) (model.ProductPrice, error)
+	ApplyScheduledPrices(ctx context.Context, now time.Time) (int64, error)
+	ImportProducts(ctx context.Context, products []model.Product, dryRun bool) ([]ImportResult, error)
 }
 
-func NewProductRepository(connection *sql.DB) ProductRepository {
-	return ProductRepository{
-		connection: connection,
-	}
+// ImportResult is the outcome of one product of ImportProducts. Before is the
+// product it replaced, nil when it was created, and After the product written.
+// Err is set instead when the product could not be written.
+type ImportResult struct {
+	Before *model.Product
+	After  model.Product
+	Err    error
 }
 
-func (pr *ProductRepository) GetProducts(page, limit int, name string) ([]model.Product, error) {
+type productRepository struct {
+	connection   *sql.DB
+	queryTimeout time.Duration
+}
 
-	if page < 1 {
-		page = 1
+func NewProductRepository(connection *sql.DB, queryTimeout time.Duration) ProductRepository {
+	return &productRepository{
+		connection:   connection,
+		queryTimeout: queryTimeout,
 	}
+}
 
-	if limit < 1 {
-		limit = 10
-	}
+// productSortColumns whitelists the columns products can be sorted by, so
+// sort fields never reach the query as text from the request.
+var productSortColumns = map[string]string{
+	model.ProductSortName:      "product_name",
+	model.ProductSortPrice:     "price",
+	model.ProductSortCreatedAt: "created_at",
+}
 
-	offset := (page - 1) * limit
+// productColumns are the columns scanProduct reads. The price list is
+// gathered from product_currency_price as a JSON object of currency to price.
+const productColumns = "id, sku, product_name, description, brand, price, " +
+	"(SELECT COALESCE(jsonb_object_agg(currency, price), '{}'::jsonb) FROM product_currency_price WHERE product_id = product.id) AS price_list, " +
+	"status, category_id, attributes, created_at, updated_at, deleted_at"
 
-	query := "SELECT id, product_name, price FROM product"
+func (pr *productRepository) GetProducts(ctx context.Context, page model.PageRequest, filter model.ProductFilter) (model.Page[model.Product], error) {
+	ctx, cancel := context.WithTimeout(ctx, pr.queryTimeout)
+	defer cancel()
+
+	conditions := []string{"deleted_at IS NULL"}
 	var args []interface{}
-	argIdx := 1
+	condition := func(format string, value interface{}) {
+		args = append(args, value)
+		conditions = append(conditions, fmt.Sprintf(format, len(args)))
+	}
 
-	if name != "" {
-		query += fmt.Sprintf(" WHERE product_name ILIKE $%d", argIdx)
-		args = append(args, "%"+name+"%")
-		argIdx++
+	if filter.Name != "" {
+		condition("product_name ILIKE $%d", "%"+filter.Name+"%")
+	}
+	if filter.Status != "" {
+		condition("status = $%d", filter.Status)
 	}
 
-	query += fmt.Sprintf(" LIMIT $%d OFFSET $%d", argIdx, argIdx+1)
-	args = append(args, limit, offset)
+	if filter.CategoryID > 0 {
+		if filter.IncludeSubcategories {
+			condition(`category_id IN (
+				WITH RECURSIVE tree AS (
+					SELECT id FROM category WHERE id = $%d
+					UNION ALL
+					SELECT c.id FROM category c JOIN tree t ON c.parent_id = t.id
+				)
+				SELECT id FROM tree)`, filter.CategoryID)
+		} else {
+			condition("category_id = $%d", filter.CategoryID)
+		}
+	}
 
-	rows, err := pr.connection.Query(query, args...)
+	if len(filter.IDs) > 0 {
+		condition("id = ANY($%d)", pq.Array(filter.IDs))
+	}
+	if filter.MinPrice != nil {
+		condition("price >= $%d", *filter.MinPrice)
+	}
+	if filter.MaxPrice != nil {
+		condition("price <= $%d", *filter.MaxPrice)
+	}
+	if !filter.CreatedFrom.IsZero() {
+		condition("created_at >= $%d", filter.CreatedFrom)
+	}
+	if !filter.CreatedTo.IsZero() {
+		condition("created_at < $%d", filter.CreatedTo)
+	}
+	if !filter.UpdatedFrom.IsZero() {
+		condition("updated_at >= $%d", filter.UpdatedFrom)
+	}
+	if !filter.UpdatedTo.IsZero() {
+		condition("updated_at < $%d", filter.UpdatedTo)
+	}
 
+	orderBy, err := sortClause(filter.Sort, productSortColumns)
 	if err != nil {
-		return []model.Product{}, err
+		return model.Page[model.Product]{}, err
 	}
 
-	var productList []model.Product
-	var productObj model.Product
+	return queryPage(ctx, pr.connection, productColumns, "product", conditions, args, page, orderBy, scanProduct)
+}
 
-	for rows.Next() {
-		if err := rows.Scan(&productObj.ID, &productObj.Name, &productObj.Price); err != nil {
-			return []model.Product{}, err
+// SearchProducts ranks the products matching search with ts_rank, or with the
+// trigram similarity of the name when fuzzy matching finds a better match.
+// Results are ordered by rank, so only offset pages are supported.
+func (pr *productRepository) SearchProducts(ctx context.Context, page model.PageRequest, search model.ProductSearch) (model.Page[model.ProductSearchResult], error) {
+	ctx, cancel := context.WithTimeout(ctx, pr.queryTimeout)
+	defer cancel()
+
+	page.Cursor = false
+	args := []interface{}{prefixQuery(SearchTerms(search.Query))}
+	condition := "search_vector @@ to_tsquery('simple', $1)"
+	rank := "ts_rank(search_vector, to_tsquery('simple', $1))"
+	if search.Fuzzy {
+		args = append(args, search.Query)
+		condition = "(" + condition + " OR product_name % $2)"
+		rank = "GREATEST(" + rank + ", similarity(product_name, $2))"
+	}
+	conditions := []string{condition, "deleted_at IS NULL"}
+	if search.Status != "" {
+		args = append(args, search.Status)
+		conditions = append(conditions, fmt.Sprintf("status = $%d", len(args)))
+	}
+	columns := productColumns + ", " + rank + " AS rank, " +
+		"ts_headline('simple', product_name, to_tsquery('simple', $1), 'StartSel=<mark>, StopSel=</mark>, HighlightAll=true') AS highlight"
+
+	return queryPage(ctx, pr.connection, columns, "product", conditions, args, page, "rank DESC, id", func(row rowScanner) (model.ProductSearchResult, error) {
+		var result model.ProductSearchResult
+		var rank float64
+		var highlight string
+		product, err := scanProductWith(row, &rank, &highlight)
+		result.Product, result.Rank, result.Highlight = product, rank, highlight
+		return result, err
+	})
+}
+
+func scanProduct(row rowScanner) (model.Product, error) {
+	return scanProductWith(row)
+}
+
+// scanProductWith reads the productColumns of row, followed by the extra
+// columns of the query into extra.
+func scanProductWith(row rowScanner, extra ...interface{}) (model.Product, error) {
+	var product model.Product
+	var categoryID sql.NullInt64
+	var priceList, attributes []byte
+	var deletedAt sql.NullTime
+	dest := []interface{}{
+		&product.ID, &product.SKU, &product.Name, &product.Description, &product.Brand, &product.Price, &priceList,
+		&product.Status, &categoryID, &attributes, &product.CreatedAt, &product.UpdatedAt, &deletedAt,
+	}
+	if err := row.Scan(append(dest, extra...)...); err != nil {
+		return model.Product{}, err
+	}
+	product.CategoryID = nullIntToPtr(categoryID)
+	if deletedAt.Valid {
+		product.DeletedAt = &deletedAt.Time
+	}
+	if err := json.Unmarshal(priceList, &product.PriceList); err != nil {
+		return model.Product{}, err
+	}
+	if err := json.Unmarshal(attributes, &product.Attributes); err != nil {
+		return model.Product{}, err
+	}
+	return product, nil
+}
+
+// replacePriceList sets the prices of a product in other currencies to
+// priceList.
+func replacePriceList(ctx context.Context, tx dbtx, id_product int, priceList map[string]model.Money) error {
+	if _, err := tx.ExecContext(ctx, "DELETE FROM product_currency_price WHERE product_id = $1;", id_product); err != nil {
+		return err
+	}
+	for currency, price := range priceList {
+		_, err := tx.ExecContext(ctx,
+			"INSERT INTO product_currency_price (product_id, currency, price) VALUES ($1, $2, $3);", id_product, currency, price,
+		)
+		if err != nil {
+			return err
 		}
-		productList = append(productList, productObj)
 	}
+	return nil
+}
+
+// productAttributes encodes the attributes of product for their JSONB column,
+// as an empty object when they are not set.
+func productAttributes(product model.Product) ([]byte, error) {
+	if product.Attributes == nil {
+		return []byte("{}"), nil
+	}
+	return json.Marshal(product.Attributes)
+}
 
-	rows.Close()
-	return productList, nil
+// productWriteError reports a violation of the unique SKU index as
+// ErrDuplicateSKU.
+func productWriteError(ctx context.Context, err error) error {
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) && pqErr.Code == "23505" && pqErr.Constraint == "idx_product_sku" {
+		return ErrDuplicateSKU
+	}
+	return queryError(ctx, err)
 }
 
-func (pr *ProductRepository) CreateProduct(product model.Product) (int, error) {
+func (pr *productRepository) CreateProduct(ctx context.Context, product model.Product) (int, error) {
+	ctx, cancel := context.WithTimeout(ctx, pr.queryTimeout)
+	defer cancel()
 
-	var id int
-	query, err := pr.connection.Prepare(
-		"INSERT INTO product" + "(product_name, price)" + "VALUES ($1, $2) RETURNING id;",
-	)
+	tx, err := beginTx(ctx, pr.connection)
+	if err != nil {
+		return 0, queryError(ctx, err)
+	}
+	defer tx.Rollback()
+
+	id, err := insertProduct(ctx, tx, product)
 	if err != nil {
 		return 0, err
 	}
+	if err := tx.Commit(); err != nil {
+		return 0, queryError(ctx, err)
+	}
+	return id, nil
+}
 
-	err = query.QueryRow(product.Name, product.Price).Scan(&id)
+// insertProduct inserts product within tx, with its first price and its price
+// list.
+func insertProduct(ctx context.Context, tx dbtx, product model.Product) (int, error) {
+	attributes, err := productAttributes(product)
 	if err != nil {
 		return 0, err
 	}
 
-	query.Close()
+	var id int
+	err = tx.QueryRowContext(ctx,
+		"INSERT INTO product"+"(sku, product_name, description, brand, price, status, category_id, attributes)"+"VALUES ($1, $2, $3, $4, $5, $6, $7, $8) RETURNING id;",
+		product.SKU, product.Name, product.Description, product.Brand, product.Price, product.Status, product.CategoryID, attributes,
+	).Scan(&id)
+	if err != nil {
+		return 0, productWriteError(ctx, err)
+	}
+
+	// The first price starts the history of the product.
+	if _, err := tx.ExecContext(ctx, "INSERT INTO product_price (product_id, price) VALUES ($1, $2);", id, product.Price); err != nil {
+		return 0, queryError(ctx, err)
+	}
+	if err := replacePriceList(ctx, tx, id, product.PriceList); err != nil {
+		return 0, queryError(ctx, err)
+	}
 	return id, nil
 }
 
-func (pr *ProductRepository) GetProductById(id_product int) (*model.Product, error) {
-	query, err := pr.connection.Prepare("SELECT * FROM product WHERE id = $1;")
+func (pr *productRepository) GetProductById(ctx context.Context, id_product int) (*model.Product, error) {
+	ctx, cancel := context.WithTimeout(ctx, pr.queryTimeout)
+	defer cancel()
+
+	query, err := conn(ctx, pr.connection).PrepareContext(ctx, "SELECT "+productColumns+" FROM product WHERE id = $1 AND deleted_at IS NULL;")
 
 	if err != nil {
-		return nil, err
+		return nil, queryError(ctx, err)
 	}
+	defer query.Close()
 
-	var product model.Product
-
-	err = query.QueryRow(id_product).Scan(&product.ID, &product.Name, &product.Price)
+	product, err := scanProduct(query.QueryRowContext(ctx, id_product))
 
 	if err != nil {
 		if err == sql.ErrNoRows {
 			return nil, nil
 		}
 
-		return nil, err
+		return nil, queryError(ctx, err)
 	}
 
-	query.Close()
 	return &product, nil
 }
 
-func (pr *ProductRepository) DeleteProduct(id_product int) error {
-	query, err := pr.connection.Prepare("DELETE FROM product WHERE id = $1;")
+// DeleteProduct moves the product to the trash. Trashed products are hidden
+// from every other read until they are restored or purged. It reports whether
+// the product existed outside the trash.
+func (pr *productRepository) DeleteProduct(ctx context.Context, id_product int) (bool, error) {
+	ctx, cancel := context.WithTimeout(ctx, pr.queryTimeout)
+	defer cancel()
+
+	query, err := conn(ctx, pr.connection).PrepareContext(ctx, "UPDATE product SET deleted_at = NOW() WHERE id = $1 AND deleted_at IS NULL;")
 	if err != nil {
-		return err
+		return false, queryError(ctx, err)
 	}
 	defer query.Close()
 
-	_, err = query.Exec(id_product)
-	return err
+	result, err := query.ExecContext(ctx, id_product)
+	if err != nil {
+		return false, queryError(ctx, err)
+	}
+	deleted, err := result.RowsAffected()
+	if err != nil {
+		return false, queryError(ctx, err)
+	}
+	return deleted > 0, nil
 }
 
-func (pr *ProductRepository) UpdateProduct(product model.Product) (*model.Product, error) {
-	query, err := pr.connection.Prepare(
-		"UPDATE product SET product_name = $2, price = $3 WHERE id = $1 RETURNING id, product_name, price;",
-	)
+// UpdateProduct returns nil when the product does not exist or is in the
+// trash, which it may have been moved to since the caller read it.
+func (pr *productRepository) UpdateProduct(ctx context.Context, product model.Product) (*model.Product, error) {
+	ctx, cancel := context.WithTimeout(ctx, pr.queryTimeout)
+	defer cancel()
+
+	tx, err := beginTx(ctx, pr.connection)
 	if err != nil {
-		return nil, err
+		return nil, queryError(ctx, err)
 	}
+	defer tx.Rollback()
 
-	var updatedProduct model.Product
-	err = query.QueryRow(product.ID, product.Name, product.Price).Scan(
-		&updatedProduct.ID,
-		&updatedProduct.Name,
-		&updatedProduct.Price,
-	)
+	var previousPrice model.Money
+	err = tx.QueryRowContext(ctx, "SELECT price FROM product WHERE id = $1 AND deleted_at IS NULL FOR UPDATE;", product.ID).Scan(&previousPrice)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, queryError(ctx, err)
+	}
 
+	updatedProduct, err := updateProduct(ctx, tx, product, previousPrice)
 	if err != nil {
 		return nil, err
 	}
-
-	query.Close()
+	if err := tx.Commit(); err != nil {
+		return nil, queryError(ctx, err)
+	}
 	return &updatedProduct, nil
 }
+
+// updateProduct writes product within tx. Its price is added to the history
+// when it differs from previousPrice, read from the locked row.
+func updateProduct(ctx context.Context, tx dbtx, product model.Product, previousPrice model.Money) (model.Product, error) {
+	attributes, err := productAttributes(product)
+	if err != nil {
+		return model.Product{}, err
+	}
+
+	// The price list is replaced first, so the RETURNING clause reads it.
+	if err := replacePriceList(ctx, tx, product.ID, product.PriceList); err != nil {
+		return model.Product{}, queryError(ctx, err)
+	}
+
+	updatedProduct, err := scanProduct(tx.QueryRowContext(ctx,
+		`UPDATE product SET sku = $2, product_name = $3, description = $4, brand = $5, price = $6, status = $7,
+		category_id = $8, attributes = $9, updated_at = NOW() WHERE id = $1 RETURNING `+productColumns+";",
+		product.ID, product.SKU, product.Name, product.Description, product.Brand, product.Price, product.Status,
+		product.CategoryID, attributes,
+	))
+	if err != nil {
+		return model.Product{}, productWriteError(ctx, err)
+	}
+
+	// Both prices were read from the NUMERIC column, so they compare exactly.
+	if updatedProduct.Price != previousPrice {
+		_, err := tx.ExecContext(ctx, "INSERT INTO product_price (product_id, price) VALUES ($1, $2);", product.ID, updatedProduct.Price)
+		if err != nil {
+			return model.Product{}, queryError(ctx, err)
+		}
+	}
+	return updatedProduct, nil
+}
+
+// ImportProducts creates the products whose SKU is new and updates the ones
+// whose SKU exists, in one transaction. Each product is written under a
+// savepoint, so one that fails is reported in its result without undoing the
+// others. Updates keep the current status, attributes and price list when the
+// product leaves them empty. With dryRun everything written is rolled back to
+// a savepoint, even in a transaction of the caller, and the results show what
+// the import would have written. Created products without a status are
+// active. The query timeout applies to each product rather than to the whole
+// transaction, whose length grows with the batch.
+func (pr *productRepository) ImportProducts(ctx context.Context, products []model.Product, dryRun bool) ([]ImportResult, error) {
+	tx, err := beginTx(ctx, pr.connection)
+	if err != nil {
+		return nil, queryError(ctx, err)
+	}
+	defer tx.Rollback()
+
+	if dryRun {
+		if _, err := tx.ExecContext(ctx, "SAVEPOINT import_dry_run;"); err != nil {
+			return nil, queryError(ctx, err)
+		}
+	}
+
+	results := make([]ImportResult, len(products))
+	for i, product := range products {
+		result, err := pr.importProduct(ctx, tx, product)
+		if err != nil {
+			return nil, err
+		}
+		results[i] = result
+	}
+
+	if dryRun {
+		if _, err := tx.ExecContext(ctx, "ROLLBACK TO SAVEPOINT import_dry_run;"); err != nil {
+			return nil, queryError(ctx, err)
+		}
+		return results, nil
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, queryError(ctx, err)
+	}
+	return results, nil
+}
+
+// importProduct writes product under a savepoint of tx. The error is only set
+// when the savepoint itself fails, which aborts the import.
+func (pr *productRepository) importProduct(ctx context.Context, tx dbtx, product model.Product) (ImportResult, error) {
+	ctx, cancel := context.WithTimeout(ctx, pr.queryTimeout)
+	defer cancel()
+
+	if _, err := tx.ExecContext(ctx, "SAVEPOINT import_product;"); err != nil {
+		return ImportResult{}, queryError(ctx, err)
+	}
+	result := upsertProduct(ctx, tx, product)
+	release := "RELEASE SAVEPOINT import_product;"
+	if result.Err != nil {
+		release = "ROLLBACK TO SAVEPOINT import_product;"
+	}
+	if _, err := tx.ExecContext(ctx, release); err != nil {
+		return ImportResult{}, queryError(ctx, err)
+	}
+	return result, nil
+}
+
+// upsertProduct creates or updates product by its SKU within tx.
+func upsertProduct(ctx context.Context, tx dbtx, product model.Product) ImportResult {
+	existing, err := scanProduct(tx.QueryRowContext(ctx, "SELECT "+productColumns+" FROM product WHERE sku = $1 FOR UPDATE;", product.SKU))
+	if err == sql.ErrNoRows {
+		if product.Status == "" {
+			product.Status = model.ProductStatusActive
+		}
+		id, err := insertProduct(ctx, tx, product)
+		if err != nil {
+			return ImportResult{Err: err}
+		}
+		created, err := scanProduct(tx.QueryRowContext(ctx, "SELECT "+productColumns+" FROM product WHERE id = $1;", id))
+		if err != nil {
+			return ImportResult{Err: queryError(ctx, err)}
+		}
+		return ImportResult{After: created}
+	}
+	if err != nil {
+		return ImportResult{Err: queryError(ctx, err)}
+	}
+	if existing.DeletedAt != nil {
+		return ImportResult{Err: ErrSKUInTrash}
+	}
+
+	product.ID = existing.ID
+	if product.Status == "" {
+		product.Status = existing.Status
+	}
+	if product.Attributes == nil {
+		product.Attributes = existing.Attributes
+	}
+	if product.PriceList == nil {
+		product.PriceList = existing.PriceList
+	}
+	updated, err := updateProduct(ctx, tx, product, existing.Price)
+	if err != nil {
+		return ImportResult{Err: err}
+	}
+	return ImportResult{Before: &existing, After: updated}
+}
+
+// GetDeletedProducts lists the products in the trash.
+func (pr *productRepository) GetDeletedProducts(ctx context.Context, page model.PageRequest) (model.Page[model.Product], error) {
+	ctx, cancel := context.WithTimeout(ctx, pr.queryTimeout)
+	defer cancel()
+
+	return queryPage(ctx, pr.connection, productColumns, "product", []string{"deleted_at IS NOT NULL"}, nil, page, "id", scanProduct)
+}
+
+// GetDeletedProductById returns nil when the product is not in the trash.
+func (pr *productRepository) GetDeletedProductById(ctx context.Context, id_product int) (*model.Product, error) {
+	ctx, cancel := context.WithTimeout(ctx, pr.queryTimeout)
+	defer cancel()
+
+	product, err := scanProduct(conn(ctx, pr.connection).QueryRowContext(ctx,
+		"SELECT "+productColumns+" FROM product WHERE id = $1 AND deleted_at IS NOT NULL;", id_product,
+	))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, queryError(ctx, err)
+	}
+	return &product, nil
+}
+
+// RestoreProduct takes a product out of the trash. It returns nil when the
+// product is not in the trash.
+func (pr *productRepository) RestoreProduct(ctx context.Context, id_product int) (*model.Product, error) {
+	ctx, cancel := context.WithTimeout(ctx, pr.queryTimeout)
+	defer cancel()
+
+	product, err := scanProduct(conn(ctx, pr.connection).QueryRowContext(ctx,
+		"UPDATE product SET deleted_at = NULL WHERE id = $1 AND deleted_at IS NOT NULL RETURNING "+productColumns+";", id_product,
+	))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, queryError(ctx, err)
+	}
+	return &product, nil
+}
+
+// PurgeProducts permanently deletes the products trashed before
+// deletedBefore. Their stock and cart rows go with them and order items keep
+// their snapshot, as set by the foreign keys.
+func (pr *productRepository) PurgeProducts(ctx context.Context, deletedBefore time.Time) (int64, error) {
+	ctx, cancel := context.WithTimeout(ctx, pr.queryTimeout)
+	defer cancel()
+
+	result, err := conn(ctx, pr.connection).ExecContext(ctx, "DELETE FROM product WHERE deleted_at < $1;", deletedBefore)
+	if err != nil {
+		return 0, queryError(ctx, err)
+	}
+	return result.RowsAffected()
+}
+
+const priceColumns = "id, product_id, price, effective_from, created_at"
+
+func scanPrice(row rowScanner) (model.ProductPrice, error) {
+	var price model.ProductPrice
+	err := row.Scan(&price.ID, &price.ProductID, &price.Price, &price.EffectiveFrom, &price.CreatedAt)
+	return price, err
+}
+
+// GetPriceHistory lists the prices of a product, the latest effective date
+// first, including the scheduled ones.
+func (pr *productRepository) GetPriceHistory(ctx context.Context, id_product int, page model.PageRequest) (model.Page[model.ProductPrice], error) {
+	ctx, cancel := context.WithTimeout(ctx, pr.queryTimeout)
+	defer cancel()
+
+	return queryPage(ctx, pr.connection, priceColumns, "product_price", []string{"product_id = $1"}, []interface{}{id_product},
+		page, "effective_from DESC, id DESC", scanPrice)
+}
+
+// SchedulePrice adds a price to the history of a product. It becomes the
+// price of the product once ApplyScheduledPrices runs after its effective
+// date.
+func (pr *productRepository) SchedulePrice(ctx context.Context, price model.ProductPrice) (model.ProductPrice, error) {
+	ctx, cancel := context.WithTimeout(ctx, pr.queryTimeout)
+	defer cancel()
+
+	scheduled, err := scanPrice(conn(ctx, pr.connection).QueryRowContext(ctx,
+		"INSERT INTO product_price (product_id, price, effective_from) VALUES ($1, $2, $3) RETURNING "+priceColumns+";",
+		price.ProductID, price.Price, price.EffectiveFrom,
+	))
+	if err != nil {
+		return model.ProductPrice{}, queryError(ctx, err)
+	}
+	return scheduled, nil
+}
+
+// ApplyScheduledPrices sets the price of every product to the one of its
+// latest entry effective at now, and reports how many products changed.
+// Products in the trash are left as they are until they are restored.
+func (pr *productRepository) ApplyScheduledPrices(ctx context.Context, now time.Time) (int64, error) {
+	ctx, cancel := context.WithTimeout(ctx, pr.queryTimeout)
+	defer cancel()
+
+	result, err := conn(ctx, pr.connection).ExecContext(ctx,
+		`WITH current_price AS (
+			SELECT DISTINCT ON (product_id) product_id, price FROM product_price
+			WHERE effective_from <= $1
+			ORDER BY product_id, effective_from DESC, id DESC
+		)
+		UPDATE product p SET price = c.price, updated_at = NOW()
+		FROM current_price c
+		WHERE p.id = c.product_id AND p.price <> c.price AND p.deleted_at IS NULL;`, now)
+	if err != nil {
+		return 0, queryError(ctx, err)
+	}
+	return result.RowsAffected()
+}
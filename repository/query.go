@@ -0,0 +1,36 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"product-go-api/logging"
+
+	"github.com/lib/pq"
+)
+
+// queryError reports a query that was cut short by its context as the context
+// error. lib/pq cancels the statement on the server and surfaces it as a
+// generic query error, which callers could not tell apart from other failures.
+// Database errors are logged with their details, which never reach clients.
+func queryError(ctx context.Context, err error) error {
+	if err == nil {
+		return nil
+	}
+	if ctx.Err() != nil {
+		logging.FromContext(ctx).WarnContext(ctx, "query cancelled", slog.String("reason", ctx.Err().Error()))
+		return ctx.Err()
+	}
+
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) {
+		logging.FromContext(ctx).ErrorContext(ctx, "query failed",
+			slog.String("error", pqErr.Message),
+			slog.String("code", string(pqErr.Code)),
+			slog.String("detail", pqErr.Detail),
+			slog.String("constraint", pqErr.Constraint),
+			slog.String("table", pqErr.Table),
+		)
+	}
+	return err
+}
@@ -0,0 +1,48 @@
+package repository
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"log/slog"
+	"product-go-api/logging"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+func TestQueryError(t *testing.T) {
+	driverErr := errors.New("pq: canceling statement due to user request")
+
+	if err := queryError(context.Background(), nil); err != nil {
+		t.Fatalf("nil error: got %v", err)
+	}
+	if err := queryError(context.Background(), driverErr); err != driverErr {
+		t.Fatalf("live context: got %v, want the driver error", err)
+	}
+
+	expired, cancel := context.WithTimeout(context.Background(), time.Nanosecond)
+	defer cancel()
+	<-expired.Done()
+	if err := queryError(expired, driverErr); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expired context: got %v, want context.DeadlineExceeded", err)
+	}
+	if err := queryError(expired, nil); err != nil {
+		t.Fatalf("expired context without error: got %v", err)
+	}
+}
+
+func TestQueryErrorLogsDatabaseDetails(t *testing.T) {
+	var buf bytes.Buffer
+	ctx := logging.WithLogger(context.Background(), logging.New(&buf, "json", slog.LevelInfo))
+	pqErr := &pq.Error{Code: "23505", Message: "duplicate key value", Constraint: "users_email_key"}
+
+	if err := queryError(ctx, pqErr); err != pqErr {
+		t.Fatalf("got %v, want the driver error", err)
+	}
+	if !strings.Contains(buf.String(), `"code":"23505"`) || !strings.Contains(buf.String(), `"constraint":"users_email_key"`) {
+		t.Fatalf("log entry %q misses the database error details", buf.String())
+	}
+}
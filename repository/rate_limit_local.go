@@ -0,0 +1,58 @@
+package repository
+
+import (
+	"context"
+	"product-go-api/model"
+	"sync"
+	"time"
+)
+
+type localRateLimitRepository struct {
+	mu   sync.Mutex
+	tats map[string]time.Time
+	now  func() time.Time
+}
+
+// NewLocalRateLimitRepository keeps rate limits in the memory of this process.
+// It is enough for a single instance; use NewRateLimitRepository when several
+// instances must share their limits.
+func NewLocalRateLimitRepository() RateLimitRepository {
+	return &localRateLimitRepository{
+		tats: make(map[string]time.Time),
+		now:  time.Now,
+	}
+}
+
+func (lr *localRateLimitRepository) Allow(ctx context.Context, key string, limit model.RateLimit) (model.RateLimitDecision, error) {
+	lr.mu.Lock()
+	defer lr.mu.Unlock()
+
+	now := lr.now()
+	tat := lr.tats[key]
+	if tat.Before(now) {
+		tat = now
+	}
+
+	newTat := tat.Add(limit.Interval())
+	if newTat.Sub(now) > limit.Interval()*time.Duration(limit.Burst) {
+		return rateLimitDecision(lr.tats[key], now, limit, false), nil
+	}
+
+	lr.tats[key] = newTat
+	return rateLimitDecision(newTat, now, limit, true), nil
+}
+
+func (lr *localRateLimitRepository) EvictIdle(ctx context.Context) (int64, error) {
+	lr.mu.Lock()
+	defer lr.mu.Unlock()
+
+	now := lr.now()
+	var evicted int64
+	for key, tat := range lr.tats {
+		if tat.Before(now) {
+			delete(lr.tats, key)
+			evicted++
+		}
+	}
+	return evicted, nil
+}
@@ -0,0 +1,51 @@
+package repository
+
+import (
+	"context"
+	"product-go-api/model"
+	"testing"
+	"time"
+)
+
+func TestLocalRateLimitRepository(t *testing.T) {
+	ctx := context.Background()
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	repo := &localRateLimitRepository{tats: make(map[string]time.Time), now: func() time.Time { return now }}
+	limit := model.RateLimit{RequestsPerSecond: 1, Burst: 3}
+
+	for i, wantRemaining := range []int{2, 1, 0} {
+		decision, _ := repo.Allow(ctx, "ip:1", limit)
+		if !decision.Allowed || decision.Remaining != wantRemaining {
+			t.Fatalf("request %d: %+v, want allowed with %d remaining", i+1, decision, wantRemaining)
+		}
+	}
+
+	decision, _ := repo.Allow(ctx, "ip:1", limit)
+	if decision.Allowed || decision.RetryAfter != time.Second || decision.ResetAfter != 3*time.Second {
+		t.Fatalf("over the burst: %+v, want rejected, retry after 1s, reset after 3s", decision)
+	}
+	if decision, _ := repo.Allow(ctx, "ip:2", limit); !decision.Allowed {
+		t.Fatal("another key was limited")
+	}
+
+	now = now.Add(time.Second)
+	if decision, _ := repo.Allow(ctx, "ip:1", limit); !decision.Allowed || decision.Remaining != 0 {
+		t.Fatalf("after one interval: %+v, want one request allowed", decision)
+	}
+
+	now = now.Add(time.Minute)
+	evicted, _ := repo.EvictIdle(ctx)
+	if evicted != 2 || len(repo.tats) != 0 {
+		t.Fatalf("evicted %d keys, %d left; want 2 and 0", evicted, len(repo.tats))
+	}
+}
+
+func TestRateLimitDecisionRetryAfter(t *testing.T) {
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	limit := model.RateLimit{RequestsPerSecond: 2, Burst: 2}
+
+	decision := rateLimitDecision(now.Add(time.Second), now, limit, false)
+	if decision.RetryAfter != 500*time.Millisecond || decision.Remaining != 0 {
+		t.Fatalf("decision = %+v, want retry after 500ms", decision)
+	}
+}
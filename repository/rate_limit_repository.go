@@ -0,0 +1,97 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"product-go-api/model"
+	"time"
+)
+
+// RateLimitRepository keeps rate limit state with the generic cell rate
+// algorithm, which only stores the theoretical arrival time (tat) of each key.
+type RateLimitRepository interface {
+	// Allow counts a request for key and reports whether it is within limit.
+	Allow(ctx context.Context, key string, limit model.RateLimit) (model.RateLimitDecision, error)
+	// EvictIdle removes keys whose tat is in the past. Those keys have their
+	// whole burst available, so removing them does not change any decision.
+	EvictIdle(ctx context.Context) (int64, error)
+}
+
+type rateLimitRepository struct {
+	connection   *sql.DB
+	queryTimeout time.Duration
+}
+
+// NewRateLimitRepository stores rate limits in PostgreSQL so every instance of
+// the API shares them. The database clock is used for every key.
+func NewRateLimitRepository(connection *sql.DB, queryTimeout time.Duration) RateLimitRepository {
+	return &rateLimitRepository{
+		connection:   connection,
+		queryTimeout: queryTimeout,
+	}
+}
+
+func (rr *rateLimitRepository) Allow(ctx context.Context, key string, limit model.RateLimit) (model.RateLimitDecision, error) {
+	ctx, cancel := context.WithTimeout(ctx, rr.queryTimeout)
+	defer cancel()
+
+	interval := limit.Interval()
+	window := interval * time.Duration(limit.Burst)
+
+	// The update only happens when the request fits in the burst window, so
+	// no row is returned for a rejected request.
+	var tat, now time.Time
+	err := rr.connection.QueryRowContext(ctx,
+		`INSERT INTO rate_limit AS r (bucket_key, tat) VALUES ($1, NOW() + $2 * INTERVAL '1 microsecond')
+		ON CONFLICT (bucket_key) DO UPDATE
+		SET tat = GREATEST(r.tat, NOW()) + $2 * INTERVAL '1 microsecond'
+		WHERE GREATEST(r.tat, NOW()) + $2 * INTERVAL '1 microsecond' - NOW() <= $3 * INTERVAL '1 microsecond'
+		RETURNING tat, NOW();`,
+		key, interval.Microseconds(), window.Microseconds(),
+	).Scan(&tat, &now)
+	if err == nil {
+		return rateLimitDecision(tat, now, limit, true), nil
+	}
+	if err != sql.ErrNoRows {
+		return model.RateLimitDecision{}, queryError(ctx, err)
+	}
+
+	err = rr.connection.QueryRowContext(ctx, "SELECT tat, NOW() FROM rate_limit WHERE bucket_key = $1;", key).Scan(&tat, &now)
+	if err != nil {
+		return model.RateLimitDecision{}, queryError(ctx, err)
+	}
+	return rateLimitDecision(tat, now, limit, false), nil
+}
+
+func (rr *rateLimitRepository) EvictIdle(ctx context.Context) (int64, error) {
+	ctx, cancel := context.WithTimeout(ctx, rr.queryTimeout)
+	defer cancel()
+
+	result, err := rr.connection.ExecContext(ctx, "DELETE FROM rate_limit WHERE tat < NOW();")
+	if err != nil {
+		return 0, queryError(ctx, err)
+	}
+	return result.RowsAffected()
+}
+
+// rateLimitDecision describes the state of a key whose tat is stored as tat:
+// the new tat for an allowed request, the unchanged one for a rejected one.
+func rateLimitDecision(tat, now time.Time, limit model.RateLimit, allowed bool) model.RateLimitDecision {
+	interval := limit.Interval()
+	window := interval * time.Duration(limit.Burst)
+
+	decision := model.RateLimitDecision{Allowed: allowed}
+	if tat.After(now) {
+		decision.ResetAfter = tat.Sub(now)
+	}
+	if allowed {
+		decision.Remaining = int((window - decision.ResetAfter) / interval)
+		return decision
+	}
+
+	decision.RetryAfter = decision.ResetAfter + interval - window
+	if decision.RetryAfter < 0 {
+		decision.RetryAfter = 0
+	}
+	return decision
+}
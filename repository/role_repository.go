@@ -0,0 +1,227 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"product-go-api/model"
+	"strings"
+	"time"
+)
+
+type RoleRepository interface {
+	GetRoles(ctx context.Context) ([]model.Role, error)
+	GetRoleByName(ctx context.Context, name string) (*model.Role, error)
+	CreateRole(ctx context.Context, role model.Role) (int, error)
+	SetRolePermissions(ctx context.Context, name string, permissions []string) error
+	DeleteRole(ctx context.Context, name string) error
+	RoleInUse(ctx context.Context, name string) (bool, error)
+	GetPermissions(ctx context.Context) ([]model.Permission, error)
+	GetRolePermissions(ctx context.Context, name string) ([]string, error)
+}
+
+type roleRepository struct {
+	connection   *sql.DB
+	queryTimeout time.Duration
+}
+
+func NewRoleRepository(connection *sql.DB, queryTimeout time.Duration) RoleRepository {
+	return &roleRepository{
+		connection:   connection,
+		queryTimeout: queryTimeout,
+	}
+}
+
+// roleSelect returns the roles with their permission names joined by commas,
+// which permission names never contain.
+const roleSelect = `
+	SELECT r.id, r.role_name, r.level, COALESCE(string_agg(p.permission_name, ',' ORDER BY p.permission_name), '')
+	FROM role r
+	LEFT JOIN role_permission rp ON rp.role_id = r.id
+	LEFT JOIN permission p ON p.id = rp.permission_id`
+
+func (rr *roleRepository) GetRoles(ctx context.Context) ([]model.Role, error) {
+	ctx, cancel := context.WithTimeout(ctx, rr.queryTimeout)
+	defer cancel()
+
+	rows, err := conn(ctx, rr.connection).QueryContext(ctx, roleSelect+" GROUP BY r.id ORDER BY r.level DESC, r.role_name;")
+	if err != nil {
+		return []model.Role{}, queryError(ctx, err)
+	}
+	defer rows.Close()
+
+	roleList := []model.Role{}
+	for rows.Next() {
+		role, err := scanRole(rows)
+		if err != nil {
+			return []model.Role{}, queryError(ctx, err)
+		}
+		roleList = append(roleList, role)
+	}
+
+	return roleList, queryError(ctx, rows.Err())
+}
+
+func (rr *roleRepository) GetRoleByName(ctx context.Context, name string) (*model.Role, error) {
+	ctx, cancel := context.WithTimeout(ctx, rr.queryTimeout)
+	defer cancel()
+
+	role, err := scanRole(conn(ctx, rr.connection).QueryRowContext(ctx, roleSelect+" WHERE r.role_name = $1 GROUP BY r.id;", name))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, queryError(ctx, err)
+	}
+	return &role, nil
+}
+
+func (rr *roleRepository) CreateRole(ctx context.Context, role model.Role) (int, error) {
+	ctx, cancel := context.WithTimeout(ctx, rr.queryTimeout)
+	defer cancel()
+
+	tx, err := beginTx(ctx, rr.connection)
+	if err != nil {
+		return 0, queryError(ctx, err)
+	}
+	defer tx.Rollback()
+
+	var id int
+	err = tx.QueryRowContext(ctx,
+		"INSERT INTO role (role_name, level) VALUES ($1, $2) RETURNING id;", role.Name, role.Level,
+	).Scan(&id)
+	if err != nil {
+		return 0, queryError(ctx, err)
+	}
+
+	if err := grantPermissions(ctx, tx, id, role.Permissions); err != nil {
+		return 0, queryError(ctx, err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, queryError(ctx, err)
+	}
+	return id, nil
+}
+
+// SetRolePermissions replaces the permissions of a role in a single
+// transaction.
+func (rr *roleRepository) SetRolePermissions(ctx context.Context, name string, permissions []string) error {
+	ctx, cancel := context.WithTimeout(ctx, rr.queryTimeout)
+	defer cancel()
+
+	tx, err := beginTx(ctx, rr.connection)
+	if err != nil {
+		return queryError(ctx, err)
+	}
+	defer tx.Rollback()
+
+	var id int
+	err = tx.QueryRowContext(ctx, "SELECT id FROM role WHERE role_name = $1 FOR UPDATE;", name).Scan(&id)
+	if err != nil {
+		return queryError(ctx, err)
+	}
+
+	if _, err := tx.ExecContext(ctx, "DELETE FROM role_permission WHERE role_id = $1;", id); err != nil {
+		return queryError(ctx, err)
+	}
+	if err := grantPermissions(ctx, tx, id, permissions); err != nil {
+		return queryError(ctx, err)
+	}
+
+	return queryError(ctx, tx.Commit())
+}
+
+func (rr *roleRepository) DeleteRole(ctx context.Context, name string) error {
+	ctx, cancel := context.WithTimeout(ctx, rr.queryTimeout)
+	defer cancel()
+
+	_, err := conn(ctx, rr.connection).ExecContext(ctx, "DELETE FROM role WHERE role_name = $1;", name)
+	return queryError(ctx, err)
+}
+
+func (rr *roleRepository) RoleInUse(ctx context.Context, name string) (bool, error) {
+	ctx, cancel := context.WithTimeout(ctx, rr.queryTimeout)
+	defer cancel()
+
+	var exists bool
+	err := conn(ctx, rr.connection).QueryRowContext(ctx, "SELECT EXISTS (SELECT 1 FROM users WHERE role = $1);", name).Scan(&exists)
+	return exists, queryError(ctx, err)
+}
+
+func (rr *roleRepository) GetPermissions(ctx context.Context) ([]model.Permission, error) {
+	ctx, cancel := context.WithTimeout(ctx, rr.queryTimeout)
+	defer cancel()
+
+	rows, err := conn(ctx, rr.connection).QueryContext(ctx, "SELECT id, permission_name, description FROM permission ORDER BY permission_name;")
+	if err != nil {
+		return []model.Permission{}, queryError(ctx, err)
+	}
+	defer rows.Close()
+
+	permissionList := []model.Permission{}
+	for rows.Next() {
+		var permission model.Permission
+		if err := rows.Scan(&permission.ID, &permission.Name, &permission.Description); err != nil {
+			return []model.Permission{}, queryError(ctx, err)
+		}
+		permissionList = append(permissionList, permission)
+	}
+
+	return permissionList, queryError(ctx, rows.Err())
+}
+
+func (rr *roleRepository) GetRolePermissions(ctx context.Context, name string) ([]string, error) {
+	ctx, cancel := context.WithTimeout(ctx, rr.queryTimeout)
+	defer cancel()
+
+	rows, err := conn(ctx, rr.connection).QueryContext(ctx,
+		`SELECT p.permission_name FROM permission p
+		JOIN role_permission rp ON rp.permission_id = p.id
+		JOIN role r ON r.id = rp.role_id
+		WHERE r.role_name = $1;`, name)
+	if err != nil {
+		return nil, queryError(ctx, err)
+	}
+	defer rows.Close()
+
+	var permissions []string
+	for rows.Next() {
+		var permission string
+		if err := rows.Scan(&permission); err != nil {
+			return nil, queryError(ctx, err)
+		}
+		permissions = append(permissions, permission)
+	}
+
+	return permissions, queryError(ctx, rows.Err())
+}
+
+func grantPermissions(ctx context.Context, tx dbtx, id_role int, permissions []string) error {
+	for _, permission := range permissions {
+		_, err := tx.ExecContext(ctx,
+			`INSERT INTO role_permission (role_id, permission_id)
+			SELECT $1, id FROM permission WHERE permission_name = $2
+			ON CONFLICT DO NOTHING;`, id_role, permission)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+type rowScanner interface {
+	Scan(dest ...any) error
+}
+
+func scanRole(row rowScanner) (model.Role, error) {
+	var role model.Role
+	var permissions string
+	if err := row.Scan(&role.ID, &role.Name, &role.Level, &permissions); err != nil {
+		return model.Role{}, err
+	}
+	role.Permissions = []string{}
+	if permissions != "" {
+		role.Permissions = strings.Split(permissions, ",")
+	}
+	return role, nil
+}
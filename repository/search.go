@@ -0,0 +1,25 @@
+package repository
+
+import (
+	"strings"
+	"unicode"
+)
+
+// SearchTerms splits a search query into the lowercased runs of letters and
+// digits it is matched with. Everything else, including tsquery operators, is
+// dropped.
+func SearchTerms(query string) []string {
+	return strings.FieldsFunc(strings.ToLower(query), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+}
+
+// prefixQuery builds a tsquery matching documents with a word starting with
+// each term.
+func prefixQuery(terms []string) string {
+	words := make([]string, len(terms))
+	for i, term := range terms {
+		words[i] = term + ":*"
+	}
+	return strings.Join(words, " & ")
+}
@@ -0,0 +1,19 @@
+package repository
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestPrefixQuery(t *testing.T) {
+	terms := SearchTerms("Café  au-lait! | 2kg':*")
+	if fmt.Sprint(terms) != "[café au lait 2kg]" {
+		t.Fatalf("SearchTerms = %q", terms)
+	}
+	if got := prefixQuery(terms); got != "café:* & au:* & lait:* & 2kg:*" {
+		t.Fatalf("prefixQuery = %q", got)
+	}
+	if terms := SearchTerms("&|!():*"); len(terms) != 0 {
+		t.Fatalf("operators kept as terms: %q", terms)
+	}
+}
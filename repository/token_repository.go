@@ -0,0 +1,154 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"product-go-api/model"
+	"time"
+)
+
+type TokenRepository interface {
+	CreateRefreshToken(ctx context.Context, token model.RefreshToken) error
+	UseRefreshToken(ctx context.Context, tokenHash string) (*model.RefreshToken, error)
+	GetRefreshTokenByHash(ctx context.Context, tokenHash string) (*model.RefreshToken, error)
+	RevokeFamily(ctx context.Context, familyID string) error
+	RevokeAccessToken(ctx context.Context, jti string, expiresAt time.Time) error
+	IsAccessTokenRevoked(ctx context.Context, jti string) (bool, error)
+	DeleteExpired(ctx context.Context) (int64, error)
+}
+
+type tokenRepository struct {
+	connection   *sql.DB
+	queryTimeout time.Duration
+}
+
+func NewTokenRepository(connection *sql.DB, queryTimeout time.Duration) TokenRepository {
+	return &tokenRepository{
+		connection:   connection,
+		queryTimeout: queryTimeout,
+	}
+}
+
+func (tr *tokenRepository) CreateRefreshToken(ctx context.Context, token model.RefreshToken) error {
+	ctx, cancel := context.WithTimeout(ctx, tr.queryTimeout)
+	defer cancel()
+
+	_, err := conn(ctx, tr.connection).ExecContext(ctx,
+		`INSERT INTO refresh_token (user_id, token_hash, family_id, access_jti, access_expires_at, expires_at)
+		VALUES ($1, $2, $3, $4, $5, $6);`,
+		token.UserID, token.TokenHash, token.FamilyID, token.AccessJTI, token.AccessExpiresAt, token.ExpiresAt,
+	)
+	return queryError(ctx, err)
+}
+
+// UseRefreshToken marks an active refresh token as used and returns it. It
+// returns nil, nil when no active, unexpired token has that hash.
+func (tr *tokenRepository) UseRefreshToken(ctx context.Context, tokenHash string) (*model.RefreshToken, error) {
+	ctx, cancel := context.WithTimeout(ctx, tr.queryTimeout)
+	defer cancel()
+
+	var token model.RefreshToken
+	err := conn(ctx, tr.connection).QueryRowContext(ctx,
+		`UPDATE refresh_token SET revoked_at = NOW()
+		WHERE token_hash = $1 AND revoked_at IS NULL AND expires_at > NOW()
+		RETURNING id, user_id, token_hash, family_id, access_jti, access_expires_at, expires_at;`, tokenHash,
+	).Scan(&token.ID, &token.UserID, &token.TokenHash, &token.FamilyID, &token.AccessJTI, &token.AccessExpiresAt, &token.ExpiresAt)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, queryError(ctx, err)
+	}
+	return &token, nil
+}
+
+func (tr *tokenRepository) GetRefreshTokenByHash(ctx context.Context, tokenHash string) (*model.RefreshToken, error) {
+	ctx, cancel := context.WithTimeout(ctx, tr.queryTimeout)
+	defer cancel()
+
+	var token model.RefreshToken
+	err := conn(ctx, tr.connection).QueryRowContext(ctx,
+		`SELECT id, user_id, token_hash, family_id, access_jti, access_expires_at, expires_at
+		FROM refresh_token WHERE token_hash = $1;`, tokenHash,
+	).Scan(&token.ID, &token.UserID, &token.TokenHash, &token.FamilyID, &token.AccessJTI, &token.AccessExpiresAt, &token.ExpiresAt)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, queryError(ctx, err)
+	}
+	return &token, nil
+}
+
+// RevokeFamily revokes every refresh token of a family together with the
+// access tokens that were issued alongside them.
+func (tr *tokenRepository) RevokeFamily(ctx context.Context, familyID string) error {
+	ctx, cancel := context.WithTimeout(ctx, tr.queryTimeout)
+	defer cancel()
+
+	tx, err := beginTx(ctx, tr.connection)
+	if err != nil {
+		return queryError(ctx, err)
+	}
+	defer tx.Rollback()
+
+	_, err = tx.ExecContext(ctx, "UPDATE refresh_token SET revoked_at = NOW() WHERE family_id = $1 AND revoked_at IS NULL;", familyID)
+	if err != nil {
+		return queryError(ctx, err)
+	}
+
+	_, err = tx.ExecContext(ctx,
+		`INSERT INTO revoked_token (jti, expires_at)
+		SELECT access_jti, access_expires_at FROM refresh_token WHERE family_id = $1 AND access_expires_at > NOW()
+		ON CONFLICT (jti) DO NOTHING;`, familyID,
+	)
+	if err != nil {
+		return queryError(ctx, err)
+	}
+
+	return tx.Commit()
+}
+
+func (tr *tokenRepository) RevokeAccessToken(ctx context.Context, jti string, expiresAt time.Time) error {
+	ctx, cancel := context.WithTimeout(ctx, tr.queryTimeout)
+	defer cancel()
+
+	_, err := conn(ctx, tr.connection).ExecContext(ctx,
+		"INSERT INTO revoked_token (jti, expires_at) VALUES ($1, $2) ON CONFLICT (jti) DO NOTHING;", jti, expiresAt,
+	)
+	return queryError(ctx, err)
+}
+
+func (tr *tokenRepository) IsAccessTokenRevoked(ctx context.Context, jti string) (bool, error) {
+	ctx, cancel := context.WithTimeout(ctx, tr.queryTimeout)
+	defer cancel()
+
+	var revoked bool
+	err := conn(ctx, tr.connection).QueryRowContext(ctx, "SELECT EXISTS (SELECT 1 FROM revoked_token WHERE jti = $1);", jti).Scan(&revoked)
+	return revoked, queryError(ctx, err)
+}
+
+// DeleteExpired removes the revoked access tokens that expired, which are
+// rejected anyway, and the refresh tokens whose access token expired too, so
+// no revocation can need their access_jti anymore.
+func (tr *tokenRepository) DeleteExpired(ctx context.Context) (int64, error) {
+	ctx, cancel := context.WithTimeout(ctx, tr.queryTimeout)
+	defer cancel()
+
+	var deleted int64
+	for _, query := range []string{
+		"DELETE FROM revoked_token WHERE expires_at <= NOW();",
+		"DELETE FROM refresh_token WHERE expires_at <= NOW() AND access_expires_at <= NOW();",
+	} {
+		result, err := conn(ctx, tr.connection).ExecContext(ctx, query)
+		if err != nil {
+			return deleted, queryError(ctx, err)
+		}
+		rows, err := result.RowsAffected()
+		if err != nil {
+			return deleted, err
+		}
+		deleted += rows
+	}
+	return deleted, nil
+}
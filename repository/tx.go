@@ -0,0 +1,88 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+)
+
+// Transactor runs a function in a database transaction that the repositories
+// join, so writes made through different repositories commit together.
+type Transactor interface {
+	// WithinTx commits when fn returns nil and rolls back otherwise. The
+	// repositories use the transaction of the context passed to fn.
+	WithinTx(ctx context.Context, fn func(ctx context.Context) error) error
+}
+
+type txKey struct{}
+
+type transactor struct {
+	connection *sql.DB
+}
+
+func NewTransactor(connection *sql.DB) Transactor {
+	return &transactor{connection: connection}
+}
+
+func (t *transactor) WithinTx(ctx context.Context, fn func(ctx context.Context) error) error {
+	if _, ok := ctx.Value(txKey{}).(*sql.Tx); ok {
+		return fn(ctx)
+	}
+
+	tx, err := t.connection.BeginTx(ctx, nil)
+	if err != nil {
+		return queryError(ctx, err)
+	}
+	defer tx.Rollback()
+
+	if err := fn(context.WithValue(ctx, txKey{}, tx)); err != nil {
+		return err
+	}
+	return queryError(ctx, tx.Commit())
+}
+
+// dbtx is the part of *sql.DB and *sql.Tx the repositories query through.
+type dbtx interface {
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row
+	PrepareContext(ctx context.Context, query string) (*sql.Stmt, error)
+}
+
+// conn returns the transaction carried by ctx, or connection when there is
+// none.
+func conn(ctx context.Context, connection *sql.DB) dbtx {
+	if tx, ok := ctx.Value(txKey{}).(*sql.Tx); ok {
+		return tx
+	}
+	return connection
+}
+
+// scopedTx is a transaction opened by a repository method. When ctx already
+// carries a transaction the method joins it, and committing or rolling back
+// is left to its owner.
+type scopedTx struct {
+	*sql.Tx
+	owned bool
+}
+
+func beginTx(ctx context.Context, connection *sql.DB) (scopedTx, error) {
+	if tx, ok := ctx.Value(txKey{}).(*sql.Tx); ok {
+		return scopedTx{Tx: tx}, nil
+	}
+	tx, err := connection.BeginTx(ctx, nil)
+	return scopedTx{Tx: tx, owned: true}, err
+}
+
+func (t scopedTx) Commit() error {
+	if !t.owned {
+		return nil
+	}
+	return t.Tx.Commit()
+}
+
+func (t scopedTx) Rollback() error {
+	if !t.owned {
+		return nil
+	}
+	return t.Tx.Rollback()
+}
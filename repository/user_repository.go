@@ -1,24 +1,48 @@
 package repository
 
 import (
+	"context"
 	"database/sql"
-	"fmt"
+	"errors"
 	"product-go-api/model"
+	"time"
 
+	"github.com/lib/pq"
 	"golang.org/x/crypto/bcrypt"
 )
 
-type UserRepository struct {
-	connection *sql.DB
+// ErrDuplicateEmail is returned when a user is saved with the email of another
+// user, including users in the trash.
+var ErrDuplicateEmail = errors.New("duplicate email")
+
+type UserRepository interface {
+	CreateUser(ctx context.Context, user model.User) (int, error)
+	GetUserByEmail(ctx context.Context, email string) (*model.User, error)
+	GetUserById(ctx context.Context, id_user int) (*model.User, error)
+	DeleteUser(ctx context.Context, id_user int) error
+	UpdateUser(ctx context.Context, user model.User) (*model.User, error)
+	GetUsers(ctx context.Context, page model.PageRequest, name string) (model.Page[model.User], error)
+	GetDeletedUsers(ctx context.Context, page model.PageRequest) (model.Page[model.User], error)
+	GetDeletedUserById(ctx context.Context, id_user int) (*model.User, error)
+	RestoreUser(ctx context.Context, id_user int) (*model.User, error)
+	PurgeUsers(ctx context.Context, deletedBefore time.Time) (int64, error)
 }
 
-func NewUserRepository(connection *sql.DB) UserRepository {
-	return UserRepository{
-		connection: connection,
+type userRepository struct {
+	connection   *sql.DB
+	queryTimeout time.Duration
+}
+
+func NewUserRepository(connection *sql.DB, queryTimeout time.Duration) UserRepository {
+	return &userRepository{
+		connection:   connection,
+		queryTimeout: queryTimeout,
 	}
 }
 
-func (ur *UserRepository) CreateUser(user model.User) (int, error) {
+func (ur *userRepository) CreateUser(ctx context.Context, user model.User) (int, error) {
+	ctx, cancel := context.WithTimeout(ctx, ur.queryTimeout)
+	defer cancel()
 
 	var id int
 
@@ -27,84 +51,116 @@ func (ur *UserRepository) CreateUser(user model.User) (int, error) {
 		return 0, err
 	}
 
-	query, err := ur.connection.Prepare(
-		"INSERT INTO users" + "(username, email, password)" + "VALUES ($1, $2, $3) RETURNING id;",
+	query, err := conn(ctx, ur.connection).PrepareContext(ctx,
+		"INSERT INTO users"+"(username, email, password)"+"VALUES ($1, $2, $3) RETURNING id;",
 	)
 	if err != nil {
-		return 0, err
+		return 0, queryError(ctx, err)
 	}
 
-	err = query.QueryRow(user.Username, user.Email, string(hashedPassword)).Scan(&id)
+	err = query.QueryRowContext(ctx, user.Username, user.Email, string(hashedPassword)).Scan(&id)
 	if err != nil {
-		return 0, err
+		return 0, userWriteError(ctx, err)
 	}
 
 	query.Close()
 	return id, nil
 }
 
-func (ur *UserRepository) GetUserByEmail(email string) (*model.User, error) {
-	query, err := ur.connection.Prepare("SELECT * FROM users WHERE email = $1;")
+func (ur *userRepository) GetUserByEmail(ctx context.Context, email string) (*model.User, error) {
+	ctx, cancel := context.WithTimeout(ctx, ur.queryTimeout)
+	defer cancel()
+
+	query, err := conn(ctx, ur.connection).PrepareContext(ctx, "SELECT id, username, email, password, role FROM users WHERE email = $1 AND deleted_at IS NULL;")
 
 	if err != nil {
-		return nil, err
+		return nil, queryError(ctx, err)
 	}
 
 	var user model.User
 
-	err = query.QueryRow(email).Scan(&user.ID, &user.Username, &user.Email, &user.Password, &user.Role)
+	err = query.QueryRowContext(ctx, email).Scan(&user.ID, &user.Username, &user.Email, &user.Password, &user.Role)
 
 	if err != nil {
 		if err == sql.ErrNoRows {
 			return nil, nil
 		}
 
-		return nil, err
+		return nil, queryError(ctx, err)
 	}
 
 	query.Close()
 	return &user, nil
 }
 
-func (ur *UserRepository) GetUserById(id_user int) (*model.User, error) {
-	query, err := ur.connection.Prepare("SELECT * FROM users WHERE id = $1;")
+func (ur *userRepository) GetUserById(ctx context.Context, id_user int) (*model.User, error) {
+	ctx, cancel := context.WithTimeout(ctx, ur.queryTimeout)
+	defer cancel()
+
+	query, err := conn(ctx, ur.connection).PrepareContext(ctx, "SELECT id, username, email, password, role FROM users WHERE id = $1 AND deleted_at IS NULL;")
 
 	if err != nil {
-		return nil, err
+		return nil, queryError(ctx, err)
 	}
 
 	var user model.User
 
-	err = query.QueryRow(id_user).Scan(&user.ID, &user.Email, &user.Username, &user.Password, &user.Role)
+	err = query.QueryRowContext(ctx, id_user).Scan(&user.ID, &user.Username, &user.Email, &user.Password, &user.Role)
 
 	if err != nil {
 		if err == sql.ErrNoRows {
 			return nil, nil
 		}
 
-		return nil, err
+		return nil, queryError(ctx, err)
 	}
 
 	query.Close()
 	return &user, nil
 }
 
-func (ur *UserRepository) DeleteUser(id_user int) error {
-	query, err := ur.connection.Prepare("DELETE FROM users WHERE id = $1;")
+// DeleteUser moves the user to the trash, releases their active reservations
+// and revokes their tokens, so they can no longer sign in. The access tokens
+// are added to revoked_token before the refresh tokens that record their jti
+// are dropped. Their orders are kept.
+func (ur *userRepository) DeleteUser(ctx context.Context, id_user int) error {
+	ctx, cancel := context.WithTimeout(ctx, ur.queryTimeout)
+	defer cancel()
+
+	tx, err := beginTx(ctx, ur.connection)
 	if err != nil {
-		return err
+		return queryError(ctx, err)
 	}
-	defer query.Close()
+	defer tx.Rollback()
 
-	_, err = query.Exec(id_user)
-	return err
+	if _, err := tx.ExecContext(ctx, "UPDATE users SET deleted_at = NOW() WHERE id = $1 AND deleted_at IS NULL;", id_user); err != nil {
+		return queryError(ctx, err)
+	}
+	if err := releaseUserReservations(ctx, tx, id_user); err != nil {
+		return queryError(ctx, err)
+	}
+	_, err = tx.ExecContext(ctx,
+		`INSERT INTO revoked_token (jti, expires_at)
+		SELECT access_jti, access_expires_at FROM refresh_token WHERE user_id = $1 AND access_expires_at > NOW()
+		ON CONFLICT (jti) DO NOTHING;`, id_user,
+	)
+	if err != nil {
+		return queryError(ctx, err)
+	}
+	if _, err := tx.ExecContext(ctx, "DELETE FROM refresh_token WHERE user_id = $1;", id_user); err != nil {
+		return queryError(ctx, err)
+	}
+	return queryError(ctx, tx.Commit())
 }
 
-func (ur *UserRepository) UpdateUser(user model.User) (*model.User, error) {
+func (ur *userRepository) UpdateUser(ctx context.Context, user model.User) (*model.User, error) {
+	ctx, cancel := context.WithTimeout(ctx, ur.queryTimeout)
+	defer cancel()
+
 	var currentPassword string
-	err := ur.connection.QueryRow("SELECT password FROM users WHERE id = $1", user.ID).Scan(&currentPassword)
+	err := conn(ctx, ur.connection).QueryRowContext(ctx, "SELECT password FROM users WHERE id = $1 AND deleted_at IS NULL", user.ID).Scan(&currentPassword)
 	if err != nil {
-		return nil, err
+		return nil, queryError(ctx, err)
 	}
 
 	passwordToSave := currentPassword
@@ -116,16 +172,16 @@ func (ur *UserRepository) UpdateUser(user model.User) (*model.User, error) {
 		passwordToSave = string(hashedPassword)
 	}
 
-	query, err := ur.connection.Prepare(
+	query, err := conn(ctx, ur.connection).PrepareContext(ctx,
 		"UPDATE users SET username = $2, email = $3, password = $4, role = $5 WHERE id = $1 RETURNING id, username, email, password, role;",
 	)
 	if err != nil {
-		return nil, err
+		return nil, queryError(ctx, err)
 	}
 
 	var updatedUser model.User
 
-	err = query.QueryRow(user.ID, user.Username, user.Email, passwordToSave, user.Role).Scan(
+	err = query.QueryRowContext(ctx, user.ID, user.Username, user.Email, passwordToSave, user.Role).Scan(
 		&updatedUser.ID,
 		&updatedUser.Username,
 		&updatedUser.Email,
@@ -134,53 +190,159 @@ func (ur *UserRepository) UpdateUser(user model.User) (*model.User, error) {
 	)
 
 	if err != nil {
-		return nil, err
+		return nil, userWriteError(ctx, err)
 	}
 
 	query.Close()
 	return &updatedUser, nil
 }
 
-func (ur *UserRepository) GetUsers(page, limit int, name string) ([]model.User, error) {
-	if page < 1 {
-		page = 1
-	}
+func (ur *userRepository) GetUsers(ctx context.Context, page model.PageRequest, name string) (model.Page[model.User], error) {
+	ctx, cancel := context.WithTimeout(ctx, ur.queryTimeout)
+	defer cancel()
 
-	if limit < 1 {
-		limit = 10
-	}
-
-	offset := (page - 1) * limit
-	query := "SELECT id, username, email, role FROM users"
+	conditions := []string{"deleted_at IS NULL"}
 	var args []interface{}
-	argIdx := 1
 
 	if name != "" {
-		query += fmt.Sprintf(" WHERE username ILIKE $%d", argIdx)
+		conditions = append(conditions, "username ILIKE $1")
 		args = append(args, "%"+name+"%")
-		argIdx++
 	}
 
-	query += fmt.Sprintf(" LIMIT $%d OFFSET $%d", argIdx, argIdx+1)
-	args = append(args, limit, offset)
+	return queryPage(ctx, ur.connection, "id, username, email, role", "users", conditions, args, page, "id", func(rows rowScanner) (model.User, error) {
+		var userObj model.User
+		err := rows.Scan(&userObj.ID, &userObj.Username, &userObj.Email, &userObj.Role)
+		return userObj, err
+	})
+}
+
+// userWriteError reports a violation of the unique email index as
+// ErrDuplicateEmail.
+func userWriteError(ctx context.Context, err error) error {
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) && pqErr.Code == "23505" && pqErr.Constraint == "users_email_key" {
+		return ErrDuplicateEmail
+	}
+	return queryError(ctx, err)
+}
+
+const deletedUserColumns = "id, username, email, role, deleted_at"
+
+func scanDeletedUser(row rowScanner) (model.User, error) {
+	var user model.User
+	var deletedAt time.Time
+	err := row.Scan(&user.ID, &user.Username, &user.Email, &user.Role, &deletedAt)
+	user.DeletedAt = &deletedAt
+	return user, err
+}
+
+// GetDeletedUsers lists the users in the trash.
+func (ur *userRepository) GetDeletedUsers(ctx context.Context, page model.PageRequest) (model.Page[model.User], error) {
+	ctx, cancel := context.WithTimeout(ctx, ur.queryTimeout)
+	defer cancel()
+
+	return queryPage(ctx, ur.connection, deletedUserColumns, "users", []string{"deleted_at IS NOT NULL"}, nil, page, "id", scanDeletedUser)
+}
+
+// GetDeletedUserById returns nil when the user is not in the trash.
+func (ur *userRepository) GetDeletedUserById(ctx context.Context, id_user int) (*model.User, error) {
+	ctx, cancel := context.WithTimeout(ctx, ur.queryTimeout)
+	defer cancel()
+
+	user, err := scanDeletedUser(conn(ctx, ur.connection).QueryRowContext(ctx,
+		"SELECT "+deletedUserColumns+" FROM users WHERE id = $1 AND deleted_at IS NOT NULL;", id_user,
+	))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, queryError(ctx, err)
+	}
+	return &user, nil
+}
 
-	rows, err := ur.connection.Query(query, args...)
+// RestoreUser takes a user out of the trash. It returns nil when the user is
+// not in the trash.
+func (ur *userRepository) RestoreUser(ctx context.Context, id_user int) (*model.User, error) {
+	ctx, cancel := context.WithTimeout(ctx, ur.queryTimeout)
+	defer cancel()
 
+	var user model.User
+	err := conn(ctx, ur.connection).QueryRowContext(ctx,
+		"UPDATE users SET deleted_at = NULL WHERE id = $1 AND deleted_at IS NOT NULL RETURNING id, username, email, role;", id_user,
+	).Scan(&user.ID, &user.Username, &user.Email, &user.Role)
 	if err != nil {
-		return []model.User{}, err
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, queryError(ctx, err)
 	}
+	return &user, nil
+}
 
-	var userList []model.User
-	var userObj model.User
+// PurgeUsers permanently deletes the users trashed before deletedBefore. Users
+// with orders are kept in the trash, since orders must keep their customer.
+// Active reservations are released first, as the delete cascades to them.
+func (ur *userRepository) PurgeUsers(ctx context.Context, deletedBefore time.Time) (int64, error) {
+	ctx, cancel := context.WithTimeout(ctx, ur.queryTimeout)
+	defer cancel()
+
+	tx, err := beginTx(ctx, ur.connection)
+	if err != nil {
+		return 0, queryError(ctx, err)
+	}
+	defer tx.Rollback()
 
+	rows, err := tx.QueryContext(ctx,
+		"SELECT u.id FROM users u WHERE u.deleted_at < $1 AND NOT EXISTS (SELECT 1 FROM orders o WHERE o.user_id = u.id) FOR UPDATE;", deletedBefore,
+	)
+	if err != nil {
+		return 0, queryError(ctx, err)
+	}
+	var userIDs []int64
 	for rows.Next() {
-		if err := rows.Scan(&userObj.ID, &userObj.Username, &userObj.Email, &userObj.Role); err != nil {
-			return []model.User{}, err
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			return 0, queryError(ctx, err)
 		}
-		userList = append(userList, userObj)
+		userIDs = append(userIDs, id)
 	}
-
 	rows.Close()
-	return userList, nil
+	if err := rows.Err(); err != nil {
+		return 0, queryError(ctx, err)
+	}
+	if len(userIDs) == 0 {
+		return 0, nil
+	}
+
+	for _, id := range userIDs {
+		if err := releaseUserReservations(ctx, tx, int(id)); err != nil {
+			return 0, queryError(ctx, err)
+		}
+	}
+
+	result, err := tx.ExecContext(ctx, "DELETE FROM users WHERE id = ANY($1);", pq.Array(userIDs))
+	if err != nil {
+		return 0, queryError(ctx, err)
+	}
+	purged, err := result.RowsAffected()
+	if err != nil {
+		return 0, queryError(ctx, err)
+	}
+	return purged, queryError(ctx, tx.Commit())
+}
 
+// releaseUserReservations returns the stock held by the active reservations of
+// a user inside tx. Reserve took that quantity out of the product stock, so it
+// would be lost when the user is deleted and the reservations cascade.
+func releaseUserReservations(ctx context.Context, tx dbtx, id_user int) error {
+	released, err := updateReservations(ctx, tx,
+		"UPDATE stock_reservation SET status = $2 WHERE user_id = $1 AND status = $3 RETURNING id, product_id, quantity;",
+		id_user, model.ReservationReleased, model.ReservationActive,
+	)
+	if err != nil {
+		return err
+	}
+	return returnReservedStock(ctx, tx, released, "user deleted", nil)
 }
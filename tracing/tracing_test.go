@@ -0,0 +1,76 @@
+package tracing
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"product-go-api/config"
+	"strings"
+	"testing"
+
+	"go.opentelemetry.io/otel"
+)
+
+func TestSanitizeSQL(t *testing.T) {
+	tests := []struct {
+		query string
+		want  string
+	}{
+		{
+			query: "SELECT id, product_name FROM product\n\t\tWHERE product_name ILIKE $1 LIMIT $2 OFFSET $3",
+			want:  "SELECT id, product_name FROM product WHERE product_name ILIKE $1 LIMIT $2 OFFSET $3",
+		},
+		{
+			query: "UPDATE users SET password = 'secret''123' WHERE id = 42",
+			want:  "UPDATE users SET password = ? WHERE id = ?",
+		},
+		{
+			query: "SELECT price * 1.5 FROM product2 WHERE id = $10",
+			want:  "SELECT price * ? FROM product2 WHERE id = $10",
+		},
+	}
+
+	for _, tt := range tests {
+		if got := SanitizeSQL(tt.query); got != tt.want {
+			t.Errorf("SanitizeSQL(%q) = %q, want %q", tt.query, got, tt.want)
+		}
+	}
+}
+
+func TestSetupFileExporter(t *testing.T) {
+	previous := otel.GetTracerProvider()
+	t.Cleanup(func() { otel.SetTracerProvider(previous) })
+
+	file := filepath.Join(t.TempDir(), "traces.json")
+	shutdown, err := Setup(context.Background(), config.TracingConfig{
+		Exporter:    "file",
+		File:        file,
+		SampleRatio: 1,
+		ServiceName: "product-go-api-test",
+	})
+	if err != nil {
+		t.Fatalf("Setup: %v", err)
+	}
+
+	_, span := otel.Tracer("test").Start(context.Background(), "GetProducts")
+	span.End()
+	if err := shutdown(context.Background()); err != nil {
+		t.Fatalf("shutdown: %v", err)
+	}
+
+	written, err := os.ReadFile(file)
+	if err != nil {
+		t.Fatalf("read traces: %v", err)
+	}
+	for _, want := range []string{`"Name":"GetProducts"`, "product-go-api-test"} {
+		if !strings.Contains(string(written), want) {
+			t.Errorf("traces file does not contain %s:\n%s", want, written)
+		}
+	}
+}
+
+func TestSetupRejectsUnknownExporter(t *testing.T) {
+	if _, err := Setup(context.Background(), config.TracingConfig{Exporter: "zipkin"}); err == nil {
+		t.Fatal("Setup with an unknown exporter succeeded")
+	}
+}
@@ -0,0 +1,112 @@
+package usecase
+
+import (
+	"context"
+	"encoding/json"
+	"product-go-api/logging"
+	"product-go-api/model"
+	"product-go-api/repository"
+	"reflect"
+)
+
+// auditIgnoredFields change on every write and are left out of audit diffs.
+var auditIgnoredFields = map[string]bool{
+	"updated_at": true,
+}
+
+// auditRedactedFields are recorded as changed without their values.
+var auditRedactedFields = map[string]bool{
+	"password": true,
+}
+
+type AuditUsecase struct {
+	repository repository.AuditRepository
+	transactor repository.Transactor
+}
+
+func NewAuditUsecase(repository repository.AuditRepository, transactor repository.Transactor) AuditUsecase {
+	return AuditUsecase{
+		repository: repository,
+		transactor: transactor,
+	}
+}
+
+// WithinTx runs fn in a transaction. Changes are made and recorded with the
+// context passed to fn, so a change is never committed without its entry.
+func (au *AuditUsecase) WithinTx(ctx context.Context, fn func(ctx context.Context) error) error {
+	return au.transactor.WithinTx(ctx, fn)
+}
+
+// Record saves entry with the fields that differ between before and after,
+// the JSON forms of the target. A nil before records a creation and a nil
+// after records a deletion.
+func (au *AuditUsecase) Record(ctx context.Context, entry model.AuditEntry, before, after any) error {
+	changes, err := auditDiff(before, after)
+	if err != nil {
+		return err
+	}
+	entry.Changes = changes
+	_, err = au.repository.CreateAuditEntry(ctx, entry)
+	return err
+}
+
+func (au *AuditUsecase) GetAuditLog(ctx context.Context, page model.PageRequest, filter model.AuditFilter) (model.Page[model.AuditEntry], error) {
+	return au.repository.GetAuditEntries(ctx, page, filter)
+}
+
+func auditDiff(before, after any) (map[string]model.FieldChange, error) {
+	beforeFields, err := auditFields(before)
+	if err != nil {
+		return nil, err
+	}
+	afterFields, err := auditFields(after)
+	if err != nil {
+		return nil, err
+	}
+
+	changes := map[string]model.FieldChange{}
+	add := func(field string) {
+		if auditIgnoredFields[field] {
+			return
+		}
+		if _, seen := changes[field]; seen {
+			return
+		}
+		beforeValue, afterValue := beforeFields[field], afterFields[field]
+		if reflect.DeepEqual(beforeValue, afterValue) {
+			return
+		}
+		if auditRedactedFields[field] {
+			beforeValue, afterValue = redactedValue(beforeValue), redactedValue(afterValue)
+		}
+		changes[field] = model.FieldChange{Before: beforeValue, After: afterValue}
+	}
+	for field := range beforeFields {
+		add(field)
+	}
+	for field := range afterFields {
+		add(field)
+	}
+	return changes, nil
+}
+
+// auditFields returns the JSON object fields of value, or none when value is
+// nil.
+func auditFields(value any) (map[string]any, error) {
+	fields := map[string]any{}
+	if value == nil || reflect.ValueOf(value).Kind() == reflect.Pointer && reflect.ValueOf(value).IsNil() {
+		return fields, nil
+	}
+	data, err := json.Marshal(value)
+	if err != nil {
+		return nil, err
+	}
+	return fields, json.Unmarshal(data, &fields)
+}
+
+func redactedValue(value any) any {
+	if value == nil {
+		return nil
+	}
+	return logging.Redacted
+}
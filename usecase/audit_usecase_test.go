@@ -0,0 +1,56 @@
+package usecase
+
+import (
+	"context"
+	"product-go-api/logging"
+	"product-go-api/model"
+	"product-go-api/repository/memory"
+	"testing"
+	"time"
+)
+
+func TestAuditUsecase(t *testing.T) {
+	ctx := context.Background()
+	store := memory.NewStore()
+	uc := NewAuditUsecase(store.AuditRepository(), store.Transactor())
+
+	before := model.User{ID: 1, Username: "ana", Email: "ana@example.com", Password: "hash1", Role: "user"}
+	after := before
+	after.Role, after.Password = "admin", "hash2"
+	entry := model.AuditEntry{ActorID: 2, Action: model.AuditUserRoleChange, TargetType: model.AuditTargetUser, TargetID: 1}
+	if err := uc.Record(ctx, entry, before, after); err != nil {
+		t.Fatalf("record: %v", err)
+	}
+
+	// Creations record every field, without the timestamp updated on each
+	// write.
+	product := &model.Product{ID: 3, SKU: "LAMP", Name: "Lamp", Price: 40, UpdatedAt: time.Now()}
+	entry = model.AuditEntry{ActorID: 2, Action: model.AuditProductCreate, TargetType: model.AuditTargetProduct, TargetID: 3}
+	if err := uc.Record(ctx, entry, (*model.Product)(nil), product); err != nil {
+		t.Fatalf("record: %v", err)
+	}
+
+	page, err := uc.GetAuditLog(ctx, model.PageRequest{Page: 1, Limit: 10}, model.AuditFilter{TargetType: model.AuditTargetUser})
+	if err != nil || page.Total != 1 {
+		t.Fatalf("user entries = %+v, %v", page, err)
+	}
+	changes := page.Items[0].Changes
+	if len(changes) != 2 || changes["role"] != (model.FieldChange{Before: "user", After: "admin"}) {
+		t.Fatalf("role change = %+v", changes)
+	}
+	if changes["password"] != (model.FieldChange{Before: logging.Redacted, After: logging.Redacted}) {
+		t.Fatalf("password change = %+v", changes["password"])
+	}
+
+	page, err = uc.GetAuditLog(ctx, model.PageRequest{Page: 1, Limit: 10}, model.AuditFilter{Action: model.AuditProductCreate})
+	if err != nil || page.Total != 1 {
+		t.Fatalf("product entries = %+v, %v", page, err)
+	}
+	changes = page.Items[0].Changes
+	if _, ok := changes["updated_at"]; ok {
+		t.Fatalf("creation recorded updated_at: %+v", changes)
+	}
+	if changes["sku"] != (model.FieldChange{Before: nil, After: "LAMP"}) {
+		t.Fatalf("sku change = %+v", changes["sku"])
+	}
+}
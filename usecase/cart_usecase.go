@@ -0,0 +1,88 @@
+package usecase
+
+import (
+	"context"
+	"product-go-api/apperror"
+	"product-go-api/model"
+	"product-go-api/repository"
+)
+
+var (
+	ErrCartItemNotFound    = apperror.NotFound("Product is not in the cart.")
+	ErrInvalidCartQuantity = apperror.Validation("Quantity must be a positive number.", apperror.Field("quantity", "must be positive"))
+)
+
+type CartUsecase struct {
+	repository        repository.CartRepository
+	productRepository repository.ProductRepository
+	currency          string
+}
+
+// NewCartUsecase returns a CartUsecase whose carts are in currency, the store
+// currency.
+func NewCartUsecase(repository repository.CartRepository, productRepository repository.ProductRepository, currency string) CartUsecase {
+	return CartUsecase{
+		repository:        repository,
+		productRepository: productRepository,
+		currency:          currency,
+	}
+}
+
+func (cu *CartUsecase) GetCart(ctx context.Context, id_user int) (model.Cart, error) {
+	items, err := cu.repository.GetCartItems(ctx, id_user)
+	if err != nil {
+		return model.Cart{}, err
+	}
+
+	cart := model.Cart{UserID: id_user, Items: items, Currency: cu.currency}
+	for _, item := range items {
+		cart.Total += item.Subtotal
+	}
+	return cart, nil
+}
+
+func (cu *CartUsecase) AddItem(ctx context.Context, id_user int, item model.CartItemRequest) (model.Cart, error) {
+	if item.Quantity <= 0 {
+		return model.Cart{}, ErrInvalidCartQuantity
+	}
+
+	product, err := cu.productRepository.GetProductById(ctx, item.ProductID)
+	if err != nil {
+		return model.Cart{}, err
+	}
+	// Draft and archived products are not for sale.
+	if product == nil || product.Status != model.ProductStatusActive {
+		return model.Cart{}, ErrProductNotFound
+	}
+
+	if err := cu.repository.AddItem(ctx, id_user, item); err != nil {
+		return model.Cart{}, err
+	}
+	return cu.GetCart(ctx, id_user)
+}
+
+func (cu *CartUsecase) UpdateItem(ctx context.Context, id_user int, item model.CartItemRequest) (model.Cart, error) {
+	if item.Quantity <= 0 {
+		return model.Cart{}, ErrInvalidCartQuantity
+	}
+
+	found, err := cu.repository.SetItemQuantity(ctx, id_user, item)
+	if err != nil {
+		return model.Cart{}, err
+	}
+	if !found {
+		return model.Cart{}, ErrCartItemNotFound
+	}
+	return cu.GetCart(ctx, id_user)
+}
+
+func (cu *CartUsecase) RemoveItem(ctx context.Context, id_user int, id_product int) (model.Cart, error) {
+	found, err := cu.repository.RemoveItem(ctx, id_user, id_product)
+	if err != nil {
+		return model.Cart{}, err
+	}
+	if !found {
+		return model.Cart{}, ErrCartItemNotFound
+	}
+	return cu.GetCart(ctx, id_user)
+}
@@ -0,0 +1,143 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"product-go-api/apperror"
+	"product-go-api/model"
+	"product-go-api/repository"
+)
+
+var (
+	ErrCategoryNotFound = apperror.NotFound("Category not found")
+	// ErrParentCategoryNotFound is returned when the parent_id of a category
+	// does not exist, which is a problem with the request and not a 404.
+	ErrParentCategoryNotFound = apperror.Validation("Parent category not found.", apperror.Field("parent_id", "category does not exist")).Wrap(ErrCategoryNotFound)
+	ErrCategoryHasChildren    = apperror.Conflict("Category has subcategories and cannot be deleted.").Wrap(repository.ErrCategoryHasChildren)
+	ErrCategoryCycle          = apperror.Validation("A category cannot be moved under itself or one of its subcategories.", apperror.Field("parent_id", "must not be the category or one of its subcategories"))
+)
+
+type CategoryUsecase struct {
+	repository repository.CategoryRepository
+}
+
+func NewCategoryUsecase(repository repository.CategoryRepository) CategoryUsecase {
+	return CategoryUsecase{
+		repository: repository,
+	}
+}
+
+func (cu *CategoryUsecase) GetCategories(ctx context.Context) ([]model.Category, error) {
+	return cu.repository.GetCategories(ctx)
+}
+
+// GetCategoryTree returns the root categories with their subcategories nested
+// under Children.
+func (cu *CategoryUsecase) GetCategoryTree(ctx context.Context) ([]model.Category, error) {
+	categories, err := cu.repository.GetCategories(ctx)
+	if err != nil {
+		return []model.Category{}, err
+	}
+
+	childrenOf := make(map[int][]model.Category)
+	var roots []model.Category
+	for _, category := range categories {
+		if category.ParentID == nil {
+			roots = append(roots, category)
+			continue
+		}
+		childrenOf[*category.ParentID] = append(childrenOf[*category.ParentID], category)
+	}
+
+	var attach func(category model.Category) model.Category
+	attach = func(category model.Category) model.Category {
+		for _, child := range childrenOf[category.ID] {
+			category.Children = append(category.Children, attach(child))
+		}
+		return category
+	}
+
+	tree := make([]model.Category, 0, len(roots))
+	for _, root := range roots {
+		tree = append(tree, attach(root))
+	}
+	return tree, nil
+}
+
+func (cu *CategoryUsecase) GetCategoryById(ctx context.Context, id_category int) (*model.Category, error) {
+	return cu.repository.GetCategoryById(ctx, id_category)
+}
+
+func (cu *CategoryUsecase) CreateCategory(ctx context.Context, category model.Category) (model.Category, error) {
+	if category.ParentID != nil {
+		parent, err := cu.repository.GetCategoryById(ctx, *category.ParentID)
+		if err != nil {
+			return model.Category{}, err
+		}
+		if parent == nil {
+			return model.Category{}, ErrParentCategoryNotFound
+		}
+	}
+
+	categoryId, err := cu.repository.CreateCategory(ctx, category)
+	if err != nil {
+		return model.Category{}, err
+	}
+	category.ID = categoryId
+	return category, nil
+}
+
+func (cu *CategoryUsecase) UpdateCategory(ctx context.Context, category model.Category) (model.Category, error) {
+	if category.ParentID != nil {
+		if *category.ParentID == category.ID {
+			return model.Category{}, ErrCategoryCycle
+		}
+
+		parent, err := cu.repository.GetCategoryById(ctx, *category.ParentID)
+		if err != nil {
+			return model.Category{}, err
+		}
+		if parent == nil {
+			return model.Category{}, ErrParentCategoryNotFound
+		}
+
+		descendants, err := cu.repository.GetDescendantIds(ctx, category.ID)
+		if err != nil {
+			return model.Category{}, err
+		}
+		for _, id := range descendants {
+			if id == *category.ParentID {
+				return model.Category{}, ErrCategoryCycle
+			}
+		}
+	}
+
+	updatedCategory, err := cu.repository.UpdateCategory(ctx, category)
+	if err != nil {
+		return model.Category{}, err
+	}
+	return *updatedCategory, nil
+}
+
+func (cu *CategoryUsecase) DeleteCategory(ctx context.Context, id_category int) error {
+	hasChildren, err := cu.repository.HasChildren(ctx, id_category)
+	if err != nil {
+		return err
+	}
+	if hasChildren {
+		return ErrCategoryHasChildren
+	}
+	// A subcategory may be created between the check and the delete, which the
+	// foreign key then rejects.
+	deleted, err := cu.repository.DeleteCategory(ctx, id_category)
+	if errors.Is(err, repository.ErrCategoryHasChildren) {
+		return ErrCategoryHasChildren
+	}
+	if err != nil {
+		return err
+	}
+	if !deleted {
+		return ErrCategoryNotFound
+	}
+	return nil
+}
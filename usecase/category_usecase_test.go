@@ -0,0 +1,53 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"product-go-api/model"
+	"product-go-api/repository/memory"
+	"testing"
+)
+
+func TestCategoryUsecaseTreeAndCycles(t *testing.T) {
+	ctx := context.Background()
+	store := memory.NewStore()
+	uc := NewCategoryUsecase(store.CategoryRepository())
+
+	root, err := uc.CreateCategory(ctx, model.Category{Name: "Electronics"})
+	if err != nil {
+		t.Fatalf("create root: %v", err)
+	}
+	child, _ := uc.CreateCategory(ctx, model.Category{Name: "Computers", ParentID: intPtr(root.ID)})
+	grandchild, _ := uc.CreateCategory(ctx, model.Category{Name: "Laptops", ParentID: intPtr(child.ID)})
+
+	if _, err := uc.CreateCategory(ctx, model.Category{Name: "Orphan", ParentID: intPtr(999)}); !errors.Is(err, ErrCategoryNotFound) {
+		t.Fatalf("unknown parent: got %v, want ErrCategoryNotFound", err)
+	}
+
+	tree, err := uc.GetCategoryTree(ctx)
+	if err != nil {
+		t.Fatalf("tree: %v", err)
+	}
+	if len(tree) != 1 || len(tree[0].Children) != 1 || len(tree[0].Children[0].Children) != 1 {
+		t.Fatalf("tree = %+v, want a three level chain", tree)
+	}
+
+	root.ParentID = intPtr(grandchild.ID)
+	if _, err := uc.UpdateCategory(ctx, root); !errors.Is(err, ErrCategoryCycle) {
+		t.Fatalf("move under descendant: got %v, want ErrCategoryCycle", err)
+	}
+	root.ParentID = intPtr(root.ID)
+	if _, err := uc.UpdateCategory(ctx, root); !errors.Is(err, ErrCategoryCycle) {
+		t.Fatalf("move under itself: got %v, want ErrCategoryCycle", err)
+	}
+
+	if err := uc.DeleteCategory(ctx, child.ID); !errors.Is(err, ErrCategoryHasChildren) {
+		t.Fatalf("delete with children: got %v, want ErrCategoryHasChildren", err)
+	}
+	if err := uc.DeleteCategory(ctx, grandchild.ID); err != nil {
+		t.Fatalf("delete leaf: %v", err)
+	}
+	if err := uc.DeleteCategory(ctx, grandchild.ID); !errors.Is(err, ErrCategoryNotFound) {
+		t.Fatalf("delete twice: got %v, want ErrCategoryNotFound", err)
+	}
+}
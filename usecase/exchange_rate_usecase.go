@@ -0,0 +1,74 @@
+package usecase
+
+import (
+	"context"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"product-go-api/model"
+	"product-go-api/repository"
+	"regexp"
+	"strings"
+)
+
+// ratePattern matches the rates the NUMERIC(24,12) column holds exactly.
+var ratePattern = regexp.MustCompile(`^[0-9]{1,12}(\.[0-9]{1,12})?$`)
+
+type ExchangeRateUsecase struct {
+	repository repository.ExchangeRateRepository
+}
+
+func NewExchangeRateUsecase(repository repository.ExchangeRateRepository) ExchangeRateUsecase {
+	return ExchangeRateUsecase{repository: repository}
+}
+
+func (eu *ExchangeRateUsecase) GetExchangeRates(ctx context.Context) ([]model.ExchangeRate, error) {
+	return eu.repository.GetExchangeRates(ctx)
+}
+
+// LoadExchangeRates replaces the rate table with the rates of a CSV file of
+// currency,rate lines, with an optional header line. Nothing is loaded when
+// any line is invalid. It returns the number of rates loaded.
+func (eu *ExchangeRateUsecase) LoadExchangeRates(ctx context.Context, file io.Reader) (int, error) {
+	reader := csv.NewReader(file)
+	reader.FieldsPerRecord = 2
+	reader.TrimLeadingSpace = true
+
+	var rates []model.ExchangeRate
+	seen := map[string]bool{}
+	for {
+		record, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return 0, err
+		}
+		line, _ := reader.FieldPos(0)
+		currency, rate := strings.ToUpper(strings.TrimSpace(record[0])), strings.TrimSpace(record[1])
+		if line == 1 && currency == "CURRENCY" {
+			continue
+		}
+
+		if !model.SupportedCurrency(currency) {
+			return 0, fmt.Errorf("line %d: %q is not a supported ISO 4217 currency code", line, record[0])
+		}
+		if seen[currency] {
+			return 0, fmt.Errorf("line %d: duplicate rate for %s", line, currency)
+		}
+		if !ratePattern.MatchString(rate) || strings.Trim(rate, "0.") == "" {
+			return 0, fmt.Errorf("line %d: rate %q must be a positive number with at most 12 decimal places", line, rate)
+		}
+		seen[currency] = true
+		rates = append(rates, model.ExchangeRate{Currency: currency, Rate: rate})
+	}
+	if len(rates) == 0 {
+		return 0, errors.New("no exchange rates in file")
+	}
+
+	if err := eu.repository.ReplaceExchangeRates(ctx, rates); err != nil {
+		return 0, err
+	}
+	return len(rates), nil
+}
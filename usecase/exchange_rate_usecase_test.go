@@ -0,0 +1,92 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"product-go-api/model"
+	"product-go-api/repository/memory"
+	"strings"
+	"testing"
+)
+
+func TestExchangeRateUsecase(t *testing.T) {
+	ctx := context.Background()
+	store := memory.NewStore()
+	uc := NewExchangeRateUsecase(store.ExchangeRateRepository())
+
+	for _, file := range []string{
+		"",
+		"currency,rate\n",
+		"USD,1\nXYZ,2\n",
+		"USD,1\nUSD,1.1\n",
+		"USD,0\n",
+		"USD,-1\n",
+		"USD,1e3\n",
+		"USD,0.0000000000001\n",
+		"USD,1,2\n",
+	} {
+		if _, err := uc.LoadExchangeRates(ctx, strings.NewReader(file)); err == nil {
+			t.Fatalf("load %q: want an error", file)
+		}
+	}
+	if rates, _ := uc.GetExchangeRates(ctx); len(rates) != 0 {
+		t.Fatalf("rates after invalid loads = %v, want none", rates)
+	}
+
+	loaded, err := uc.LoadExchangeRates(ctx, strings.NewReader("currency,rate\nusd, 1\nEUR,0.5\nJPY,150.25\n"))
+	if err != nil || loaded != 3 {
+		t.Fatalf("load = %d, %v", loaded, err)
+	}
+	// A new load replaces every rate.
+	if _, err := uc.LoadExchangeRates(ctx, strings.NewReader("USD,1\nEUR,0.5\n")); err != nil {
+		t.Fatalf("reload: %v", err)
+	}
+	rates, err := uc.GetExchangeRates(ctx)
+	if err != nil || len(rates) != 2 || rates[0].Currency != "EUR" || rates[1].Rate != "1" {
+		t.Fatalf("rates = %+v, %v", rates, err)
+	}
+}
+
+func TestConvertPrices(t *testing.T) {
+	ctx := context.Background()
+	store := memory.NewStore()
+	products := NewProductUsecase(store.ProductRepository(), store.CategoryRepository(), store.ExchangeRateRepository(), store.Transactor(), "EUR")
+	rates := NewExchangeRateUsecase(store.ExchangeRateRepository())
+	if _, err := rates.LoadExchangeRates(ctx, strings.NewReader("USD,1\nEUR,0.8\nJPY,100\n")); err != nil {
+		t.Fatalf("load: %v", err)
+	}
+
+	// 0.05 EUR is 0.0625 USD and 6.25 JPY, rounded half away from zero to
+	// the minor unit of each currency.
+	for _, tt := range []struct {
+		currency string
+		price    model.Money
+		want     string
+	}{
+		{"USD", 5, "0.06"},
+		{"JPY", 5, "6"},
+		{"JPY", 6, "8"},
+		{"USD", 1999, "24.99"},
+		{"", 1999, "19.99"},
+		{"EUR", 1999, "19.99"},
+	} {
+		product := &model.Product{Price: tt.price, Currency: "EUR"}
+		if err := products.ConvertPrices(ctx, tt.currency, product); err != nil {
+			t.Fatalf("convert %v to %q: %v", tt.price, tt.currency, err)
+		}
+		if product.Price.String() != tt.want {
+			t.Fatalf("%v EUR in %q = %v, want %s", tt.price, tt.currency, product.Price, tt.want)
+		}
+	}
+
+	// The price list wins over the rates.
+	product := &model.Product{Price: 1000, Currency: "EUR", PriceList: map[string]model.Money{"USD": 1100}}
+	if err := products.ConvertPrices(ctx, "USD", product); err != nil || product.Price.String() != "11" || product.Currency != "USD" {
+		t.Fatalf("price list conversion = %v %s, %v", product.Price, product.Currency, err)
+	}
+
+	product = &model.Product{Price: 1000, Currency: "EUR"}
+	if err := products.ConvertPrices(ctx, "GBP", product); !errors.Is(err, ErrNoExchangeRate) {
+		t.Fatalf("conversion without a rate = %v, want ErrNoExchangeRate", err)
+	}
+}
@@ -0,0 +1,49 @@
+package usecase
+
+import (
+	"context"
+	"product-go-api/model"
+	"product-go-api/repository/memory"
+	"testing"
+)
+
+func createTestUser(t *testing.T, store *memory.Store, email string) int {
+	t.Helper()
+	ctx := context.Background()
+	id, err := store.UserRepository().CreateUser(ctx, model.User{
+		Username: email,
+		Email:    email,
+		Password: "secret123",
+	})
+	if err != nil {
+		t.Fatalf("create user: %v", err)
+	}
+	return id
+}
+
+// createTestProduct creates a product and receives stock for it through the
+// inventory repository, so the ledger matches the stock level.
+func createTestProduct(t *testing.T, store *memory.Store, name string, price model.Money, stock int) int {
+	t.Helper()
+	ctx := context.Background()
+	id, err := store.ProductRepository().CreateProduct(ctx, model.Product{SKU: name, Name: name, Price: price, Status: model.ProductStatusActive})
+	if err != nil {
+		t.Fatalf("create product: %v", err)
+	}
+	if stock > 0 {
+		_, err := store.InventoryRepository().ApplyMovement(ctx, model.StockMovement{
+			ProductID: id,
+			Type:      model.StockReceive,
+			Quantity:  stock,
+			Reason:    "initial stock",
+		})
+		if err != nil {
+			t.Fatalf("receive stock: %v", err)
+		}
+	}
+	return id
+}
+
+func intPtr(value int) *int {
+	return &value
+}
@@ -0,0 +1,190 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"product-go-api/apperror"
+	"product-go-api/config"
+	"product-go-api/logging"
+	"product-go-api/model"
+	"product-go-api/repository"
+	"time"
+)
+
+var (
+	ErrProductNotFound       = apperror.NotFound("Product not found")
+	ErrReservationNotFound   = apperror.NotFound("Reservation not found")
+	ErrReservationNotActive  = apperror.Conflict("Reservation is no longer active.")
+	ErrReservationLimit      = apperror.Conflict("You cannot reserve more of this product.").Wrap(repository.ErrReservationLimit)
+	ErrReservationForbidden  = apperror.Forbidden("Reservation belongs to another user.")
+	ErrInvalidStockMovement  = apperror.Validation("Type must be one of receive, adjust or write_off.", apperror.Field("type", "must be one of receive, adjust or write_off"))
+	ErrInsufficientStock     = apperror.Conflict("Insufficient stock.").Wrap(repository.ErrInsufficientStock)
+	ErrInvalidStockQuantity  = apperror.Validation("Invalid quantity for this movement type.", apperror.Field("quantity", "is invalid for this movement type"))
+	ErrStockReasonIsRequired = apperror.Validation("Reason is required.", apperror.Field("reason", "is required"))
+)
+
+type InventoryUsecase struct {
+	repository        repository.InventoryRepository
+	productRepository repository.ProductRepository
+	reservationConfig config.ReservationConfig
+	now               func() time.Time
+}
+
+func NewInventoryUsecase(repository repository.InventoryRepository, productRepository repository.ProductRepository, reservationConfig config.ReservationConfig) InventoryUsecase {
+	return InventoryUsecase{
+		repository:        repository,
+		productRepository: productRepository,
+		reservationConfig: reservationConfig,
+		now:               time.Now,
+	}
+}
+
+func (iu *InventoryUsecase) GetStock(ctx context.Context, id_product int) (*model.Stock, error) {
+	return iu.repository.GetStock(ctx, id_product)
+}
+
+// AdjustStock applies a manual stock movement. Receive and write-off take a
+// positive quantity; adjust takes a signed quantity to correct the count.
+func (iu *InventoryUsecase) AdjustStock(ctx context.Context, id_product int, id_user int, req model.StockAdjustmentRequest) (*model.StockMovement, error) {
+	if req.Reason == "" {
+		return nil, ErrStockReasonIsRequired
+	}
+
+	quantity := req.Quantity
+	switch req.Type {
+	case model.StockReceive:
+		if quantity <= 0 {
+			return nil, ErrInvalidStockQuantity
+		}
+	case model.StockWriteOff:
+		if quantity <= 0 {
+			return nil, ErrInvalidStockQuantity
+		}
+		quantity = -quantity
+	case model.StockAdjust:
+		if quantity == 0 {
+			return nil, ErrInvalidStockQuantity
+		}
+	default:
+		return nil, ErrInvalidStockMovement
+	}
+
+	if err := iu.checkProduct(ctx, id_product); err != nil {
+		return nil, err
+	}
+
+	movement, err := iu.repository.ApplyMovement(ctx, model.StockMovement{
+		ProductID: id_product,
+		Type:      req.Type,
+		Quantity:  quantity,
+		Reason:    req.Reason,
+		UserID:    &id_user,
+	})
+	return movement, stockError(err)
+}
+
+// Reserve holds quantity units of a product for the user until the
+// reservation expires. A user can hold at most the configured maximum of each
+// product in active reservations.
+func (iu *InventoryUsecase) Reserve(ctx context.Context, id_product int, id_user int, quantity int) (*model.Reservation, error) {
+	if quantity <= 0 {
+		return nil, ErrInvalidStockQuantity
+	}
+
+	if err := iu.checkProduct(ctx, id_product); err != nil {
+		return nil, err
+	}
+
+	reservation, err := iu.repository.Reserve(ctx, model.Reservation{
+		ProductID: id_product,
+		UserID:    id_user,
+		Quantity:  quantity,
+		ExpiresAt: iu.now().Add(iu.reservationConfig.TTL),
+	}, iu.reservationConfig.MaxQuantity)
+	return reservation, stockError(err)
+}
+
+// Release gives a reservation back to stock. Only the user who made the
+// reservation or, with releaseAny, any user may release it.
+func (iu *InventoryUsecase) Release(ctx context.Context, id_reservation int, id_user int, releaseAny bool) (*model.Reservation, error) {
+	reservation, err := iu.repository.GetReservationById(ctx, id_reservation)
+	if err != nil {
+		return nil, err
+	}
+	if reservation == nil {
+		return nil, ErrReservationNotFound
+	}
+	if reservation.UserID != id_user && !releaseAny {
+		return nil, ErrReservationForbidden
+	}
+
+	released, err := iu.repository.Release(ctx, id_reservation, id_user)
+	if err != nil {
+		return nil, err
+	}
+	if released == nil {
+		return nil, ErrReservationNotActive
+	}
+	return released, nil
+}
+
+// ExpireReservations gives back the stock of the reservations that expired
+// and reports how many there were.
+func (iu *InventoryUsecase) ExpireReservations(ctx context.Context) (int64, error) {
+	expired, err := iu.repository.ExpireReservations(ctx, iu.now())
+	if expired > 0 {
+		logging.FromContext(ctx).InfoContext(ctx, "reservations expired", slog.Int64("reservations", expired))
+	}
+	return expired, err
+}
+
+// RunReservationExpiry expires reservations every interval until ctx is done.
+func (iu *InventoryUsecase) RunReservationExpiry(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if _, err := iu.ExpireReservations(ctx); err != nil && ctx.Err() == nil {
+				logging.FromContext(ctx).ErrorContext(ctx, "failed to expire reservations", slog.String("error", err.Error()))
+			}
+		}
+	}
+}
+
+func (iu *InventoryUsecase) GetMovements(ctx context.Context, id_product, page, limit int) ([]model.StockMovement, error) {
+	return iu.repository.GetMovements(ctx, id_product, page, limit)
+}
+
+func (iu *InventoryUsecase) Reconcile(ctx context.Context, id_product int) (*model.StockReconciliation, error) {
+	return iu.repository.Reconcile(ctx, id_product)
+}
+
+func (iu *InventoryUsecase) checkProduct(ctx context.Context, id_product int) error {
+	product, err := iu.productRepository.GetProductById(ctx, id_product)
+	if err != nil {
+		return err
+	}
+	if product == nil {
+		return ErrProductNotFound
+	}
+	return nil
+}
+
+// stockError replaces the sentinel errors of the inventory and order
+// repositories with their application errors.
+func stockError(err error) error {
+	switch {
+	case errors.Is(err, repository.ErrInsufficientStock):
+		return ErrInsufficientStock
+	case errors.Is(err, repository.ErrReservationLimit):
+		return ErrReservationLimit
+	case errors.Is(err, repository.ErrCartEmpty):
+		return ErrCartEmpty
+	}
+	return err
+}
@@ -0,0 +1,169 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"product-go-api/config"
+	"product-go-api/model"
+	"product-go-api/repository/memory"
+	"testing"
+	"time"
+)
+
+func newTestInventoryUsecase(store *memory.Store) InventoryUsecase {
+	return NewInventoryUsecase(store.InventoryRepository(), store.ProductRepository(), config.ReservationConfig{TTL: 15 * time.Minute, MaxQuantity: 10})
+}
+
+func TestInventoryUsecaseAdjustStock(t *testing.T) {
+	ctx := context.Background()
+	store := memory.NewStore()
+	uc := newTestInventoryUsecase(store)
+	id_user := createTestUser(t, store, "admin@example.com")
+	id_product := createTestProduct(t, store, "Keyboard", 100, 0)
+
+	tests := []struct {
+		name    string
+		req     model.StockAdjustmentRequest
+		want    int
+		wantErr error
+	}{
+		{"receive", model.StockAdjustmentRequest{Type: model.StockReceive, Quantity: 10, Reason: "delivery"}, 10, nil},
+		{"write off", model.StockAdjustmentRequest{Type: model.StockWriteOff, Quantity: 3, Reason: "damaged"}, 7, nil},
+		{"negative adjust", model.StockAdjustmentRequest{Type: model.StockAdjust, Quantity: -2, Reason: "count"}, 5, nil},
+		{"missing reason", model.StockAdjustmentRequest{Type: model.StockReceive, Quantity: 1}, 5, ErrStockReasonIsRequired},
+		{"non positive receive", model.StockAdjustmentRequest{Type: model.StockReceive, Quantity: 0, Reason: "x"}, 5, ErrInvalidStockQuantity},
+		{"zero adjust", model.StockAdjustmentRequest{Type: model.StockAdjust, Quantity: 0, Reason: "x"}, 5, ErrInvalidStockQuantity},
+		{"system type", model.StockAdjustmentRequest{Type: model.StockSale, Quantity: 1, Reason: "x"}, 5, ErrInvalidStockMovement},
+		{"below zero", model.StockAdjustmentRequest{Type: model.StockWriteOff, Quantity: 6, Reason: "lost"}, 5, ErrInsufficientStock},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			movement, err := uc.AdjustStock(ctx, id_product, id_user, tt.req)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("got error %v, want %v", err, tt.wantErr)
+			}
+			if err == nil && movement.BalanceAfter != tt.want {
+				t.Fatalf("balance after = %d, want %d", movement.BalanceAfter, tt.want)
+			}
+			stock, _ := uc.GetStock(ctx, id_product)
+			if stock.Quantity != tt.want {
+				t.Fatalf("stock = %d, want %d", stock.Quantity, tt.want)
+			}
+		})
+	}
+
+	if _, err := uc.AdjustStock(ctx, 999, id_user, model.StockAdjustmentRequest{Type: model.StockReceive, Quantity: 1, Reason: "x"}); !errors.Is(err, ErrProductNotFound) {
+		t.Fatalf("unknown product: got %v, want ErrProductNotFound", err)
+	}
+
+	reconciliation, err := uc.Reconcile(ctx, id_product)
+	if err != nil || !reconciliation.Consistent {
+		t.Fatalf("reconcile = %+v, %v; want consistent", reconciliation, err)
+	}
+}
+
+func TestInventoryUsecaseReserveAndRelease(t *testing.T) {
+	ctx := context.Background()
+	store := memory.NewStore()
+	uc := newTestInventoryUsecase(store)
+	owner := createTestUser(t, store, "owner@example.com")
+	other := createTestUser(t, store, "other@example.com")
+	id_product := createTestProduct(t, store, "Mouse", 50, 5)
+
+	if _, err := uc.Reserve(ctx, id_product, owner, 6); !errors.Is(err, ErrInsufficientStock) {
+		t.Fatalf("over reserve: got %v, want ErrInsufficientStock", err)
+	}
+	if _, err := uc.Reserve(ctx, id_product, owner, 0); !errors.Is(err, ErrInvalidStockQuantity) {
+		t.Fatalf("zero reserve: got %v, want ErrInvalidStockQuantity", err)
+	}
+
+	reservation, err := uc.Reserve(ctx, id_product, owner, 2)
+	if err != nil {
+		t.Fatalf("reserve: %v", err)
+	}
+	if stock, _ := uc.GetStock(ctx, id_product); stock.Quantity != 3 {
+		t.Fatalf("stock after reserve = %d, want 3", stock.Quantity)
+	}
+
+	if _, err := uc.Release(ctx, reservation.ID, other, false); !errors.Is(err, ErrReservationForbidden) {
+		t.Fatalf("release by another user: got %v, want ErrReservationForbidden", err)
+	}
+	if _, err := uc.Release(ctx, 999, owner, false); !errors.Is(err, ErrReservationNotFound) {
+		t.Fatalf("release unknown: got %v, want ErrReservationNotFound", err)
+	}
+
+	released, err := uc.Release(ctx, reservation.ID, owner, false)
+	if err != nil || released.Status != model.ReservationReleased {
+		t.Fatalf("release = %+v, %v; want released", released, err)
+	}
+	if _, err := uc.Release(ctx, reservation.ID, owner, false); !errors.Is(err, ErrReservationNotActive) {
+		t.Fatalf("double release: got %v, want ErrReservationNotActive", err)
+	}
+	if stock, _ := uc.GetStock(ctx, id_product); stock.Quantity != 5 {
+		t.Fatalf("stock after release = %d, want 5", stock.Quantity)
+	}
+
+	movements, err := uc.GetMovements(ctx, id_product, 1, 10)
+	if err != nil {
+		t.Fatalf("movements: %v", err)
+	}
+	if len(movements) != 3 || movements[0].Type != model.StockRelease {
+		t.Fatalf("movements = %+v, want 3 newest first", movements)
+	}
+}
+
+func TestInventoryUsecaseReservationLimitExpiryAndCheckout(t *testing.T) {
+	ctx := context.Background()
+	store := memory.NewStore()
+	uc := NewInventoryUsecase(store.InventoryRepository(), store.ProductRepository(), config.ReservationConfig{TTL: time.Minute, MaxQuantity: 3})
+	cartUC := NewCartUsecase(store.CartRepository(), store.ProductRepository(), model.DefaultCurrency)
+	orderUC := NewOrderUsecase(store.OrderRepository(), model.DefaultCurrency)
+	ana := createTestUser(t, store, "ana@example.com")
+	bob := createTestUser(t, store, "bob@example.com")
+	id_product := createTestProduct(t, store, "Mouse", 50, 5)
+
+	if _, err := uc.Reserve(ctx, id_product, ana, 2); err != nil {
+		t.Fatalf("reserve: %v", err)
+	}
+	if _, err := uc.Reserve(ctx, id_product, ana, 2); !errors.Is(err, ErrReservationLimit) {
+		t.Fatalf("reserve over the limit: got %v, want ErrReservationLimit", err)
+	}
+	// The limit is per user.
+	if _, err := uc.Reserve(ctx, id_product, bob, 3); err != nil {
+		t.Fatalf("reserve by another user: %v", err)
+	}
+	if stock, _ := uc.GetStock(ctx, id_product); stock.Quantity != 0 {
+		t.Fatalf("stock after reserving = %d, want 0", stock.Quantity)
+	}
+
+	// The checkout of a user takes the units they reserved, even with no free
+	// stock left.
+	cartUC.AddItem(ctx, ana, model.CartItemRequest{ProductID: id_product, Quantity: 2})
+	order, err := orderUC.Checkout(ctx, ana)
+	if err != nil || len(order.Items) != 1 {
+		t.Fatalf("checkout with a reservation = %+v, %v", order, err)
+	}
+	if stock, _ := uc.GetStock(ctx, id_product); stock.Quantity != 0 {
+		t.Fatalf("stock after checkout = %d, want 0", stock.Quantity)
+	}
+	// The reservation was consumed, so it no longer counts towards the limit.
+	if _, err := uc.Reserve(ctx, id_product, ana, 3); !errors.Is(err, ErrInsufficientStock) {
+		t.Fatalf("reserve after checkout: got %v, want ErrInsufficientStock", err)
+	}
+
+	if expired, err := uc.ExpireReservations(ctx); err != nil || expired != 0 {
+		t.Fatalf("expire before the TTL = %d, %v; want 0", expired, err)
+	}
+	uc.now = func() time.Time { return time.Now().Add(2 * time.Minute) }
+	if expired, err := uc.ExpireReservations(ctx); err != nil || expired != 1 {
+		t.Fatalf("expire after the TTL = %d, %v; want 1", expired, err)
+	}
+	if stock, _ := uc.GetStock(ctx, id_product); stock.Quantity != 3 {
+		t.Fatalf("stock after expiry = %d, want 3", stock.Quantity)
+	}
+	reconciliation, err := uc.Reconcile(ctx, id_product)
+	if err != nil || !reconciliation.Consistent {
+		t.Fatalf("reconcile = %+v, %v; want consistent", reconciliation, err)
+	}
+}
@@ -0,0 +1,76 @@
+package usecase
+
+import (
+	"context"
+	"product-go-api/apperror"
+	"product-go-api/model"
+	"product-go-api/repository"
+	"slices"
+)
+
+var (
+	ErrCartEmpty               = apperror.Validation("Cart is empty.").Wrap(repository.ErrCartEmpty)
+	ErrOrderNotFound           = apperror.NotFound("Order not found")
+	ErrInvalidOrderStatus      = apperror.Validation("Status must be one of pending, paid, shipped or cancelled.", apperror.Field("status", "must be one of pending, paid, shipped or cancelled"))
+	ErrOrderTransitionRejected = apperror.Conflict("Order cannot move to the requested status.")
+)
+
+type OrderUsecase struct {
+	repository repository.OrderRepository
+	currency   string
+}
+
+// NewOrderUsecase returns an OrderUsecase that places orders in currency, the
+// store currency.
+func NewOrderUsecase(repository repository.OrderRepository, currency string) OrderUsecase {
+	return OrderUsecase{
+		repository: repository,
+		currency:   currency,
+	}
+}
+
+func (ou *OrderUsecase) Checkout(ctx context.Context, id_user int) (*model.Order, error) {
+	order, err := ou.repository.Checkout(ctx, id_user, ou.currency)
+	return order, stockError(err)
+}
+
+func (ou *OrderUsecase) GetOrders(ctx context.Context, page, limit int, id_user int, status string) ([]model.Order, error) {
+	if status != "" {
+		if _, ok := model.OrderTransitions[status]; !ok {
+			return []model.Order{}, ErrInvalidOrderStatus
+		}
+	}
+	return ou.repository.GetOrders(ctx, page, limit, id_user, status)
+}
+
+func (ou *OrderUsecase) GetOrderById(ctx context.Context, id_order int) (*model.Order, error) {
+	return ou.repository.GetOrderById(ctx, id_order)
+}
+
+func (ou *OrderUsecase) UpdateStatus(ctx context.Context, id_order int, status string, id_user int) (*model.Order, error) {
+	if _, ok := model.OrderTransitions[status]; !ok {
+		return nil, ErrInvalidOrderStatus
+	}
+
+	order, err := ou.repository.GetOrderById(ctx, id_order)
+	if err != nil {
+		return nil, err
+	}
+	if order == nil {
+		return nil, ErrOrderNotFound
+	}
+
+	if !slices.Contains(model.OrderTransitions[order.Status], status) {
+		return nil, ErrOrderTransitionRejected
+	}
+
+	updatedOrder, err := ou.repository.UpdateStatus(ctx, id_order, order.Status, status, id_user)
+	if err != nil {
+		return nil, err
+	}
+	if updatedOrder == nil {
+		// The order changed status between the read and the update.
+		return nil, ErrOrderTransitionRejected
+	}
+	return updatedOrder, nil
+}
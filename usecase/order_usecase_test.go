@@ -0,0 +1,131 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"product-go-api/model"
+	"product-go-api/repository/memory"
+	"testing"
+)
+
+func TestCartUsecase(t *testing.T) {
+	ctx := context.Background()
+	store := memory.NewStore()
+	uc := NewCartUsecase(store.CartRepository(), store.ProductRepository(), model.DefaultCurrency)
+	id_user := createTestUser(t, store, "ana@example.com")
+	keyboard := createTestProduct(t, store, "Keyboard", 100, 5)
+	mouse := createTestProduct(t, store, "Mouse", 25, 5)
+
+	if _, err := uc.AddItem(ctx, id_user, model.CartItemRequest{ProductID: keyboard, Quantity: 0}); !errors.Is(err, ErrInvalidCartQuantity) {
+		t.Fatalf("zero quantity: got %v, want ErrInvalidCartQuantity", err)
+	}
+	if _, err := uc.AddItem(ctx, id_user, model.CartItemRequest{ProductID: 999, Quantity: 1}); !errors.Is(err, ErrProductNotFound) {
+		t.Fatalf("unknown product: got %v, want ErrProductNotFound", err)
+	}
+	draft, err := store.ProductRepository().CreateProduct(ctx, model.Product{SKU: "DRAFT", Name: "Draft", Price: 10, Status: model.ProductStatusDraft})
+	if err != nil {
+		t.Fatalf("create draft: %v", err)
+	}
+	if _, err := uc.AddItem(ctx, id_user, model.CartItemRequest{ProductID: draft, Quantity: 1}); !errors.Is(err, ErrProductNotFound) {
+		t.Fatalf("draft product: got %v, want ErrProductNotFound", err)
+	}
+
+	uc.AddItem(ctx, id_user, model.CartItemRequest{ProductID: keyboard, Quantity: 1})
+	uc.AddItem(ctx, id_user, model.CartItemRequest{ProductID: mouse, Quantity: 2})
+	cart, err := uc.AddItem(ctx, id_user, model.CartItemRequest{ProductID: keyboard, Quantity: 1})
+	if err != nil {
+		t.Fatalf("add item: %v", err)
+	}
+	if len(cart.Items) != 2 || cart.Items[0].Quantity != 2 || cart.Total != 250 {
+		t.Fatalf("cart = %+v, want 2 keyboards and 2 mice totalling 250", cart)
+	}
+
+	cart, err = uc.UpdateItem(ctx, id_user, model.CartItemRequest{ProductID: mouse, Quantity: 1})
+	if err != nil || cart.Total != 225 {
+		t.Fatalf("update item = %+v, %v; want total 225", cart, err)
+	}
+	cart, err = uc.RemoveItem(ctx, id_user, keyboard)
+	if err != nil || len(cart.Items) != 1 {
+		t.Fatalf("remove item = %+v, %v; want 1 item", cart, err)
+	}
+	if _, err := uc.RemoveItem(ctx, id_user, keyboard); !errors.Is(err, ErrCartItemNotFound) {
+		t.Fatalf("remove missing item: got %v, want ErrCartItemNotFound", err)
+	}
+	if _, err := uc.UpdateItem(ctx, id_user, model.CartItemRequest{ProductID: keyboard, Quantity: 1}); !errors.Is(err, ErrCartItemNotFound) {
+		t.Fatalf("update missing item: got %v, want ErrCartItemNotFound", err)
+	}
+}
+
+func TestOrderUsecaseCheckoutAndStatus(t *testing.T) {
+	ctx := context.Background()
+	store := memory.NewStore()
+	cartUC := NewCartUsecase(store.CartRepository(), store.ProductRepository(), model.DefaultCurrency)
+	orderUC := NewOrderUsecase(store.OrderRepository(), model.DefaultCurrency)
+	inventoryUC := newTestInventoryUsecase(store)
+	id_user := createTestUser(t, store, "ana@example.com")
+	id_product := createTestProduct(t, store, "Keyboard", 100, 3)
+
+	if _, err := orderUC.Checkout(ctx, id_user); !errors.Is(err, ErrCartEmpty) {
+		t.Fatalf("empty checkout: got %v, want ErrCartEmpty", err)
+	}
+
+	cartUC.AddItem(ctx, id_user, model.CartItemRequest{ProductID: id_product, Quantity: 4})
+	if _, err := orderUC.Checkout(ctx, id_user); !errors.Is(err, ErrInsufficientStock) {
+		t.Fatalf("checkout over stock: got %v, want ErrInsufficientStock", err)
+	}
+	if cart, _ := cartUC.GetCart(ctx, id_user); len(cart.Items) != 1 {
+		t.Fatal("failed checkout cleared the cart")
+	}
+
+	cartUC.UpdateItem(ctx, id_user, model.CartItemRequest{ProductID: id_product, Quantity: 2})
+	// A product archived after it was added to the cart is not sold.
+	lamp := createTestProduct(t, store, "Lamp", 40, 3)
+	cartUC.AddItem(ctx, id_user, model.CartItemRequest{ProductID: lamp, Quantity: 1})
+	archived, _ := store.ProductRepository().GetProductById(ctx, lamp)
+	archived.Status = model.ProductStatusArchived
+	if _, err := store.ProductRepository().UpdateProduct(ctx, *archived); err != nil {
+		t.Fatalf("archive: %v", err)
+	}
+	order, err := orderUC.Checkout(ctx, id_user)
+	if err != nil {
+		t.Fatalf("checkout: %v", err)
+	}
+	if order.Status != model.OrderPending || order.Total != 200 || len(order.Items) != 1 {
+		t.Fatalf("order = %+v, want a pending order totalling 200", order)
+	}
+	if stock, _ := inventoryUC.GetStock(ctx, id_product); stock.Quantity != 1 {
+		t.Fatalf("stock after checkout = %d, want 1", stock.Quantity)
+	}
+	if stock, _ := inventoryUC.GetStock(ctx, lamp); stock.Quantity != 3 {
+		t.Fatalf("archived product stock after checkout = %d, want 3", stock.Quantity)
+	}
+	if cart, _ := cartUC.GetCart(ctx, id_user); len(cart.Items) != 0 {
+		t.Fatal("checkout did not clear the cart")
+	}
+
+	if _, err := orderUC.UpdateStatus(ctx, order.ID, "lost", id_user); !errors.Is(err, ErrInvalidOrderStatus) {
+		t.Fatalf("unknown status: got %v, want ErrInvalidOrderStatus", err)
+	}
+	if _, err := orderUC.UpdateStatus(ctx, order.ID, model.OrderShipped, id_user); !errors.Is(err, ErrOrderTransitionRejected) {
+		t.Fatalf("pending to shipped: got %v, want ErrOrderTransitionRejected", err)
+	}
+	if _, err := orderUC.UpdateStatus(ctx, 999, model.OrderPaid, id_user); !errors.Is(err, ErrOrderNotFound) {
+		t.Fatalf("unknown order: got %v, want ErrOrderNotFound", err)
+	}
+
+	cancelled, err := orderUC.UpdateStatus(ctx, order.ID, model.OrderCancelled, id_user)
+	if err != nil || cancelled.Status != model.OrderCancelled {
+		t.Fatalf("cancel = %+v, %v; want cancelled", cancelled, err)
+	}
+	if stock, _ := inventoryUC.GetStock(ctx, id_product); stock.Quantity != 3 {
+		t.Fatalf("stock after cancel = %d, want 3", stock.Quantity)
+	}
+
+	orders, err := orderUC.GetOrders(ctx, 1, 10, id_user, model.OrderCancelled)
+	if err != nil || len(orders) != 1 {
+		t.Fatalf("orders = %+v, %v; want 1 cancelled order", orders, err)
+	}
+	if _, err := orderUC.GetOrders(ctx, 1, 10, 0, "lost"); !errors.Is(err, ErrInvalidOrderStatus) {
+		t.Fatalf("filter by unknown status: got %v, want ErrInvalidOrderStatus", err)
+	}
+}
@@ -0,0 +1,53 @@
+package usecase
+
+import (
+	"context"
+	"log/slog"
+	"product-go-api/logging"
+	"product-go-api/repository"
+	"time"
+)
+
+// PriceScheduleUsecase makes scheduled prices the price of their product once
+// their effective date is reached.
+type PriceScheduleUsecase struct {
+	productRepository repository.ProductRepository
+	now               func() time.Time
+}
+
+func NewPriceScheduleUsecase(productRepository repository.ProductRepository) PriceScheduleUsecase {
+	return PriceScheduleUsecase{
+		productRepository: productRepository,
+		now:               time.Now,
+	}
+}
+
+// ApplyDuePrices applies the prices whose effective date has passed and
+// reports how many products changed price.
+func (pu *PriceScheduleUsecase) ApplyDuePrices(ctx context.Context) (int64, error) {
+	applied, err := pu.productRepository.ApplyScheduledPrices(ctx, pu.now())
+	if err != nil {
+		return 0, err
+	}
+	if applied > 0 {
+		logging.FromContext(ctx).InfoContext(ctx, "scheduled prices applied", slog.Int64("products", applied))
+	}
+	return applied, nil
+}
+
+// Run applies due prices every interval until ctx is done.
+func (pu *PriceScheduleUsecase) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if _, err := pu.ApplyDuePrices(ctx); err != nil && ctx.Err() == nil {
+				logging.FromContext(ctx).ErrorContext(ctx, "failed to apply scheduled prices", slog.String("error", err.Error()))
+			}
+		}
+	}
+}
@@ -0,0 +1,65 @@
+package usecase
+
+import (
+	"context"
+	"product-go-api/model"
+	"product-go-api/repository/memory"
+	"testing"
+	"time"
+)
+
+func TestPriceScheduleUsecase(t *testing.T) {
+	ctx := context.Background()
+	store := memory.NewStore()
+	products := store.ProductRepository()
+	uc := NewPriceScheduleUsecase(products)
+
+	lamp := createTestProduct(t, store, "Lamp", 40, 0)
+	chair := createTestProduct(t, store, "Chair", 80, 0)
+	now := time.Now()
+	for _, price := range []model.ProductPrice{
+		{ProductID: lamp, Price: 35, EffectiveFrom: now.Add(time.Hour)},
+		{ProductID: lamp, Price: 30, EffectiveFrom: now.Add(2 * time.Hour)},
+		{ProductID: chair, Price: 70, EffectiveFrom: now.Add(3 * time.Hour)},
+	} {
+		if _, err := products.SchedulePrice(ctx, price); err != nil {
+			t.Fatalf("schedule price: %v", err)
+		}
+	}
+
+	if applied, err := uc.ApplyDuePrices(ctx); err != nil || applied != 0 {
+		t.Fatalf("apply before any date = %d, %v; want nothing applied", applied, err)
+	}
+
+	// The latest price that is due wins, even when earlier ones were never
+	// applied.
+	uc.now = func() time.Time { return now.Add(150 * time.Minute) }
+	if applied, err := uc.ApplyDuePrices(ctx); err != nil || applied != 1 {
+		t.Fatalf("apply = %d, %v; want 1", applied, err)
+	}
+	if product, _ := products.GetProductById(ctx, lamp); product.Price != 30 {
+		t.Fatalf("lamp price = %v, want 30", product.Price)
+	}
+	if product, _ := products.GetProductById(ctx, chair); product.Price != 80 {
+		t.Fatalf("chair price = %v, want 80", product.Price)
+	}
+	if applied, err := uc.ApplyDuePrices(ctx); err != nil || applied != 0 {
+		t.Fatalf("second apply = %d, %v; want nothing applied", applied, err)
+	}
+
+	// Products in the trash keep their price until they are restored.
+	products.DeleteProduct(ctx, chair)
+	uc.now = func() time.Time { return now.Add(4 * time.Hour) }
+	if applied, err := uc.ApplyDuePrices(ctx); err != nil || applied != 0 {
+		t.Fatalf("apply to a trashed product = %d, %v; want nothing applied", applied, err)
+	}
+	products.RestoreProduct(ctx, chair)
+	if applied, err := uc.ApplyDuePrices(ctx); err != nil || applied != 1 {
+		t.Fatalf("apply after restore = %d, %v; want 1", applied, err)
+	}
+
+	history, err := products.GetPriceHistory(ctx, lamp, model.PageRequest{Page: 1, Limit: 10})
+	if err != nil || history.Total != 3 || history.Items[0].Price != 30 || history.Items[2].Price != 40 {
+		t.Fatalf("lamp price history = %+v, %v", history, err)
+	}
+}
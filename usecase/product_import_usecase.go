@@ -0,0 +1,164 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"product-go-api/apperror"
+	"product-go-api/logging"
+	"product-go-api/model"
+	"product-go-api/repository"
+)
+
+// errDryRunRollback makes the transaction of a dry run import roll back once
+// its rows are checked.
+var errDryRunRollback = errors.New("dry run import rolled back")
+
+// ImportProducts creates the rows whose SKU is new and updates the rows whose
+// SKU exists. Every row is checked before any is saved: rows that failed to
+// parse, break the product rules or repeat the SKU of an earlier row are
+// reported as failed and skipped. The other rows are saved in batches of
+// options.BatchSize rows, each in its own transaction, or all in one when it
+// is zero; the transactions of a dry run are always rolled back. A row that
+// fails to save only fails itself, but an error of the database or of
+// options.Audit aborts the import; batches saved before it stay saved.
+func (pu *ProductUsecase) ImportProducts(ctx context.Context, rows []model.ProductImportRow, options model.ImportOptions) (model.ImportReport, error) {
+	report := model.ImportReport{DryRun: options.DryRun, Rows: make([]model.ImportRowResult, len(rows))}
+	categories := map[int]bool{}
+	skuLines := map[string]int{}
+	var pending []int
+	for i, row := range rows {
+		report.Rows[i] = model.ImportRowResult{Line: row.Line, SKU: row.Product.SKU, Errors: row.Errors}
+		if len(row.Errors) == 0 {
+			fields, err := pu.checkImportRow(ctx, row, categories, skuLines)
+			if err != nil {
+				return model.ImportReport{}, err
+			}
+			report.Rows[i].Errors = fields
+		}
+		if len(report.Rows[i].Errors) > 0 {
+			report.Rows[i].Status = model.ImportFailed
+			continue
+		}
+		pending = append(pending, i)
+	}
+
+	batchSize := options.BatchSize
+	if batchSize <= 0 {
+		batchSize = len(pending)
+	}
+	for start := 0; start < len(pending); start += batchSize {
+		batch := pending[start:min(start+batchSize, len(pending))]
+		products := make([]model.Product, len(batch))
+		for j, i := range batch {
+			products[j] = rows[i].Product
+		}
+		save := func(ctx context.Context) error {
+			results, err := pu.repository.ImportProducts(ctx, products, options.DryRun)
+			if err != nil {
+				return err
+			}
+			for j, i := range batch {
+				pu.importResult(ctx, &report.Rows[i], results[j], options.DryRun)
+				if options.Audit != nil && report.Rows[i].Status != model.ImportFailed {
+					if err := options.Audit(ctx, report.Rows[i]); err != nil {
+						return err
+					}
+				}
+			}
+			return nil
+		}
+		err := pu.transactor.WithinTx(ctx, func(ctx context.Context) error {
+			if err := save(ctx); err != nil {
+				return err
+			}
+			if options.DryRun {
+				return errDryRunRollback
+			}
+			return nil
+		})
+		if err != nil && !errors.Is(err, errDryRunRollback) {
+			return model.ImportReport{}, err
+		}
+	}
+
+	for _, row := range report.Rows {
+		switch row.Status {
+		case model.ImportCreated:
+			report.Created++
+		case model.ImportUpdated:
+			report.Updated++
+		default:
+			report.Failed++
+		}
+	}
+	return report, nil
+}
+
+// checkImportRow applies the rules of product writes to row and returns the
+// fields it breaks. categories caches the categories already looked up, and
+// skuLines the line of the first row of each SKU.
+func (pu *ProductUsecase) checkImportRow(ctx context.Context, row model.ProductImportRow, categories map[int]bool, skuLines map[string]int) ([]model.FieldError, error) {
+	product := row.Product
+	if line, ok := skuLines[product.SKU]; ok {
+		return []model.FieldError{apperror.Field("sku", fmt.Sprintf("repeats the sku of line %d", line))}, nil
+	}
+	skuLines[product.SKU] = row.Line
+
+	var appErr *apperror.Error
+	if err := pu.checkPrices(product); errors.As(err, &appErr) {
+		return appErr.Fields, nil
+	}
+	if product.CategoryID != nil {
+		exists, ok := categories[*product.CategoryID]
+		if !ok {
+			category, err := pu.categoryRepository.GetCategoryById(ctx, *product.CategoryID)
+			if err != nil {
+				return nil, err
+			}
+			exists = category != nil
+			categories[*product.CategoryID] = exists
+		}
+		if !exists {
+			return ErrProductCategoryNotFound.Fields, nil
+		}
+	}
+	return nil, nil
+}
+
+// importResult fills row with the outcome of saving it.
+func (pu *ProductUsecase) importResult(ctx context.Context, row *model.ImportRowResult, result repository.ImportResult, dryRun bool) {
+	switch {
+	case errors.Is(result.Err, repository.ErrDuplicateSKU):
+		row.Status, row.Errors = model.ImportFailed, []model.FieldError{apperror.Field("sku", "already exists")}
+		return
+	case errors.Is(result.Err, repository.ErrSKUInTrash):
+		row.Status, row.Errors = model.ImportFailed, []model.FieldError{apperror.Field("sku", "belongs to a product in the trash, restore it to update it")}
+		return
+	case result.Err != nil:
+		logging.FromContext(ctx).ErrorContext(ctx, "failed to import product",
+			slog.Int("line", row.Line),
+			slog.String("sku", row.SKU),
+			slog.String("error", result.Err.Error()),
+		)
+		row.Status, row.Errors = model.ImportFailed, []model.FieldError{apperror.Field("product", "could not be saved")}
+		return
+	}
+
+	after := result.After
+	after.Currency = pu.currency
+	row.After = &after
+	if result.Before == nil {
+		row.Status = model.ImportCreated
+		// Products created by a dry run were rolled back, so their IDs do not
+		// exist.
+		if !dryRun {
+			row.ProductID = after.ID
+		}
+		return
+	}
+	before := *result.Before
+	before.Currency = pu.currency
+	row.Status, row.Before, row.ProductID = model.ImportUpdated, &before, after.ID
+}
@@ -0,0 +1,132 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"product-go-api/model"
+	"product-go-api/repository"
+	"product-go-api/repository/memory"
+	"slices"
+	"testing"
+)
+
+// batchRecorder records the number of products of each ImportProducts call.
+type batchRecorder struct {
+	repository.ProductRepository
+	batches []int
+}
+
+func (b *batchRecorder) ImportProducts(ctx context.Context, products []model.Product, dryRun bool) ([]repository.ImportResult, error) {
+	b.batches = append(b.batches, len(products))
+	return b.ProductRepository.ImportProducts(ctx, products, dryRun)
+}
+
+// txRecorder records whether each transaction committed.
+type txRecorder struct {
+	repository.Transactor
+	committed []bool
+}
+
+func (r *txRecorder) WithinTx(ctx context.Context, fn func(ctx context.Context) error) error {
+	err := r.Transactor.WithinTx(ctx, fn)
+	r.committed = append(r.committed, err == nil)
+	return err
+}
+
+func TestImportProducts(t *testing.T) {
+	ctx := context.Background()
+	store := memory.NewStore()
+	products := &batchRecorder{ProductRepository: store.ProductRepository()}
+	transactor := &txRecorder{Transactor: store.Transactor()}
+	uc := NewProductUsecase(products, store.CategoryRepository(), store.ExchangeRateRepository(), transactor, model.DefaultCurrency)
+	lamp := createTestProduct(t, store, "LAMP", 4000, 0)
+
+	rows := []model.ProductImportRow{
+		{Line: 2, Product: model.Product{SKU: "LAMP", Name: "Desk lamp", Price: 4550}},
+		{Line: 3, Product: model.Product{SKU: "PEN", Name: "Pen", Price: 10, PriceList: map[string]model.Money{"EUR": 9}}},
+		{Line: 4, Product: model.Product{SKU: "CUP", Name: "Cup", Price: 500}},
+		{Line: 5, Product: model.Product{SKU: "PEN", Name: "Pen again", Price: 20}},
+		{Line: 6, Product: model.Product{SKU: "YEN", Name: "Yen", Price: 100, PriceList: map[string]model.Money{"JPY": 150}}},
+		{Line: 7, Product: model.Product{SKU: "BOX", Name: "Box", Price: 100, CategoryID: intPtr(999)}},
+		{Line: 8, Product: model.Product{SKU: "BAD"}, Errors: []model.FieldError{{Field: "name", Message: "is required"}}},
+	}
+
+	report, err := uc.ImportProducts(ctx, rows, model.ImportOptions{DryRun: true})
+	if err != nil {
+		t.Fatalf("dry run: %v", err)
+	}
+	if report.Created != 2 || report.Updated != 1 || report.Failed != 4 || !slices.Equal(products.batches, []int{3}) {
+		t.Fatalf("dry run report = %+v, batches %v", report, products.batches)
+	}
+	if !slices.Equal(transactor.committed, []bool{false}) {
+		t.Fatalf("dry run transactions committed = %v, want rolled back", transactor.committed)
+	}
+	if report.Rows[1].ProductID != 0 || report.Rows[0].ProductID != lamp {
+		t.Fatalf("dry run product IDs = %d, %d", report.Rows[0].ProductID, report.Rows[1].ProductID)
+	}
+	if product, _ := uc.GetProductById(ctx, lamp); product.Name != "LAMP" {
+		t.Fatalf("dry run updated the lamp: %+v", product)
+	}
+
+	products.batches, transactor.committed = nil, nil
+	var audited []string
+	report, err = uc.ImportProducts(ctx, rows, model.ImportOptions{BatchSize: 2, Audit: func(ctx context.Context, row model.ImportRowResult) error {
+		audited = append(audited, row.SKU)
+		return nil
+	}})
+	if err != nil {
+		t.Fatalf("import: %v", err)
+	}
+	if !slices.Equal(products.batches, []int{2, 1}) || !slices.Equal(transactor.committed, []bool{true, true}) {
+		t.Fatalf("batches = %v, committed %v, want [2 1] committed", products.batches, transactor.committed)
+	}
+	if !slices.Equal(audited, []string{"LAMP", "PEN", "CUP"}) {
+		t.Fatalf("audited rows = %v, want the saved rows", audited)
+	}
+	failed := map[int]string{}
+	for _, row := range report.Rows {
+		if row.Status == model.ImportFailed {
+			failed[row.Line] = row.Errors[0].Field
+		}
+	}
+	want := map[int]string{5: "sku", 6: "price_list[JPY]", 7: "category_id", 8: "name"}
+	if len(failed) != len(want) {
+		t.Fatalf("failed rows = %v, want %v", failed, want)
+	}
+	for line, field := range want {
+		if failed[line] != field {
+			t.Fatalf("failed rows = %v, want %v", failed, want)
+		}
+	}
+
+	pen, _ := uc.GetProductById(ctx, report.Rows[1].ProductID)
+	if pen == nil || pen.Status != model.ProductStatusActive || pen.PriceList["EUR"] != 9 {
+		t.Fatalf("imported pen = %+v", pen)
+	}
+	updated, _ := uc.GetProductById(ctx, lamp)
+	if updated.Name != "Desk lamp" || updated.Price != 4550 {
+		t.Fatalf("imported lamp = %+v", updated)
+	}
+	prices, _ := uc.GetPriceHistory(ctx, lamp, model.PageRequest{Page: 1, Limit: 10})
+	if prices.Total != 2 {
+		t.Fatalf("price history of the lamp = %+v, want the new price added", prices.Items)
+	}
+
+	// An audit entry that fails to save aborts the import.
+	auditErr := errors.New("audit log unavailable")
+	_, err = uc.ImportProducts(ctx, rows[2:3], model.ImportOptions{Audit: func(ctx context.Context, row model.ImportRowResult) error {
+		return auditErr
+	}})
+	if !errors.Is(err, auditErr) {
+		t.Fatalf("import with a failing audit: got %v, want the audit error", err)
+	}
+
+	// A SKU in the trash is neither updated nor restored.
+	if err := uc.DeleteProduct(ctx, lamp); err != nil {
+		t.Fatalf("delete: %v", err)
+	}
+	report, err = uc.ImportProducts(ctx, rows[:1], model.ImportOptions{})
+	if err != nil || report.Failed != 1 || report.Rows[0].Errors[0].Field != "sku" {
+		t.Fatalf("import of a trashed sku = %+v, %v", report, err)
+	}
+}
@@ -1,54 +1,297 @@
 package usecase
 
 import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+	"product-go-api/apperror"
 	"product-go-api/model"
 	"product-go-api/repository"
+	"time"
 )
 
+// ErrProductCategoryNotFound is returned when the category_id of a product
+// does not exist.
+var ErrProductCategoryNotFound = apperror.Validation("Category not found.", apperror.Field("category_id", "category does not exist")).Wrap(ErrCategoryNotFound)
+
+// ErrSKUExists is returned when a product is saved with the SKU of another
+// product.
+var ErrSKUExists = apperror.Conflict("A product with this SKU already exists.").Wrap(repository.ErrDuplicateSKU)
+
+// ErrProductNotInTrash is returned when restoring a product that is not in
+// the trash.
+var ErrProductNotInTrash = apperror.NotFound("Product not found in the trash.")
+
+// ErrPriceNotInFuture is returned when a price is scheduled for a date that
+// has already passed. Immediate changes go through product updates.
+var ErrPriceNotInFuture = apperror.Validation("Scheduled prices must take effect in the future.", apperror.Field("effective_from", "must be in the future"))
+
+// ErrEmptySearch is returned when a search query has no words to match.
+var ErrEmptySearch = apperror.Validation("Search query must contain letters or digits.", apperror.Field("q", "must contain letters or digits"))
+
+// ErrNoExchangeRate is returned when prices are requested in a currency that
+// is missing from the exchange rates, for a product without a price in it.
+var ErrNoExchangeRate = apperror.Validation("No exchange rate is loaded for this currency.", apperror.Field("currency", "has no exchange rate"))
+
 type ProductUsecase struct {
-	repository repository.ProductRepository
+	repository             repository.ProductRepository
+	categoryRepository     repository.CategoryRepository
+	exchangeRateRepository repository.ExchangeRateRepository
+	transactor             repository.Transactor
+	currency               string
 }
 
-func NewProductUsecase(repository repository.ProductRepository) ProductUsecase {
+// NewProductUsecase returns a ProductUsecase whose prices are in currency,
+// the store currency. Import batches are saved through transactor.
+func NewProductUsecase(repository repository.ProductRepository, categoryRepository repository.CategoryRepository, exchangeRateRepository repository.ExchangeRateRepository, transactor repository.Transactor, currency string) ProductUsecase {
 	return ProductUsecase{
-		repository: repository,
+		repository:             repository,
+		categoryRepository:     categoryRepository,
+		exchangeRateRepository: exchangeRateRepository,
+		transactor:             transactor,
+		currency:               currency,
 	}
 }
 
-func (pu *ProductUsecase) GetProducts(page, limit int, name string) ([]model.Product, error) {
-	return pu.repository.GetProducts(page, limit, name)
+func (pu *ProductUsecase) GetProducts(ctx context.Context, page model.PageRequest, filter model.ProductFilter) (model.Page[model.Product], error) {
+	products, err := pu.repository.GetProducts(ctx, page, filter)
+	for i := range products.Items {
+		products.Items[i].Currency = pu.currency
+	}
+	return products, err
+}
+
+func (pu *ProductUsecase) SearchProducts(ctx context.Context, page model.PageRequest, search model.ProductSearch) (model.Page[model.ProductSearchResult], error) {
+	if len(repository.SearchTerms(search.Query)) == 0 {
+		return model.Page[model.ProductSearchResult]{}, ErrEmptySearch
+	}
+	results, err := pu.repository.SearchProducts(ctx, page, search)
+	for i := range results.Items {
+		results.Items[i].Product.Currency = pu.currency
+	}
+	return results, err
 }
 
-func (pu *ProductUsecase) CreateProduct(product model.Product) (model.Product, error) {
-	productId, err := pu.repository.CreateProduct(product)
+func (pu *ProductUsecase) CreateProduct(ctx context.Context, product model.Product) (model.Product, error) {
+	if err := pu.checkPrices(product); err != nil {
+		return model.Product{}, err
+	}
+	if err := pu.checkCategory(ctx, product.CategoryID); err != nil {
+		return model.Product{}, err
+	}
+	if product.Status == "" {
+		product.Status = model.ProductStatusActive
+	}
+
+	productId, err := pu.repository.CreateProduct(ctx, product)
+	if err != nil {
+		return model.Product{}, productError(err)
+	}
+
+	// Read the product back for the values set by the database.
+	created, err := pu.repository.GetProductById(ctx, productId)
 	if err != nil {
 		return model.Product{}, err
 	}
-	product.ID = productId
-	return product, nil
+	if created == nil {
+		return model.Product{}, ErrProductNotFound
+	}
+	created.Currency = pu.currency
+	return *created, nil
 }
 
-func (pu *ProductUsecase) GetProductById(id_product int) (*model.Product, error) {
-	product, err := pu.repository.GetProductById(id_product)
+func (pu *ProductUsecase) GetProductById(ctx context.Context, id_product int) (*model.Product, error) {
+	product, err := pu.repository.GetProductById(ctx, id_product)
 	if err != nil {
 		return nil, err
 	}
 
+	if product != nil {
+		product.Currency = pu.currency
+	}
 	return product, nil
 }
 
-func (pu *ProductUsecase) DeleteProduct(id_product int) error {
-	err := pu.repository.DeleteProduct(id_product)
+func (pu *ProductUsecase) DeleteProduct(ctx context.Context, id_product int) error {
+	deleted, err := pu.repository.DeleteProduct(ctx, id_product)
 	if err != nil {
 		return err
 	}
+	if !deleted {
+		return ErrProductNotFound
+	}
 	return nil
 }
 
-func (pu *ProductUsecase) UpdateProduct(product model.Product) (model.Product, error) {
-	updatedProduct, err := pu.repository.UpdateProduct(product)
-	if err != nil {
+func (pu *ProductUsecase) UpdateProduct(ctx context.Context, product model.Product) (model.Product, error) {
+	if err := pu.checkPrices(product); err != nil {
+		return model.Product{}, err
+	}
+	if err := pu.checkCategory(ctx, product.CategoryID); err != nil {
 		return model.Product{}, err
 	}
+
+	updatedProduct, err := pu.repository.UpdateProduct(ctx, product)
+	if err != nil {
+		return model.Product{}, productError(err)
+	}
+	if updatedProduct == nil {
+		return model.Product{}, ErrProductNotFound
+	}
+	updatedProduct.Currency = pu.currency
 	return *updatedProduct, nil
 }
+
+func (pu *ProductUsecase) GetDeletedProducts(ctx context.Context, page model.PageRequest) (model.Page[model.Product], error) {
+	products, err := pu.repository.GetDeletedProducts(ctx, page)
+	for i := range products.Items {
+		products.Items[i].Currency = pu.currency
+	}
+	return products, err
+}
+
+func (pu *ProductUsecase) GetDeletedProductById(ctx context.Context, id_product int) (*model.Product, error) {
+	product, err := pu.repository.GetDeletedProductById(ctx, id_product)
+	if product != nil {
+		product.Currency = pu.currency
+	}
+	return product, err
+}
+
+func (pu *ProductUsecase) RestoreProduct(ctx context.Context, id_product int) (model.Product, error) {
+	product, err := pu.repository.RestoreProduct(ctx, id_product)
+	if err != nil {
+		return model.Product{}, err
+	}
+	if product == nil {
+		return model.Product{}, ErrProductNotInTrash
+	}
+	product.Currency = pu.currency
+	return *product, nil
+}
+
+func (pu *ProductUsecase) GetPriceHistory(ctx context.Context, id_product int, page model.PageRequest) (model.Page[model.ProductPrice], error) {
+	prices, err := pu.repository.GetPriceHistory(ctx, id_product, page)
+	for i := range prices.Items {
+		prices.Items[i].Currency = pu.currency
+	}
+	return prices, err
+}
+
+// SchedulePrice schedules a price change of a product for a future date.
+func (pu *ProductUsecase) SchedulePrice(ctx context.Context, price model.ProductPrice) (model.ProductPrice, error) {
+	if !price.EffectiveFrom.After(time.Now()) {
+		return model.ProductPrice{}, ErrPriceNotInFuture
+	}
+	if !model.FitsCurrency(price.Price, pu.currency) {
+		return model.ProductPrice{}, pu.priceError("price", pu.currency)
+	}
+	product, err := pu.repository.GetProductById(ctx, price.ProductID)
+	if err != nil {
+		return model.ProductPrice{}, err
+	}
+	if product == nil {
+		return model.ProductPrice{}, ErrProductNotFound
+	}
+	scheduled, err := pu.repository.SchedulePrice(ctx, price)
+	scheduled.Currency = pu.currency
+	return scheduled, err
+}
+
+// ConvertPrices sets the prices of products in currency. A product uses its
+// price in that currency from its price list when it has one, and otherwise
+// its store price converted with the exchange rates, rounded half away from
+// zero to the minor unit of the currency. An empty currency keeps the store
+// prices.
+func (pu *ProductUsecase) ConvertPrices(ctx context.Context, currency string, products ...*model.Product) error {
+	if currency == "" || currency == pu.currency {
+		return nil
+	}
+
+	var rates map[string]*big.Rat
+	for _, product := range products {
+		if price, ok := product.PriceList[currency]; ok {
+			product.Price, product.Currency = price, currency
+			continue
+		}
+
+		if rates == nil {
+			var err error
+			if rates, err = pu.exchangeRates(ctx); err != nil {
+				return err
+			}
+		}
+		from, to := rates[pu.currency], rates[currency]
+		if from == nil || to == nil {
+			return ErrNoExchangeRate
+		}
+		converted := new(big.Rat).Mul(product.Price.Rat(), to)
+		converted.Quo(converted, from)
+		product.Price, product.Currency = model.RoundMoney(converted, model.CurrencyDigits(currency)), currency
+	}
+	return nil
+}
+
+// exchangeRates returns the loaded exchange rates by currency.
+func (pu *ProductUsecase) exchangeRates(ctx context.Context) (map[string]*big.Rat, error) {
+	loaded, err := pu.exchangeRateRepository.GetExchangeRates(ctx)
+	if err != nil {
+		return nil, err
+	}
+	rates := make(map[string]*big.Rat, len(loaded))
+	for _, rate := range loaded {
+		value, ok := new(big.Rat).SetString(rate.Rate)
+		if !ok || value.Sign() <= 0 {
+			return nil, fmt.Errorf("invalid exchange rate %q for %s", rate.Rate, rate.Currency)
+		}
+		rates[rate.Currency] = value
+	}
+	return rates, nil
+}
+
+// checkPrices checks that the prices of product fit their currencies, and
+// that its price list does not repeat the store currency.
+func (pu *ProductUsecase) checkPrices(product model.Product) error {
+	if !model.FitsCurrency(product.Price, pu.currency) {
+		return pu.priceError("price", pu.currency)
+	}
+	for currency, price := range product.PriceList {
+		field := "price_list[" + currency + "]"
+		if currency == pu.currency {
+			return apperror.Validation("The price list cannot contain the store currency.", apperror.Field(field, "is the store currency, set price instead"))
+		}
+		if !model.FitsCurrency(price, currency) {
+			return pu.priceError(field, currency)
+		}
+	}
+	return nil
+}
+
+// priceError reports a price with more decimal places than its currency has.
+func (pu *ProductUsecase) priceError(field, currency string) error {
+	digits := model.CurrencyDigits(currency)
+	return apperror.Validation("Invalid price.", apperror.Field(field, fmt.Sprintf("must have at most %d decimal places in %s", digits, currency)))
+}
+
+// productError translates the repository errors of product writes.
+func productError(err error) error {
+	if errors.Is(err, repository.ErrDuplicateSKU) {
+		return ErrSKUExists
+	}
+	return err
+}
+
+func (pu *ProductUsecase) checkCategory(ctx context.Context, id_category *int) error {
+	if id_category == nil {
+		return nil
+	}
+	category, err := pu.categoryRepository.GetCategoryById(ctx, *id_category)
+	if err != nil {
+		return err
+	}
+	if category == nil {
+		return ErrProductCategoryNotFound
+	}
+	return nil
+}
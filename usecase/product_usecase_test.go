@@ -0,0 +1,64 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"product-go-api/model"
+	"product-go-api/repository/memory"
+	"testing"
+)
+
+func TestProductUsecaseCategoryFilter(t *testing.T) {
+	ctx := context.Background()
+	store := memory.NewStore()
+	uc := NewProductUsecase(store.ProductRepository(), store.CategoryRepository(), store.ExchangeRateRepository(), store.Transactor(), model.DefaultCurrency)
+	categoryUC := NewCategoryUsecase(store.CategoryRepository())
+
+	electronics, _ := categoryUC.CreateCategory(ctx, model.Category{Name: "Electronics"})
+	laptops, _ := categoryUC.CreateCategory(ctx, model.Category{Name: "Laptops", ParentID: intPtr(electronics.ID)})
+
+	if _, err := uc.CreateProduct(ctx, model.Product{SKU: "GHOST", Name: "Ghost", Price: 1, CategoryID: intPtr(999)}); !errors.Is(err, ErrCategoryNotFound) {
+		t.Fatalf("unknown category: got %v, want ErrCategoryNotFound", err)
+	}
+
+	tv, err := uc.CreateProduct(ctx, model.Product{SKU: "TV", Name: "TV", Price: 500, CategoryID: intPtr(electronics.ID)})
+	if err != nil {
+		t.Fatalf("create product: %v", err)
+	}
+	uc.CreateProduct(ctx, model.Product{SKU: "NB", Name: "Notebook", Price: 900, CategoryID: intPtr(laptops.ID)})
+	uc.CreateProduct(ctx, model.Product{SKU: "CHAIR", Name: "Chair", Price: 80})
+
+	firstPage := model.PageRequest{Page: 1, Limit: 10}
+	direct, _ := uc.GetProducts(ctx, firstPage, model.ProductFilter{CategoryID: electronics.ID})
+	if len(direct.Items) != 1 || direct.Items[0].ID != tv.ID {
+		t.Fatalf("direct category filter = %+v, want only the TV", direct.Items)
+	}
+	nested, _ := uc.GetProducts(ctx, firstPage, model.ProductFilter{CategoryID: electronics.ID, IncludeSubcategories: true})
+	if len(nested.Items) != 2 {
+		t.Fatalf("subcategory filter = %+v, want 2 products", nested.Items)
+	}
+	byName, _ := uc.GetProducts(ctx, firstPage, model.ProductFilter{Name: "chai"})
+	if len(byName.Items) != 1 {
+		t.Fatalf("name filter = %+v, want the chair", byName.Items)
+	}
+
+	tv.Price = 450
+	updated, err := uc.UpdateProduct(ctx, tv)
+	if err != nil || updated.Price != 450 {
+		t.Fatalf("update = %+v, %v; want price 450", updated, err)
+	}
+
+	if err := uc.DeleteProduct(ctx, tv.ID); err != nil {
+		t.Fatalf("delete: %v", err)
+	}
+	if product, _ := uc.GetProductById(ctx, tv.ID); product != nil {
+		t.Fatalf("deleted product still returned: %+v", product)
+	}
+	if err := uc.DeleteProduct(ctx, tv.ID); !errors.Is(err, ErrProductNotFound) {
+		t.Fatalf("delete twice: got %v, want ErrProductNotFound", err)
+	}
+	// A product trashed after it was read is not found when updated.
+	if _, err := uc.UpdateProduct(ctx, tv); !errors.Is(err, ErrProductNotFound) {
+		t.Fatalf("update of a deleted product: got %v, want ErrProductNotFound", err)
+	}
+}
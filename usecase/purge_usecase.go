@@ -0,0 +1,68 @@
+package usecase
+
+import (
+	"context"
+	"log/slog"
+	"product-go-api/logging"
+	"product-go-api/model"
+	"product-go-api/repository"
+	"time"
+)
+
+// PurgeUsecase permanently deletes the products and users that stayed in the
+// trash longer than the retention window.
+type PurgeUsecase struct {
+	productRepository repository.ProductRepository
+	userRepository    repository.UserRepository
+	retention         time.Duration
+	now               func() time.Time
+}
+
+func NewPurgeUsecase(productRepository repository.ProductRepository, userRepository repository.UserRepository, retention time.Duration) PurgeUsecase {
+	return PurgeUsecase{
+		productRepository: productRepository,
+		userRepository:    userRepository,
+		retention:         retention,
+		now:               time.Now,
+	}
+}
+
+// Purge deletes the items trashed before the retention window and reports how
+// many were removed.
+func (pu *PurgeUsecase) Purge(ctx context.Context) (model.PurgeResult, error) {
+	deletedBefore := pu.now().Add(-pu.retention)
+
+	var result model.PurgeResult
+	var err error
+	if result.Products, err = pu.productRepository.PurgeProducts(ctx, deletedBefore); err != nil {
+		return result, err
+	}
+	if result.Users, err = pu.userRepository.PurgeUsers(ctx, deletedBefore); err != nil {
+		return result, err
+	}
+
+	if result.Products > 0 || result.Users > 0 {
+		logging.FromContext(ctx).InfoContext(ctx, "trash purged",
+			slog.Int64("products", result.Products),
+			slog.Int64("users", result.Users),
+		)
+	}
+	return result, nil
+}
+
+// Run purges the trash every interval until ctx is done.
+func (pu *PurgeUsecase) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if _, err := pu.Purge(ctx); err != nil && ctx.Err() == nil {
+				logging.FromContext(ctx).ErrorContext(ctx, "failed to purge trash", slog.String("error", err.Error()))
+			}
+		}
+	}
+}
@@ -0,0 +1,63 @@
+package usecase
+
+import (
+	"context"
+	"product-go-api/model"
+	"product-go-api/repository/memory"
+	"testing"
+	"time"
+)
+
+func TestPurgeUsecase(t *testing.T) {
+	ctx := context.Background()
+	store := memory.NewStore()
+	products, users := store.ProductRepository(), store.UserRepository()
+	uc := NewPurgeUsecase(products, users, time.Hour)
+
+	lamp := createTestProduct(t, store, "Lamp", 40, 5)
+	chair := createTestProduct(t, store, "Chair", 80, 3)
+	customer := createTestUser(t, store, "ana@example.com")
+	visitor := createTestUser(t, store, "bo@example.com")
+
+	// Users with orders stay in the trash so their orders keep a customer.
+	if err := store.CartRepository().AddItem(ctx, customer, model.CartItemRequest{ProductID: lamp, Quantity: 1}); err != nil {
+		t.Fatalf("add item: %v", err)
+	}
+	if _, err := store.OrderRepository().Checkout(ctx, customer, model.DefaultCurrency); err != nil {
+		t.Fatalf("checkout: %v", err)
+	}
+
+	// Deleting a user gives the stock of their active reservations back.
+	if _, err := store.InventoryRepository().Reserve(ctx, model.Reservation{ProductID: chair, UserID: visitor, Quantity: 2, ExpiresAt: time.Now().Add(time.Hour)}, 10); err != nil {
+		t.Fatalf("reserve: %v", err)
+	}
+
+	products.DeleteProduct(ctx, lamp)
+	users.DeleteUser(ctx, customer)
+	users.DeleteUser(ctx, visitor)
+
+	if stock, _ := store.InventoryRepository().GetStock(ctx, chair); stock.Quantity != 3 {
+		t.Fatalf("stock after deleting the user = %d, want the reservation released", stock.Quantity)
+	}
+
+	if result, err := uc.Purge(ctx); err != nil || result != (model.PurgeResult{}) {
+		t.Fatalf("purge within retention = %+v, %v; want nothing purged", result, err)
+	}
+
+	uc.now = func() time.Time { return time.Now().Add(2 * time.Hour) }
+	result, err := uc.Purge(ctx)
+	if err != nil || result != (model.PurgeResult{Products: 1, Users: 1}) {
+		t.Fatalf("purge after retention = %+v, %v; want 1 product and 1 user", result, err)
+	}
+
+	if page, _ := products.GetDeletedProducts(ctx, model.PageRequest{}); page.Total != 0 {
+		t.Fatalf("product trash after purge = %+v", page)
+	}
+	if product, _ := products.GetProductById(ctx, chair); product == nil {
+		t.Fatal("purge removed a product that was not deleted")
+	}
+	page, _ := users.GetDeletedUsers(ctx, model.PageRequest{})
+	if page.Total != 1 || page.Items[0].ID != customer {
+		t.Fatalf("user trash after purge = %+v, want only the user with orders", page)
+	}
+}
@@ -0,0 +1,200 @@
+package usecase
+
+import (
+	"context"
+	"product-go-api/apperror"
+	"product-go-api/model"
+	"product-go-api/repository"
+)
+
+var (
+	ErrRoleNotFound       = apperror.NotFound("Role not found")
+	ErrRoleExists         = apperror.Conflict("Role already exists.")
+	ErrRoleInUse          = apperror.Conflict("Role is assigned to users and cannot be deleted.")
+	ErrInvalidRoleName    = apperror.Validation("Role name must start with a lowercase letter and contain up to 20 lowercase letters, digits or underscores.", apperror.Field("name", "must match ^[a-z][a-z0-9_]{0,19}$"))
+	ErrUnknownPermission  = apperror.Validation("Unknown permission.", apperror.Field("permissions", "contains an unknown permission"))
+	ErrRoleLockout        = apperror.Conflict("You cannot remove role:manage from your own role.")
+	ErrRoleNotOutranked   = apperror.Forbidden("You can only manage users with a lower role than yours.")
+	ErrRoleAboveRequester = apperror.Forbidden("You cannot assign a role above your own.")
+)
+
+type RoleUsecase struct {
+	repository repository.RoleRepository
+}
+
+func NewRoleUsecase(repository repository.RoleRepository) RoleUsecase {
+	return RoleUsecase{
+		repository: repository,
+	}
+}
+
+func (ru *RoleUsecase) GetRoles(ctx context.Context) ([]model.Role, error) {
+	return ru.repository.GetRoles(ctx)
+}
+
+func (ru *RoleUsecase) GetPermissions(ctx context.Context) ([]model.Permission, error) {
+	return ru.repository.GetPermissions(ctx)
+}
+
+// GetRolePermissions returns the permissions granted to a role, or none when
+// the role does not exist.
+func (ru *RoleUsecase) GetRolePermissions(ctx context.Context, name string) ([]string, error) {
+	return ru.repository.GetRolePermissions(ctx, name)
+}
+
+// GetRoleByName returns the role with its permissions, or nil when it does not
+// exist.
+func (ru *RoleUsecase) GetRoleByName(ctx context.Context, name string) (*model.Role, error) {
+	return ru.repository.GetRoleByName(ctx, name)
+}
+
+func (ru *RoleUsecase) CreateRole(ctx context.Context, role model.Role) (model.Role, error) {
+	if !model.RoleNamePattern.MatchString(role.Name) {
+		return model.Role{}, ErrInvalidRoleName
+	}
+	if err := ru.checkPermissions(ctx, role.Permissions); err != nil {
+		return model.Role{}, err
+	}
+
+	existing, err := ru.repository.GetRoleByName(ctx, role.Name)
+	if err != nil {
+		return model.Role{}, err
+	}
+	if existing != nil {
+		return model.Role{}, ErrRoleExists
+	}
+
+	if _, err := ru.repository.CreateRole(ctx, role); err != nil {
+		return model.Role{}, err
+	}
+	created, err := ru.repository.GetRoleByName(ctx, role.Name)
+	if err != nil {
+		return model.Role{}, err
+	}
+	return *created, nil
+}
+
+// SetRolePermissions replaces the permissions of a role. The requester cannot
+// remove role:manage from their own role, so nobody is locked out of role
+// management.
+func (ru *RoleUsecase) SetRolePermissions(ctx context.Context, requesterRole, name string, permissions []string) (model.Role, error) {
+	role, err := ru.repository.GetRoleByName(ctx, name)
+	if err != nil {
+		return model.Role{}, err
+	}
+	if role == nil {
+		return model.Role{}, ErrRoleNotFound
+	}
+	if err := ru.checkPermissions(ctx, permissions); err != nil {
+		return model.Role{}, err
+	}
+	if name == requesterRole && !containsPermission(permissions, model.PermissionRoleManage) {
+		return model.Role{}, ErrRoleLockout
+	}
+
+	if err := ru.repository.SetRolePermissions(ctx, name, permissions); err != nil {
+		return model.Role{}, err
+	}
+	updated, err := ru.repository.GetRoleByName(ctx, name)
+	if err != nil {
+		return model.Role{}, err
+	}
+	return *updated, nil
+}
+
+func (ru *RoleUsecase) DeleteRole(ctx context.Context, name string) error {
+	role, err := ru.repository.GetRoleByName(ctx, name)
+	if err != nil {
+		return err
+	}
+	if role == nil {
+		return ErrRoleNotFound
+	}
+
+	inUse, err := ru.repository.RoleInUse(ctx, name)
+	if err != nil {
+		return err
+	}
+	if inUse {
+		return ErrRoleInUse
+	}
+	return ru.repository.DeleteRole(ctx, name)
+}
+
+// CheckOutranks returns ErrRoleNotOutranked unless requesterRole has a higher
+// level than targetRole. It guards deleting users and changing their role.
+func (ru *RoleUsecase) CheckOutranks(ctx context.Context, requesterRole, targetRole string) error {
+	requester, target, err := ru.rolePair(ctx, requesterRole, targetRole)
+	if err != nil {
+		return err
+	}
+	if target != nil && requester.Level <= target.Level {
+		return ErrRoleNotOutranked
+	}
+	return nil
+}
+
+// CheckAssignable returns ErrRoleNotFound when newRole does not exist and,
+// unless manageAll is set, ErrRoleAboveRequester when newRole has a higher
+// level than requesterRole.
+func (ru *RoleUsecase) CheckAssignable(ctx context.Context, requesterRole, newRole string, manageAll bool) error {
+	requester, role, err := ru.rolePair(ctx, requesterRole, newRole)
+	if err != nil {
+		return err
+	}
+	if role == nil {
+		return ErrRoleNotFound
+	}
+	if !manageAll && role.Level > requester.Level {
+		return ErrRoleAboveRequester
+	}
+	return nil
+}
+
+// rolePair loads the requester role, which must exist, and another role,
+// which is nil when it does not exist.
+func (ru *RoleUsecase) rolePair(ctx context.Context, requesterRole, otherRole string) (*model.Role, *model.Role, error) {
+	requester, err := ru.repository.GetRoleByName(ctx, requesterRole)
+	if err != nil {
+		return nil, nil, err
+	}
+	if requester == nil {
+		return nil, nil, ErrRoleNotFound
+	}
+
+	other, err := ru.repository.GetRoleByName(ctx, otherRole)
+	if err != nil {
+		return nil, nil, err
+	}
+	return requester, other, nil
+}
+
+func (ru *RoleUsecase) checkPermissions(ctx context.Context, permissions []string) error {
+	known, err := ru.repository.GetPermissions(ctx)
+	if err != nil {
+		return err
+	}
+
+	for _, name := range permissions {
+		found := false
+		for _, permission := range known {
+			if permission.Name == name {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return ErrUnknownPermission
+		}
+	}
+	return nil
+}
+
+func containsPermission(permissions []string, permission string) bool {
+	for _, p := range permissions {
+		if p == permission {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,91 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"product-go-api/model"
+	"product-go-api/repository/memory"
+	"testing"
+)
+
+func TestRoleUsecaseHierarchy(t *testing.T) {
+	ctx := context.Background()
+	store := memory.NewStore()
+	uc := NewRoleUsecase(store.RoleRepository())
+
+	if _, err := uc.CreateRole(ctx, model.Role{Name: "catalog_manager", Level: 10}); err != nil {
+		t.Fatalf("create role: %v", err)
+	}
+
+	cases := []struct {
+		requester, target string
+		want              error
+	}{
+		{"admin", "user", nil},
+		{"admin", "catalog_manager", nil},
+		{"admin", "admin", ErrRoleNotOutranked},
+		{"admin", "super_admin", ErrRoleNotOutranked},
+		{"catalog_manager", "user", nil},
+		{"user", "catalog_manager", ErrRoleNotOutranked},
+	}
+	for _, c := range cases {
+		if err := uc.CheckOutranks(ctx, c.requester, c.target); !errors.Is(err, c.want) {
+			t.Errorf("CheckOutranks(%s, %s) = %v, want %v", c.requester, c.target, err, c.want)
+		}
+	}
+
+	if err := uc.CheckAssignable(ctx, "admin", "admin", false); err != nil {
+		t.Fatalf("admin assigns admin: %v", err)
+	}
+	if err := uc.CheckAssignable(ctx, "admin", "super_admin", false); !errors.Is(err, ErrRoleAboveRequester) {
+		t.Fatalf("admin assigns super_admin: got %v, want ErrRoleAboveRequester", err)
+	}
+	if err := uc.CheckAssignable(ctx, "admin", "super_admin", true); err != nil {
+		t.Fatalf("assign with manage all: %v", err)
+	}
+	if err := uc.CheckAssignable(ctx, "super_admin", "ghost", true); !errors.Is(err, ErrRoleNotFound) {
+		t.Fatalf("assign unknown role: got %v, want ErrRoleNotFound", err)
+	}
+}
+
+func TestRoleUsecasePermissions(t *testing.T) {
+	ctx := context.Background()
+	store := memory.NewStore()
+	uc := NewRoleUsecase(store.RoleRepository())
+
+	permissions, err := uc.GetRolePermissions(ctx, "admin")
+	if err != nil {
+		t.Fatalf("admin permissions: %v", err)
+	}
+	for _, permission := range permissions {
+		if permission == model.PermissionRoleManage || permission == model.PermissionUserManageAll {
+			t.Fatalf("admin has %s", permission)
+		}
+	}
+
+	if _, err := uc.CreateRole(ctx, model.Role{Name: "auditor", Permissions: []string{"fly"}}); !errors.Is(err, ErrUnknownPermission) {
+		t.Fatalf("unknown permission: got %v, want ErrUnknownPermission", err)
+	}
+	if _, err := uc.CreateRole(ctx, model.Role{Name: "Auditor"}); !errors.Is(err, ErrInvalidRoleName) {
+		t.Fatalf("invalid name: got %v, want ErrInvalidRoleName", err)
+	}
+
+	role, err := uc.SetRolePermissions(ctx, "super_admin", "user", []string{model.PermissionStockRead, model.PermissionStockRead})
+	if err != nil {
+		t.Fatalf("set permissions: %v", err)
+	}
+	if len(role.Permissions) != 1 || role.Permissions[0] != model.PermissionStockRead {
+		t.Fatalf("user permissions = %v, want [%s]", role.Permissions, model.PermissionStockRead)
+	}
+	if _, err := uc.SetRolePermissions(ctx, "super_admin", "super_admin", nil); !errors.Is(err, ErrRoleLockout) {
+		t.Fatalf("drop own role:manage: got %v, want ErrRoleLockout", err)
+	}
+
+	createTestUser(t, store, "ana@example.com")
+	if err := uc.DeleteRole(ctx, "user"); !errors.Is(err, ErrRoleInUse) {
+		t.Fatalf("delete role in use: got %v, want ErrRoleInUse", err)
+	}
+	if err := uc.DeleteRole(ctx, "ghost"); !errors.Is(err, ErrRoleNotFound) {
+		t.Fatalf("delete unknown role: got %v, want ErrRoleNotFound", err)
+	}
+}
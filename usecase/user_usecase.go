@@ -1,33 +1,54 @@
 package usecase
 
 import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"log/slog"
+	"product-go-api/apperror"
+	"product-go-api/config"
+	"product-go-api/logging"
 	"product-go-api/model"
 	"product-go-api/repository"
 
-	"os"
 	"time"
 
 	"github.com/golang-jwt/jwt/v5"
-	"github.com/joho/godotenv"
 	"golang.org/x/crypto/bcrypt"
 )
 
+var (
+	ErrInvalidRefreshToken = apperror.Unauthorized("Invalid refresh token")
+	ErrRefreshTokenReused  = apperror.Unauthorized("Invalid refresh token")
+	ErrInvalidCredentials  = apperror.Unauthorized("Invalid email or password")
+	ErrUserNotFound        = apperror.NotFound("User not found")
+	ErrUserNotInTrash      = apperror.NotFound("User not found in the trash")
+	ErrEmailTaken          = apperror.Conflict("A user with this email already exists").Wrap(repository.ErrDuplicateEmail)
+)
+
 type UserUsecase struct {
-	repository repository.UserRepository
+	repository      repository.UserRepository
+	tokenRepository repository.TokenRepository
+	jwtConfig       config.JWTConfig
 }
 
-func NewUserUsecase(repository repository.UserRepository) UserUsecase {
+func NewUserUsecase(repository repository.UserRepository, tokenRepository repository.TokenRepository, jwtConfig config.JWTConfig) UserUsecase {
 	return UserUsecase{
-		repository: repository,
+		repository:      repository,
+		tokenRepository: tokenRepository,
+		jwtConfig:       jwtConfig,
 	}
 }
 
-func (uu *UserUsecase) GetUsers(page, limit int, name string) ([]model.User, error) {
-	return uu.repository.GetUsers(page, limit, name)
+func (uu *UserUsecase) GetUsers(ctx context.Context, page model.PageRequest, name string) (model.Page[model.User], error) {
+	return uu.repository.GetUsers(ctx, page, name)
 }
 
-func (uu *UserUsecase) CreateUser(user model.User) (model.User, error) {
-	existingUser, err := uu.repository.GetUserByEmail(user.Email)
+func (uu *UserUsecase) CreateUser(ctx context.Context, user model.User) (model.User, error) {
+	existingUser, err := uu.repository.GetUserByEmail(ctx, user.Email)
 
 	if err != nil {
 		return model.User{}, err
@@ -37,46 +58,210 @@ func (uu *UserUsecase) CreateUser(user model.User) (model.User, error) {
 		return *existingUser, nil
 	}
 
-	userId, err := uu.repository.CreateUser(user)
+	userId, err := uu.repository.CreateUser(ctx, user)
 	if err != nil {
-		return model.User{}, err
+		return model.User{}, userError(err)
 	}
 
 	user.ID = userId
 	return user, nil
 }
 
-func (uu *UserUsecase) GetUserByEmail(req model.LoginRequest) (string, error) {
-	godotenv.Load()
-	var jwtKey = []byte(os.Getenv("JWT_SECRET_KEY"))
-	user, err := uu.repository.GetUserByEmail(req.Email)
+func (uu *UserUsecase) GetUserByEmail(ctx context.Context, req model.LoginRequest) (*model.TokenPair, error) {
+	user, err := uu.repository.GetUserByEmail(ctx, req.Email)
 
-	if err != nil || user == nil {
-		return "", err
+	if err != nil {
+		return nil, err
+	}
+
+	if user == nil {
+		return nil, ErrInvalidCredentials
 	}
 
 	if err := bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(req.Password)); err != nil {
-		return "", err
+		if errors.Is(err, bcrypt.ErrMismatchedHashAndPassword) {
+			return nil, ErrInvalidCredentials
+		}
+		return nil, err
+	}
+
+	familyID, err := randomToken(16)
+	if err != nil {
+		return nil, err
+	}
+
+	return uu.issueTokens(ctx, *user, familyID)
+}
+
+// RefreshToken exchanges a refresh token for a new token pair. Each refresh
+// token can be used once; presenting a used token again revokes its whole
+// family, since it means the token was leaked.
+func (uu *UserUsecase) RefreshToken(ctx context.Context, refreshToken string) (*model.TokenPair, error) {
+	tokenHash := hashToken(refreshToken)
+
+	stored, err := uu.tokenRepository.UseRefreshToken(ctx, tokenHash)
+	if err != nil {
+		return nil, err
+	}
+
+	if stored == nil {
+		previous, err := uu.tokenRepository.GetRefreshTokenByHash(ctx, tokenHash)
+		if err != nil {
+			return nil, err
+		}
+		if previous == nil || previous.ExpiresAt.Before(time.Now()) {
+			return nil, ErrInvalidRefreshToken
+		}
+		if err := uu.tokenRepository.RevokeFamily(ctx, previous.FamilyID); err != nil {
+			return nil, err
+		}
+		logging.FromContext(ctx).WarnContext(ctx, "refresh token reused, token family revoked",
+			slog.Int("token_user_id", previous.UserID),
+			slog.String("family_id", previous.FamilyID),
+		)
+		return nil, ErrRefreshTokenReused
+	}
+
+	user, err := uu.repository.GetUserById(ctx, stored.UserID)
+	if err != nil {
+		return nil, err
+	}
+	if user == nil {
+		return nil, ErrInvalidRefreshToken
+	}
+
+	if err := uu.tokenRepository.RevokeAccessToken(ctx, stored.AccessJTI, stored.AccessExpiresAt); err != nil {
+		return nil, err
+	}
+
+	return uu.issueTokens(ctx, *user, stored.FamilyID)
+}
+
+// Logout revokes the access token identified by jti and, when given, the
+// family of the refresh token. The refresh token must belong to id_user, the
+// authenticated user, so nobody can sign another user out; nothing is
+// revoked when it does not.
+func (uu *UserUsecase) Logout(ctx context.Context, id_user int, jti string, accessExpiresAt time.Time, refreshToken string) error {
+	var stored *model.RefreshToken
+	if refreshToken != "" {
+		var err error
+		stored, err = uu.tokenRepository.GetRefreshTokenByHash(ctx, hashToken(refreshToken))
+		if err != nil {
+			return err
+		}
+		if stored == nil || stored.UserID != id_user {
+			return ErrInvalidRefreshToken
+		}
+	}
+
+	if jti != "" {
+		if err := uu.tokenRepository.RevokeAccessToken(ctx, jti, accessExpiresAt); err != nil {
+			return err
+		}
+	}
+
+	if stored == nil {
+		return nil
+	}
+	return uu.tokenRepository.RevokeFamily(ctx, stored.FamilyID)
+}
+
+func (uu *UserUsecase) IsAccessTokenRevoked(ctx context.Context, jti string) (bool, error) {
+	return uu.tokenRepository.IsAccessTokenRevoked(ctx, jti)
+}
+
+// DeleteExpiredTokens removes the tokens that can no longer be used, so the
+// token tables do not grow without bound.
+func (uu *UserUsecase) DeleteExpiredTokens(ctx context.Context) (int64, error) {
+	deleted, err := uu.tokenRepository.DeleteExpired(ctx)
+	if deleted > 0 {
+		logging.FromContext(ctx).InfoContext(ctx, "expired tokens deleted", slog.Int64("tokens", deleted))
 	}
+	return deleted, err
+}
+
+// RunTokenCleanup deletes the expired tokens every interval until ctx is done.
+func (uu *UserUsecase) RunTokenCleanup(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if _, err := uu.DeleteExpiredTokens(ctx); err != nil && ctx.Err() == nil {
+				logging.FromContext(ctx).ErrorContext(ctx, "failed to delete expired tokens", slog.String("error", err.Error()))
+			}
+		}
+	}
+}
+
+func (uu *UserUsecase) issueTokens(ctx context.Context, user model.User, familyID string) (*model.TokenPair, error) {
+	var jwtKey = []byte(uu.jwtConfig.Secret)
+
+	jti, err := randomToken(16)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	accessExpiresAt := now.Add(uu.jwtConfig.AccessTTL)
 
 	token := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
 		"id":    user.ID,
 		"email": user.Email,
 		"role":  user.Role,
-		"exp":   time.Now().Add(time.Hour * 2).Unix(),
+		"jti":   jti,
+		"iat":   now.Unix(),
+		"exp":   accessExpiresAt.Unix(),
 	})
 
 	tokenString, err := token.SignedString(jwtKey)
 
 	if err != nil {
+		return nil, err
+	}
+
+	refreshToken, err := randomToken(32)
+	if err != nil {
+		return nil, err
+	}
+
+	err = uu.tokenRepository.CreateRefreshToken(ctx, model.RefreshToken{
+		UserID:          user.ID,
+		TokenHash:       hashToken(refreshToken),
+		FamilyID:        familyID,
+		AccessJTI:       jti,
+		AccessExpiresAt: accessExpiresAt,
+		ExpiresAt:       now.Add(uu.jwtConfig.RefreshTTL),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &model.TokenPair{
+		AccessToken:  tokenString,
+		RefreshToken: refreshToken,
+		ExpiresIn:    int(uu.jwtConfig.AccessTTL.Seconds()),
+	}, nil
+}
+
+func randomToken(size int) (string, error) {
+	b := make([]byte, size)
+	if _, err := rand.Read(b); err != nil {
 		return "", err
 	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
 
-	return tokenString, nil
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
 }
 
-func (uu *UserUsecase) GetUserById(id_user int) (*model.User, error) {
-	user, err := uu.repository.GetUserById(id_user)
+func (uu *UserUsecase) GetUserById(ctx context.Context, id_user int) (*model.User, error) {
+	user, err := uu.repository.GetUserById(ctx, id_user)
 	if err != nil {
 		return nil, err
 	}
@@ -84,18 +269,46 @@ func (uu *UserUsecase) GetUserById(id_user int) (*model.User, error) {
 	return user, nil
 }
 
-func (uu *UserUsecase) DeleteUser(id_user int) error {
-	err := uu.repository.DeleteUser(id_user)
+func (uu *UserUsecase) DeleteUser(ctx context.Context, id_user int) error {
+	err := uu.repository.DeleteUser(ctx, id_user)
 	if err != nil {
 		return err
 	}
 	return nil
 }
 
-func (uu *UserUsecase) UpdateUser(user model.User) (model.User, error) {
-	updatedUser, err := uu.repository.UpdateUser(user)
+func (uu *UserUsecase) UpdateUser(ctx context.Context, user model.User) (model.User, error) {
+	updatedUser, err := uu.repository.UpdateUser(ctx, user)
 	if err != nil {
-		return model.User{}, err
+		return model.User{}, userError(err)
 	}
 	return *updatedUser, nil
 }
+
+// userError translates the repository errors of user writes. Emails of users
+// in the trash stay taken until they are purged.
+func userError(err error) error {
+	if errors.Is(err, repository.ErrDuplicateEmail) {
+		return ErrEmailTaken
+	}
+	return err
+}
+
+func (uu *UserUsecase) GetDeletedUsers(ctx context.Context, page model.PageRequest) (model.Page[model.User], error) {
+	return uu.repository.GetDeletedUsers(ctx, page)
+}
+
+func (uu *UserUsecase) GetDeletedUserById(ctx context.Context, id_user int) (*model.User, error) {
+	return uu.repository.GetDeletedUserById(ctx, id_user)
+}
+
+func (uu *UserUsecase) RestoreUser(ctx context.Context, id_user int) (model.User, error) {
+	user, err := uu.repository.RestoreUser(ctx, id_user)
+	if err != nil {
+		return model.User{}, err
+	}
+	if user == nil {
+		return model.User{}, ErrUserNotInTrash
+	}
+	return *user, nil
+}
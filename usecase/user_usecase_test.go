@@ -0,0 +1,177 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"product-go-api/config"
+	"product-go-api/model"
+	"product-go-api/repository/memory"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+func newTestUserUsecase(store *memory.Store) UserUsecase {
+	return NewUserUsecase(store.UserRepository(), store.TokenRepository(), config.JWTConfig{
+		Secret:     "test-secret",
+		AccessTTL:  time.Minute,
+		RefreshTTL: time.Hour,
+	})
+}
+
+func TestUserUsecaseLogin(t *testing.T) {
+	ctx := context.Background()
+	store := memory.NewStore()
+	uc := newTestUserUsecase(store)
+	createTestUser(t, store, "ana@example.com")
+
+	tokens, err := uc.GetUserByEmail(ctx, model.LoginRequest{Email: "ana@example.com", Password: "secret123"})
+	if err != nil {
+		t.Fatalf("login: %v", err)
+	}
+	if tokens.AccessToken == "" || tokens.RefreshToken == "" || tokens.ExpiresIn != 60 {
+		t.Fatalf("unexpected token pair: %+v", tokens)
+	}
+
+	if _, err := uc.GetUserByEmail(ctx, model.LoginRequest{Email: "ana@example.com", Password: "wrong"}); err == nil {
+		t.Fatal("login with a wrong password succeeded")
+	}
+	if _, err := uc.GetUserByEmail(ctx, model.LoginRequest{Email: "nobody@example.com", Password: "secret123"}); !errors.Is(err, ErrInvalidCredentials) {
+		t.Fatalf("unknown email: got %v, want ErrInvalidCredentials", err)
+	}
+}
+
+func TestUserUsecaseCreateUserReturnsExisting(t *testing.T) {
+	ctx := context.Background()
+	store := memory.NewStore()
+	uc := newTestUserUsecase(store)
+
+	first, err := uc.CreateUser(ctx, model.User{Username: "ana", Email: "ana@example.com", Password: "secret123"})
+	if err != nil {
+		t.Fatalf("create user: %v", err)
+	}
+	second, err := uc.CreateUser(ctx, model.User{Username: "other", Email: "ana@example.com", Password: "secret123"})
+	if err != nil {
+		t.Fatalf("create duplicate user: %v", err)
+	}
+	if second.ID != first.ID {
+		t.Fatalf("duplicate email created user %d, want existing user %d", second.ID, first.ID)
+	}
+}
+
+func TestUserUsecaseRefreshTokenRotation(t *testing.T) {
+	ctx := context.Background()
+	store := memory.NewStore()
+	uc := newTestUserUsecase(store)
+	createTestUser(t, store, "ana@example.com")
+
+	login, err := uc.GetUserByEmail(ctx, model.LoginRequest{Email: "ana@example.com", Password: "secret123"})
+	if err != nil {
+		t.Fatalf("login: %v", err)
+	}
+
+	rotated, err := uc.RefreshToken(ctx, login.RefreshToken)
+	if err != nil {
+		t.Fatalf("refresh: %v", err)
+	}
+	if rotated.RefreshToken == login.RefreshToken {
+		t.Fatal("refresh token was not rotated")
+	}
+
+	if _, err := uc.RefreshToken(ctx, login.RefreshToken); !errors.Is(err, ErrRefreshTokenReused) {
+		t.Fatalf("reused token: got %v, want ErrRefreshTokenReused", err)
+	}
+	// Reuse revoked the whole family, including the token issued by rotation.
+	if _, err := uc.RefreshToken(ctx, rotated.RefreshToken); !errors.Is(err, ErrRefreshTokenReused) {
+		t.Fatalf("token from a revoked family: got %v, want ErrRefreshTokenReused", err)
+	}
+	if _, err := uc.RefreshToken(ctx, "not-a-token"); !errors.Is(err, ErrInvalidRefreshToken) {
+		t.Fatalf("unknown token: got %v, want ErrInvalidRefreshToken", err)
+	}
+}
+
+func TestUserUsecaseLogoutRevokesAccessToken(t *testing.T) {
+	ctx := context.Background()
+	store := memory.NewStore()
+	uc := newTestUserUsecase(store)
+
+	if err := uc.Logout(ctx, 1, "jti-1", time.Now().Add(time.Minute), ""); err != nil {
+		t.Fatalf("logout: %v", err)
+	}
+	revoked, err := uc.IsAccessTokenRevoked(ctx, "jti-1")
+	if err != nil || !revoked {
+		t.Fatalf("IsAccessTokenRevoked = %v, %v; want true", revoked, err)
+	}
+	if err := uc.Logout(ctx, 1, "jti-2", time.Now().Add(time.Minute), "not-a-token"); !errors.Is(err, ErrInvalidRefreshToken) {
+		t.Fatalf("logout with unknown refresh token: got %v, want ErrInvalidRefreshToken", err)
+	}
+
+	// The refresh token of another user is rejected and stays usable.
+	id_ana := createTestUser(t, store, "ana@example.com")
+	login, err := uc.GetUserByEmail(ctx, model.LoginRequest{Email: "ana@example.com", Password: "secret123"})
+	if err != nil {
+		t.Fatalf("login: %v", err)
+	}
+	if err := uc.Logout(ctx, id_ana+1, "jti-3", time.Now().Add(time.Minute), login.RefreshToken); !errors.Is(err, ErrInvalidRefreshToken) {
+		t.Fatalf("logout with another user's refresh token: got %v, want ErrInvalidRefreshToken", err)
+	}
+	if _, err := uc.RefreshToken(ctx, login.RefreshToken); err != nil {
+		t.Fatalf("refresh after a rejected logout: %v", err)
+	}
+}
+
+func TestUserUsecaseDeleteUserRevokesAccessToken(t *testing.T) {
+	ctx := context.Background()
+	store := memory.NewStore()
+	uc := newTestUserUsecase(store)
+	id_user := createTestUser(t, store, "ana@example.com")
+
+	login, err := uc.GetUserByEmail(ctx, model.LoginRequest{Email: "ana@example.com", Password: "secret123"})
+	if err != nil {
+		t.Fatalf("login: %v", err)
+	}
+	claims := jwt.MapClaims{}
+	if _, _, err := jwt.NewParser().ParseUnverified(login.AccessToken, claims); err != nil {
+		t.Fatalf("parse access token: %v", err)
+	}
+
+	if err := uc.DeleteUser(ctx, id_user); err != nil {
+		t.Fatalf("delete user: %v", err)
+	}
+	revoked, err := uc.IsAccessTokenRevoked(ctx, claims["jti"].(string))
+	if err != nil || !revoked {
+		t.Fatalf("IsAccessTokenRevoked after delete = %v, %v; want true", revoked, err)
+	}
+	if _, err := uc.RefreshToken(ctx, login.RefreshToken); !errors.Is(err, ErrInvalidRefreshToken) {
+		t.Fatalf("refresh after delete: got %v, want ErrInvalidRefreshToken", err)
+	}
+}
+
+func TestUserUsecaseDeleteExpiredTokens(t *testing.T) {
+	ctx := context.Background()
+	store := memory.NewStore()
+	uc := newTestUserUsecase(store)
+	id_user := createTestUser(t, store, "ana@example.com")
+	tokens := store.TokenRepository()
+	past, future := time.Now().Add(-time.Minute), time.Now().Add(time.Minute)
+
+	tokens.RevokeAccessToken(ctx, "expired", past)
+	tokens.RevokeAccessToken(ctx, "live", future)
+	tokens.CreateRefreshToken(ctx, model.RefreshToken{UserID: id_user, TokenHash: "old", AccessJTI: "a", AccessExpiresAt: past, ExpiresAt: past})
+	tokens.CreateRefreshToken(ctx, model.RefreshToken{UserID: id_user, TokenHash: "new", AccessJTI: "b", AccessExpiresAt: future, ExpiresAt: future})
+
+	deleted, err := uc.DeleteExpiredTokens(ctx)
+	if err != nil || deleted != 2 {
+		t.Fatalf("DeleteExpiredTokens = %d, %v; want 2", deleted, err)
+	}
+	if revoked, _ := uc.IsAccessTokenRevoked(ctx, "live"); !revoked {
+		t.Fatal("an unexpired revoked token was deleted")
+	}
+	if token, _ := tokens.GetRefreshTokenByHash(ctx, "old"); token != nil {
+		t.Fatalf("expired refresh token was kept: %+v", token)
+	}
+	if token, _ := tokens.GetRefreshTokenByHash(ctx, "new"); token == nil {
+		t.Fatal("an unexpired refresh token was deleted")
+	}
+}